@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+)
+
+// SetActualText specifies whether the text printed by CellFormat() and the
+// methods built on it, such as Cell(), MultiCell() and Write(), is marked with
+// its logical text in an ActualText span. Viewers use the logical text when
+// text is copied or searched, so that the wide spacing of justified lines
+// does not introduce extra spaces, and the hyphens shown where MultiCell() or
+// Write() break a word at a soft hyphen do not become part of the word. The
+// logical text of a line that ends at a space includes the space, which keeps
+// the last word of one line apart from the first word of the next. Marking
+// is off by default. ActualText spans require PDF version 1.5, which is
+// declared in documents that contain them.
+func (f *Fpdf) SetActualText(on bool) {
+	f.actualText = on
+}
+
+// BeginActualText begins an ActualText span whose content is replaced by
+// textStr, a UTF-8 string, when text is extracted from the document. This
+// identifies the logical text of content whose glyphs do not convey it
+// reliably, such as text placed character by character with TextOnPath() or
+// a word drawn as vector graphics. Each call must be balanced with a call to
+// EndActualText() on the same page. See SetActualText() for marking the text
+// printed by CellFormat() automatically.
+func (f *Fpdf) BeginActualText(textStr string) {
+	if f.err != nil {
+		return
+	}
+	f.out(f.actualTextSpan(textStr))
+	f.actualTextNest++
+}
+
+// EndActualText ends the ActualText span begun with the most recent call to
+// BeginActualText().
+func (f *Fpdf) EndActualText() {
+	if f.err != nil {
+		return
+	}
+	if f.actualTextNest == 0 {
+		f.err = fmt.Errorf("EndActualText called without matching BeginActualText")
+		return
+	}
+	f.out("EMC")
+	f.actualTextNest--
+}
+
+// actualTextSpan returns the operator that begins an ActualText span with
+// logical text textStr, given in UTF-8
+func (f *Fpdf) actualTextSpan(textStr string) string {
+	f.requirePDFVersion("1.5", "ActualText")
+	return "/Span <</ActualText (" + f.escape(textEncode(textStr)) + ")>> BDC"
+}
+
+// cellText returns the logical text of txtStr, as printed by CellFormat() in
+// the current font, in UTF-8
+func (f *Fpdf) cellText(txtStr string) string {
+	if f.currentFont.Enc != nil {
+		// Symbolic fonts are given Unicode text
+		return txtStr
+	}
+	return cp1252Decode(txtStr)
+}
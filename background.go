@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+	"math"
+)
+
+// backgroundType describes an image that is drawn beneath the content of each
+// page
+type backgroundType struct {
+	info    *ImageInfoType
+	opacity float64
+	fitStr  string
+}
+
+// BackgroundImage sets an image that is drawn beneath all other content of
+// the current page and of each page that is subsequently added, for example a
+// company logo used as a watermark. The image is drawn with the specified
+// opacity, from 0.0 (invisible) to 1.0 (fully opaque), by means of a graphics
+// state that does not affect the alpha value set with SetAlpha(). It is marked
+// as an artifact so that it is not mistaken for document content.
+//
+// imageNameStr is the name of an image registered with one of the
+// RegisterImage methods or the name of an image file; see ImageOptions(). When
+// this method is called with an empty imageNameStr, the background is removed
+// from the current page and is not drawn on pages that are added afterward.
+// Similarly, a background that is set while a page is open replaces the
+// background of that page.
+//
+// fitStr specifies how the image is arranged on the page. "center" places it
+// at its natural size in the middle of the page, "tile" repeats it at its
+// natural size from the upper left corner of the page until the page is
+// covered, and "stretch" scales it to cover the page exactly.
+func (f *Fpdf) BackgroundImage(imageNameStr string, opacity float64, fitStr string) {
+	if f.err != nil {
+		return
+	}
+	if imageNameStr == "" {
+		f.background = nil
+	} else {
+		if opacity < 0 || opacity > 1 {
+			f.err = fmt.Errorf("background opacity (0.0 - 1.0) is out of range: %.3f", opacity)
+			return
+		}
+		switch fitStr {
+		case "center", "tile", "stretch":
+		default:
+			f.err = fmt.Errorf("unrecognized background fit \"%s\"", fitStr)
+			return
+		}
+		info := f.RegisterImageOptions(imageNameStr, ImageOptions{})
+		if f.err != nil {
+			return
+		}
+		f.background = &backgroundType{info: info, opacity: opacity, fitStr: fitStr}
+	}
+	if f.page > 0 {
+		f.putbackground()
+	}
+}
+
+// putbackground replaces the background at the start of the current page
+// with the current background image, if any. Content already written to the
+// page remains on top of it.
+func (f *Fpdf) putbackground() {
+	page := f.pages[f.page]
+	var opsStr string
+	if f.background != nil {
+		opsStr = f.backgroundOps() + "\n"
+	}
+	content := append([]byte(opsStr), page.Bytes()[f.backgroundLen:]...)
+	page.Reset()
+	page.Write(content)
+	f.backgroundLen = len(opsStr)
+}
+
+// backgroundOps returns the operators that draw the background image on the
+// current page
+func (f *Fpdf) backgroundOps() string {
+	bg := f.background
+	info := bg.info
+	var s fmtBuffer
+	s.printf("/Artifact <</Type /Pagination /Subtype /Watermark>> BDC q /GS%d gs ",
+		f.blendState(bg.opacity, bg.opacity, "Normal"))
+	wd, ht := info.Width(), info.Height()
+	switch bg.fitStr {
+	case "center":
+		s.printf("%.5f 0 0 %.5f %.5f %.5f cm /I%d Do ", wd*f.k, ht*f.k,
+			(f.w-wd)/2*f.k, (f.h-ht)/2*f.k, info.i)
+	case "tile":
+		s.printf(f.precision("0 0 %.2f %.2f re W n "), f.wPt, f.hPt)
+		for y := 0.0; y < f.h; y += ht {
+			for x := 0.0; x < f.w; x += wd {
+				s.printf("q %.5f 0 0 %.5f %.5f %.5f cm /I%d Do Q ", wd*f.k, ht*f.k,
+					x*f.k, (f.h-y-ht)*f.k, info.i)
+			}
+		}
+	case "stretch":
+		wd, ht = f.w, f.h
+		s.printf(f.precision("%.2f 0 0 %.2f 0 0 cm /I%d Do "), f.wPt, f.hPt, info.i)
+	}
+	s.printf("Q EMC")
+	info.placedWd = math.Max(info.placedWd, wd*f.k)
+	info.placedHt = math.Max(info.placedHt, ht*f.k)
+	return s.String()
+}
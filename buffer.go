@@ -0,0 +1,232 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// docFlushSize is the size in bytes to which the assembled document is
+// allowed to grow in memory before it is passed on, if it is not retained
+const docFlushSize = 1 << 20
+
+// spillType locates page content that has been moved to the spill file
+type spillType struct {
+	offset int64
+	length int
+}
+
+// pageSpillMap locates the content of page buffers that have been moved to
+// the spill file
+type pageSpillMap map[*bytes.Buffer]spillType
+
+// SetBufferStrategy specifies how the document is held while it is generated
+// and output, trading memory for speed. strategyStr is one of the following:
+//
+// "memory", the default, keeps the content of every page and the assembled
+// document in memory until the document is sent with Output(). This is the
+// fastest strategy, but a document of many thousands of pages can require
+// a great deal of memory.
+//
+// "file" moves the content of each page to a temporary file once the page
+// and the page after it are complete, and assembles the document in a second
+// temporary file from which Output() copies it. Only the most recent pages
+// and the objects being written are kept in memory, which suits very large
+// reports. The temporary files are created in the default directory for
+// temporary files (see os.TempDir()). The page file is removed when the
+// document is closed and the document file when the document is sent with
+// Output() or retrieved with GetBytes().
+//
+// "stream" keeps the page content in memory, but writes the assembled document
+// directly to the writer passed to Output(), or to the file created by
+// OutputFileAndClose(), without holding it in memory. This applies only if
+// the document is still open when it is output; once it has been closed, for
+// example with Close() or GetBytes(), it is held in memory. Since the writer
+// receives the document as it is assembled, a writer may be left with an
+// incomplete document if an error occurs; OutputFileAndClose() removes the
+// incomplete file in that case.
+//
+// Linearized documents (see SetLinearized()) are rearranged once they are
+// complete, so they are assembled in memory with any strategy; the content
+// of pages is still moved to a temporary file with "file". The strategy can
+// be changed at any time before the document is closed. Page content that
+// has already been moved to a file stays there.
+func (f *Fpdf) SetBufferStrategy(strategyStr string) {
+	if f.err != nil {
+		return
+	}
+	switch strategyStr {
+	case "memory", "file", "stream":
+	default:
+		f.err = fmt.Errorf("unrecognized buffer strategy \"%s\"", strategyStr)
+		return
+	}
+	if f.state == 3 {
+		f.closedError()
+		return
+	}
+	f.bufferStrategy = strategyStr
+}
+
+// spillPages moves the content of the page that was current when the
+// previous page was begun to the spill file, and marks the page that is
+// being left as the next to be moved. The page that was most recently left
+// stays in memory because content, such as the closing border of a
+// MultiCell() block, can still be added to it after a page break.
+func (f *Fpdf) spillPages() {
+	if f.bufferStrategy != "file" {
+		f.spillNext = nil
+		return
+	}
+	if buf := f.spillNext; buf != nil {
+		if _, ok := f.spillMap[buf]; !ok {
+			f.spill(buf)
+		}
+	}
+	f.spillNext = nil
+	if f.page > 0 {
+		f.spillNext = f.pages[f.page]
+	}
+}
+
+// spill moves the content of buf to the end of the spill file
+func (f *Fpdf) spill(buf *bytes.Buffer) {
+	if f.spillFile == nil {
+		file, err := ioutil.TempFile("", "gofpdf")
+		if err != nil {
+			f.err = err
+			return
+		}
+		f.spillFile = file
+		f.spillMap = make(pageSpillMap)
+	}
+	n, err := f.spillFile.Write(buf.Bytes())
+	if err != nil {
+		f.err = err
+		return
+	}
+	f.spillMap[buf] = spillType{offset: f.spillLen, length: n}
+	f.spillLen += int64(n)
+	// Release the memory held by the buffer while keeping its identity
+	*buf = bytes.Buffer{}
+}
+
+// pageContent returns the content of page n, reading it from the spill file
+// if it has been moved there
+func (f *Fpdf) pageContent(n int) []byte {
+	buf := f.pages[n]
+	sp, ok := f.spillMap[buf]
+	if !ok {
+		return buf.Bytes()
+	}
+	data := make([]byte, sp.length)
+	if _, err := f.spillFile.ReadAt(data, sp.offset); err != nil && f.err == nil {
+		f.err = err
+	}
+	return data
+}
+
+// pageLen returns the length in bytes of the content of page n
+func (f *Fpdf) pageLen(n int) int {
+	if sp, ok := f.spillMap[f.pages[n]]; ok {
+		return sp.length
+	}
+	return f.pages[n].Len()
+}
+
+// removeSpill removes the spill file once the page content is no longer
+// needed
+func (f *Fpdf) removeSpill() {
+	if f.spillFile != nil {
+		f.spillFile.Close()
+		os.Remove(f.spillFile.Name())
+		f.spillFile = nil
+	}
+	f.spillMap = nil
+	f.spillNext = nil
+}
+
+// docBegin establishes the destination of the document that is about to be
+// assembled: memory, or a file or writer to which it is passed on as it
+// grows
+func (f *Fpdf) docBegin() {
+	if f.linearize {
+		return
+	}
+	switch f.bufferStrategy {
+	case "file":
+		file, err := ioutil.TempFile("", "gofpdf")
+		if err != nil {
+			f.err = err
+			return
+		}
+		f.docFile = file
+		f.docSink = file
+	case "stream":
+		f.docSink = f.streamWriter
+	}
+}
+
+// docOffset returns the position in the document at which the next byte is
+// written
+func (f *Fpdf) docOffset() int {
+	return f.docFlushed + f.buffer.Len()
+}
+
+// docFlush passes the assembled part of the document on to its destination
+// if it is not retained in memory. Unless final is true, this is done only
+// once the part exceeds docFlushSize.
+func (f *Fpdf) docFlush(final bool) {
+	if f.docSink == nil || (!final && f.buffer.Len() < docFlushSize) {
+		return
+	}
+	n, err := f.buffer.WriteTo(f.docSink)
+	f.docFlushed += int(n)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	if final {
+		f.docSink = nil
+	}
+}
+
+// docFileOut copies the document assembled in a temporary file to w and
+// removes the file
+func (f *Fpdf) docFileOut(w io.Writer) {
+	_, err := f.docFile.Seek(0, os.SEEK_SET)
+	if err == nil {
+		_, err = io.Copy(w, f.docFile)
+	}
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	f.removeDocFile()
+}
+
+// removeDocFile removes the temporary file in which the document was
+// assembled
+func (f *Fpdf) removeDocFile() {
+	if f.docFile != nil {
+		f.docFile.Close()
+		os.Remove(f.docFile.Name())
+		f.docFile = nil
+	}
+}
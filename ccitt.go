@@ -0,0 +1,267 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+// CCITTOptions describes image data that has been encoded with CCITT fax
+// compression. It is used with RegisterImageCCITTReader().
+//
+// K selects the encoding: a negative value for Group 4, zero for one
+// dimensional Group 3 and a positive value for mixed one and two dimensional
+// Group 3 encoding. This is the convention of the PDF CCITTFaxDecode filter.
+//
+// Columns and Rows are the width and height of the image in pixels.
+//
+// BlackIs1 specifies that the runs encoded as black are to be shown white and
+// vice versa, as in TIFF images with a photometric interpretation of
+// BlackIsZero. By default, black runs are shown black.
+//
+// EncodedByteAlign specifies that each encoded row begins on a byte boundary.
+type CCITTOptions struct {
+	K                int
+	Columns          int
+	Rows             int
+	BlackIs1         bool
+	EncodedByteAlign bool
+}
+
+// RegisterImageCCITTReader registers a bilevel image that has already been
+// encoded with CCITT fax compression, reading the encoded data from r. The
+// data is embedded unchanged with the CCITTFaxDecode filter, which keeps
+// scanned text documents much smaller than any other encoding can. Use
+// Image() or ImageOptions() with imgName to place the image on a page.
+//
+// To encode a bilevel image with CCITT Group 4 compression, pass it to
+// RegisterImageImage() with an ImageType of "CCITT".
+func (f *Fpdf) RegisterImageCCITTReader(imgName string, options CCITTOptions, r io.Reader) (info *ImageInfoType) {
+	if f.err != nil {
+		return
+	}
+	info, ok := f.images[imgName]
+	if ok {
+		return
+	}
+	if options.Columns <= 0 || options.Rows <= 0 {
+		f.err = fmt.Errorf("invalid CCITT image size %d x %d", options.Columns, options.Rows)
+		return
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		f.err = err
+		return
+	}
+	k := options.K
+	if k < 0 {
+		k = -1
+	}
+	info = f.ccittInfo(data, options.Columns, options.Rows)
+	info.dp = ccittParms(k, options.Columns, options.Rows, options.EncodedByteAlign, options.BlackIs1)
+	info.i = len(f.images) + 1
+	f.images[imgName] = info
+	return
+}
+
+// ccittInfo returns image info for CCITT fax encoded data
+func (f *Fpdf) ccittInfo(data []byte, w, h int) (info *ImageInfoType) {
+	info = f.newImageInfo()
+	info.data = data
+	info.w, info.h = float64(w), float64(h)
+	info.cs = "DeviceGray"
+	info.bpc = 1
+	info.f = "CCITTFaxDecode"
+	return
+}
+
+// ccittImage encodes img with CCITT Group 4 compression. Pixels darker than
+// middle gray become black; transparent pixels become white.
+func (f *Fpdf) ccittImage(img image.Image) (info *ImageInfoType) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= 0 || h <= 0 {
+		f.err = fmt.Errorf("invalid image size %d x %d", w, h)
+		return
+	}
+	rows := make([][]byte, h)
+	for y := 0; y < h; y++ {
+		rows[y] = make([]byte, w)
+		for x := 0; x < w; x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			lum := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+			if c.A >= 128 && lum < 128 {
+				rows[y][x] = 1
+			}
+		}
+	}
+	info = f.ccittInfo(ccittEncodeG4(rows, w), w, h)
+	info.dp = ccittParms(-1, w, h, false, false)
+	return
+}
+
+// Code words of the modified Huffman run lengths of T.4. The terminating codes
+// are indexed by run length, the make-up codes by run length / 64 - 1.
+var (
+	ccittWhiteTerm = []string{
+		"00110101", "000111", "0111", "1000", "1011", "1100", "1110", "1111",
+		"10011", "10100", "00111", "01000", "001000", "000011", "110100", "110101",
+		"101010", "101011", "0100111", "0001100", "0001000", "0010111", "0000011", "0000100",
+		"0101000", "0101011", "0010011", "0100100", "0011000", "00000010", "00000011", "00011010",
+		"00011011", "00010010", "00010011", "00010100", "00010101", "00010110", "00010111", "00101000",
+		"00101001", "00101010", "00101011", "00101100", "00101101", "00000100", "00000101", "00001010",
+		"00001011", "01010010", "01010011", "01010100", "01010101", "00100100", "00100101", "01011000",
+		"01011001", "01011010", "01011011", "01001010", "01001011", "00110010", "00110011", "00110100",
+	}
+	ccittWhiteMakeup = []string{
+		"11011", "10010", "010111", "0110111", "00110110", "00110111", "01100100", "01100101",
+		"01101000", "01100111", "011001100", "011001101", "011010010", "011010011", "011010100", "011010101",
+		"011010110", "011010111", "011011000", "011011001", "011011010", "011011011", "010011000", "010011001",
+		"010011010", "011000", "010011011",
+	}
+	ccittBlackTerm = []string{
+		"0000110111", "010", "11", "10", "011", "0011", "0010", "00011",
+		"000101", "000100", "0000100", "0000101", "0000111", "00000100", "00000111", "000011000",
+		"0000010111", "0000011000", "0000001000", "00001100111", "00001101000", "00001101100", "00000110111", "00000101000",
+		"00000010111", "00000011000", "000011001010", "000011001011", "000011001100", "000011001101", "000001101000", "000001101001",
+		"000001101010", "000001101011", "000011010010", "000011010011", "000011010100", "000011010101", "000011010110", "000011010111",
+		"000001101100", "000001101101", "000011011010", "000011011011", "000001010100", "000001010101", "000001010110", "000001010111",
+		"000001100100", "000001100101", "000001010010", "000001010011", "000000100100", "000000110111", "000000111000", "000000100111",
+		"000000101000", "000001011000", "000001011001", "000000101011", "000000101100", "000001011010", "000001100110", "000001100111",
+	}
+	ccittBlackMakeup = []string{
+		"0000001111", "000011001000", "000011001001", "000001011011", "000000110011", "000000110100", "000000110101", "0000001101100",
+		"0000001101101", "0000001001010", "0000001001011", "0000001001100", "0000001001101", "0000001110010", "0000001110011", "0000001110100",
+		"0000001110101", "0000001110110", "0000001110111", "0000001010010", "0000001010011", "0000001010100", "0000001010101", "0000001011010",
+		"0000001011011", "0000001100100", "0000001100101",
+	}
+	// Make-up codes for runs of 1792 to 2560 pixels, common to both colors
+	ccittExtMakeup = []string{
+		"00000001000", "00000001100", "00000001101", "000000010010", "000000010011", "000000010100", "000000010101",
+		"000000010110", "000000010111", "000000011100", "000000011101", "000000011110", "000000011111",
+	}
+	// Vertical mode codes indexed by a1 - b1 + 3
+	ccittVertical = []string{"0000010", "000010", "010", "1", "011", "000011", "0000011"}
+)
+
+const (
+	ccittPass       = "0001"
+	ccittHorizontal = "001"
+	ccittEOL        = "000000000001"
+)
+
+// ccittWriter accumulates code words into bytes, most significant bit first
+type ccittWriter struct {
+	buf   bytes.Buffer
+	acc   byte
+	nBits uint
+}
+
+func (cw *ccittWriter) put(code string) {
+	for j := 0; j < len(code); j++ {
+		cw.acc = cw.acc<<1 | (code[j] - '0')
+		cw.nBits++
+		if cw.nBits == 8 {
+			cw.buf.WriteByte(cw.acc)
+			cw.acc, cw.nBits = 0, 0
+		}
+	}
+}
+
+func (cw *ccittWriter) run(n int, black bool) {
+	term, makeup := ccittWhiteTerm, ccittWhiteMakeup
+	if black {
+		term, makeup = ccittBlackTerm, ccittBlackMakeup
+	}
+	for n >= 2560 {
+		cw.put(ccittExtMakeup[len(ccittExtMakeup)-1])
+		n -= 2560
+	}
+	switch {
+	case n >= 1792:
+		cw.put(ccittExtMakeup[n/64-28])
+	case n >= 64:
+		cw.put(makeup[n/64-1])
+	}
+	cw.put(term[n%64])
+}
+
+func (cw *ccittWriter) bytes() []byte {
+	if cw.nBits > 0 {
+		cw.buf.WriteByte(cw.acc << (8 - cw.nBits))
+		cw.acc, cw.nBits = 0, 0
+	}
+	return cw.buf.Bytes()
+}
+
+// ccittChange returns the position of the first changing element after pos
+// in row whose color is c, or w if there is none. Pixels before the start of
+// the row are white.
+func ccittChange(row []byte, pos, w int, c byte) int {
+	for x := pos + 1; x < w; x++ {
+		prev := byte(0)
+		if x > 0 {
+			prev = row[x-1]
+		}
+		if row[x] == c && prev != c {
+			return x
+		}
+	}
+	return w
+}
+
+// ccittEncodeG4 encodes rows of w pixels, each 1 for black or 0 for white,
+// with the two dimensional coding scheme of T.6
+func ccittEncodeG4(rows [][]byte, w int) []byte {
+	var cw ccittWriter
+	ref := make([]byte, w)
+	for _, row := range rows {
+		a0, color := -1, byte(0)
+		for a0 < w {
+			a1 := ccittChange(row, a0, w, 1-color)
+			b1 := ccittChange(ref, a0, w, 1-color)
+			b2 := ccittChange(ref, b1, w, color)
+			switch {
+			case b2 < a1:
+				cw.put(ccittPass)
+				a0 = b2
+			case a1-b1 >= -3 && a1-b1 <= 3:
+				cw.put(ccittVertical[a1-b1+3])
+				a0, color = a1, 1-color
+			default:
+				a2 := ccittChange(row, a1, w, color)
+				start := a0
+				if start < 0 {
+					start = 0
+				}
+				cw.put(ccittHorizontal)
+				cw.run(a1-start, color == 1)
+				cw.run(a2-a1, color == 0)
+				a0 = a2
+			}
+		}
+		ref = row
+	}
+	cw.put(ccittEOL)
+	cw.put(ccittEOL)
+	return cw.bytes()
+}
@@ -0,0 +1,310 @@
+/*
+ * Copyright (c) 2013-2015 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+)
+
+// g4Reader decodes data encoded with CCITT Group 4 compression. It serves to
+// check the encoder and is written from the description of the coding scheme
+// in T.6 rather than from the encoder.
+type g4Reader struct {
+	data []byte
+	pos  int // position in bits
+}
+
+func (r *g4Reader) bit() (byte, error) {
+	if r.pos >= 8*len(r.data) {
+		return 0, fmt.Errorf("unexpected end of data")
+	}
+	b := r.data[r.pos/8] >> (7 - uint(r.pos%8)) & 1
+	r.pos++
+	return b, nil
+}
+
+// code reads bits until they form one of the code words in codes and returns
+// the value of that code word
+func (r *g4Reader) code(codes map[string]int) (int, error) {
+	var s []byte
+	for len(s) < 14 {
+		b, err := r.bit()
+		if err != nil {
+			return 0, err
+		}
+		s = append(s, '0'+b)
+		if v, ok := codes[string(s)]; ok {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid code word %s at bit %d", s, r.pos-len(s))
+}
+
+// run reads the run length of the specified color, which consists of any
+// number of make-up codes followed by a terminating code
+func (r *g4Reader) run(black bool) (int, error) {
+	codes := g4WhiteCodes
+	if black {
+		codes = g4BlackCodes
+	}
+	n := 0
+	for {
+		v, err := r.code(codes)
+		if err != nil {
+			return 0, err
+		}
+		n += v
+		if v < 64 {
+			return n, nil
+		}
+	}
+}
+
+// Values of the coding modes of T.6. The vertical modes are given by the
+// difference a1 - b1.
+const (
+	g4Pass       = 100
+	g4Horizontal = 101
+	g4EOL        = 102
+)
+
+var g4ModeCodes = map[string]int{
+	"1": 0, "011": 1, "000011": 2, "0000011": 3, "010": -1, "000010": -2, "0000010": -3,
+	"0001": g4Pass, "001": g4Horizontal, "000000000001": g4EOL,
+}
+
+var g4WhiteCodes, g4BlackCodes = g4RunCodes(false), g4RunCodes(true)
+
+func g4RunCodes(black bool) map[string]int {
+	term, makeup := ccittWhiteTerm, ccittWhiteMakeup
+	if black {
+		term, makeup = ccittBlackTerm, ccittBlackMakeup
+	}
+	codes := make(map[string]int)
+	for n, code := range term {
+		codes[code] = n
+	}
+	for j, code := range makeup {
+		codes[code] = 64 * (j + 1)
+	}
+	for j, code := range ccittExtMakeup {
+		codes[code] = 1792 + 64*j
+	}
+	return codes
+}
+
+// g4Changing returns the first changing element to the right of a0 in row
+// whose color is c. The line begins with an imaginary white element.
+func g4Changing(row []byte, a0 int, c byte) int {
+	for x := a0 + 1; x < len(row); x++ {
+		if row[x] == c && (x == 0 && c == 1 || x > 0 && row[x-1] != c) {
+			return x
+		}
+	}
+	return len(row)
+}
+
+// decodeG4 decodes h rows of w pixels, each 1 for black or 0 for white,
+// followed by the end of facsimile block
+func decodeG4(data []byte, w, h int) (rows [][]byte, err error) {
+	r := g4Reader{data: data}
+	ref := make([]byte, w)
+	for y := 0; y < h; y++ {
+		row := make([]byte, w)
+		a0, clr := -1, byte(0)
+		fill := func(end int, c byte) error {
+			if end > w || end < a0 {
+				return fmt.Errorf("row %d: invalid changing element %d", y, end)
+			}
+			for x := a0; x < end; x++ {
+				if x >= 0 {
+					row[x] = c
+				}
+			}
+			return nil
+		}
+		for a0 < w {
+			b1 := g4Changing(ref, a0, 1-clr)
+			var mode int
+			mode, err = r.code(g4ModeCodes)
+			if err != nil {
+				return
+			}
+			switch mode {
+			case g4Pass:
+				b2 := g4Changing(ref, b1, clr)
+				if err = fill(b2, clr); err != nil {
+					return
+				}
+				a0 = b2
+			case g4Horizontal:
+				if a0 < 0 {
+					a0 = 0
+				}
+				var n1, n2 int
+				if n1, err = r.run(clr == 1); err != nil {
+					return
+				}
+				if n2, err = r.run(clr == 0); err != nil {
+					return
+				}
+				if err = fill(a0+n1, clr); err != nil {
+					return
+				}
+				a0 += n1
+				if err = fill(a0+n2, 1-clr); err != nil {
+					return
+				}
+				a0 += n2
+			case g4EOL:
+				err = fmt.Errorf("row %d: unexpected end of facsimile block", y)
+				return
+			default:
+				if err = fill(b1+mode, clr); err != nil {
+					return
+				}
+				a0, clr = b1+mode, 1-clr
+			}
+		}
+		rows = append(rows, row)
+		ref = row
+	}
+	for j := 0; j < 2; j++ {
+		var mode int
+		if mode, err = r.code(g4ModeCodes); err == nil && mode != g4EOL {
+			err = fmt.Errorf("missing end of facsimile block")
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// TestCCITTEncodeG4Reference compares the encoding of a small bitmap, which
+// exercises the horizontal, pass and vertical modes, with code words
+// assembled by hand from the tables of T.4 and T.6.
+func TestCCITTEncodeG4Reference(t *testing.T) {
+	rows := [][]byte{
+		// H, white 2 (0111), black 4 (011), V0: 001 0111 011 1
+		{0, 0, 1, 1, 1, 1, 0, 0},
+		// VR1, VL1, V0: 011 010 1
+		{0, 0, 0, 1, 1, 0, 0, 0},
+		// P, V0: 0001 1
+		{0, 0, 0, 0, 0, 0, 0, 0},
+	}
+	// Followed by two EOL code words and padding
+	want := []byte{0x2E, 0xED, 0x46, 0x00, 0x20, 0x02}
+	if got := ccittEncodeG4(rows, 8); !bytes.Equal(got, want) {
+		t.Fatalf("got % X, want % X", got, want)
+	}
+}
+
+// TestCCITTEncodeG4RoundTrip encodes bitmaps of various sizes and densities
+// and decodes them again.
+func TestCCITTEncodeG4RoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	bitmap := func(w, h int, pixel func(x, y int) byte) [][]byte {
+		rows := make([][]byte, h)
+		for y := range rows {
+			rows[y] = make([]byte, w)
+			for x := range rows[y] {
+				rows[y][x] = pixel(x, y)
+			}
+		}
+		return rows
+	}
+	for _, tc := range []struct {
+		name  string
+		w, h  int
+		pixel func(x, y int) byte
+	}{
+		{"white", 17, 3, func(x, y int) byte { return 0 }},
+		{"black", 17, 3, func(x, y int) byte { return 1 }},
+		{"long runs", 6000, 4, func(x, y int) byte { return byte(x / (1000 + 700*y) % 2) }},
+		{"checkerboard", 64, 64, func(x, y int) byte { return byte((x/3 + y/5) % 2) }},
+		{"diagonal", 50, 50, func(x, y int) byte { return byte((x + y) / 4 % 2) }},
+		{"sparse", 97, 40, func(x, y int) byte { return byte(rnd.Intn(20) / 19) }},
+		{"noise", 97, 40, func(x, y int) byte { return byte(rnd.Intn(2)) }},
+		{"dense", 97, 40, func(x, y int) byte { return byte(1 - rnd.Intn(20)/19) }},
+	} {
+		rows := bitmap(tc.w, tc.h, tc.pixel)
+		got, err := decodeG4(ccittEncodeG4(rows, tc.w), tc.w, tc.h)
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		for y := range rows {
+			if !bytes.Equal(got[y], rows[y]) {
+				t.Errorf("%s: row %d differs", tc.name, y)
+				break
+			}
+		}
+	}
+}
+
+// TestCCITTFixture checks the fixture image/logo.g4. It holds image/logo.png,
+// 104 by 71 pixels, converted to black and white and encoded by ccittImage().
+// The fixture is regenerated by running the test with GOFPDF_UPDATE_G4 set.
+func TestCCITTFixture(t *testing.T) {
+	fl, err := os.Open("image/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(fl)
+	fl.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := New("P", "mm", "A4", "")
+	info := pdf.ccittImage(img)
+	if pdf.err != nil {
+		t.Fatal(pdf.err)
+	}
+	if os.Getenv("GOFPDF_UPDATE_G4") != "" {
+		if err = ioutil.WriteFile("image/logo.g4", info.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile("image/logo.g4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, info.data) {
+		t.Fatal("image/logo.g4 differs from the encoding of image/logo.png")
+	}
+	rows, err := decodeG4(data, 104, 71)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y, row := range rows {
+		for x, px := range row {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			black := c.A >= 128 && (299*int(c.R)+587*int(c.G)+114*int(c.B))/1000 < 128
+			if black != (px == 1) {
+				t.Fatalf("pixel %d, %d of image/logo.g4 differs from image/logo.png", x, y)
+			}
+		}
+	}
+}
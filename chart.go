@@ -0,0 +1,508 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// chartPalette holds the colors assigned to chart series and bars for which
+// no color is specified
+var chartPalette = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+// ChartSeriesType is a data series of a line chart drawn with LineChart().
+//
+// Points holds the data points in the units of the chart axes. They are
+// connected in the order given. Label identifies the series in the legend.
+//
+// ColorStr is the color of the line as a hexadecimal string such as
+// "#1a2b3c". If it is empty, a color is taken from a built-in palette.
+// LineWidth is the width of the line in user units; if it is zero, the
+// current line width is used.
+type ChartSeriesType struct {
+	Label     string
+	Points    []PointType
+	ColorStr  string
+	LineWidth float64
+}
+
+// ChartOptions specifies the axes and decorations of a chart drawn with
+// LineChart().
+//
+// XMin, XMax, YMin and YMax are the ranges of the axes. If the minimum and
+// maximum of an axis are equal, the range of that axis is chosen to cover the
+// data, extended to round values.
+//
+// XTicks and YTicks are the number of intervals between labeled tick marks on
+// each axis. If zero, about five intervals are used. For a range derived from
+// the data the number is approximate, since the ticks are placed at round
+// values.
+//
+// If Grid is true, light gray grid lines are drawn at the tick marks. If Legend
+// is true, a legend that identifies the series by their labels is drawn above
+// the plot.
+type ChartOptions struct {
+	XMin, XMax float64
+	YMin, YMax float64
+	XTicks     int
+	YTicks     int
+	Grid       bool
+	Legend     bool
+}
+
+// chartAxisType is an axis of a chart with its range and tick labels
+type chartAxisType struct {
+	min, max float64
+	step     float64
+	labels   []string
+}
+
+// chartAxis returns the axis for the range min to max with the specified
+// number of intervals. If auto is true, the range is extended to round values.
+func chartAxis(min, max float64, ticks int, auto bool) (axis chartAxisType) {
+	if ticks <= 0 {
+		ticks = 5
+	}
+	if max < min {
+		min, max = max, min
+	}
+	if max == min {
+		// A single value is shown in the middle of the axis
+		d := math.Abs(min) / 2
+		if d == 0 {
+			d = 1
+		}
+		min, max = min-d, max+d
+	}
+	step := (max - min) / float64(ticks)
+	if auto {
+		// Round the step to 1, 2 or 5 times a power of ten
+		mag := math.Pow(10, math.Floor(math.Log10(step)))
+		switch frac := step / mag; {
+		case frac <= 1:
+			step = mag
+		case frac <= 2:
+			step = 2 * mag
+		case frac <= 5:
+			step = 5 * mag
+		default:
+			step = 10 * mag
+		}
+		min = math.Floor(min/step+1e-9) * step
+		max = math.Ceil(max/step-1e-9) * step
+		ticks = round((max - min) / step)
+	}
+	axis.min, axis.max, axis.step = min, max, step
+	dec := 0
+	for dec < 6 {
+		scaled := step * math.Pow(10, float64(dec))
+		if math.Abs(scaled-math.Floor(scaled+0.5)) < 1e-6*scaled {
+			break
+		}
+		dec++
+	}
+	for j := 0; j <= ticks; j++ {
+		v := min + float64(j)*step
+		if math.Abs(v) < step*1e-9 {
+			v = 0
+		}
+		axis.labels = append(axis.labels, strconv.FormatFloat(v, 'f', dec, 64))
+	}
+	return
+}
+
+// chartState holds the settings that drawing a chart changes
+type chartState struct {
+	color     struct{ draw, fill, text clrType }
+	colorFlag bool
+	lineWidth float64
+}
+
+// chartBegin saves the graphics state before a chart is drawn
+func (f *Fpdf) chartBegin() (state chartState) {
+	state.color = f.color
+	state.colorFlag = f.colorFlag
+	state.lineWidth = f.lineWidth
+	f.out("q [] 0 d")
+	return
+}
+
+// chartEnd restores the graphics state saved by chartBegin()
+func (f *Fpdf) chartEnd(state chartState) {
+	f.out("Q")
+	f.color = state.color
+	f.colorFlag = state.colorFlag
+	f.lineWidth = state.lineWidth
+}
+
+// chartText prints txtStr with its baseline at y, which is measured from the
+// top of the page. alignStr is "L", "C" or "R" for the alignment of the text
+// relative to x.
+func (f *Fpdf) chartText(x, y float64, txtStr, alignStr string) {
+	switch alignStr {
+	case "C":
+		x -= f.GetStringWidth(txtStr) / 2
+	case "R":
+		x -= f.GetStringWidth(txtStr)
+	}
+	f.Text(x, f.userY(y), txtStr)
+}
+
+// chartGridLine draws a light gray line between two points measured from the
+// top of the page
+func (f *Fpdf) chartGridLine(x1, y1, x2, y2 float64) {
+	f.outf("q 0.800 G 0.25 w %.2f %.2f m %.2f %.2f l S Q", x1*f.k, (f.h-y1)*f.k, x2*f.k, (f.h-y2)*f.k)
+}
+
+// LineChart draws a line chart with the data of series in the rectangle with
+// its upper left corner at (x, y), w units wide and h units high. The chart
+// consists of the axes with labeled tick marks, optional grid lines, a line
+// for each series, scaled to the ranges of the axes, and an optional legend.
+// See ChartOptions for the settings of the chart.
+//
+// Labels are printed in the current font and text color, and the axes are
+// drawn with the current draw color and line width. Lines are clipped at the
+// edges of the plot area. The current position and the colors, line width and
+// font in effect before the call are unchanged.
+func (f *Fpdf) LineChart(x, y, w, h float64, series []ChartSeriesType, opt ChartOptions) {
+	if !f.fontReady() {
+		return
+	}
+	if w <= 0 || h <= 0 {
+		f.err = fmt.Errorf("invalid chart size %.2f x %.2f", w, h)
+		return
+	}
+	if f.originBottom {
+		y = f.h - y - h
+	}
+	// Axis ranges
+	xAuto, yAuto := opt.XMin == opt.XMax, opt.YMin == opt.YMax
+	xMin, xMax, yMin, yMax := opt.XMin, opt.XMax, opt.YMin, opt.YMax
+	first := true
+	for _, s := range series {
+		for _, pt := range s.Points {
+			if first {
+				if xAuto {
+					xMin, xMax = pt.X, pt.X
+				}
+				if yAuto {
+					yMin, yMax = pt.Y, pt.Y
+				}
+				first = false
+			}
+			if xAuto {
+				xMin, xMax = math.Min(xMin, pt.X), math.Max(xMax, pt.X)
+			}
+			if yAuto {
+				yMin, yMax = math.Min(yMin, pt.Y), math.Max(yMax, pt.Y)
+			}
+		}
+	}
+	xAxis := chartAxis(xMin, xMax, opt.XTicks, xAuto)
+	yAxis := chartAxis(yMin, yMax, opt.YTicks, yAuto)
+	// Layout
+	fs := f.fontSize
+	top, bottom := y+fs/2, y+h-1.5*fs
+	if opt.Legend {
+		top += 1.5 * fs
+	}
+	labelWd := 0.0
+	for _, s := range yAxis.labels {
+		labelWd = math.Max(labelWd, f.GetStringWidth(s))
+	}
+	left := x + labelWd + fs/2
+	right := x + w - f.GetStringWidth(xAxis.labels[len(xAxis.labels)-1])/2
+	if right <= left || bottom <= top {
+		f.err = fmt.Errorf("chart size %.2f x %.2f is too small", w, h)
+		return
+	}
+	px := func(v float64) float64 {
+		return left + (v-xAxis.min)/(xAxis.max-xAxis.min)*(right-left)
+	}
+	py := func(v float64) float64 {
+		return bottom - (v-yAxis.min)/(yAxis.max-yAxis.min)*(bottom-top)
+	}
+	state := f.chartBegin()
+	// Grid lines and tick labels
+	for j, s := range xAxis.labels {
+		xt := px(xAxis.min + float64(j)*xAxis.step)
+		if opt.Grid && j > 0 {
+			f.chartGridLine(xt, top, xt, bottom)
+		}
+		f.line(xt, bottom, xt, bottom+fs/4)
+		f.chartText(xt, bottom+1.25*fs, s, "C")
+	}
+	for j, s := range yAxis.labels {
+		yt := py(yAxis.min + float64(j)*yAxis.step)
+		if opt.Grid && j > 0 {
+			f.chartGridLine(left, yt, right, yt)
+		}
+		f.line(left-fs/4, yt, left, yt)
+		f.chartText(left-fs/2, yt+0.35*fs, s, "R")
+	}
+	// Axes
+	f.line(left, top, left, bottom)
+	f.line(left, bottom, right, bottom)
+	// Series, clipped to the plot area
+	f.outf("q %.2f %.2f %.2f %.2f re W n", left*f.k, (f.h-top)*f.k, (right-left)*f.k, (top-bottom)*f.k)
+	f.out("1 J 1 j")
+	for j, s := range series {
+		f.out("q")
+		f.chartSeriesStyle(j, s)
+		for k, pt := range s.Points {
+			op := "l"
+			if k == 0 {
+				op = "m"
+			}
+			f.outf("%.2f %.2f %s", px(pt.X)*f.k, (f.h-py(pt.Y))*f.k, op)
+		}
+		if len(s.Points) > 1 {
+			f.out("S")
+		} else {
+			f.out("n")
+		}
+		f.chartSeriesEnd(state)
+	}
+	f.out("Q")
+	// Legend
+	if opt.Legend {
+		lx, ly := left, y+fs
+		for j, s := range series {
+			f.out("q")
+			f.chartSeriesStyle(j, s)
+			f.line(lx, ly-0.35*fs, lx+2*fs, ly-0.35*fs)
+			f.chartSeriesEnd(state)
+			f.chartText(lx+2.5*fs, ly, s.Label, "L")
+			lx += 4*fs + f.GetStringWidth(s.Label)
+		}
+	}
+	f.chartEnd(state)
+}
+
+// chartSeriesEnd restores the graphics state saved before the style of a
+// series was set with chartSeriesStyle()
+func (f *Fpdf) chartSeriesEnd(state chartState) {
+	f.out("Q")
+	f.color.draw = state.color.draw
+	f.lineWidth = state.lineWidth
+}
+
+// chartSeriesStyle sets the color and line width of series s, the jth series
+// of a chart
+func (f *Fpdf) chartSeriesStyle(j int, s ChartSeriesType) {
+	clrStr := s.ColorStr
+	if clrStr == "" {
+		clrStr = chartPalette[j%len(chartPalette)]
+	}
+	f.SetDrawColorHex(clrStr)
+	if s.LineWidth > 0 {
+		f.SetLineWidth(s.LineWidth)
+	}
+}
+
+// BarChartOptions specifies the appearance of a chart drawn with BarChart().
+//
+// If Horizontal is true, the bars extend from left to right with the
+// categories listed down the left side; otherwise they extend upwards with
+// the categories along the bottom.
+//
+// Min and Max are the range of the value axis. If they are equal, the range
+// is chosen to cover the values and zero, extended to round values. Ticks is
+// the number of intervals between labeled tick marks on the value axis; if
+// zero, about five intervals are used. If Grid is true, light gray grid lines
+// are drawn at the tick marks.
+//
+// ColorStrs holds the colors of the bars as hexadecimal strings such as
+// "#1a2b3c". The colors are used in turn, so a single color applies to all
+// bars. If ColorStrs is empty, the bars are colored from a built-in palette.
+//
+// BarWidth is the width of each bar as a fraction of the space allotted to its
+// category. If zero, 0.7 is used.
+//
+// ValueFormatStr is the format, as used with fmt.Sprintf(), of the value
+// printed at the end of each bar, for example "%.1f%%". If it is empty, values
+// are printed with as many decimals as they need. If it is "-", no values are
+// printed.
+type BarChartOptions struct {
+	Horizontal     bool
+	Min, Max       float64
+	Ticks          int
+	Grid           bool
+	ColorStrs      []string
+	BarWidth       float64
+	ValueFormatStr string
+}
+
+// BarChart draws a bar chart in the rectangle with its upper left corner at
+// (x, y), w units wide and h units high. Each element of values is shown as
+// a bar labeled with the corresponding element of categories and annotated
+// with its value. Bars extend from the baseline, which is drawn at zero or, if
+// the range of the value axis does not include zero, at the end of the range
+// nearest to zero. Negative values extend in the opposite direction. See
+// BarChartOptions for the settings of the chart.
+//
+// Labels are printed in the current font and text color, and the axes are
+// drawn with the current draw color and line width. The current position and
+// the colors, line width and font in effect before the call are unchanged.
+func (f *Fpdf) BarChart(x, y, w, h float64, categories []string, values []float64, opt BarChartOptions) {
+	if !f.fontReady() {
+		return
+	}
+	if len(categories) != len(values) {
+		f.err = fmt.Errorf("bar chart has %d categories but %d values", len(categories), len(values))
+		return
+	}
+	if w <= 0 || h <= 0 {
+		f.err = fmt.Errorf("invalid chart size %.2f x %.2f", w, h)
+		return
+	}
+	if f.originBottom {
+		y = f.h - y - h
+	}
+	auto := opt.Min == opt.Max
+	min, max := opt.Min, opt.Max
+	if auto {
+		for _, v := range values {
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+	}
+	axis := chartAxis(min, max, opt.Ticks, auto)
+	barWd := opt.BarWidth
+	if barWd <= 0 {
+		barWd = 0.7
+	}
+	valueStr := func(v float64) string {
+		switch opt.ValueFormatStr {
+		case "":
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case "-":
+			return ""
+		}
+		return sprintf(opt.ValueFormatStr, v)
+	}
+	base := math.Min(math.Max(0, axis.min), axis.max)
+	fs := f.fontSize
+	n := float64(len(values))
+	// Layout of the plot area. pos returns the position of a value along
+	// the value axis.
+	var left, right, top, bottom, catX float64
+	var pos func(v float64) float64
+	if opt.Horizontal {
+		// Values are printed beyond the ends of the bars, so room is left
+		// for them on either side
+		catWd, negWd, posWd := 0.0, 0.0, f.GetStringWidth(axis.labels[len(axis.labels)-1])/2
+		for j, s := range categories {
+			catWd = math.Max(catWd, f.GetStringWidth(s))
+			wd := f.GetStringWidth(valueStr(values[j])) + fs/2
+			if values[j] >= 0 {
+				posWd = math.Max(posWd, wd)
+			} else {
+				negWd = math.Max(negWd, wd)
+			}
+		}
+		catX = x + catWd
+		left, right = catX+fs/2+negWd, x+w-posWd
+		top, bottom = y, y+h-1.5*fs
+		pos = func(v float64) float64 {
+			return left + (v-axis.min)/(axis.max-axis.min)*(right-left)
+		}
+	} else {
+		labelWd := 0.0
+		for _, s := range axis.labels {
+			labelWd = math.Max(labelWd, f.GetStringWidth(s))
+		}
+		left, right = x+labelWd+fs/2, x+w
+		top, bottom = y+1.5*fs, y+h-1.5*fs
+		for _, v := range values {
+			if v < 0 {
+				bottom -= 1.5 * fs
+				break
+			}
+		}
+		pos = func(v float64) float64 {
+			return bottom - (v-axis.min)/(axis.max-axis.min)*(bottom-top)
+		}
+	}
+	if right <= left || bottom <= top {
+		f.err = fmt.Errorf("chart size %.2f x %.2f is too small", w, h)
+		return
+	}
+	state := f.chartBegin()
+	// Value axis with grid lines and tick labels
+	for j, s := range axis.labels {
+		p := pos(axis.min + float64(j)*axis.step)
+		if opt.Horizontal {
+			if opt.Grid {
+				f.chartGridLine(p, top, p, bottom)
+			}
+			f.line(p, bottom, p, bottom+fs/4)
+			f.chartText(p, bottom+1.25*fs, s, "C")
+		} else {
+			if opt.Grid {
+				f.chartGridLine(left, p, right, p)
+			}
+			f.line(left-fs/4, p, left, p)
+			f.chartText(left-fs/2, p+0.35*fs, s, "R")
+		}
+	}
+	if opt.Horizontal {
+		f.line(left, bottom, right, bottom)
+	} else {
+		f.line(left, top, left, bottom)
+	}
+	// Bars with their labels and values
+	for j, v := range values {
+		clrStr := chartPalette[j%len(chartPalette)]
+		if len(opt.ColorStrs) > 0 {
+			clrStr = opt.ColorStrs[j%len(opt.ColorStrs)]
+		}
+		f.SetFillColorHex(clrStr)
+		b, e := pos(base), pos(math.Min(math.Max(v, axis.min), axis.max))
+		txtStr := valueStr(v)
+		if opt.Horizontal {
+			slot := (bottom - top) / n
+			c := top + (float64(j)+0.5)*slot
+			f.rect(math.Min(b, e), c-barWd*slot/2, math.Abs(e-b), barWd*slot, "F")
+			f.chartText(catX, c+0.35*fs, categories[j], "R")
+			if v >= 0 {
+				f.chartText(e+fs/4, c+0.35*fs, txtStr, "L")
+			} else {
+				f.chartText(e-fs/4, c+0.35*fs, txtStr, "R")
+			}
+		} else {
+			slot := (right - left) / n
+			c := left + (float64(j)+0.5)*slot
+			f.rect(c-barWd*slot/2, math.Min(b, e), barWd*slot, math.Abs(e-b), "F")
+			f.chartText(c, y+h-0.25*fs, categories[j], "C")
+			if v >= 0 {
+				f.chartText(c, e-fs/4, txtStr, "C")
+			} else {
+				f.chartText(c, e+fs, txtStr, "C")
+			}
+		}
+	}
+	// Baseline
+	if opt.Horizontal {
+		f.line(pos(base), top, pos(base), bottom)
+	} else {
+		f.line(left, pos(base), right, pos(base))
+	}
+	f.chartEnd(state)
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// CodeBlockOptions specifies the appearance of a block printed with
+// CodeBlock().
+//
+// FontFamily is the monospace font used for the code. If empty, "Courier" is
+// used. FontSize is the font size in points; if zero, the current font size is
+// used. The line height is 1.25 times the font size.
+//
+// If LineNumbers is true, each line is numbered in a gutter to the left of the
+// code. If Wrap is true, lines that are too long for the block are continued
+// on the following rows; otherwise they are clipped at the right edge of the
+// block.
+//
+// If Fill is true, the block is painted with the current fill color, and if
+// Border is true, it is outlined with the current draw color and line width.
+type CodeBlockOptions struct {
+	FontFamily  string
+	FontSize    float64
+	LineNumbers bool
+	Wrap        bool
+	Fill        bool
+	Border      bool
+}
+
+// CodeBlock prints codeStr, for example a program listing, as a block of
+// monospaced text that is w units wide. If w is zero, the block extends to the
+// right margin. The block begins at the current position, lines are separated
+// by the newline character and spaces are printed exactly as given, so
+// indentation and alignment are preserved. Tab characters advance to the next
+// multiple of four columns. The block continues on the next page as needed;
+// the border, if requested, is closed at the bottom of each page and reopened
+// on the next.
+//
+// Upon return, the current position is at the left edge of the block, just
+// below it, and the font in effect before the call is restored.
+func (f *Fpdf) CodeBlock(w float64, codeStr string, opt CodeBlockOptions) {
+	if f.err != nil {
+		return
+	}
+	familyStr, styleStr, sizePt := f.fontFamily, f.fontStyle, f.fontSizePt
+	if f.underline {
+		styleStr += "U"
+	}
+	if opt.FontFamily == "" {
+		opt.FontFamily = "Courier"
+	}
+	f.SetFont(opt.FontFamily, "", opt.FontSize)
+	if f.err != nil {
+		return
+	}
+	x := f.x
+	if w == 0 {
+		w = f.w - f.rMargin - x
+	}
+	h := 1.25 * f.fontSize
+	lines := strings.Split(strings.Replace(codeStr, "\r", "", -1), "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var gutter float64
+	if opt.LineNumbers {
+		gutter = f.GetStringWidth(strconv.Itoa(len(lines))) + 4*f.cMargin
+	}
+	textWd := w - gutter - 2*f.cMargin
+	// Break the lines into the rows that are printed
+	type rowType struct {
+		num int
+		txt string
+	}
+	var rows []rowType
+	for j, line := range lines {
+		line = codeExpandTabs(line)
+		num := j + 1
+		for {
+			n := f.codeFit(line, textWd)
+			if n == len(line) || !opt.Wrap {
+				rows = append(rows, rowType{num, line[:n]})
+				break
+			}
+			if n == 0 {
+				_, n = utf8.DecodeRuneInString(line)
+			}
+			rows = append(rows, rowType{num, line[:n]})
+			line = line[n:]
+			num = 0
+		}
+	}
+	top := true
+	for j, row := range rows {
+		if f.y+h > f.pageBreakTrigger && !f.inHeader && !f.inFooter && f.acceptPageBreak() {
+			f.AddPageFormat(f.curOrientation, f.curPageSize)
+			if f.err != nil {
+				return
+			}
+			top = true
+		}
+		var borderStr string
+		if opt.Border {
+			borderStr = "LR"
+			if top {
+				borderStr += "T"
+			}
+			if j == len(rows)-1 || (f.y+2*h > f.pageBreakTrigger && !f.inHeader && !f.inFooter) {
+				borderStr += "B"
+			}
+		}
+		top = false
+		f.x = x
+		if opt.LineNumbers {
+			var numStr string
+			if row.num > 0 {
+				numStr = strconv.Itoa(row.num)
+			}
+			f.CellFormat(gutter, h, numStr, strings.Replace(borderStr, "R", "", -1), 0, "R", opt.Fill, 0, "")
+			if opt.Border {
+				f.line(f.x, f.y, f.x, f.y+h)
+			}
+			borderStr = strings.Replace(borderStr, "L", "", -1)
+		}
+		f.CellFormat(w-gutter, h, row.txt, borderStr, 0, "L", opt.Fill, 0, "")
+		f.y += h
+	}
+	f.x = x
+	if familyStr != "" {
+		f.SetFont(familyStr, styleStr, sizePt)
+	}
+}
+
+// codeFit returns the number of leading bytes of s that fit in width wd with
+// the current font. s is measured a character at a time, so that the count
+// never falls within a UTF-8 sequence; bytes that do not form one, such as
+// cp1252 text, count as characters of their own.
+func (f *Fpdf) codeFit(s string, wd float64) int {
+	limit := f.glyphSpace(wd)
+	cw := &f.currentFont.Cw
+	l := 0.0
+	for j := 0; j < len(s); {
+		_, size := utf8.DecodeRuneInString(s[j:])
+		for k := j; k < j+size; k++ {
+			l += float64((*cw)[rune(s[k])])
+		}
+		if l > limit {
+			return j
+		}
+		j += size
+	}
+	return len(s)
+}
+
+// codeExpandTabs replaces each tab in s with the spaces that advance it to
+// the next multiple of four columns
+func codeExpandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var buf bytes.Buffer
+	col := 0
+	for j := 0; j < len(s); {
+		_, size := utf8.DecodeRuneInString(s[j:])
+		if s[j] == '\t' {
+			n := 4 - col%4
+			buf.WriteString(strings.Repeat(" ", n))
+			col += n
+		} else {
+			buf.WriteString(s[j : j+size])
+			col++
+		}
+		j += size
+	}
+	return buf.String()
+}
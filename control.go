@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// SetControlChars specifies how control characters, the codes below 32 and
+// the code 127, are treated in text printed with Cell(), CellFormat(),
+// MultiCell(), Write() and the methods based on them. By default, with an
+// empty modeStr, text is printed as given: codes other than line breaks and,
+// in Write(), tabs are passed to the font, which usually shows them as
+// missing glyphs or blank space, and positioning that depends on the width
+// of the text is unreliable.
+//
+// With modeStr set to "strip" or "replace", text from arbitrary sources is
+// printed predictably:
+//
+// A tab advances text printed with Write() to the next tab stop, as it always
+// does (see SetTabStops()). Elsewhere it is printed as a space.
+//
+// A form feed ends the current page in MultiCell() and Write(), and the text
+// that follows it continues at the top of the next page. MultiCell() resumes
+// at the horizontal position at which the block began. Elsewhere a form feed
+// is treated like the codes below.
+//
+// A line feed ends a line in MultiCell() and Write(); a carriage return is
+// ignored there. Every other control character, including these two when
+// they are printed with CellFormat(), is removed with "strip" and replaced by
+// placeholderStr with "replace". An empty placeholderStr is replaced with
+// "?". The placeholder is printed in the current font and is not itself
+// subject to this treatment.
+func (f *Fpdf) SetControlChars(modeStr, placeholderStr string) {
+	if f.err != nil {
+		return
+	}
+	switch modeStr {
+	case "", "strip":
+	case "replace":
+		if placeholderStr == "" {
+			placeholderStr = "?"
+		}
+	default:
+		f.err = fmt.Errorf("unrecognized control character mode \"%s\"", modeStr)
+		return
+	}
+	f.ctrlMode = modeStr
+	f.ctrlPlaceholder = placeholderStr
+}
+
+// isControl reports whether ch is a control character
+func isControl(ch byte) bool {
+	return ch < 32 || ch == 127
+}
+
+// controlText applies the setting of SetControlChars() to s. The control
+// characters in keepStr are retained for the caller to act upon. A tab that
+// is not retained is replaced by a space.
+func (f *Fpdf) controlText(s, keepStr string) string {
+	if f.ctrlMode == "" {
+		return s
+	}
+	pos := strings.IndexFunc(s, func(r rune) bool {
+		return r < 128 && isControl(byte(r)) && strings.IndexRune(keepStr, r) < 0
+	})
+	if pos < 0 {
+		return s
+	}
+	var buf bytes.Buffer
+	buf.WriteString(s[:pos])
+	for j := pos; j < len(s); j++ {
+		ch := s[j]
+		switch {
+		case !isControl(ch) || strings.IndexByte(keepStr, ch) >= 0:
+			buf.WriteByte(ch)
+		case ch == '\t':
+			buf.WriteByte(' ')
+		case f.ctrlMode == "replace":
+			buf.WriteString(f.ctrlPlaceholder)
+		}
+	}
+	return buf.String()
+}
+
+// formFeedSplit divides s at its form feeds if form feeds are acted upon, so
+// that the caller can begin a new page between the pieces. Otherwise, or if s
+// contains no form feed, the result is nil.
+func (f *Fpdf) formFeedSplit(s string) []string {
+	if f.ctrlMode == "" || !strings.Contains(s, "\f") {
+		return nil
+	}
+	return strings.Split(s, "\f")
+}
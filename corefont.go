@@ -0,0 +1,369 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// coreFontType describes one of the standard PDF fonts. These fonts are
+// available in every conforming viewer and are not embedded in the document.
+// The metrics are taken from the Adobe Font Metrics (AFM) files; character
+// widths are indexed by cp1252 code.
+type coreFontType struct {
+	name   string
+	up, ut int
+	desc   FontDescType
+	cw     *[256]int
+	enc    map[rune]byte // Unicode mapping of a symbolic font
+}
+
+// coreFontMap associates font keys (lowercase family followed by uppercase
+// style) with the standard PDF fonts.
+var coreFontMap = map[string]coreFontType{
+	"courier": {name: "Courier", up: -100, ut: 50, cw: &cwCourier,
+		desc: FontDescType{Ascent: 629, Descent: -157, CapHeight: 562, Flags: FontFlagFixedPitch | FontFlagSerif | FontFlagNonsymbolic,
+			FontBBox: fontBoxType{-23, -250, 715, 805}, ItalicAngle: 0}},
+	"courierB": {name: "Courier-Bold", up: -100, ut: 50, cw: &cwCourier,
+		desc: FontDescType{Ascent: 629, Descent: -157, CapHeight: 562, Flags: FontFlagFixedPitch | FontFlagSerif | FontFlagNonsymbolic,
+			FontBBox: fontBoxType{-113, -250, 749, 801}, ItalicAngle: 0}},
+	"courierI": {name: "Courier-Oblique", up: -100, ut: 50, cw: &cwCourier,
+		desc: FontDescType{Ascent: 629, Descent: -157, CapHeight: 562, Flags: FontFlagFixedPitch | FontFlagSerif | FontFlagNonsymbolic | FontFlagItalic,
+			FontBBox: fontBoxType{-27, -250, 849, 805}, ItalicAngle: -12}},
+	"courierBI": {name: "Courier-BoldOblique", up: -100, ut: 50, cw: &cwCourier,
+		desc: FontDescType{Ascent: 629, Descent: -157, CapHeight: 562, Flags: FontFlagFixedPitch | FontFlagSerif | FontFlagNonsymbolic | FontFlagItalic,
+			FontBBox: fontBoxType{-57, -250, 869, 801}, ItalicAngle: -12}},
+	"helvetica": {name: "Helvetica", up: -100, ut: 50, cw: &cwHelvetica,
+		desc: FontDescType{Ascent: 718, Descent: -207, CapHeight: 718, Flags: FontFlagNonsymbolic,
+			FontBBox: fontBoxType{-166, -225, 1000, 931}, ItalicAngle: 0}},
+	"helveticaB": {name: "Helvetica-Bold", up: -100, ut: 50, cw: &cwHelveticaB,
+		desc: FontDescType{Ascent: 718, Descent: -207, CapHeight: 718, Flags: FontFlagNonsymbolic,
+			FontBBox: fontBoxType{-170, -228, 1003, 962}, ItalicAngle: 0}},
+	"helveticaI": {name: "Helvetica-Oblique", up: -100, ut: 50, cw: &cwHelvetica,
+		desc: FontDescType{Ascent: 718, Descent: -207, CapHeight: 718, Flags: FontFlagNonsymbolic | FontFlagItalic,
+			FontBBox: fontBoxType{-170, -225, 1116, 931}, ItalicAngle: -12}},
+	"helveticaBI": {name: "Helvetica-BoldOblique", up: -100, ut: 50, cw: &cwHelveticaB,
+		desc: FontDescType{Ascent: 718, Descent: -207, CapHeight: 718, Flags: FontFlagNonsymbolic | FontFlagItalic,
+			FontBBox: fontBoxType{-174, -228, 1114, 962}, ItalicAngle: -12}},
+	"times": {name: "Times-Roman", up: -100, ut: 50, cw: &cwTimes,
+		desc: FontDescType{Ascent: 683, Descent: -217, CapHeight: 662, Flags: FontFlagSerif | FontFlagNonsymbolic,
+			FontBBox: fontBoxType{-168, -218, 1000, 898}, ItalicAngle: 0}},
+	"timesB": {name: "Times-Bold", up: -100, ut: 50, cw: &cwTimesB,
+		desc: FontDescType{Ascent: 683, Descent: -217, CapHeight: 676, Flags: FontFlagSerif | FontFlagNonsymbolic,
+			FontBBox: fontBoxType{-168, -218, 1000, 935}, ItalicAngle: 0}},
+	"timesI": {name: "Times-Italic", up: -100, ut: 50, cw: &cwTimesI,
+		desc: FontDescType{Ascent: 683, Descent: -217, CapHeight: 653, Flags: FontFlagSerif | FontFlagNonsymbolic | FontFlagItalic,
+			FontBBox: fontBoxType{-169, -217, 1010, 883}, ItalicAngle: -15}},
+	"timesBI": {name: "Times-BoldItalic", up: -100, ut: 50, cw: &cwTimesBI,
+		desc: FontDescType{Ascent: 683, Descent: -217, CapHeight: 669, Flags: FontFlagSerif | FontFlagNonsymbolic | FontFlagItalic,
+			FontBBox: fontBoxType{-200, -218, 996, 921}, ItalicAngle: -15}},
+	"symbol": {name: "Symbol", up: -100, ut: 50, cw: &cwSymbol, enc: symbolEncoding,
+		desc: FontDescType{Ascent: 1010, Descent: -293, CapHeight: 0, Flags: FontFlagSymbolic,
+			FontBBox: fontBoxType{-180, -293, 1090, 1010}, ItalicAngle: 0}},
+	"zapfdingbats": {name: "ZapfDingbats", up: -100, ut: 50, cw: &cwZapfDingbats, enc: zapfDingbatsEncoding,
+		desc: FontDescType{Ascent: 820, Descent: -143, CapHeight: 0, Flags: FontFlagSymbolic,
+			FontBBox: fontBoxType{-1, -143, 981, 820}, ItalicAngle: 0}},
+}
+
+// symbolEncoding maps Unicode code points to the codes of the Symbol font.
+// Printable ASCII characters that are not listed are used as is.
+var symbolEncoding = map[rune]byte{
+	0x2200: 0x22, 0x2203: 0x24, 0x220B: 0x27, 0x2217: 0x2A, 0x2212: 0x2D, 0x2245: 0x40,
+	0x0391: 0x41, 0x0392: 0x42, 0x03A7: 0x43, 0x0394: 0x44, 0x2206: 0x44, 0x0395: 0x45,
+	0x03A6: 0x46, 0x0393: 0x47, 0x0397: 0x48, 0x0399: 0x49, 0x03D1: 0x4A, 0x039A: 0x4B,
+	0x039B: 0x4C, 0x039C: 0x4D, 0x039D: 0x4E, 0x039F: 0x4F, 0x03A0: 0x50, 0x0398: 0x51,
+	0x03A1: 0x52, 0x03A3: 0x53, 0x03A4: 0x54, 0x03A5: 0x55, 0x03C2: 0x56, 0x03A9: 0x57,
+	0x2126: 0x57, 0x039E: 0x58, 0x03A8: 0x59, 0x0396: 0x5A, 0x2234: 0x5C, 0x22A5: 0x5E,
+	0x03B1: 0x61, 0x03B2: 0x62, 0x03C7: 0x63, 0x03B4: 0x64, 0x03B5: 0x65, 0x03C6: 0x66,
+	0x03B3: 0x67, 0x03B7: 0x68, 0x03B9: 0x69, 0x03D5: 0x6A, 0x03BA: 0x6B, 0x03BB: 0x6C,
+	0x00B5: 0x6D, 0x03BC: 0x6D, 0x03BD: 0x6E, 0x03BF: 0x6F, 0x03C0: 0x70, 0x03B8: 0x71,
+	0x03C1: 0x72, 0x03C3: 0x73, 0x03C4: 0x74, 0x03C5: 0x75, 0x03D6: 0x76, 0x03C9: 0x77,
+	0x03BE: 0x78, 0x03C8: 0x79, 0x03B6: 0x7A, 0x223C: 0x7E, 0x20AC: 0xA0, 0x03D2: 0xA1,
+	0x2032: 0xA2, 0x2264: 0xA3, 0x2044: 0xA4, 0x221E: 0xA5, 0x0192: 0xA6, 0x2663: 0xA7,
+	0x2666: 0xA8, 0x2665: 0xA9, 0x2660: 0xAA, 0x2194: 0xAB, 0x2190: 0xAC, 0x2191: 0xAD,
+	0x2192: 0xAE, 0x2193: 0xAF, 0x00B0: 0xB0, 0x00B1: 0xB1, 0x2033: 0xB2, 0x2265: 0xB3,
+	0x00D7: 0xB4, 0x221D: 0xB5, 0x2202: 0xB6, 0x2022: 0xB7, 0x00F7: 0xB8, 0x2260: 0xB9,
+	0x2261: 0xBA, 0x2248: 0xBB, 0x2026: 0xBC, 0x21B5: 0xBF, 0x2135: 0xC0, 0x2111: 0xC1,
+	0x211C: 0xC2, 0x2118: 0xC3, 0x2297: 0xC4, 0x2295: 0xC5, 0x2205: 0xC6, 0x2229: 0xC7,
+	0x222A: 0xC8, 0x2283: 0xC9, 0x2287: 0xCA, 0x2284: 0xCB, 0x2282: 0xCC, 0x2286: 0xCD,
+	0x2208: 0xCE, 0x2209: 0xCF, 0x2220: 0xD0, 0x2207: 0xD1, 0x00AE: 0xD2, 0x00A9: 0xD3,
+	0x2122: 0xD4, 0x220F: 0xD5, 0x221A: 0xD6, 0x22C5: 0xD7, 0x00AC: 0xD8, 0x2227: 0xD9,
+	0x2228: 0xDA, 0x21D4: 0xDB, 0x21D0: 0xDC, 0x21D1: 0xDD, 0x21D2: 0xDE, 0x21D3: 0xDF,
+	0x25CA: 0xE0, 0x2329: 0xE1, 0x2211: 0xE5, 0x232A: 0xF1, 0x222B: 0xF2, 0x2320: 0xF3,
+	0x2321: 0xF5,
+}
+
+// zapfDingbatsEncoding maps Unicode code points to the codes of the
+// ZapfDingbats font. Most of the dingbats occupy contiguous ranges of both
+// the font encoding and the Unicode Dingbats block.
+var zapfDingbatsEncoding = func() map[rune]byte {
+	m := map[rune]byte{
+		0x260E: 0x25, 0x261B: 0x2A, 0x261E: 0x2B, 0x2605: 0x48, 0x25CF: 0x6D, 0x25B2: 0x73,
+		0x25BC: 0x74, 0x25C6: 0x75, 0x25D7: 0x77, 0x2663: 0xA8, 0x2666: 0xA9, 0x2665: 0xAA,
+		0x2660: 0xAB, 0x2192: 0xD5, 0x2194: 0xD6, 0x2195: 0xD7,
+	}
+	used := make(map[byte]bool)
+	for _, c := range m {
+		used[c] = true
+	}
+	add := func(first, last byte, offset rune) {
+		for c := first; c <= last; c++ {
+			if !used[c] {
+				m[rune(c)+offset] = c
+			}
+		}
+	}
+	add(0x21, 0x7E, 0x26E0)
+	add(0x80, 0x8D, 0x26E8)
+	add(0xA1, 0xA7, 0x26C0)
+	add(0xAC, 0xB5, 0x23B4)
+	add(0xB6, 0xD4, 0x26C0)
+	add(0xD8, 0xEF, 0x26C0)
+	add(0xF1, 0xFE, 0x26C0)
+	return m
+}()
+
+var cwCourier = [256]int{
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+	600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600, 600,
+}
+
+var cwHelvetica = [256]int{
+	278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278,
+	278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278,
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, 350,
+	556, 350, 222, 556, 333, 1000, 556, 556, 333, 1000, 667, 333, 1000, 350, 611, 350,
+	350, 222, 222, 333, 333, 350, 556, 1000, 333, 1000, 500, 333, 944, 350, 500, 667,
+	278, 333, 556, 556, 556, 556, 260, 556, 333, 737, 370, 556, 584, 333, 737, 333,
+	400, 584, 333, 333, 333, 556, 537, 278, 333, 333, 365, 556, 834, 834, 834, 611,
+	667, 667, 667, 667, 667, 667, 1000, 722, 667, 667, 667, 667, 278, 278, 278, 278,
+	722, 722, 778, 778, 778, 778, 778, 584, 778, 722, 722, 722, 722, 667, 667, 611,
+	556, 556, 556, 556, 556, 556, 889, 500, 556, 556, 556, 556, 278, 278, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 584, 611, 556, 556, 556, 556, 500, 556, 500,
+}
+
+var cwHelveticaB = [256]int{
+	278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278,
+	278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278, 278,
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584, 350,
+	556, 350, 278, 556, 500, 1000, 556, 556, 333, 1000, 667, 333, 1000, 350, 611, 350,
+	350, 278, 278, 500, 500, 350, 556, 1000, 333, 1000, 556, 333, 944, 350, 500, 667,
+	278, 333, 556, 556, 556, 556, 280, 556, 333, 737, 370, 556, 584, 333, 737, 333,
+	400, 584, 333, 333, 333, 611, 556, 278, 333, 333, 365, 556, 834, 834, 834, 611,
+	722, 722, 722, 722, 722, 722, 1000, 722, 667, 667, 667, 667, 278, 278, 278, 278,
+	722, 722, 778, 778, 778, 778, 778, 584, 778, 722, 722, 722, 722, 667, 667, 611,
+	556, 556, 556, 556, 556, 556, 889, 556, 556, 556, 556, 556, 278, 278, 278, 278,
+	611, 611, 611, 611, 611, 611, 611, 584, 611, 611, 611, 611, 611, 556, 611, 556,
+}
+
+var cwSymbol = [256]int{
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 333, 713, 500, 549, 833, 778, 439, 333, 333, 500, 549, 250, 549, 250, 278,
+	500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 549, 549, 549, 444,
+	549, 722, 667, 722, 612, 611, 763, 603, 722, 333, 631, 722, 686, 889, 722, 722,
+	768, 741, 556, 592, 611, 690, 439, 768, 645, 795, 611, 333, 863, 333, 658, 500,
+	500, 631, 549, 549, 494, 439, 521, 411, 603, 329, 603, 549, 549, 576, 521, 549,
+	549, 521, 549, 603, 439, 576, 713, 686, 493, 686, 494, 480, 200, 480, 549, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	750, 620, 247, 549, 167, 713, 500, 753, 753, 753, 753, 1042, 987, 603, 987, 603,
+	400, 549, 411, 549, 549, 713, 494, 460, 549, 549, 549, 549, 1000, 603, 1000, 658,
+	823, 686, 795, 987, 768, 768, 823, 768, 768, 713, 713, 713, 713, 713, 713, 713,
+	768, 713, 790, 790, 890, 823, 549, 250, 713, 603, 603, 1042, 987, 603, 987, 603,
+	494, 329, 790, 790, 786, 713, 384, 384, 384, 384, 384, 384, 494, 494, 494, 494,
+	0, 329, 274, 686, 686, 686, 384, 384, 384, 384, 384, 384, 494, 494, 494, 0,
+}
+
+var cwTimes = [256]int{
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 333, 408, 500, 500, 833, 778, 180, 333, 333, 500, 564, 250, 333, 250, 278,
+	500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 564, 564, 564, 444,
+	921, 722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889, 722, 722,
+	556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611, 333, 278, 333, 469, 500,
+	333, 444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778, 500, 500,
+	500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444, 480, 200, 480, 541, 350,
+	500, 350, 333, 500, 444, 1000, 500, 500, 333, 1000, 556, 333, 889, 350, 611, 350,
+	350, 333, 333, 444, 444, 350, 500, 1000, 333, 980, 389, 333, 722, 350, 444, 722,
+	250, 333, 500, 500, 500, 500, 200, 500, 333, 760, 276, 500, 564, 333, 760, 333,
+	400, 564, 300, 300, 333, 500, 453, 250, 333, 300, 310, 500, 750, 750, 750, 444,
+	722, 722, 722, 722, 722, 722, 889, 667, 611, 611, 611, 611, 333, 333, 333, 333,
+	722, 722, 722, 722, 722, 722, 722, 564, 722, 722, 722, 722, 722, 722, 556, 500,
+	444, 444, 444, 444, 444, 444, 667, 444, 444, 444, 444, 444, 278, 278, 278, 278,
+	500, 500, 500, 500, 500, 500, 500, 564, 500, 500, 500, 500, 500, 500, 500, 500,
+}
+
+var cwTimesB = [256]int{
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 333, 555, 500, 500, 1000, 833, 278, 333, 333, 500, 570, 250, 333, 250, 278,
+	500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 570, 570, 570, 500,
+	930, 722, 667, 722, 722, 667, 611, 778, 778, 389, 500, 778, 667, 944, 722, 778,
+	611, 778, 722, 556, 667, 722, 722, 1000, 722, 722, 667, 333, 278, 333, 581, 500,
+	333, 500, 556, 444, 556, 444, 333, 500, 556, 278, 333, 556, 278, 833, 556, 500,
+	556, 556, 444, 389, 333, 556, 500, 722, 500, 500, 444, 394, 220, 394, 520, 350,
+	500, 350, 333, 500, 500, 1000, 500, 500, 333, 1000, 556, 333, 1000, 350, 667, 350,
+	350, 333, 333, 500, 500, 350, 500, 1000, 333, 1000, 389, 333, 722, 350, 444, 722,
+	250, 333, 500, 500, 500, 500, 220, 500, 333, 747, 300, 500, 570, 333, 747, 333,
+	400, 570, 300, 300, 333, 556, 540, 250, 333, 300, 330, 500, 750, 750, 750, 500,
+	722, 722, 722, 722, 722, 722, 1000, 722, 667, 667, 667, 667, 389, 389, 389, 389,
+	722, 722, 778, 778, 778, 778, 778, 570, 778, 722, 722, 722, 722, 722, 611, 556,
+	500, 500, 500, 500, 500, 500, 722, 444, 444, 444, 444, 444, 278, 278, 278, 278,
+	500, 556, 500, 500, 500, 500, 500, 570, 500, 556, 556, 556, 556, 500, 556, 500,
+}
+
+var cwTimesI = [256]int{
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 333, 420, 500, 500, 833, 778, 214, 333, 333, 500, 675, 250, 333, 250, 278,
+	500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 675, 675, 675, 500,
+	920, 611, 611, 667, 722, 611, 611, 722, 722, 333, 444, 667, 556, 833, 667, 722,
+	611, 722, 611, 500, 556, 722, 611, 833, 611, 556, 556, 389, 278, 389, 422, 500,
+	333, 500, 500, 444, 500, 444, 278, 500, 500, 278, 278, 444, 278, 722, 500, 500,
+	500, 500, 389, 389, 278, 500, 444, 667, 444, 444, 389, 400, 275, 400, 541, 350,
+	500, 350, 333, 500, 556, 889, 500, 500, 333, 1000, 500, 333, 944, 350, 556, 350,
+	350, 333, 333, 556, 556, 350, 500, 889, 333, 980, 389, 333, 667, 350, 389, 556,
+	250, 389, 500, 500, 500, 500, 275, 500, 333, 760, 276, 500, 675, 333, 760, 333,
+	400, 675, 300, 300, 333, 500, 523, 250, 333, 300, 310, 500, 750, 750, 750, 500,
+	611, 611, 611, 611, 611, 611, 889, 667, 611, 611, 611, 611, 333, 333, 333, 333,
+	722, 667, 722, 722, 722, 722, 722, 675, 722, 722, 722, 722, 722, 556, 611, 500,
+	500, 500, 500, 500, 500, 500, 667, 444, 444, 444, 444, 444, 278, 278, 278, 278,
+	500, 500, 500, 500, 500, 500, 500, 675, 500, 500, 500, 500, 500, 444, 500, 444,
+}
+
+var cwTimesBI = [256]int{
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250, 250,
+	250, 389, 555, 500, 500, 833, 778, 278, 333, 333, 500, 570, 250, 333, 250, 278,
+	500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 570, 570, 570, 500,
+	832, 667, 667, 667, 722, 667, 667, 722, 778, 389, 500, 667, 611, 889, 722, 722,
+	611, 722, 667, 556, 611, 722, 667, 889, 667, 611, 611, 333, 278, 333, 570, 500,
+	333, 500, 500, 444, 500, 444, 333, 500, 556, 278, 278, 500, 278, 778, 556, 500,
+	500, 500, 389, 389, 278, 556, 444, 667, 500, 444, 389, 348, 220, 348, 570, 350,
+	500, 350, 333, 500, 500, 1000, 500, 500, 333, 1000, 556, 333, 944, 350, 611, 350,
+	350, 333, 333, 500, 500, 350, 500, 1000, 333, 1000, 389, 333, 722, 350, 389, 611,
+	250, 389, 500, 500, 500, 500, 220, 500, 333, 747, 266, 500, 606, 333, 747, 333,
+	400, 570, 300, 300, 333, 576, 500, 250, 333, 300, 300, 500, 750, 750, 750, 500,
+	667, 667, 667, 667, 667, 667, 944, 667, 667, 667, 667, 667, 389, 389, 389, 389,
+	722, 722, 722, 722, 722, 722, 722, 570, 722, 722, 722, 722, 722, 611, 611, 500,
+	500, 500, 500, 500, 500, 500, 722, 444, 444, 444, 444, 444, 278, 278, 278, 278,
+	500, 556, 500, 500, 500, 500, 500, 570, 500, 556, 556, 556, 556, 444, 500, 444,
+}
+
+var cwZapfDingbats = [256]int{
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	278, 974, 961, 974, 980, 719, 789, 790, 791, 690, 960, 939, 549, 855, 911, 933,
+	911, 945, 974, 755, 846, 762, 761, 571, 677, 763, 760, 759, 754, 494, 552, 537,
+	577, 692, 786, 788, 788, 790, 793, 794, 816, 823, 789, 841, 823, 833, 816, 831,
+	923, 744, 723, 749, 790, 792, 695, 776, 768, 792, 759, 707, 708, 682, 701, 826,
+	815, 789, 789, 707, 687, 696, 689, 786, 787, 713, 791, 785, 791, 873, 761, 762,
+	762, 759, 759, 892, 892, 788, 784, 438, 138, 277, 415, 392, 392, 668, 668, 0,
+	390, 390, 317, 317, 276, 276, 509, 509, 410, 410, 234, 234, 334, 334, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 732, 544, 544, 910, 667, 760, 760, 776, 595, 694, 626, 788, 788, 788, 788,
+	788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788,
+	788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788, 788,
+	788, 788, 788, 788, 894, 838, 1016, 458, 748, 924, 748, 918, 927, 928, 928, 834,
+	873, 828, 924, 924, 917, 930, 931, 463, 883, 836, 836, 867, 867, 696, 696, 874,
+	0, 874, 760, 946, 771, 865, 771, 888, 967, 888, 831, 873, 927, 970, 918, 0,
+}
+
+// coreFontKey returns the key of the standard font that corresponds to the
+// specified family and style. ok is false if there is no such font.
+func coreFontKey(familyStr, styleStr string) (key string, ok bool) {
+	switch familyStr {
+	case "arial":
+		familyStr = "helvetica"
+	case "symbol", "zapfdingbats":
+		styleStr = ""
+	}
+	key = familyStr + styleStr
+	_, ok = coreFontMap[key]
+	return
+}
+
+// addCoreFont makes the standard font identified by key available under
+// fontkey. The font is not embedded; only its metrics are used.
+func (f *Fpdf) addCoreFont(fontkey, key string) {
+	def := coreFontMap[key]
+	info := fontType{
+		Tp:           "Core",
+		Name:         def.name,
+		Desc:         def.desc,
+		Up:           def.up,
+		Ut:           def.ut,
+		IsFixedPitch: def.desc.Flags&FontFlagFixedPitch != 0,
+		Cw:           make(map[rune]int, 256),
+		Contains:     make(map[rune]byte),
+		UniDiff:      make([]rune, 0),
+		Enc:          def.enc,
+	}
+	for j, w := range def.cw {
+		info.Cw[rune(j)] = w
+	}
+	info.Bold = strings.Contains(key, "B")
+	info.I = len(f.fonts)
+	f.fonts[fontkey] = &info
+}
+
+// symbolEncode converts text to the codes of a symbolic font using its Unicode
+// mapping. Printable ASCII characters without a mapping, and bytes that are not
+// part of a valid UTF-8 sequence, are passed through unchanged so that font
+// codes may also be given directly. Other characters are replaced with a
+// space.
+func symbolEncode(s string, enc map[rune]byte) string {
+	buf := make([]byte, 0, len(s))
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if c, ok := enc[r]; ok {
+			buf = append(buf, c)
+		} else if r < 0x80 || (r == utf8.RuneError && size == 1) {
+			buf = append(buf, s[0])
+		} else {
+			buf = append(buf, ' ')
+		}
+		s = s[size:]
+	}
+	return string(buf)
+}
@@ -19,6 +19,9 @@ package gofpdf
 import (
 	"bytes"
 	"io"
+	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -58,20 +61,24 @@ func (p PointType) XY() (float64, float64) {
 
 // ImageInfoType contains size, color and other information about an image
 type ImageInfoType struct {
-	data  []byte
-	smask []byte
-	i     int
-	n     int
-	w     float64
-	h     float64
-	cs    string
-	pal   []byte
-	bpc   int
-	f     string
-	dp    string
-	trns  []int
-	scale float64 // document scaling factor
-	dpi   float64
+	data        []byte
+	smask       []byte
+	i           int
+	n           int
+	w           float64
+	h           float64
+	cs          string
+	pal         []byte
+	bpc         int
+	f           string
+	dp          string
+	trns        []int
+	scale       float64 // document scaling factor
+	dpi         float64
+	interpolate bool    // viewers should smooth the image when scaling it
+	invert      bool    // CMYK samples are stored inverted (Adobe APP14)
+	placedWd    float64 // largest width, in points, at which the image is placed
+	placedHt    float64 // largest height, in points, at which the image is placed
 }
 
 // PointConvert returns the value of pt, expressed in points (1/72 inch), as a
@@ -128,6 +135,8 @@ type linkType struct {
 	x, y, wd, ht float64
 	link         int    // Auto-generated internal link ID or...
 	linkStr      string // ...application-provided external link string
+	elem         int    // 1-based index of the Link structure element, 0 if untagged
+	obj, key     int    // object number and parent tree key of a tagged annotation
 }
 
 type intLinkType struct {
@@ -143,17 +152,60 @@ type outlineType struct {
 	p                                      int
 }
 
+// contextType holds the drawing settings saved by SaveContext
+type contextType struct {
+	x, y                float64
+	fontFamily          string
+	fontStyle           string
+	fontSizePt          float64
+	underline           bool
+	textScale           float64
+	lineWidth           float64
+	capStyle, joinStyle int
+	renderingIntent     string
+	overprint           overprintType
+	dashArray           []float64
+	dashPhase           float64
+	alpha               float64
+	strokeAlpha         float64
+	blendMode           string
+	originBottom        bool
+	colorFlag           bool
+	draw, fill, text    clrType
+	font                *fontType
+}
+
+// MarginsType holds the left, top, right and bottom page margins. The bottom
+// margin is the distance from the bottom of the page that triggers an
+// automatic page break.
+type MarginsType struct {
+	Left, Top, Right, Bottom float64
+}
+
 // InitType is used with NewCustom() to customize an Fpdf instance.
 // OrientationStr, UnitStr, SizeStr and FontDirStr correspond to the arguments
 // accepted by New(). If the Wd and Ht fields of Size are each greater than
 // zero, Size will be used to set the default page size rather than SizeStr. Wd
 // and Ht are specified in the units of measure indicated by UnitStr.
+//
+// The remaining fields establish defaults that would otherwise be set after
+// the instance is created. LineWidth, if greater than zero, replaces the
+// default line width of 0.2 mm. DrawColorStr, FillColorStr and TextColorStr,
+// if not empty, are hexadecimal colors such as "#1a2b3c" that replace the
+// default black; see SetDrawColorHex(). Margins, if not nil, replaces the
+// default margins of 1 cm and the automatic page break margin of 2 cm. The
+// lengths are specified in the units of measure indicated by UnitStr.
 type InitType struct {
 	OrientationStr string
 	UnitStr        string
 	SizeStr        string
 	Size           SizeType
 	FontDirStr     string
+	LineWidth      float64
+	DrawColorStr   string
+	FillColorStr   string
+	TextColorStr   string
+	Margins        *MarginsType
 }
 
 // FontLoader is used to read fonts (JSON font specification and zlib compressed font binaries)
@@ -168,14 +220,19 @@ type FontLoader interface {
 // Fpdf is the principal structure for creating a single PDF document
 type Fpdf struct {
 	page             int                       // current page number
+	insertAt         int                       // index at which InsertPage() begins the next page, 0 if none
 	n                int                       // current object number
 	offsets          []int                     // array of object offsets
 	templates        map[int64]Template        // templates used in this document
 	templateObjects  map[int64]int             // template object IDs within this document
 	buffer           fmtBuffer                 // buffer holding in-memory PDF
+	docShared        bool                      // the contents of buffer have been returned by GetBytes()
 	pages            []*bytes.Buffer           // slice[page] of page content; 1-based
+	pagePool         []*bytes.Buffer           // empty page buffers kept by Reset() for reuse
 	state            int                       // current document state
 	compress         bool                      // compression flag
+	compressLevel    int                       // zlib compression level
+	noImageCompress  bool                      // image data encoded by this package is not compressed
 	k                float64                   // scale factor (number of points in user unit)
 	defOrientation   string                    // default orientation
 	curOrientation   string                    // current orientation
@@ -183,6 +240,25 @@ type Fpdf struct {
 	defPageSize      SizeType                  // default page size
 	curPageSize      SizeType                  // current page size
 	pageSizes        map[int]SizeType          // used for pages with non default sizes or orientations
+	streamLimit      int                       // size in bytes at which page content is split into another stream; 0 to disable
+	pageObjs         []int                     // object numbers of the page objects written, by page
+	pageBoxes        map[int]pageBoxMap        // crop, bleed, trim and art boxes by page
+	blankPages       map[int]bool              // completed pages on which nothing was drawn
+	suppressBlank    bool                      // pages on which nothing was drawn are omitted from the output
+	keepPage         bool                      // the current page is kept even if nothing is drawn on it
+	contentStart     int                       // length of the current page buffer after the header
+	defPageBoxes     pageBoxMap                // page boxes applied to each new page
+	pageTrans        map[int]pageTransType     // transition effects and display durations by page
+	defPageTrans     pageTransType             // transition applied to each new page
+	pageRotation     map[int]int               // clockwise rotation in degrees by page
+	defPageRotation  int                       // rotation applied to each new page
+	originBottom     bool                      // drawing primitives measure y upward from the bottom of the page
+	contextList      []contextType             // stack of drawing contexts saved with SaveContext
+	emoji            *emojiFontType            // color emoji font used by Write, nil if none
+	imageMaxDPI      float64                   // resolution to which images are reduced on output; 0 for none
+	imageNatural     bool                      // images without a size are placed at the resolution they specify
+	background       *backgroundType           // image drawn beneath the content of each page, nil if none
+	backgroundLen    int                       // length of the background operators that begin the current page
 	unitStr          string                    // unit of measure for all rendered objects except fonts
 	wPt, hPt         float64                   // dimensions of current page in points
 	w, h             float64                   // dimensions of current page in user unit
@@ -191,29 +267,57 @@ type Fpdf struct {
 	rMargin          float64                   // right margin
 	bMargin          float64                   // page break margin
 	cMargin          float64                   // cell margin
+	decimalSepStr    string                    // decimal separator used for "D" cell alignment
+	decimalPadStr    string                    // character whose width reserves room for fractional digits
+	decimalFracLen   int                       // number of fractional digits reserved for "D" cell alignment
+	numberFormat     NumberFormatType          // format of FormatNumber and NumberCell
+	tabStops         []float64                 // tab stop positions relative to left margin, ascending
+	paraBefore       float64                   // space above each MultiCell paragraph
+	paraAfter        float64                   // space below each MultiCell paragraph
+	paraIndent       float64                   // indent of the first line of each MultiCell paragraph
+	paraHanging      float64                   // indent of the following lines of each MultiCell paragraph
+	wsCollapse       bool                      // runs of spaces in Write and MultiCell text print as one
+	wsTrim           bool                      // spaces at the start and end of each line are not printed
+	keepWords        bool                      // words too long for a line are not broken
+	precRepl         *strings.Replacer         // rewrites numeric formats for non-default output precision
 	x, y             float64                   // current position in user unit
 	lasth            float64                   // height of last printed cell
 	lineWidth        float64                   // line width in user unit
+	hairlineWidth    float64                   // width in points of lines of zero width, 0 for device hairlines
+	lineSnap         float64                   // resolution in dpi to which strokes are snapped; 0 for none
 	fontpath         string                    // path containing fonts
 	fontLoader       FontLoader                // used to load font files from arbitrary locations
 	fonts            map[string]*fontType      // array of used fonts
+	fontCache        map[string]*fontType      // fonts kept by Reset() for reuse
+	rawObjs          []rawObjType              // objects allocated with NewObject()
+	rawResources     map[string]map[string]int // raw objects by resource category and name
+	newFnc           func(f *Fpdf)             // establishes the initial settings of a cleared instance
 	fontFamily       string                    // current font family
 	fontStyle        string                    // current font style
 	underline        bool                      // underlining flag
 	currentFont      *fontType                 // current font info
+	defFontFamily    string                    // font family selected when text is output before SetFont
+	defFontStyle     string                    // style of default font
+	defFontSize      float64                   // size in points of default font; 0 for current size
 	fontSizePt       float64                   // current font size in points
 	fontSize         float64                   // current font size in user unit
 	ws               float64                   // word spacing
+	textScale        float64                   // horizontal text scaling in percent
 	images           map[string]*ImageInfoType // array of used images
 	pageLinks        [][]linkType              // pageLinks[page][link], both 1-based
 	links            []intLinkType             // array of internal links
+	sigFields        []sigFieldType            // signature fields in the order added
 	outlines         []outlineType             // array of outlines
 	outlineRoot      int                       // root of outlines
+	namedDests       map[string]intLinkType    // named destinations
+	namedDestsObj    int                       // object number of named destination tree
 	autoPageBreak    bool                      // automatic page breaking
 	acceptPageBreak  func() bool               // returns true to accept page break
 	pageBreakTrigger float64                   // threshold used to trigger page breaks
 	inHeader         bool                      // flag set when processing header
 	headerFnc        func()                    // function provided by app and called to write header
+	inTableHeader    bool                      // flag set when repeating table header rows
+	tableHeaderFnc   func()                    // function provided by app and called to repeat table header rows
 	inFooter         bool                      // flag set when processing footer
 	footerFnc        func()                    // function provided by app and called to write footer
 	zoomMode         string                    // zoom display mode
@@ -223,25 +327,65 @@ type Fpdf struct {
 	author           string                    // author
 	keywords         string                    // keywords
 	creator          string                    // creator
+	lang             string                    // natural language of the document, empty if unspecified
+	producer         string                    // producer
+	noMetadata       bool                      // omit document information dictionary
+	metadataObj      int                       // object number of the empty XMP metadata stream
 	creationDate     time.Time                 // override for dcoument CreationDate value
+	docID            [2]string                 // file identifier set with SetDocumentID
+	fileID           [2]string                 // file identifier written to the trailer
 	aliasNbPagesStr  string                    // alias for total number of pages
-	pdfVersion       string                    // PDF version number
+	pdfVersion       string                    // minimum PDF version required by features used
+	pdfFeature       string                    // feature that requires pdfVersion
+	pdfVersionSet    string                    // PDF version set by SetPDFVersion()
+	userUnit         float64                   // size of a unit of default user space in points, 0 if not set
 	fontDirStr       string                    // location of font definition files
 	capStyle         int                       // line cap style: butt 0, round 1, square 2
 	joinStyle        int                       // line segment join style: miter 0, round 1, bevel 2
+	renderingIntent  string                    // color rendering intent, empty for the default
+	overprint        overprintType             // current overprint settings
+	overprintList    []overprintStateType      // graphics states that establish overprint settings
+	overprintMap     map[overprintType]int     // map into overprintList, 1-based
 	dashArray        []float64                 // dash array
 	dashPhase        float64                   // dash phase
 	blendList        []blendModeType           // slice[idx] of alpha transparency modes, 1-based
 	blendMap         map[string]int            // map into blendList
 	blendMode        string                    // current blend mode
 	alpha            float64                   // current transpacency
+	strokeAlpha      float64                   // current transparency of strokes
 	gradientList     []gradientType            // slice[idx] of gradient records
+	patternList      []patternType             // slice[idx] of tiling patterns, 1-based
 	clipNest         int                       // Number of active clipping contexts
+	pathBegun        bool                      // path started with BeginPath awaits DrawPath
+	pathContext      contextType               // drawing context when BeginPath was called
+	artifactNest     int                       // Number of open artifact marked-content sequences
+	actualTextNest   int                       // Number of open ActualText spans
+	actualText       bool                      // text printed by CellFormat is marked with its logical text
+	actualTextStr    string                    // logical text of the next text printed by CellFormat, if it differs
+	wordPositions    bool                      // positions of words printed by CellFormat are recorded
+	wordList         []WordPositionType        // recorded word positions
+	tabularFigures   bool                      // digits printed by CellFormat share the width of the widest one
+	fontFeatures     []string                  // OpenType features applied to text, see SetFontFeatures
+	ctrlMode         string                    // treatment of control characters in text, empty to print them as given
+	ctrlPlaceholder  string                    // replacement of control characters in the "replace" mode
 	transformNest    int                       // Number of active transformation contexts
 	err              error                     // Set if error occurs during life cycle of instance
+	errMutex         sync.Mutex                // serializes access to err by the error methods
 	protect          protectType               // document protection structure
 	layer            layerRecType              // manages optional layers in document
+	structure        structRecType             // logical structure of a tagged document
+	list             listRecType               // state of the current bulleted or numbered list
 	catalogSort      bool                      // sort resource catalogs in document
+	linearize        bool                      // arrange document for fast web view
+	bufferStrategy   string                    // "memory" (or empty), "file" or "stream"; see SetBufferStrategy
+	spillFile        *os.File                  // temporary file holding the content of completed pages
+	spillLen         int64                     // length of spillFile
+	spillMap         pageSpillMap              // location in spillFile of the content of page buffers
+	spillNext        *bytes.Buffer             // page buffer to be moved to spillFile when the next page begins
+	docSink          io.Writer                 // destination to which the assembled document is passed on, nil to retain it
+	docFile          *os.File                  // temporary file holding the assembled document
+	docFlushed       int                       // length of the part of the document passed on to docSink
+	streamWriter     io.Writer                 // writer passed to Output with the "stream" strategy
 	colorFlag        bool                      // indicates whether fill and text colors are different
 	color            struct {                  // Composite values of colors
 		draw, fill, text clrType
@@ -338,18 +482,25 @@ type FontDescType struct {
 }
 
 type fontType struct {
-	Data         []byte        // Original source of ttf file (zlib compressed)
-	OrigLen      int           // Length of TTF w/o compression
-	Bold         bool          // Is this font considered a bold font?
-	IsFixedPitch bool          // Is this font a fixedPitch font?
-	Tp           string        // "Core", "TrueType", ...
-	Name         string        // "Courier-Bold", ...
-	Desc         FontDescType  // Font descriptor
-	Up           int           // Underline position
-	Ut           int           // Underline thickness
-	Cw           map[rune]int  // Character width by ordinal
-	I            int           // 1-based position in font list, set by font loader, not this program
-	N            int           // Set by font loader
-	Contains     map[rune]byte // A previously set code point for the differences array
-	UniDiff      []rune        // The ordered list of added unicode points
+	Data         []byte            // Original source of ttf file
+	OrigLen      int               // Length of TTF w/o compression
+	Bold         bool              // Is this font considered a bold font?
+	IsFixedPitch bool              // Is this font a fixedPitch font?
+	Tp           string            // "Core", "TrueType", ...
+	Name         string            // "Courier-Bold", ...
+	Desc         FontDescType      // Font descriptor
+	Up           int               // Underline position
+	Ut           int               // Underline thickness
+	Cw           map[rune]int      // Character width by ordinal
+	I            int               // 1-based position in font list, set by font loader, not this program
+	N            int               // Set by font loader
+	Contains     map[rune]byte     // A previously set code point for the differences array
+	UniDiff      []rune            // The ordered list of added unicode points
+	Enc          map[rune]byte     // Unicode to code mapping of a symbolic font
+	widths       *widthCache       // Recently measured string widths
+	gw           []int             // Width of each glyph by glyph index
+	gids         map[rune]uint16   // Glyph index by ordinal, from the character map
+	gsub         *gsubType         // Glyph substitutions, nil if the font has none
+	glyphs       map[uint16]string // Glyphs printed by index and the text they represent
+	glyphN       int               // Object number of the Type0 font that prints glyphs by index
 }
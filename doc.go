@@ -92,6 +92,24 @@ method. At any time during the life cycle of the Fpdf instance, the error state
 can be determined with a call to Ok() or Err(). The error itself can be
 retrieved with a call to Error().
 
+Document Life Cycle
+
+An Fpdf instance passes through three stages. After it is created with New()
+or NewCustom(), document-wide settings such as the margins, compression and
+metadata can be established. The first call to AddPage() opens the document,
+after which content is written to the current page until the next call to
+AddPage() or AddPageFormat() ends it. Finally, Close() ends the last page,
+calling the footer function, and writes the complete document to an internal
+buffer. Output(), OutputAndClose(), OutputFileAndClose() and GetBytes() call
+Close() if it has not yet been called.
+
+A closed document is final. Methods that would add pages or content to it set
+an error rather than producing a corrupt document, so any inspection of the
+result that may lead to additional pages must take place before Close() is
+called, for example by means of PageNo(), GetY() or SplitLines(). Output()
+consumes the internal buffer; GetBytes() does not and may be called repeatedly
+before or instead of Output().
+
 Conversion Notes
 
 This package is a relatively straightforward translation from the original FPDF
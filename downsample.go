@@ -0,0 +1,280 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"math"
+)
+
+// SetImageMaxDPI sets the maximum resolution, in dots per inch, at which
+// images are stored in the document. When the document is written, each
+// image whose resolution at the largest size at which it has been placed on a
+// page exceeds dpi is resampled to that resolution. This can reduce the size
+// of documents that contain photographs considerably. Images that are already
+// at or below the limit are stored unchanged. The default value of 0 disables
+// resampling.
+//
+// JPEG images are decoded, reduced and encoded again as JPEG with a quality of
+// 90. Other images are reduced losslessly when they have 8 bits per
+// component; their transparency is reduced along with them. CMYK JPEG images
+// and images with fewer than 8 bits per component are not resampled.
+//
+// The placed size of an image is the size given to ImageOptions() and the
+// other methods that place images. Scaling that applies to the page content
+// as a whole, such as the transformations begun with TransformBegin() and the
+// size at which a template is used, is not taken into account. An image that
+// is placed small and enlarged in this way is therefore reduced to the
+// resolution of its small size and appears blurred. Such images should be
+// placed at the size at which they appear, or the limit should not be set.
+func (f *Fpdf) SetImageMaxDPI(dpi float64) {
+	if dpi < 0 {
+		f.err = fmt.Errorf("maximum image resolution must not be negative: %.2f", dpi)
+		return
+	}
+	f.imageMaxDPI = dpi
+}
+
+// downsampleImage reduces the resolution of info, if necessary, so that it
+// does not exceed the limit set with SetImageMaxDPI at its placed size.
+func (f *Fpdf) downsampleImage(info *ImageInfoType) {
+	if info.placedWd <= 0 || info.placedHt <= 0 {
+		return
+	}
+	tw := math.Ceil(info.placedWd / 72 * f.imageMaxDPI)
+	th := math.Ceil(info.placedHt / 72 * f.imageMaxDPI)
+	if info.w <= tw && info.h <= th {
+		return
+	}
+	scale := math.Max(tw/info.w, th/info.h)
+	dw := int(math.Max(1, math.Ceil(info.w*scale)))
+	dh := int(math.Max(1, math.Ceil(info.h*scale)))
+	sw, sh := int(info.w), int(info.h)
+	switch {
+	case info.f == "DCTDecode":
+		if info.cs == "DeviceCMYK" {
+			return
+		}
+		img, err := jpeg.Decode(bytes.NewReader(info.data))
+		if err != nil {
+			return
+		}
+		var dst image.Image
+		switch src := img.(type) {
+		case *image.Gray:
+			g := image.NewGray(image.Rect(0, 0, dw, dh))
+			resamplePixels(src.Pix, src.Stride, g.Pix, g.Stride, 1, sw, sh, dw, dh, false)
+			dst = g
+		default:
+			rgba := image.NewRGBA(image.Rect(0, 0, sw, sh))
+			draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+			d := image.NewRGBA(image.Rect(0, 0, dw, dh))
+			resamplePixels(rgba.Pix, rgba.Stride, d.Pix, d.Stride, 4, sw, sh, dw, dh, false)
+			dst = d
+		}
+		var buf bytes.Buffer
+		if jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 90}) != nil {
+			return
+		}
+		info.data = buf.Bytes()
+	case info.f == "FlateDecode" && info.bpc == 8:
+		colors := 1
+		switch info.cs {
+		case "DeviceRGB":
+			colors = 3
+		case "DeviceCMYK":
+			colors = 4
+		}
+		nearest := info.cs == "Indexed" || len(info.trns) > 0
+		predict := info.dp != ""
+		data, ok := inflateRows(info.data, predict, colors, sw, sh)
+		if !ok {
+			return
+		}
+		var smask []byte
+		if len(info.smask) > 0 {
+			if smask, ok = inflateRows(info.smask, true, 1, sw, sh); !ok {
+				return
+			}
+		}
+		info.data = deflateRows(data, predict, colors, sw, sh, dw, dh, nearest, f.compressLevel)
+		if smask != nil {
+			info.smask = deflateRows(smask, true, 1, sw, sh, dw, dh, false, f.compressLevel)
+		}
+		if predict {
+			info.dp = sprintf("/Predictor 15 /Colors %d /BitsPerComponent 8 /Columns %d", colors, dw)
+		}
+	default:
+		return
+	}
+	info.w, info.h = float64(dw), float64(dh)
+}
+
+// inflateRows decompresses image data and, if predict is true, reverses the
+// PNG row filters, returning the unfiltered samples of an 8-bit image with
+// the specified number of color components.
+func inflateRows(data []byte, predict bool, colors, w, h int) (pix []byte, ok bool) {
+	raw, err := sliceUncompress(data)
+	if err != nil {
+		return
+	}
+	rowLen := colors * w
+	if !predict {
+		return raw, len(raw) >= rowLen*h
+	}
+	if len(raw) < (rowLen+1)*h {
+		return
+	}
+	pix = make([]byte, rowLen*h)
+	prev := make([]byte, rowLen)
+	for y := 0; y < h; y++ {
+		filter := raw[y*(rowLen+1)]
+		src := raw[y*(rowLen+1)+1 : (y+1)*(rowLen+1)]
+		row := pix[y*rowLen : (y+1)*rowLen]
+		for x := 0; x < rowLen; x++ {
+			var a, c int
+			b := int(prev[x])
+			if x >= colors {
+				a = int(row[x-colors])
+				c = int(prev[x-colors])
+			}
+			row[x] = src[x] + byte(pngPredict(int(filter), a, b, c))
+		}
+		prev = row
+	}
+	return pix, true
+}
+
+// deflateRows resamples 8-bit samples to dw by dh pixels and compresses them
+// at the specified zlib level.
+// If predict is true, each row is filtered with the PNG filter type that is
+// likely to compress best and is preceded by the filter type.
+func deflateRows(pix []byte, predict bool, colors, sw, sh, dw, dh int, nearest bool, level int) []byte {
+	dst := make([]byte, colors*dw*dh)
+	resamplePixels(pix, colors*sw, dst, colors*dw, colors, sw, sh, dw, dh, nearest)
+	if !predict {
+		return sliceCompress(dst, level)
+	}
+	rowLen := colors * dw
+	var buf bytes.Buffer
+	buf.Grow(len(dst) + dh)
+	prev := make([]byte, rowLen)
+	cand := make([][]byte, 5)
+	for j := range cand {
+		cand[j] = make([]byte, rowLen)
+	}
+	for y := 0; y < dh; y++ {
+		row := dst[y*rowLen : (y+1)*rowLen]
+		best, bestSum := 0, -1
+		for filter := range cand {
+			out := cand[filter]
+			sum := 0
+			for x := 0; x < rowLen; x++ {
+				var a, c int
+				b := int(prev[x])
+				if x >= colors {
+					a = int(row[x-colors])
+					c = int(prev[x-colors])
+				}
+				out[x] = row[x] - byte(pngPredict(filter, a, b, c))
+				sum += intAbs(int(int8(out[x])))
+			}
+			if bestSum < 0 || sum < bestSum {
+				best, bestSum = filter, sum
+			}
+		}
+		buf.WriteByte(byte(best))
+		buf.Write(cand[best])
+		prev = row
+	}
+	return sliceCompress(buf.Bytes(), level)
+}
+
+// pngPredict returns the value predicted by the PNG filter type for a sample
+// with left neighbor a, upper neighbor b and upper left neighbor c
+func pngPredict(filter, a, b, c int) int {
+	switch filter {
+	case 1:
+		return a
+	case 2:
+		return b
+	case 3:
+		return (a + b) / 2
+	case 4:
+		p := a + b - c
+		pa, pb, pc := intAbs(p-a), intAbs(p-b), intAbs(p-c)
+		if pa <= pb && pa <= pc {
+			return a
+		} else if pb <= pc {
+			return b
+		}
+		return c
+	}
+	return 0
+}
+
+// resamplePixels reduces an image of sw by sh pixels to dw by dh pixels. Each
+// destination pixel is the average of the source pixels it covers or, if
+// nearest is true, a copy of one of them, which preserves palette indexes
+// and color key values.
+func resamplePixels(src []byte, srcStride int, dst []byte, dstStride int, colors, sw, sh, dw, dh int, nearest bool) {
+	sum := make([]int, colors)
+	for dy := 0; dy < dh; dy++ {
+		y0, y1 := dy*sh/dh, (dy+1)*sh/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for dx := 0; dx < dw; dx++ {
+			x0, x1 := dx*sw/dw, (dx+1)*sw/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			out := dst[dy*dstStride+dx*colors : dy*dstStride+(dx+1)*colors]
+			if nearest {
+				pos := ((y0+y1)/2)*srcStride + ((x0+x1)/2)*colors
+				copy(out, src[pos:pos+colors])
+				continue
+			}
+			for k := range sum {
+				sum[k] = 0
+			}
+			for y := y0; y < y1; y++ {
+				row := src[y*srcStride:]
+				for x := x0; x < x1; x++ {
+					for k := 0; k < colors; k++ {
+						sum[k] += int(row[x*colors+k])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			for k := range sum {
+				out[k] = byte((sum[k] + n/2) / n)
+			}
+		}
+	}
+}
+
+func intAbs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
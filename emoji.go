@@ -0,0 +1,311 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"unicode/utf8"
+)
+
+// emojiFontType holds the color bitmap glyphs of an emoji font. Color emoji
+// fonts store each glyph as a PNG image rather than as an outline, so the
+// glyphs are placed on the page as inline images.
+type emojiFontType struct {
+	glyphs map[rune]uint16 // glyph index by code point
+	sbix   []byte          // sbix table, nil if absent
+	cblc   []byte          // CBLC table, nil if absent
+	cbdt   []byte          // CBDT table, nil if absent
+	nGlyph int             // number of glyphs in font
+}
+
+// AddEmojiFont loads a color emoji font from the file specified by fileStr.
+// Once loaded, characters written with Write() or WriteLinkString() for which
+// the font has a color bitmap glyph are rendered by placing the glyph's PNG
+// image inline with the text, scaled to the current font size. All other
+// characters are written with the current font as usual.
+//
+// Fonts that store their glyphs in an sbix table (Apple Color Emoji) or in
+// CBDT/CBLC tables (Noto Color Emoji) are supported. Only glyphs stored as PNG
+// images are used. The variation selector U+FE0F that commonly follows an
+// emoji is discarded.
+func (f *Fpdf) AddEmojiFont(fileStr string) {
+	if f.err != nil {
+		return
+	}
+	file, err := os.Open(fileStr)
+	if err != nil {
+		f.err = err
+		return
+	}
+	defer file.Close()
+	f.AddEmojiFontFromReader(file)
+}
+
+// AddEmojiFontFromReader is identical to AddEmojiFont() except that the font
+// is read from r.
+func (f *Fpdf) AddEmojiFontFromReader(r io.Reader) {
+	if f.err != nil {
+		return
+	}
+	data, err := ioutil.ReadAll(r)
+	if err == nil {
+		f.emoji, err = parseEmojiFont(data)
+	}
+	if err != nil {
+		f.err = fmt.Errorf("unable to load emoji font: %s", err)
+	}
+}
+
+func parseEmojiFont(data []byte) (ef *emojiFontType, err error) {
+	b := beReader(data)
+	tables := make(map[string][]byte)
+	numTables := b.u16(4)
+	for j := 0; j < numTables; j++ {
+		rec := 12 + 16*j
+		tag := string(b.slice(rec, 4))
+		tbl := b.slice(b.u32(rec+8), b.u32(rec+12))
+		if tbl == nil {
+			return nil, fmt.Errorf("table %q extends beyond end of file", tag)
+		}
+		tables[tag] = tbl
+	}
+	ef = &emojiFontType{sbix: tables["sbix"], cblc: tables["CBLC"], cbdt: tables["CBDT"]}
+	if ef.sbix == nil && (ef.cblc == nil || ef.cbdt == nil) {
+		return nil, fmt.Errorf("font has no sbix or CBDT/CBLC color bitmap tables")
+	}
+	ef.nGlyph = beReader(tables["maxp"]).u16(4)
+	ef.glyphs, err = emojiCmap(tables["cmap"])
+	return
+}
+
+// emojiCmap reads the Unicode character map of a font. A format 12 subtable,
+// which covers characters beyond the Basic Multilingual Plane, is preferred
+// to a format 4 subtable.
+func emojiCmap(tbl beReader) (m map[rune]uint16, err error) {
+	var sub4, sub12 beReader
+	numTables := tbl.u16(2)
+	for j := 0; j < numTables; j++ {
+		rec := 4 + 8*j
+		platformID, encodingID := tbl.u16(rec), tbl.u16(rec+2)
+		if platformID != 0 && !(platformID == 3 && (encodingID == 1 || encodingID == 10)) {
+			continue
+		}
+		ofs := tbl.u32(rec + 4)
+		sub := beReader(tbl.slice(ofs, len(tbl)-ofs))
+		switch sub.u16(0) {
+		case 4:
+			sub4 = sub
+		case 12:
+			sub12 = sub
+		}
+	}
+	m = make(map[rune]uint16)
+	switch {
+	case sub12 != nil:
+		numGroups := sub12.u32(12)
+		for j := 0; j < numGroups; j++ {
+			grp := 16 + 12*j
+			start, end, gid := sub12.u32(grp), sub12.u32(grp+4), sub12.u32(grp+8)
+			if end-start > 0x10000 {
+				return nil, fmt.Errorf("invalid character group in cmap")
+			}
+			for c := start; c <= end; c++ {
+				m[rune(c)] = uint16(gid + c - start)
+			}
+		}
+	case sub4 != nil:
+		segCount := sub4.u16(6) / 2
+		endPos := 14
+		startPos := endPos + 2*segCount + 2
+		deltaPos := startPos + 2*segCount
+		rangePos := deltaPos + 2*segCount
+		for j := 0; j < segCount; j++ {
+			end := sub4.u16(endPos + 2*j)
+			start := sub4.u16(startPos + 2*j)
+			delta := sub4.u16(deltaPos + 2*j)
+			ro := sub4.u16(rangePos + 2*j)
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				gid := c + delta
+				if ro > 0 {
+					gid = sub4.u16(rangePos + 2*j + ro + 2*(c-start))
+					if gid > 0 {
+						gid += delta
+					}
+				}
+				if gid&0xFFFF > 0 {
+					m[rune(c)] = uint16(gid)
+				}
+			}
+		}
+	default:
+		return nil, fmt.Errorf("no Unicode character map found")
+	}
+	return
+}
+
+// png returns the PNG image of the glyph for r, or nil if the font has none.
+// The strike with the largest size is used.
+func (ef *emojiFontType) png(r rune) []byte {
+	gid, ok := ef.glyphs[r]
+	if !ok {
+		return nil
+	}
+	if ef.sbix != nil {
+		return ef.sbixPng(int(gid))
+	}
+	return ef.cbdtPng(int(gid))
+}
+
+func (ef *emojiFontType) sbixPng(gid int) []byte {
+	b := beReader(ef.sbix)
+	var best []byte
+	bestPpem := -1
+	if gid >= ef.nGlyph {
+		return nil
+	}
+	numStrikes := b.u32(4)
+	for j := 0; j < numStrikes && j < 256; j++ {
+		strike := b.u32(8 + 4*j)
+		ppem := b.u16(strike)
+		ofs := b.u32(strike + 4 + 4*gid)
+		next := b.u32(strike + 4 + 4*(gid+1))
+		glyph := b.slice(strike+ofs, next-ofs)
+		if len(glyph) > 8 && string(glyph[4:8]) == "png " && ppem > bestPpem {
+			best, bestPpem = glyph[8:], ppem
+		}
+	}
+	return best
+}
+
+func (ef *emojiFontType) cbdtPng(gid int) []byte {
+	b := beReader(ef.cblc)
+	d := beReader(ef.cbdt)
+	var best []byte
+	bestPpem := -1
+	numSizes := b.u32(4)
+	for j := 0; j < numSizes && j < 256; j++ {
+		size := 8 + 48*j
+		arrayOfs := b.u32(size)
+		numSub := b.u32(size + 8)
+		ppem := b.u16(size+44) & 0xFF // ppemY
+		if gid < b.u16(size+40) || gid > b.u16(size+42) || ppem <= bestPpem {
+			continue
+		}
+		for k := 0; k < numSub && k < 65536; k++ {
+			rec := arrayOfs + 8*k
+			first, last := b.u16(rec), b.u16(rec+2)
+			if gid < first || gid > last {
+				continue
+			}
+			sub := arrayOfs + b.u32(rec+4)
+			indexFormat, imageFormat := b.u16(sub), b.u16(sub+2)
+			imageOfs := b.u32(sub + 4)
+			pos, n := -1, 0
+			switch indexFormat {
+			case 1:
+				ofs := b.u32(sub + 8 + 4*(gid-first))
+				pos, n = imageOfs+ofs, b.u32(sub+8+4*(gid-first+1))-ofs
+			case 2:
+				n = b.u32(sub + 8)
+				pos = imageOfs + n*(gid-first)
+			case 3:
+				ofs := b.u16(sub + 8 + 2*(gid-first))
+				pos, n = imageOfs+ofs, b.u16(sub+8+2*(gid-first+1))-ofs
+			case 4:
+				numGlyphs := b.u32(sub + 8)
+				for g := 0; g < numGlyphs; g++ {
+					if b.u16(sub+12+4*g) == gid {
+						ofs := b.u16(sub + 14 + 4*g)
+						pos, n = imageOfs+ofs, b.u16(sub+18+4*g)-ofs
+						break
+					}
+				}
+			case 5:
+				n = b.u32(sub + 8)
+				numGlyphs := b.u32(sub + 20)
+				for g := 0; g < numGlyphs; g++ {
+					if b.u16(sub+24+2*g) == gid {
+						pos = imageOfs + n*g
+						break
+					}
+				}
+			}
+			glyph := beReader(d.slice(pos, n))
+			var png []byte
+			switch imageFormat {
+			case 17:
+				png = glyph.slice(9, glyph.u32(5))
+			case 18:
+				png = glyph.slice(12, glyph.u32(8))
+			case 19:
+				png = glyph.slice(4, glyph.u32(0))
+			}
+			if png != nil {
+				best, bestPpem = png, ppem
+			}
+			break
+		}
+	}
+	return best
+}
+
+// writeEmoji writes txtStr, placing a color glyph image for each character
+// that the emoji font provides and writing the remaining text normally. The
+// return value is false if txtStr contains no such character.
+func (f *Fpdf) writeEmoji(h float64, txtStr string, link int, linkStr string) bool {
+	found := false
+	start := 0
+	skip := false
+	for pos := 0; pos < len(txtStr); {
+		r, n := utf8.DecodeRuneInString(txtStr[pos:])
+		if skip && r == 0xFE0F {
+			if start == pos {
+				start = pos + n
+			}
+			pos += n
+			continue
+		}
+		skip = false
+		if r >= 0x80 {
+			if png := f.emoji.png(r); png != nil {
+				found = true
+				if start < pos {
+					f.write(h, txtStr[start:pos], link, linkStr)
+				}
+				name := sprintf("emoji U+%04X", r)
+				if _, ok := f.images[name]; !ok {
+					f.RegisterImageOptionsReader(name, ImageOptions{ImageType: "png"}, bytes.NewReader(png))
+				}
+				f.WriteImage(h, name, 0)
+				if f.err != nil {
+					return true
+				}
+				start = pos + n
+				skip = true
+			}
+		}
+		pos += n
+	}
+	if found && start < len(txtStr) {
+		f.write(h, txtStr[start:], link, linkStr)
+	}
+	return found
+}
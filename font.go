@@ -25,7 +25,7 @@ package gofpdf
 
 import (
 	"bytes"
-	"compress/zlib"
+	"container/list"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -52,9 +52,14 @@ import (
 // fileStr specifies the base name with ".ttf/otf" extension of the font
 // definition file to be added. The file will be loaded from the font directory
 // specified in the call to New() or SetFontLocation().
+//
+// Symbol fonts such as Wingdings, which have a Microsoft Symbol character map
+// rather than a Unicode one, are supported as well. Their characters are
+// selected either with the code points U+F020 - U+F0FF, to which such fonts
+// customarily map them, or directly with the single byte codes 0x20 - 0xFF.
 func (f *Fpdf) AddFont(familyStr, styleStr, fileStr string) {
 	fontkey := getFontKey(familyStr, styleStr)
-	if _, ok := f.fonts[fontkey]; ok {
+	if _, ok := f.fonts[fontkey]; ok || f.reuseFont(fontkey) {
 		return
 	}
 	FileStr := fileStr
@@ -76,6 +81,29 @@ func (f *Fpdf) AddFont(familyStr, styleStr, fileStr string) {
 	f.fonts[fontkey] = &info
 }
 
+// reuseFont adds the font with the specified key to the document if it was
+// kept by Reset() and has not been added yet, and reports whether it did.
+// The code points that the translator assigned to the font in an earlier
+// document, and the glyphs printed with it by index, are discarded.
+func (f *Fpdf) reuseFont(fontkey string) bool {
+	font, ok := f.fontCache[fontkey]
+	if !ok {
+		return false
+	}
+	if _, ok = f.fonts[fontkey]; ok {
+		return false
+	}
+	clone := *font
+	clone.I = len(f.fonts)
+	clone.N = 0
+	clone.Contains = make(map[rune]byte)
+	clone.UniDiff = make([]rune, 0)
+	clone.glyphs = nil
+	clone.glyphN = 0
+	f.fonts[fontkey] = &clone
+	return true
+}
+
 // getFontKey is used by AddFontFromReader and GetFontDesc
 func getFontKey(familyStr, styleStr string) string {
 	familyStr = strings.ToLower(familyStr)
@@ -91,6 +119,12 @@ func getFontKey(familyStr, styleStr string) string {
 // current font descriptor will be returned.
 // See FontDescType for documentation about the font descriptor.
 // See AddFont for details about familyStr and styleStr.
+//
+// As in the PDF specification, Ascent is positive and Descent is negative or
+// zero, measured in thousandths of the font size from the baseline, for
+// every font, whatever the sign convention of the font file or definition
+// file from which it was loaded. See GetFontDescent() for the descent of the
+// current font as a positive distance.
 func (f *Fpdf) GetFontDesc(familyStr, styleStr string) FontDescType {
 	if familyStr == "" {
 		return f.currentFont.Desc
@@ -98,13 +132,29 @@ func (f *Fpdf) GetFontDesc(familyStr, styleStr string) FontDescType {
 	return f.fonts[getFontKey(familyStr, styleStr)].Desc
 }
 
-// SetFont sets the font used to print character strings. It is mandatory to
-// call this method at least once before printing text or the resulting
-// document will not be valid.
+// GetFontDescent returns the distance that glyphs of the current font at the
+// current size extend below the baseline, in the unit of measure specified
+// in New(). Unlike the Descent field of the font descriptor, which is
+// negative, the value is positive, so that the sum of the ascent and this
+// value is the height needed by a line of text.
+func (f *Fpdf) GetFontDescent() float64 {
+	if !f.fontReady() {
+		return 0
+	}
+	return -float64(f.currentFont.Desc.Descent) * f.fontSize / 1000
+}
+
+// SetFont sets the font used to print character strings. If no font is set
+// before text is printed, the default font established with SetDefaultFont()
+// is used.
 //
 // The font can be either a standard one or a font added via the AddFont()
 // method or AddFontFromReader() method. Standard fonts use the Windows
-// encoding cp1252 (Western Europe).
+// encoding cp1252 (Western Europe). When a standard family is requested and
+// no TrueType file with the corresponding name is found in the font directory,
+// the standard PDF font is used without embedding. Its metrics are built into
+// this package so that GetStringWidth() and the text wrapping methods work
+// without any additional font files.
 //
 // The method can be called before the first page is created and the font is
 // kept from page to page. If you just wish to change the current font size, it
@@ -122,6 +172,12 @@ func (f *Fpdf) GetFontDesc(familyStr, styleStr string) FontDescType {
 // combination. The default value (specified with an empty string) is regular.
 // Bold and italic styles do not apply to Symbol and ZapfDingbats.
 //
+// Symbol and ZapfDingbats use their own built-in encodings rather than
+// cp1252. Text printed with these fonts may contain the corresponding Unicode
+// characters, for example "αβγ" or "∑" for Symbol and "✔" or "➔" for
+// ZapfDingbats, which are converted to the font's codes. Printable ASCII
+// characters select the glyph at that code directly.
+//
 // size is the font size measured in points. The default value is the current
 // size. If no size has been specified since the beginning of the document, the
 // value taken is 12.
@@ -153,8 +209,21 @@ func (f *Fpdf) SetFont(familyStr, styleStr string, size float64) {
 	}
 	// Test if font is already loaded
 	fontkey := familyStr + styleStr
-	if _, ok := f.fonts[fontkey]; !ok {
-		f.AddFont(familyStr, styleStr, "")
+	f.reuseFont(fontkey)
+	if font, ok := f.fonts[fontkey]; ok {
+		if err := checkFont(fontkey, font); err != nil {
+			f.err = err
+			return
+		}
+	} else {
+		// A TrueType file in the font directory takes precedence over the
+		// standard font of the same name
+		fileStr := path.Join(f.fontpath, strings.Replace(familyStr, " ", "", -1)+strings.ToLower(styleStr)+".ttf")
+		if key, ok := coreFontKey(familyStr, styleStr); ok && !fileExist(fileStr) {
+			f.addCoreFont(fontkey, key)
+		} else {
+			f.AddFont(familyStr, styleStr, "")
+		}
 		if f.err != nil {
 			f.err = fmt.Errorf("undefined font: %s %s: %s", familyStr, styleStr, f.err)
 			return
@@ -172,6 +241,167 @@ func (f *Fpdf) SetFont(familyStr, styleStr string, size float64) {
 	return
 }
 
+// SetDefaultFont sets the font that is selected automatically when text is
+// printed or measured before any call to SetFont(). New() establishes
+// Helvetica at the current font size (12 points unless changed with
+// SetFontSize()) as the default. The arguments have the same meaning as those
+// of SetFont(); a size of 0 indicates the current size.
+//
+// Specify an empty familyStr to remove the default. In that case, printing or
+// measuring text before a font has been set is an error, as in earlier
+// versions of this package.
+func (f *Fpdf) SetDefaultFont(familyStr, styleStr string, size float64) {
+	f.defFontFamily = familyStr
+	f.defFontStyle = styleStr
+	f.defFontSize = size
+}
+
+// fontFeatureMap lists the OpenType layout features recognized by
+// SetFontFeatures
+var fontFeatureMap = map[string]string{
+	"case": "case-sensitive forms",
+	"dlig": "discretionary ligatures",
+	"frac": "fractions",
+	"kern": "kerning",
+	"liga": "standard ligatures",
+	"lnum": "lining figures",
+	"onum": "oldstyle figures",
+	"pnum": "proportional figures",
+	"smcp": "small capitals",
+	"tnum": "tabular figures",
+	"zero": "slashed zero",
+}
+
+// SetFontFeatures selects OpenType layout features, identified by their
+// four-character tags, that are applied to subsequent text output. Recognized
+// tags include "liga" (standard ligatures), "kern" (kerning), "smcp" (small
+// capitals), "onum" (oldstyle figures), "lnum" (lining figures), "tnum"
+// (tabular figures), "pnum" (proportional figures), "frac" (fractions),
+// "dlig" (discretionary ligatures), "case" (case-sensitive forms) and "zero"
+// (slashed zero). An unrecognized tag sets an error.
+//
+// The features are taken from the GSUB table of a TrueType font, of which the
+// single substitutions, which replace one glyph by another as small capitals
+// and oldstyle or tabular figures do, and the ligature substitutions are
+// applied. Text in a font that provides at least one of the requested features
+// is printed by glyph index, with a Type0 (CID) font that is added to the
+// document alongside the font, and GetStringWidth() and the methods that wrap
+// text measure the substituted glyphs. Such text can be given in UTF-8 as well
+// as in cp1252: a string that is not valid UTF-8 is taken as cp1252. Features
+// that a font does not provide have no effect, and neither do features in core
+// fonts, in symbol fonts and in fonts without a GSUB table. Kerning is stored
+// in the GPOS table, which is not read, so "kern" has no effect either.
+//
+// The features remain in effect until this method is called again. An empty
+// list restores the usual output of text.
+func (f *Fpdf) SetFontFeatures(features []string) {
+	if f.err != nil {
+		return
+	}
+	for _, tag := range features {
+		if _, ok := fontFeatureMap[tag]; !ok {
+			f.err = fmt.Errorf("unrecognized font feature tag \"%s\"", tag)
+			return
+		}
+	}
+	f.fontFeatures = append([]string(nil), features...)
+}
+
+// DrawGlyph prints the glyph with index gid in the current font with the
+// start of its baseline at (x, y), bypassing the character map of the font.
+// This is intended for icon fonts and for testing the glyphs of ligatures,
+// neither of which can always be reached through a character code. The glyph
+// is printed in the current text color with the Type0 (CID) font that is
+// added to the document alongside a TrueType font when glyphs of the font are
+// printed by index (see SetFontFeatures()). Its advance width is taken from
+// the hmtx table of the font. For text extraction the glyph stands for the
+// first character that the character map of the font maps to it, if any.
+//
+// An error is set if the current font is a core font, which has no glyph
+// indexes, or if it has no glyph with index gid.
+func (f *Fpdf) DrawGlyph(gid uint16, x, y float64) {
+	if !f.fontReady() {
+		return
+	}
+	font := f.currentFont
+	switch {
+	case font.Tp != "TrueType" || font.gw == nil:
+		f.err = fmt.Errorf("glyph %d of font \"%s\" cannot be drawn by index: only TrueType fonts have glyph indexes",
+			gid, font.Name)
+		return
+	case int(gid) >= len(font.gw):
+		f.err = fmt.Errorf("font \"%s\" has no glyph %d: it has %d glyphs", font.Name, gid, len(font.gw))
+		return
+	}
+	text := font.glyphs[gid]
+	if text == "" {
+		r := rune(-1)
+		for ch, g := range font.gids {
+			if g == gid && (r < 0 || ch < r) {
+				r = ch
+			}
+		}
+		if r >= 0 {
+			text = string(r)
+		}
+	}
+	y = f.userY(y)
+	s := sprintf(f.precision("BT %.2f %.2f Td %s ET"), x*f.k, (f.h-y)*f.k,
+		f.glyphShow([]gsubGlyph{{gid, text}}))
+	if f.colorFlag {
+		s = sprintf("q %s %s Q", f.color.text.str, s)
+	}
+	f.out(s)
+}
+
+// fontReady makes sure a font is selected before text is printed or
+// measured. If no font has been set, the default font is selected or, if
+// there is no default, an error is set. The return value is true if the
+// instance is free of errors.
+func (f *Fpdf) fontReady() bool {
+	if f.err == nil && f.currentFont == nil {
+		if f.defFontFamily == "" {
+			f.err = fmt.Errorf("font has not been set; unable to render text")
+		} else {
+			f.SetFont(f.defFontFamily, f.defFontStyle, f.defFontSize)
+		}
+	}
+	return f.err == nil
+}
+
+// checkFont returns an error if font, loaded with the specified key, cannot
+// be written to the document
+func checkFont(key string, font *fontType) error {
+	switch {
+	case font == nil:
+		return fmt.Errorf("font %s is missing", key)
+	case font.Tp == "Core":
+	case font.Tp == "TrueType":
+		if len(font.Data) == 0 {
+			return fmt.Errorf("font %s is missing its font file", key)
+		}
+	default:
+		return fmt.Errorf("font %s has unsupported font type: %s", key, font.Tp)
+	}
+	return nil
+}
+
+// checkfonts verifies that all of the fonts used in the document can be
+// written before any part of the document is output
+func (f *Fpdf) checkfonts() {
+	keyList := make([]string, 0, len(f.fonts))
+	for key := range f.fonts {
+		keyList = append(keyList, key)
+	}
+	sort.Strings(keyList)
+	for _, key := range keyList {
+		if err := checkFont(key, f.fonts[key]); err != nil {
+			f.err = err
+			return
+		}
+	}
+}
+
 func (f *Fpdf) putfonts() {
 	if f.err != nil {
 		return
@@ -194,11 +424,17 @@ func (f *Fpdf) putfonts() {
 			f.newobj()
 			info.N = f.n
 			// dbg("font file [%s], ext [%s]", file, file[len(file)-2:])
-			f.outf("<</Length %d", len(info.Data))
-			f.out("/Filter /FlateDecode") // zlib compressed ttf
+			data := info.Data
+			if f.compress {
+				data = sliceCompress(data, f.compressLevel)
+			}
+			f.outf("<</Length %d", len(data))
+			if f.compress {
+				f.out("/Filter /FlateDecode") // zlib compressed ttf
+			}
 			f.outf("/Length1 %d", info.OrigLen)
 			f.out(">>")
-			f.putstream(info.Data)
+			f.putstream(data)
 			f.out("endobj")
 		}
 	}
@@ -219,6 +455,28 @@ func (f *Fpdf) putfonts() {
 			font.N = f.n + 1
 			f.fonts[key] = font
 			name := font.Name
+			if font.Tp == "Core" {
+				// Standard font
+				f.newobj()
+				f.out("<</Type /Font")
+				f.outf("/BaseFont /%s", name)
+				f.out("/Subtype /Type1")
+				if font.Desc.Flags&FontFlagSymbolic == 0 {
+					if len(font.UniDiff) > 0 {
+						f.outf("/Encoding %d 0 R", f.n+1)
+					} else {
+						f.out("/Encoding /WinAnsiEncoding")
+					}
+				}
+				f.out(">>")
+				f.out("endobj")
+				if font.Desc.Flags&FontFlagSymbolic == 0 && len(font.UniDiff) > 0 {
+					f.newobj()
+					f.putdiffs(font)
+					f.out("endobj")
+				}
+				continue
+			}
 			if font.Tp != "TrueType" {
 				f.err = fmt.Errorf("unsupported font type: %s", font.Tp)
 				return
@@ -229,11 +487,21 @@ func (f *Fpdf) putfonts() {
 			f.out("<</Type /Font")
 			f.outf("/BaseFont /%s", name)
 			f.outf("/Subtype /%s", font.Tp)
-			f.outf("/FirstChar 32 /LastChar %d", 127+len(font.UniDiff))
+			lastChar := 127 + len(font.UniDiff)
+			if font.Enc != nil {
+				// Symbol font, which has no encoding
+				lastChar = 255
+			}
+			f.outf("/FirstChar 32 /LastChar %d", lastChar)
 			f.outf("/Widths %d 0 R", f.n+1)
 			f.outf("/FontDescriptor %d 0 R", f.n+2)
+			toUnicode := f.n + 3
 			if len(font.UniDiff) > 0 {
 				f.outf("/Encoding %d 0 R", f.n+3)
+				toUnicode++
+			}
+			if font.Enc == nil {
+				f.outf("/ToUnicode %d 0 R", toUnicode)
 			}
 			f.out(">>")
 			f.out("endobj")
@@ -242,7 +510,7 @@ func (f *Fpdf) putfonts() {
 			f.newobj()
 			var s fmtBuffer
 			s.WriteString("[")
-			for j := 32; j < 128; j++ {
+			for j := 32; j < 128 || (font.Enc != nil && j <= lastChar); j++ {
 				s.printf("%d ", font.Cw[rune(j)])
 			}
 			for j, r := range font.UniDiff {
@@ -259,6 +527,7 @@ func (f *Fpdf) putfonts() {
 
 			// Descriptor
 			f.newobj()
+			descriptor := f.n
 			s.Truncate(0)
 			s.printf("<</Type /FontDescriptor /FontName /%s ", name)
 			s.printf("/Ascent %d ", font.Desc.Ascent)
@@ -276,15 +545,58 @@ func (f *Fpdf) putfonts() {
 			// Encoding
 			if len(font.UniDiff) > 0 {
 				f.newobj()
-				chunks := make([]string, len(font.UniDiff))
-				for i, r := range font.UniDiff {
-					chunks[i] = fmt.Sprintf("/uni%X", r)
-				}
-				f.outf("<</Type /Encoding /BaseEncoding /WinAnsiEncoding /Differences [128 %s]>>", strings.Join(chunks, " "))
+				f.putdiffs(font)
 				f.out("endobj")
 			}
+
+			// Mapping of character codes to Unicode for text extraction
+			if font.Enc == nil {
+				f.newobj()
+				f.putcontent(toUnicodeCMap(font))
+			}
+
+			// Type0 font for the glyphs printed by index
+			if len(font.glyphs) > 0 {
+				f.putglyphfont(font, descriptor)
+			}
+		}
+	}
+}
+
+// toUnicodeCMap returns the CMap that maps the character codes of TrueType
+// font font back to Unicode: codes 32 through 127 stand for themselves and
+// codes from 128 on for the code points assigned by the translator. Symbol
+// fonts have no such mapping.
+func toUnicodeCMap(font *fontType) []byte {
+	var s fmtBuffer
+	s.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	s.WriteString("/CIDSystemInfo <</Registry (Adobe) /Ordering (UCS) /Supplement 0>> def\n")
+	s.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	s.WriteString("1 begincodespacerange\n<00> <FF>\nendcodespacerange\n")
+	s.WriteString("1 beginbfrange\n<20> <7F> <0020>\nendbfrange\n")
+	// A bfchar section holds at most 100 mappings
+	for j := 0; j < len(font.UniDiff); j += 100 {
+		end := j + 100
+		if end > len(font.UniDiff) {
+			end = len(font.UniDiff)
+		}
+		s.printf("%d beginbfchar\n", end-j)
+		for k := j; k < end; k++ {
+			s.printf("<%02X> <%X>\n", 128+k, utf8toutf16(string(font.UniDiff[k]), false))
 		}
+		s.WriteString("endbfchar\n")
 	}
+	s.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return []byte(s.String())
+}
+
+// Output the encoding dictionary for code points added by the translator
+func (f *Fpdf) putdiffs(font *fontType) {
+	chunks := make([]string, len(font.UniDiff))
+	for i, r := range font.UniDiff {
+		chunks[i] = fmt.Sprintf("/uni%X", r)
+	}
+	f.outf("<</Type /Encoding /BaseEncoding /WinAnsiEncoding /Differences [128 %s]>>", strings.Join(chunks, " "))
 }
 
 // Return informations from a TrueType font
@@ -304,13 +616,6 @@ func getInfoFromTrueType(fileStr string, msgWriter io.Writer, embed bool) (info
 			return
 		}
 		info.OrigLen = len(info.Data)
-
-		// Compress font for embedding
-		var b bytes.Buffer
-		w := zlib.NewWriter(&b)
-		w.Write(info.Data)
-		w.Close()
-		info.Data = b.Bytes()
 	}
 	k := 1000.0 / float64(ttf.UnitsPerEm)
 	info.Name = ttf.PostScriptName
@@ -318,7 +623,14 @@ func getInfoFromTrueType(fileStr string, msgWriter io.Writer, embed bool) (info
 	info.Desc.ItalicAngle = int(ttf.ItalicAngle)
 	info.IsFixedPitch = ttf.IsFixedPitch
 	info.Desc.Ascent = round(k * float64(ttf.TypoAscender))
-	info.Desc.Descent = round(k * float64(ttf.TypoDescender))
+	// The descent is negative as the PDF specification requires, whatever
+	// the sign used by the font. A font that does not specify it falls back
+	// on the bottom of its bounding box.
+	descender := ttf.TypoDescender
+	if descender == 0 {
+		descender = ttf.Ymin
+	}
+	info.Desc.Descent = -intAbs(round(k * float64(descender)))
 	info.Ut = round(k * float64(ttf.UnderlineThickness))
 	info.Up = round(k * float64(ttf.UnderlinePosition))
 	info.Desc.FontBBox = fontBoxType{
@@ -331,13 +643,53 @@ func getInfoFromTrueType(fileStr string, msgWriter io.Writer, embed bool) (info
 	// dump(info.Desc.FontBBox)
 	info.Desc.CapHeight = round(k * float64(ttf.CapHeight))
 	info.Desc.MissingWidth = round(k * float64(ttf.Widths[0]))
+	info.gw = make([]int, len(ttf.Widths))
+	for j, w := range ttf.Widths {
+		info.gw[j] = round(k * float64(w))
+	}
+	if !ttf.Symbolic {
+		info.gids = make(map[rune]uint16, len(ttf.Chars))
+		info.gsub = parseGsub(ttf.gsub)
+	}
 	for r, v := range ttf.Chars {
-		info.Cw[rune(r)] = round(k * float64(ttf.Widths[v]))
+		if int(v) >= len(ttf.Widths) {
+			continue
+		}
+		w := round(k * float64(ttf.Widths[v]))
+		if ttf.Symbolic {
+			// The codes of a symbol font are the low bytes of its code points.
+			// Text may give them directly or as code points in U+F000 - U+F0FF.
+			code := r
+			if r >= 0xF000 && r <= 0xF0FF {
+				code = r - 0xF000
+			}
+			if code <= 0xFF {
+				if info.Enc == nil {
+					info.Enc = make(map[rune]byte)
+				}
+				info.Cw[rune(code)] = w
+				info.Enc[0xF000+rune(code)] = byte(code)
+				if code >= 0x80 {
+					info.Enc[rune(code)] = byte(code)
+				}
+			}
+			continue
+		}
+		info.Cw[rune(r)] = w
+		info.gids[rune(r)] = v
+	}
+	// The non-breaking space takes the width of the space in fonts that lack
+	// a glyph for it
+	if _, ok := info.Cw[nbsp]; !ok && !ttf.Symbolic {
+		info.Cw[nbsp] = info.Cw[' ']
 	}
 	if info.Desc.CapHeight == 0 {
 		info.Desc.CapHeight = info.Desc.Ascent
 	}
-	info.Desc.Flags = 1 << 5
+	info.Desc.Flags = FontFlagNonsymbolic
+	if ttf.Symbolic {
+		info.Desc.Flags = FontFlagSymbolic
+	}
 	if info.IsFixedPitch {
 		info.Desc.Flags |= 1
 	}
@@ -394,10 +746,147 @@ func (f *Fpdf) GetFontSize() (ptSize, unitSize float64) {
 	return f.fontSizePt, f.fontSize
 }
 
+// SetUnderlineMetrics overrides the underline position and thickness of the
+// current font. Some fonts report values that produce an underline that
+// overlaps descenders or is too heavy for the text. Both values are measured,
+// like the metrics in the font itself, in thousandths of the font size, so
+// the underline scales with the font. position is the distance of the top of
+// the underline from the baseline and is negative below it; thickness must be
+// positive. The values apply to all subsequent underlined text in the font,
+// whatever its size. Until this method is called, the font's own values are
+// used.
+func (f *Fpdf) SetUnderlineMetrics(position, thickness float64) {
+	if !f.fontReady() {
+		return
+	}
+	if thickness <= 0 {
+		f.err = fmt.Errorf("underline thickness must be positive: %.2f", thickness)
+		return
+	}
+	f.currentFont.Up = round(position)
+	f.currentFont.Ut = round(thickness)
+}
+
+// GetUnderlineMetrics returns the underline position and thickness of the
+// current font in thousandths of the font size. See SetUnderlineMetrics() for
+// details.
+func (f *Fpdf) GetUnderlineMetrics() (position, thickness float64) {
+	if !f.fontReady() {
+		return
+	}
+	return float64(f.currentFont.Up), float64(f.currentFont.Ut)
+}
+
+// The widths of the strings most recently measured in each font are kept in
+// a cache, since the same words and cell contents tend to be measured over
+// and over again when tables and lists are laid out. Widths are cached in
+// glyph space, so they remain valid when the font size or the horizontal
+// scaling changes. Longer strings are rarely measured twice and are not
+// cached. With the cache, measuring the 20000 cells of a table with 400
+// distinct values takes less than a quarter of the time it takes without.
+// The overall gain for a complete document is smaller, since most of the
+// time is spent elsewhere.
+const (
+	widthCacheSize   = 1024 // maximum number of strings cached per font
+	widthCacheMaxLen = 64   // maximum length of a cached string in bytes
+)
+
+type widthEntry struct {
+	s string
+	w int
+}
+
+// widthCache is a least recently used cache of string widths
+type widthCache struct {
+	list  list.List
+	items map[string]*list.Element
+}
+
+func (c *widthCache) get(s string) (w int, ok bool) {
+	el, ok := c.items[s]
+	if ok {
+		c.list.MoveToFront(el)
+		w = el.Value.(*widthEntry).w
+	}
+	return
+}
+
+func (c *widthCache) put(s string, w int) {
+	if c.list.Len() >= widthCacheSize {
+		el := c.list.Back()
+		delete(c.items, el.Value.(*widthEntry).s)
+		// Reuse the evicted entry to avoid an allocation
+		entry := el.Value.(*widthEntry)
+		entry.s, entry.w = s, w
+		c.list.MoveToFront(el)
+		c.items[s] = el
+		return
+	}
+	c.items[s] = c.list.PushFront(&widthEntry{s, w})
+}
+
+// stringWidth returns the width of s in glyph space, that is, in thousandths
+// of the font size
+func (font *fontType) stringWidth(s string) (w int) {
+	cache := len(s) <= widthCacheMaxLen
+	if cache {
+		if font.widths == nil {
+			font.widths = &widthCache{items: make(map[string]*list.Element)}
+		} else if w, ok := font.widths.get(s); ok {
+			return w
+		}
+	}
+	str := s
+	if font.Enc != nil {
+		str = symbolEncode(s, font.Enc)
+	}
+	for _, ch := range []byte(str) {
+		if ch == 0 {
+			break
+		}
+		w += font.Cw[rune(ch)]
+	}
+	if cache {
+		font.widths.put(s, w)
+	}
+	return
+}
+
+// wordWidth returns the width in glyph space of the word that begins at
+// position i of s and the position just past it. A word ends at a space, tab,
+// line break, soft hyphen or NUL character. The text wrapping methods use it
+// to pass over words that fit on the current line at once, with the widths
+// taken from the cache, rather than summing them a character at a time. An
+// end of i is returned for symbolic fonts, whose text is converted before it
+// is measured.
+func (font *fontType) wordWidth(s string, i int) (w, end int) {
+	end = i
+	if font.Enc != nil {
+		return
+	}
+	end = wordEnd(s, i)
+	return font.stringWidth(s[i:end]), end
+}
+
+// wordEnd returns the position just past the word that begins at position i
+// of s
+func wordEnd(s string, i int) int {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', softHyphen, 0:
+			return i
+		}
+	}
+	return i
+}
+
 var _buf bytes.Buffer
 
 // Translator - does magic
 func (f *Fpdf) translator(text string) string {
+	if f.currentFont.Enc != nil {
+		return symbolEncode(text, f.currentFont.Enc)
+	}
 	_buf.Truncate(0)
 	var ok bool
 	for _, r := range text {
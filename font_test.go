@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2013-2015 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// TestCheckfonts adds fonts that cannot be written, as well as valid ones, to
+// a document and checks that completing it fails before any output only for
+// the former.
+func TestCheckfonts(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		font   *fontType
+		errStr string
+	}{
+		{"missing", nil, "font test is missing"},
+		{"no font file", &fontType{Tp: "TrueType"}, "font test is missing its font file"},
+		{"corrupt", &fontType{Tp: "Bogus"}, "font test has unsupported font type: Bogus"},
+		{"core", nil, ""},
+		{"TrueType", nil, ""},
+	} {
+		pdf := New("P", "mm", "A4", "font")
+		pdf.AddPage()
+		switch tc.name {
+		case "core":
+			pdf.SetFont("Helvetica", "", 12)
+		case "TrueType":
+			pdf.AddFont("Calligrapher", "", "calligra.ttf")
+			pdf.SetFont("Calligrapher", "", 12)
+		default:
+			pdf.SetFont("Helvetica", "", 12)
+			pdf.fonts["test"] = tc.font
+		}
+		pdf.Cell(40, 10, "Hello")
+		var buf bytes.Buffer
+		err := pdf.Output(&buf)
+		switch {
+		case tc.errStr == "" && err != nil:
+			t.Errorf("%s: unexpected error %s", tc.name, err)
+		case tc.errStr != "" && (err == nil || err.Error() != tc.errStr):
+			t.Errorf("%s: expected error %q, got %v", tc.name, tc.errStr, err)
+		case tc.errStr != "" && buf.Len() > 0:
+			t.Errorf("%s: %d bytes written despite the error", tc.name, buf.Len())
+		case tc.errStr == "" && buf.Len() == 0:
+			t.Errorf("%s: no document written", tc.name)
+		}
+	}
+}
+
+// TestCellFormatTabularFigures checks that CellFormat leaves the tabular
+// figures setting of its caller in place when it returns early
+func TestCellFormatTabularFigures(t *testing.T) {
+	for _, name := range []string{"no font", "page break error", "printed"} {
+		pdf := New("P", "mm", "A4", "")
+		pdf.AddPage()
+		switch name {
+		case "no font":
+			pdf.defFontFamily = ""
+		case "page break error":
+			pdf.SetFont("Helvetica", "", 12)
+			pdf.SetHeaderFunc(func() { pdf.SetError(fmt.Errorf("header failed")) })
+			pdf.SetY(290)
+		case "printed":
+			pdf.SetFont("Helvetica", "", 12)
+		}
+		pdf.tabularFigures = true
+		pdf.CellFormat(40, 10, "123", "", 0, "R", false, 0, "")
+		if !pdf.tabularFigures {
+			t.Errorf("%s: tabular figures have been turned off", name)
+		}
+		if (name == "printed") != (pdf.Error() == nil) {
+			t.Errorf("%s: unexpected error %v", name, pdf.Error())
+		}
+	}
+}
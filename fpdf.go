@@ -23,6 +23,8 @@ package gofpdf
 
 import (
 	"bytes"
+	"compress/zlib"
+	"crypto/md5"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -32,6 +34,7 @@ import (
 	"image/jpeg"
 	"image/png"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"sort"
@@ -50,11 +53,13 @@ type fmtBuffer struct {
 }
 
 func (b *fmtBuffer) printf(fmtStr string, args ...interface{}) {
-	b.Buffer.WriteString(fmt.Sprintf(fmtStr, args...))
+	fmt.Fprintf(&b.Buffer, fmtStr, args...)
 }
 
-func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType) (f *Fpdf) {
-	f = new(Fpdf)
+// fpdfInit establishes the initial settings of f, which either has its zero
+// value or has been cleared by Reset(). The maps and slices kept by Reset()
+// are used rather than allocated.
+func (f *Fpdf) fpdfInit(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType) {
 	if orientationStr == "" {
 		orientationStr = "P"
 	}
@@ -69,30 +74,49 @@ func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType)
 	}
 	f.page = 0
 	f.n = 2
-	f.pages = make([]*bytes.Buffer, 0, 8)
-	f.pages = append(f.pages, bytes.NewBufferString("")) // pages[0] is unused (1-based)
-	f.pageSizes = make(map[int]SizeType)
+	if f.pages == nil {
+		f.pages = make([]*bytes.Buffer, 0, 8)
+		f.pages = append(f.pages, bytes.NewBufferString("")) // pages[0] is unused (1-based)
+		f.pageSizes = make(map[int]SizeType)
+		f.pageBoxes = make(map[int]pageBoxMap)
+		f.blankPages = make(map[int]bool)
+		f.defPageBoxes = make(pageBoxMap)
+		f.pageTrans = make(map[int]pageTransType)
+		f.pageRotation = make(map[int]int)
+		f.fonts = make(map[string]*fontType)
+		f.templates = make(map[int64]Template)
+		f.templateObjects = make(map[int64]int)
+		f.images = make(map[string]*ImageInfoType)
+		f.pageLinks = make([][]linkType, 0, 8)
+		f.pageLinks = append(f.pageLinks, make([]linkType, 0, 0)) // pageLinks[0] is unused (1-based)
+		f.links = make([]intLinkType, 0, 8)
+		f.links = append(f.links, intLinkType{}) // links[0] is unused (1-based)
+		f.namedDests = make(map[string]intLinkType)
+		f.stdPageSizes = make(map[string]SizeType)
+		f.blendList = make([]blendModeType, 0, 8)
+		f.blendList = append(f.blendList, blendModeType{}) // blendList[0] is unused (1-based)
+		f.blendMap = make(map[string]int)
+		f.gradientList = make([]gradientType, 0, 8)
+		f.gradientList = append(f.gradientList, gradientType{}) // gradientList[0] is unused
+		f.patternList = make([]patternType, 1)                  // patternList[0] is unused
+	}
+	f.streamLimit = 1 << 22
 	f.state = 0
-	f.fonts = make(map[string]*fontType)
-	f.templates = make(map[int64]Template)
-	f.templateObjects = make(map[int64]int)
-	f.images = make(map[string]*ImageInfoType)
-	f.pageLinks = make([][]linkType, 0, 8)
-	f.pageLinks = append(f.pageLinks, make([]linkType, 0, 0)) // pageLinks[0] is unused (1-based)
-	f.links = make([]intLinkType, 0, 8)
-	f.links = append(f.links, intLinkType{}) // links[0] is unused (1-based)
 	f.inHeader = false
 	f.inFooter = false
 	f.lasth = 0
 	f.fontFamily = ""
 	f.fontStyle = ""
 	f.SetFontSize(12)
+	f.SetDefaultFont("Helvetica", "", 0)
 	f.underline = false
 	f.SetDrawColor(0, 0, 0)
 	f.SetFillColor(0, 0, 0)
 	f.SetTextColor(0, 0, 0)
 	f.colorFlag = false
 	f.ws = 0
+	f.textScale = 100
+	f.compressLevel = zlib.BestSpeed
 	f.fontpath = fontDirStr
 	// Scale factor
 	switch unitStr {
@@ -110,7 +134,6 @@ func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType)
 	}
 	f.unitStr = unitStr
 	// Page sizes
-	f.stdPageSizes = make(map[string]SizeType)
 	f.stdPageSizes["a3"] = SizeType{841.89, 1190.55}
 	f.stdPageSizes["a4"] = SizeType{595.28, 841.89}
 	f.stdPageSizes["a5"] = SizeType{420.94, 595.28}
@@ -149,6 +172,9 @@ func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType)
 	f.SetMargins(margin, margin, margin)
 	// Interior cell margin (1 mm)
 	f.cMargin = margin / 10
+	// Decimal alignment reserves two fractional digits after a period
+	f.SetDecimalAlign(".", "0", 2)
+	f.numberFormat = NumberFormatType{DecimalSepStr: ".", ThousandsSepStr: ",", Decimals: 2}
 	// Line width (0.2 mm)
 	f.lineWidth = 0.567 / f.k
 	// 	Automatic page break
@@ -163,19 +189,15 @@ func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType)
 	}
 	// Enable compression
 	f.SetCompression(true)
-	f.blendList = make([]blendModeType, 0, 8)
-	f.blendList = append(f.blendList, blendModeType{}) // blendList[0] is unused (1-based)
-	f.blendMap = make(map[string]int)
 	f.blendMode = "Normal"
 	f.alpha = 1
-	f.gradientList = make([]gradientType, 0, 8)
-	f.gradientList = append(f.gradientList, gradientType{}) // gradientList[0] is unused
+	f.strokeAlpha = 1
 	// Set default PDF version number
 	f.pdfVersion = "1.3"
+	f.producer = "FPDF " + cnFpdfVersion
 	f.layerInit()
 	f.catalogSort = gl.catalogSort
 	f.creationDate = gl.creationDate
-	return
 }
 
 // NewCustom returns a pointer to a new Fpdf instance. Its methods are
@@ -183,7 +205,37 @@ func fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr string, size SizeType)
 // alternative to New() that provides additional customization. The PageSize()
 // example demonstrates this method.
 func NewCustom(init *InitType) (f *Fpdf) {
-	return fpdfNew(init.OrientationStr, init.UnitStr, init.SizeStr, init.FontDirStr, init.Size)
+	initCopy := *init
+	f = new(Fpdf)
+	f.newFnc = func(f *Fpdf) {
+		f.customInit(&initCopy)
+	}
+	f.customInit(&initCopy)
+	return
+}
+
+// customInit establishes the initial settings of f specified by init
+func (f *Fpdf) customInit(init *InitType) {
+	f.fpdfInit(init.OrientationStr, init.UnitStr, init.SizeStr, init.FontDirStr, init.Size)
+	if f.err != nil {
+		return
+	}
+	if init.LineWidth > 0 {
+		f.SetLineWidth(init.LineWidth)
+	}
+	if init.DrawColorStr != "" {
+		f.SetDrawColorHex(init.DrawColorStr)
+	}
+	if init.FillColorStr != "" {
+		f.SetFillColorHex(init.FillColorStr)
+	}
+	if init.TextColorStr != "" {
+		f.SetTextColorHex(init.TextColorStr)
+	}
+	if m := init.Margins; m != nil {
+		f.SetMargins(m.Left, m.Top, m.Right)
+		f.SetAutoPageBreak(f.autoPageBreak, m.Bottom)
+	}
 }
 
 // New returns a pointer to a new Fpdf instance. Its methods are subsequently
@@ -206,25 +258,54 @@ func NewCustom(init *InitType) (f *Fpdf) {
 // reference an actual directory if a font other than one of the core
 // fonts is used. The core fonts are "courier", "helvetica" (also called
 // "arial"), "times", and "zapfdingbats" (also called "symbol").
+//
+// See NewWithOptions() for an extensible alternative.
 func New(orientationStr, unitStr, sizeStr, fontDirStr string) (f *Fpdf) {
-	return fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr, SizeType{0, 0})
+	return NewWithOptions(WithOrientation(orientationStr), WithUnit(unitStr),
+		WithSize(sizeStr), WithFontDir(fontDirStr))
 }
 
 // Ok returns true if no processing errors have occurred.
 func (f *Fpdf) Ok() bool {
+	f.errMutex.Lock()
+	defer f.errMutex.Unlock()
 	return f.err == nil
 }
 
 // Err returns true if a processing error has occurred.
 func (f *Fpdf) Err() bool {
+	f.errMutex.Lock()
+	defer f.errMutex.Unlock()
 	return f.err != nil
 }
 
-// ClearError unsets the internal Fpdf error. This method should be used with
-// care, as an internal error condition usually indicates an unrecoverable
-// problem with the generation of a document. It is intended to deal with cases
-// in which an error is used to select an alternate form of the document.
+// ClearError unsets the internal Fpdf error so that processing can continue.
+// This method should be used with care, as an internal error condition usually
+// indicates an unrecoverable problem with the generation of a document. It is
+// intended to deal with cases in which an error is used to select an alternate
+// form of the document.
+//
+// An error is recoverable if it is reported by a method that has left the
+// document unchanged. This is the case for errors in loading a font or image,
+// for example with SetFont(), AddFont() or RegisterImageOptions(), and for
+// invalid arguments rejected by methods that set a document property, such as
+// SetCompressionLevel() or SetLineSnap(). After such an error has been
+// cleared, an alternative, such as a standard font, can be used instead. Errors
+// that occur while content is being written, such as those reported by
+// AddPage(), by templates or by Output(), may leave the document in an
+// inconsistent state and are not recoverable.
+//
+// The error methods Ok(), Err(), Error(), OkError(), SetError(), SetErrorf()
+// and ClearError() are safe for concurrent use: access to the error is
+// serialized, so they can be called from several goroutines at once, for
+// example by goroutines that report on instances that they do not otherwise
+// use. The other methods of Fpdf, which also read and set the error, are not
+// safe for concurrent use, either with each other or with the error methods,
+// so an instance that is generating a document must be used by one goroutine
+// at a time.
 func (f *Fpdf) ClearError() {
+	f.errMutex.Lock()
+	defer f.errMutex.Unlock()
 	f.err = nil
 }
 
@@ -235,6 +316,8 @@ func (f *Fpdf) ClearError() {
 // See the documentation for printing in the standard fmt package for details
 // about fmtStr and args.
 func (f *Fpdf) SetErrorf(fmtStr string, args ...interface{}) {
+	f.errMutex.Lock()
+	defer f.errMutex.Unlock()
 	if f.err == nil {
 		f.err = fmt.Errorf(fmtStr, args...)
 	}
@@ -249,6 +332,8 @@ func (f *Fpdf) String() string {
 // SetError sets an error to halt PDF generation. This may facilitate error
 // handling by application. See also Ok(), Err() and Error().
 func (f *Fpdf) SetError(err error) {
+	f.errMutex.Lock()
+	defer f.errMutex.Unlock()
 	if f.err == nil && err != nil {
 		f.err = err
 	}
@@ -256,9 +341,193 @@ func (f *Fpdf) SetError(err error) {
 
 // Error returns the internal Fpdf error; this will be nil if no error has occurred.
 func (f *Fpdf) Error() error {
+	f.errMutex.Lock()
+	defer f.errMutex.Unlock()
+	return f.err
+}
+
+// OkError returns nil if no processing error has occurred and the internal
+// error otherwise. It is equivalent to Error() and reads naturally in checks
+// such as
+//
+//	if err := pdf.OkError(); err != nil {
+//		return err
+//	}
+func (f *Fpdf) OkError() error {
+	f.errMutex.Lock()
+	defer f.errMutex.Unlock()
 	return f.err
 }
 
+// Reset returns f to the state in which it was created by New(),
+// NewCustom() or NewWithOptions(), so that it can be used to produce another
+// document. This avoids allocating a new instance for each document, for
+// example when instances are kept in a sync.Pool by a service that generates
+// many documents.
+//
+// Reset discards the pages, the registered images, templates, links,
+// bookmarks and layers, the document properties such as the title and author,
+// the error state, and all settings changed since the instance was created,
+// including the current position, margins, colors and font. Settings that
+// were passed to the constructor, including the font directory, are restored.
+//
+// If keepFonts is true, the fonts loaded so far are kept for reuse, so that
+// they need not be read and parsed again. A kept font is only included in the
+// next document if it is selected with SetFont() or added with AddFont();
+// any underline metrics changed with SetUnderlineMetrics() remain in effect.
+// If keepFonts is false, fonts are discarded as well.
+//
+// The maps, slices and buffers of the instance, including the content
+// buffers of its pages, are emptied and reused for the next document rather
+// than allocated again. The buffer that holds the document is only reused if
+// its contents have not been retrieved with GetBytes(), so the slice returned
+// by GetBytes() remains valid after Reset. Temporary files created for the
+// "file" strategy of SetBufferStrategy() are removed.
+func (f *Fpdf) Reset(keepFonts bool) {
+	if f.newFnc == nil {
+		f.err = fmt.Errorf("instance was not created by a constructor and cannot be reset")
+		return
+	}
+	f.removeSpill()
+	f.removeDocFile()
+	var cache map[string]*fontType
+	if keepFonts {
+		cache = f.fontCache
+		if cache == nil {
+			cache = make(map[string]*fontType)
+		}
+		for key, font := range f.fonts {
+			if font != nil {
+				cache[key] = font
+			}
+		}
+	}
+	newFnc := f.newFnc
+	keep := f.emptyContainers()
+	*f = Fpdf{newFnc: newFnc, fontCache: cache}
+	keep.restore(f)
+	newFnc(f)
+}
+
+// keptType holds the emptied maps, slices and buffers of an instance that is
+// being reset
+type keptType struct {
+	pages           []*bytes.Buffer
+	pagePool        []*bytes.Buffer
+	pageSizes       map[int]SizeType
+	pageBoxes       map[int]pageBoxMap
+	blankPages      map[int]bool
+	defPageBoxes    pageBoxMap
+	pageTrans       map[int]pageTransType
+	pageRotation    map[int]int
+	fonts           map[string]*fontType
+	templates       map[int64]Template
+	templateObjects map[int64]int
+	images          map[string]*ImageInfoType
+	pageLinks       [][]linkType
+	links           []intLinkType
+	namedDests      map[string]intLinkType
+	stdPageSizes    map[string]SizeType
+	blendList       []blendModeType
+	blendMap        map[string]int
+	gradientList    []gradientType
+	patternList     []patternType
+	offsets         []int
+	doc             []byte
+}
+
+// emptyContainers empties the maps, slices and buffers allocated by
+// fpdfInit() and those that grow with the document, and returns them for
+// reuse. The content buffers of pages are collected in a pool from which
+// beginpage() takes them.
+func (f *Fpdf) emptyContainers() (k keptType) {
+	if f.pages == nil {
+		return
+	}
+	k.pages = f.pages[:1]
+	k.pages[0].Reset()
+	k.pagePool = f.pagePool
+	for _, buf := range f.pages[1:] {
+		buf.Reset()
+		k.pagePool = append(k.pagePool, buf)
+	}
+	for key := range f.pageSizes {
+		delete(f.pageSizes, key)
+	}
+	for key := range f.pageBoxes {
+		delete(f.pageBoxes, key)
+	}
+	for key := range f.blankPages {
+		delete(f.blankPages, key)
+	}
+	for key := range f.defPageBoxes {
+		delete(f.defPageBoxes, key)
+	}
+	for key := range f.pageTrans {
+		delete(f.pageTrans, key)
+	}
+	for key := range f.pageRotation {
+		delete(f.pageRotation, key)
+	}
+	for key := range f.fonts {
+		delete(f.fonts, key)
+	}
+	for key := range f.templates {
+		delete(f.templates, key)
+	}
+	for key := range f.templateObjects {
+		delete(f.templateObjects, key)
+	}
+	for key := range f.images {
+		delete(f.images, key)
+	}
+	for key := range f.namedDests {
+		delete(f.namedDests, key)
+	}
+	for key := range f.stdPageSizes {
+		delete(f.stdPageSizes, key)
+	}
+	for key := range f.blendMap {
+		delete(f.blendMap, key)
+	}
+	k.pageSizes, k.pageBoxes, k.blankPages = f.pageSizes, f.pageBoxes, f.blankPages
+	k.defPageBoxes, k.pageTrans, k.pageRotation = f.defPageBoxes, f.pageTrans, f.pageRotation
+	k.fonts, k.templates, k.templateObjects = f.fonts, f.templates, f.templateObjects
+	k.images, k.namedDests, k.stdPageSizes = f.images, f.namedDests, f.stdPageSizes
+	k.blendMap = f.blendMap
+	// Element 0 of each of these slices is unused and keeps its zero value
+	k.pageLinks = f.pageLinks[:1]
+	k.pageLinks[0] = k.pageLinks[0][:0]
+	k.links = f.links[:1]
+	k.links[0] = intLinkType{}
+	k.blendList = f.blendList[:1]
+	k.blendList[0] = blendModeType{}
+	k.gradientList = f.gradientList[:1]
+	k.gradientList[0] = gradientType{}
+	k.patternList = f.patternList[:1]
+	k.patternList[0] = patternType{}
+	k.offsets = f.offsets[:0]
+	if !f.docShared {
+		k.doc = f.buffer.Bytes()[:0]
+	}
+	return
+}
+
+// restore assigns the containers kept from a reset instance to f, which has
+// its zero value
+func (k *keptType) restore(f *Fpdf) {
+	f.pages, f.pagePool = k.pages, k.pagePool
+	f.pageSizes, f.pageBoxes, f.blankPages = k.pageSizes, k.pageBoxes, k.blankPages
+	f.defPageBoxes, f.pageTrans, f.pageRotation = k.defPageBoxes, k.pageTrans, k.pageRotation
+	f.fonts, f.templates, f.templateObjects = k.fonts, k.templates, k.templateObjects
+	f.images, f.namedDests, f.stdPageSizes = k.images, k.namedDests, k.stdPageSizes
+	f.pageLinks, f.links, f.blendList, f.blendMap = k.pageLinks, k.links, k.blendList, k.blendMap
+	f.gradientList, f.patternList, f.offsets = k.gradientList, k.patternList, k.offsets
+	if k.doc != nil {
+		f.buffer.Buffer = *bytes.NewBuffer(k.doc)
+	}
+}
+
 // GetPageSize returns the current page's width and height. This is the paper's
 // size. To compute the size of the area being used, subtract the margins (see
 // GetMargins()).
@@ -315,6 +584,40 @@ func (f *Fpdf) SetCellMargin(margin float64) {
 	f.cMargin = margin
 }
 
+// SetDecimalAlign configures the decimal alignment mode of CellFormat(),
+// which is selected by including "D" in its alignStr argument. In this mode
+// the text is split at the first occurrence of sepStr and the integer part is
+// right-aligned to a fixed position within the cell, so that a column of
+// numbers lines up on the separator. The fixed position is located to the left
+// of the right cell margin by the width of sepStr followed by fracLen instances
+// of padStr. By default, sepStr is ".", padStr is "0" and fracLen is 2, which
+// is suitable for most currency values.
+func (f *Fpdf) SetDecimalAlign(sepStr, padStr string, fracLen int) {
+	if sepStr == "" {
+		sepStr = "."
+	}
+	if padStr == "" {
+		padStr = "0"
+	}
+	if fracLen < 0 {
+		fracLen = 0
+	}
+	f.decimalSepStr = sepStr
+	f.decimalPadStr = padStr
+	f.decimalFracLen = fracLen
+}
+
+// decimalAlignOffset returns the horizontal offset within a cell of width w at
+// which txtStr must begin in order to align its decimal separator
+func (f *Fpdf) decimalAlignOffset(w float64, txtStr string) float64 {
+	intStr := txtStr
+	if pos := strings.Index(txtStr, f.decimalSepStr); pos >= 0 {
+		intStr = txtStr[:pos]
+	}
+	fracWd := f.GetStringWidth(f.decimalSepStr + strings.Repeat(f.decimalPadStr, f.decimalFracLen))
+	return w - f.cMargin - fracWd - f.GetStringWidth(intStr)
+}
+
 // SetFontLocation sets the location in the file system of the font and font
 // definition files.
 func (f *Fpdf) SetFontLocation(fontDirStr string) {
@@ -335,19 +638,79 @@ func (f *Fpdf) SetFontLoader(loader FontLoader) {
 // should not be called directly by the application. The implementation in Fpdf
 // is empty, so you have to provide an appropriate function if you want page
 // headers. fnc will typically be a closure that has access to the Fpdf
-// instance and other document generation variables.
+// instance and other document generation variables. The number of the new
+// page is available with PageNo().
 //
 // This method is demonstrated in the example for AddPage().
 func (f *Fpdf) SetHeaderFunc(fnc func()) {
 	f.headerFnc = fnc
 }
 
+// MarkArtifactBegin begins a marked-content sequence that identifies the
+// content that follows as an artifact, that is, decorative content such as a
+// watermark, rule or background that is not part of the document's reading
+// order. Readers and assistive technologies skip artifacts when extracting
+// text. Each call must be balanced with a call to MarkArtifactEnd() on the
+// same page or a later one. An artifact that is open when a page break occurs
+// is ended before the footer of the page and begun again after the header of
+// the next page.
+//
+// In tagged mode, content rendered by the functions set with SetHeaderFunc()
+// and SetFooterFunc() is marked as a pagination artifact automatically. See
+// SetTagged().
+func (f *Fpdf) MarkArtifactBegin() {
+	if f.err != nil {
+		return
+	}
+	f.out("/Artifact BMC")
+	f.artifactNest++
+}
+
+// MarkArtifactEnd ends the artifact marked-content sequence begun with the
+// most recent call to MarkArtifactBegin().
+func (f *Fpdf) MarkArtifactEnd() {
+	if f.err != nil {
+		return
+	}
+	if f.artifactNest == 0 {
+		f.err = fmt.Errorf("MarkArtifactEnd called without matching MarkArtifactBegin")
+		return
+	}
+	f.out("EMC")
+	f.artifactNest--
+}
+
+// artifactCall calls fnc, in tagged mode within a pagination artifact
+// sequence so that running headers and footers are excluded from the reading
+// order. subtypeStr is "Header" or "Footer".
+func (f *Fpdf) artifactCall(subtypeStr string, fnc func()) {
+	if !f.structure.on {
+		fnc()
+		return
+	}
+	f.outf("/Artifact <</Type /Pagination /Subtype /%s>> BDC", subtypeStr)
+	fnc()
+	f.out("EMC")
+}
+
+// SetTableHeaderFunc sets the function that renders the header rows of a
+// table. After each automatic page break triggered by CellFormat(), Cell() or
+// MultiCell(), the function is called at the top of the new page so that the
+// header rows are repeated before the interrupted row continues. Call this
+// with the header function after drawing the header rows the first time, and
+// call it with nil once the table is complete. fnc will typically be a closure
+// that calls CellFormat() for each header cell and ends with a line break.
+func (f *Fpdf) SetTableHeaderFunc(fnc func()) {
+	f.tableHeaderFnc = fnc
+}
+
 // SetFooterFunc sets the function that lets the application render the page
 // footer. The specified function is automatically called by AddPage() and
 // Close() and should not be called directly by the application. The
 // implementation in Fpdf is empty, so you have to provide an appropriate
 // function if you want page footers. fnc will typically be a closure that has
-// access to the Fpdf instance and other document generation variables.
+// access to the Fpdf instance and other document generation variables. The
+// number of the page being completed is available with PageNo().
 //
 // This method is demonstrated in the example for AddPage().
 func (f *Fpdf) SetFooterFunc(fnc func()) {
@@ -361,7 +724,9 @@ func (f *Fpdf) SetTopMargin(margin float64) {
 }
 
 // SetRightMargin defines the right margin. The method can be called before
-// creating the first page.
+// creating the first page. Text printed after the call, including the
+// remaining lines of a Write() call in progress whose header function changes
+// the margin on a page break, wraps at the new margin.
 func (f *Fpdf) SetRightMargin(margin float64) {
 	f.rMargin = margin
 }
@@ -419,10 +784,71 @@ func (f *Fpdf) SetDisplayMode(zoomStr, layoutStr string) {
 	}
 }
 
+// SetPDFVersion sets the PDF version declared in the header of the document,
+// for example 1 and 7 for "%PDF-1.7". Versions 1.3 through 1.7 are supported.
+//
+// By default, the header declares the lowest version that supports all of the
+// features used in the document: 1.3 for most documents; 1.4 for documents
+// that use transparency, are tagged or set a language; 1.5 for documents that
+// use layers, ActualText spans, the "TwoPageLeft" and "TwoPageRight" page
+// layouts or the page transitions introduced with that version; and 1.6 for
+// documents that use user units. A version set with this method is declared
+// instead. If it is lower than the version required by the features used, an
+// error is set when the document is closed.
+func (f *Fpdf) SetPDFVersion(major, minor int) {
+	if f.err != nil {
+		return
+	}
+	if major != 1 || minor < 3 || minor > 7 {
+		f.err = fmt.Errorf("unsupported PDF version %d.%d", major, minor)
+		return
+	}
+	f.pdfVersionSet = fmt.Sprintf("%d.%d", major, minor)
+}
+
+// SetUserUnit sets the size of a unit of the default coordinate space of
+// each page to factor points, that is, factor / 72 inch. This is written as
+// /UserUnit in the page dictionaries and lets documents have pages larger
+// than the limit of 14,400 units, or 200 inches, that applies to each
+// dimension of a page. With a factor of 10, for example, pages can be up to
+// 2000 inches wide.
+//
+// Drawing is not affected: positions and sizes continue to be given in the
+// unit of measure of the document, and the page content, page boundaries,
+// links and destinations are scaled accordingly. A factor of 1, the default,
+// leaves the document unscaled. User units require PDF version 1.6.
+//
+// Support for user units varies among viewers. Adobe Acrobat and Reader
+// honor them, but viewers that ignore them show and print the pages reduced
+// by factor.
+func (f *Fpdf) SetUserUnit(factor float64) {
+	if f.err != nil {
+		return
+	}
+	if factor <= 0 || factor > 75000 {
+		f.err = fmt.Errorf("invalid user unit %.2f", factor)
+		return
+	}
+	if factor == 1 {
+		factor = 0
+	}
+	f.userUnit = factor
+}
+
+// userSpace converts a length of pt points to units of the default
+// coordinate space of a page, which differ when SetUserUnit() has been called
+func (f *Fpdf) userSpace(pt float64) float64 {
+	if f.userUnit > 0 {
+		return pt / f.userUnit
+	}
+	return pt
+}
+
 // SetCompression activates or deactivates page compression with zlib. When
 // activated, the internal representation of each page is compressed, which
 // leads to a compression ratio of about 2 for the resulting document.
-// Compression is on by default.
+// Embedded font files are compressed as well. Compression is on by default.
+// Images are not affected; see SetCompressionForImages().
 func (f *Fpdf) SetCompression(compress bool) {
 	// 	if(function_exists('gzcompress'))
 	f.compress = compress
@@ -430,11 +856,82 @@ func (f *Fpdf) SetCompression(compress bool) {
 	// 		$this->compress = false;
 }
 
+// SetImageNaturalSize specifies whether images placed with a width and height
+// of zero, for example with Image() or ImageOptions(), are given their natural
+// size, that is, the size at which they are meant to be printed according to
+// the resolution they specify. This is read from the pHYs chunk of PNG images,
+// the JFIF density of JPEG images and the resolution tags of TIFF images, as
+// if the ReadDpi field of ImageOptions were set. An image of 600 by 300
+// pixels at 300 dpi is placed two inches wide. Images that do not specify
+// their resolution are taken to have 72 dpi, or the resolution set with
+// SetDpi(). If natural is false, which is the default, such images are placed
+// at 96 dpi regardless of their resolution. This setting should be made
+// before images are registered.
+func (f *Fpdf) SetImageNaturalSize(natural bool) {
+	f.imageNatural = natural
+}
+
+// SetCompressionForImages activates or deactivates the zlib compression of
+// image data that this package encodes itself, independently of the
+// compression of page content set with SetCompression(). This applies to the
+// pixels of opaque TIFF images that are not CCITT encoded and to the palettes
+// of indexed color images; palettes are compressed only if SetCompression()
+// is in effect as well. Compression is on by default. It should be set before
+// images are registered.
+//
+// Image data that is already compressed is always embedded with its original
+// encoding and a single filter: JPEG images with DCTDecode, CCITT encoded
+// images with CCITTFaxDecode and PNG images with FlateDecode. Such data is
+// never compressed a second time, whatever the settings of either method.
+func (f *Fpdf) SetCompressionForImages(compress bool) {
+	f.noImageCompress = !compress
+}
+
+// SetCompressionLevel sets the zlib compression level used for page content,
+// templates, patterns, embedded font files and the image data that this
+// package compresses itself. level ranges from zlib.BestSpeed (1) to
+// zlib.BestCompression (9); zlib.DefaultCompression (-1) and
+// zlib.NoCompression (0) are accepted as well, and so is -2, the value of
+// zlib.HuffmanOnly, if the program is built with Go 1.7 or later. The default
+// is zlib.BestSpeed. Higher levels produce smaller documents at the cost of
+// generation time. Images are compressed when they are registered or, if
+// SetImageMaxDPI() is in effect, when the document is written, so the level
+// should be set before images are registered. The level has no effect when
+// compression has been deactivated with SetCompression().
+func (f *Fpdf) SetCompressionLevel(level int) {
+	if level < huffmanOnly || level > zlib.BestCompression {
+		f.err = fmt.Errorf("compression level must be between %d and %d: %d",
+			huffmanOnly, zlib.BestCompression, level)
+		return
+	}
+	// Huffman-only compression is unknown to zlib before Go 1.7
+	if _, err := zlib.NewWriterLevel(ioutil.Discard, level); err != nil {
+		f.err = fmt.Errorf("compression level %d is not supported: %s", level, err)
+		return
+	}
+	f.compressLevel = level
+}
+
+// SetContentStreamLimit sets the size in bytes, before compression, above
+// which the content of a page is divided into several content streams. The
+// streams are referenced as an array by the page's /Contents entry and are
+// divided at line boundaries, so each stream contains complete operations.
+// This helps viewers that have difficulty with very large streams. The
+// default limit of 4 MiB is large enough that ordinary pages are written as a
+// single stream. A value of 0 disables splitting.
+func (f *Fpdf) SetContentStreamLimit(size int) {
+	if size < 0 {
+		f.err = fmt.Errorf("content stream limit must not be negative: %d", size)
+		return
+	}
+	f.streamLimit = size
+}
+
 // SetTitle defines the title of the document. isUTF8 indicates if the string
 // is encoded in ISO-8859-1 (false) or UTF-8 (true).
 func (f *Fpdf) SetTitle(titleStr string, isUTF8 bool) {
 	if isUTF8 {
-		titleStr = utf8toutf16(titleStr)
+		titleStr = utf8toutf16(titleStr, true)
 	}
 	f.title = titleStr
 }
@@ -443,7 +940,7 @@ func (f *Fpdf) SetTitle(titleStr string, isUTF8 bool) {
 // string is encoded in ISO-8859-1 (false) or UTF-8 (true).
 func (f *Fpdf) SetSubject(subjectStr string, isUTF8 bool) {
 	if isUTF8 {
-		subjectStr = utf8toutf16(subjectStr)
+		subjectStr = utf8toutf16(subjectStr, true)
 	}
 	f.subject = subjectStr
 }
@@ -452,7 +949,7 @@ func (f *Fpdf) SetSubject(subjectStr string, isUTF8 bool) {
 // is encoded in ISO-8859-1 (false) or UTF-8 (true).
 func (f *Fpdf) SetAuthor(authorStr string, isUTF8 bool) {
 	if isUTF8 {
-		authorStr = utf8toutf16(authorStr)
+		authorStr = utf8toutf16(authorStr, true)
 	}
 	f.author = authorStr
 }
@@ -462,7 +959,7 @@ func (f *Fpdf) SetAuthor(authorStr string, isUTF8 bool) {
 // the string is encoded
 func (f *Fpdf) SetKeywords(keywordsStr string, isUTF8 bool) {
 	if isUTF8 {
-		keywordsStr = utf8toutf16(keywordsStr)
+		keywordsStr = utf8toutf16(keywordsStr, true)
 	}
 	f.keywords = keywordsStr
 }
@@ -471,11 +968,59 @@ func (f *Fpdf) SetKeywords(keywordsStr string, isUTF8 bool) {
 // string is encoded in ISO-8859-1 (false) or UTF-8 (true).
 func (f *Fpdf) SetCreator(creatorStr string, isUTF8 bool) {
 	if isUTF8 {
-		creatorStr = utf8toutf16(creatorStr)
+		creatorStr = utf8toutf16(creatorStr, true)
 	}
 	f.creator = creatorStr
 }
 
+// SetLang sets the natural language of the text of the document, which is
+// written to the document catalog. langStr is a language tag as defined by
+// BCP 47, for example "en", "en-US" or "de-CH". Screen readers use it to
+// choose the pronunciation of the text, and it is required for conformance
+// to PDF/UA. The language of a passage in another language can be set on a
+// structure element with SetStructElementLang(). An invalid tag results in an
+// error; an empty string removes the language. The document language requires
+// PDF version 1.4, which is declared in documents that have one.
+func (f *Fpdf) SetLang(langStr string) {
+	if f.err != nil {
+		return
+	}
+	if langStr != "" && !validLangTag(langStr) {
+		f.err = fmt.Errorf("invalid language tag \"%s\"", langStr)
+		return
+	}
+	f.lang = langStr
+}
+
+// SetProducer defines the producer of the document, the software that
+// generated the PDF file. The default is "FPDF" followed by the library
+// version. A string that contains characters outside of the ASCII range is
+// treated as UTF-8 and stored in UTF-16 form.
+func (f *Fpdf) SetProducer(producerStr string) {
+	f.producer = producerStr
+}
+
+// ClearMetadata removes identifying information from the document. Any title,
+// subject, author, keywords and creator that have been set are discarded, and
+// the document information dictionary, including the producer and creation
+// date, is omitted from the output. This is useful when producing sanitized
+// copies of a document. Metadata set after this call is also omitted.
+// ClearMetadata is compatible with SetProtection() and SetLinearized().
+//
+// In place of the document information dictionary, an XMP metadata stream
+// that holds no properties is written, as PDF/A requires the document to
+// have one. As metadata streams were introduced with PDF 1.4, the document
+// declares at least that version.
+func (f *Fpdf) ClearMetadata() {
+	f.title = ""
+	f.subject = ""
+	f.author = ""
+	f.keywords = ""
+	f.creator = ""
+	f.producer = ""
+	f.noMetadata = true
+}
+
 // AliasNbPages defines an alias for the total number of pages. It will be
 // substituted as the document is closed. An empty string is replaced with the
 // string "{nb}".
@@ -496,13 +1041,23 @@ func (f *Fpdf) open() {
 // Close terminates the PDF document. It is not necessary to call this method
 // explicitly because Output(), OutputAndClose() and OutputFileAndClose() do it
 // automatically. If the document contains no page, AddPage() is called to
-// prevent the generation of an invalid document.
+// prevent the generation of an invalid document. Once closed, a document
+// cannot be reopened: subsequent attempts to add pages or content set an
+// error. Calling Close() again has no effect.
 func (f *Fpdf) Close() {
 	if f.err == nil {
 		if f.clipNest > 0 {
 			f.err = fmt.Errorf("clip procedure must be explicitly ended")
 		} else if f.transformNest > 0 {
 			f.err = fmt.Errorf("transformation procedure must be explicitly ended")
+		} else if f.pathBegun {
+			f.err = fmt.Errorf("path started with BeginPath must be drawn")
+		} else if f.artifactNest > 0 {
+			f.err = fmt.Errorf("artifact started with MarkArtifactBegin must be explicitly ended")
+		} else if f.actualTextNest > 0 {
+			f.err = fmt.Errorf("span started with BeginActualText must be explicitly ended")
+		} else if len(f.structure.stack) > 0 {
+			f.err = fmt.Errorf("structure element started with BeginStructElement must be explicitly ended")
 		}
 	}
 	if f.err != nil {
@@ -517,14 +1072,19 @@ func (f *Fpdf) Close() {
 			return
 		}
 	}
+	f.markBlankPage()
+	f.structSuspend()
 	// Page footer
 	if f.footerFnc != nil {
 		f.inFooter = true
-		f.footerFnc()
+		f.artifactCall("Footer", f.footerFnc)
 		f.inFooter = false
 	}
 	// Close page
 	f.endpage()
+	if f.suppressBlank {
+		f.dropBlankPages()
+	}
 	// Close document
 	f.enddoc()
 	return
@@ -557,6 +1117,10 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	if f.err != nil {
 		return
 	}
+	if f.state == 3 {
+		f.closedError()
+		return
+	}
 	if f.state == 0 {
 		f.open()
 	}
@@ -567,15 +1131,23 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	}
 	fontsize := f.fontSizePt
 	lw := f.lineWidth
+	ts := f.textScale
 	dc := f.color.draw
 	fc := f.color.fill
 	tc := f.color.text
 	cf := f.colorFlag
+	artifactNest := f.artifactNest
 	if f.page > 0 {
+		f.markBlankPage()
+		// Artifacts begun with MarkArtifactBegin() continue on the new page
+		for j := 0; j < artifactNest; j++ {
+			f.out("EMC")
+		}
+		f.structSuspend()
 		// Page footer
 		if f.footerFnc != nil {
 			f.inFooter = true
-			f.footerFnc()
+			f.artifactCall("Footer", f.footerFnc)
 			f.inFooter = false
 		}
 		// Close page
@@ -583,14 +1155,26 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	}
 	// Start new page
 	f.beginpage(orientationStr, size)
+	// Background image
+	if f.background != nil {
+		f.putbackground()
+	}
 	// 	Set line cap style to current value
 	// f.out("2 J")
 	f.outf("%d J", f.capStyle)
 	// 	Set line join style to current value
 	f.outf("%d j", f.joinStyle)
+	// Set rendering intent
+	if f.renderingIntent != "" {
+		f.outputRenderingIntent()
+	}
+	// Set overprint
+	if f.overprint != (overprintType{}) {
+		f.outputOverprint()
+	}
 	// Set line width
 	f.lineWidth = lw
-	f.outf("%.2f w", lw*f.k)
+	f.outf("%.2f w", f.lineWidthPt())
 	// Set dash pattern
 	if len(f.dashArray) > 0 {
 		f.outputDashPattern()
@@ -602,6 +1186,10 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 			return
 		}
 	}
+	// Set horizontal text scaling
+	if ts != 100 {
+		f.outf("%.2f Tz", ts)
+	}
 	// 	Set colors
 	f.color.draw = dc
 	if dc.str != "0 G" {
@@ -616,13 +1204,17 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	// 	Page header
 	if f.headerFnc != nil {
 		f.inHeader = true
-		f.headerFnc()
+		f.artifactCall("Header", f.headerFnc)
 		f.inHeader = false
 	}
+	f.structResume()
+	for j := 0; j < artifactNest; j++ {
+		f.out("/Artifact BMC")
+	}
 	// 	Restore line width
 	if f.lineWidth != lw {
 		f.lineWidth = lw
-		f.outf("%.2f w", lw*f.k)
+		f.outf("%.2f w", f.lineWidthPt())
 	}
 	// Restore font
 	if familyStr != "" {
@@ -631,6 +1223,11 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 			return
 		}
 	}
+	// Restore horizontal text scaling
+	if f.textScale != ts {
+		f.textScale = ts
+		f.outf("%.2f Tz", ts)
+	}
 	// Restore colors
 	if f.color.draw.str != dc.str {
 		f.color.draw = dc
@@ -642,6 +1239,7 @@ func (f *Fpdf) AddPageFormat(orientationStr string, size SizeType) {
 	}
 	f.color.text = tc
 	f.colorFlag = cf
+	f.contentStart = f.pages[f.page].Len()
 	return
 }
 
@@ -668,13 +1266,55 @@ func (f *Fpdf) AddPage() {
 	return
 }
 
-// PageNo returns the current page number.
+// InsertPage adds a new page to the document at the specified index, which
+// ranges from 1, for a page that precedes all others, to PageCount() + 1, for
+// a page that follows them. The new page becomes the current page and is
+// otherwise set up as with AddPage(), including the calls to the footer and
+// header functions. This allows a cover or a table of contents to be added
+// after the content it refers to is known.
+//
+// The pages from index on are shifted back by one. Links, bookmarks and named
+// destinations continue to refer to the same pages, now at their new
+// positions. Page numbers that have already been printed on the shifted pages
+// are not changed, although AliasNbPages() still works. Bookmarks keep the
+// order in which they were added, so a bookmark for an inserted page appears
+// at the end of the outline. Pages begun with AddPage() or by an automatic
+// page break follow the current page, so a table of contents that spans
+// several pages remains contiguous. To resume adding pages at the end of the
+// document, call InsertPage(PageCount() + 1).
+func (f *Fpdf) InsertPage(index int) {
+	if f.err != nil {
+		return
+	}
+	if index < 1 || index > f.PageCount()+1 {
+		f.err = fmt.Errorf("page index %d is out of range 1 to %d", index, f.PageCount()+1)
+		return
+	}
+	f.insertAt = index
+	f.AddPageFormat(f.defOrientation, f.defPageSize)
+	f.insertAt = 0
+}
+
+// PageNo returns the current page number. Within the functions set with
+// SetHeaderFunc() and SetFooterFunc(), it is the number of the page whose
+// header or footer is being rendered. It is zero before the first page is
+// added.
 //
 // See the example for AddPage() for a demonstration of this method.
 func (f *Fpdf) PageNo() int {
 	return f.page
 }
 
+// PageCount returns the number of pages that have been added to the document
+// so far, including the current one. Unless pages have been inserted with
+// InsertPage(), the current page is the last one and this equals PageNo(),
+// including within header and footer functions; the total number of pages of
+// the finished document is not known until it is closed and can be printed
+// with AliasNbPages().
+func (f *Fpdf) PageCount() int {
+	return len(f.pages) - 1
+}
+
 type clrType struct {
 	r, g, b    float64
 	ir, ig, ib int
@@ -691,19 +1331,19 @@ func colorComp(v int) (int, float64) {
 	return v, float64(v) / 255.0
 }
 
-func colorValue(r, g, b int, grayStr, fullStr string) (clr clrType) {
+func (f *Fpdf) colorValue(r, g, b int, grayStr, fullStr string) (clr clrType) {
 	clr.ir, clr.r = colorComp(r)
 	clr.ig, clr.g = colorComp(g)
 	clr.ib, clr.b = colorComp(b)
 	clr.gray = clr.ir == clr.ig && clr.r == clr.b
 	if len(grayStr) > 0 {
 		if clr.gray {
-			clr.str = sprintf("%.3f %s", clr.r, grayStr)
+			clr.str = sprintf(f.precision("%.3f %s"), clr.r, grayStr)
 		} else {
-			clr.str = sprintf("%.3f %.3f %.3f %s", clr.r, clr.g, clr.b, fullStr)
+			clr.str = sprintf(f.precision("%.3f %.3f %.3f %s"), clr.r, clr.g, clr.b, fullStr)
 		}
 	} else {
-		clr.str = sprintf("%.3f %.3f %.3f", clr.r, clr.g, clr.b)
+		clr.str = sprintf(f.precision("%.3f %.3f %.3f"), clr.r, clr.g, clr.b)
 	}
 	return
 }
@@ -713,7 +1353,7 @@ func colorValue(r, g, b int, grayStr, fullStr string) (clr clrType) {
 // The method can be called before the first page is created. The value is
 // retained from page to page.
 func (f *Fpdf) SetDrawColor(r, g, b int) {
-	f.color.draw = colorValue(r, g, b, "G", "RG")
+	f.color.draw = f.colorValue(r, g, b, "G", "RG")
 	if f.page > 0 {
 		f.out(f.color.draw.str)
 	}
@@ -729,7 +1369,7 @@ func (f *Fpdf) GetDrawColor() (int, int, int) {
 // -255). The method can be called before the first page is created and the
 // value is retained from page to page.
 func (f *Fpdf) SetFillColor(r, g, b int) {
-	f.color.fill = colorValue(r, g, b, "g", "rg")
+	f.color.fill = f.colorValue(r, g, b, "g", "rg")
 	f.colorFlag = f.color.fill.str != f.color.text.str
 	if f.page > 0 {
 		f.out(f.color.fill.str)
@@ -745,7 +1385,7 @@ func (f *Fpdf) GetFillColor() (int, int, int) {
 // components (0 - 255). The method can be called before the first page is
 // created. The value is retained from page to page.
 func (f *Fpdf) SetTextColor(r, g, b int) {
-	f.color.text = colorValue(r, g, b, "g", "rg")
+	f.color.text = f.colorValue(r, g, b, "g", "rg")
 	f.colorFlag = f.color.fill.str != f.color.text.str
 }
 
@@ -754,29 +1394,110 @@ func (f *Fpdf) GetTextColor() (int, int, int) {
 	return f.color.text.ir, f.color.text.ig, f.color.text.ib
 }
 
+// grayValue returns a DeviceGray color for level, which is limited to the
+// range 0 (black) to 1 (white).
+func (f *Fpdf) grayValue(level float64, opStr string) (clr clrType) {
+	level = math.Max(0, math.Min(1, level))
+	clr.r, clr.g, clr.b = level, level, level
+	clr.ir = int(math.Floor(level*255 + 0.5))
+	clr.ig, clr.ib = clr.ir, clr.ir
+	clr.gray = true
+	clr.str = sprintf(f.precision("%.3f %s"), level, opStr)
+	return
+}
+
+// SetDrawColorGray defines the color used for all drawing operations as a
+// gray level from 0 (black) to 1 (white). The color is specified in the
+// DeviceGray color space, which is more compact than RGB and allows finer
+// gradations than the 256 levels of SetDrawColor(). Like the latter, it can
+// be called before the first page is created and the value is retained from
+// page to page. GetDrawColor() returns the nearest RGB equivalent.
+func (f *Fpdf) SetDrawColorGray(level float64) {
+	f.color.draw = f.grayValue(level, "G")
+	if f.page > 0 {
+		f.out(f.color.draw.str)
+	}
+}
+
+// SetFillColorGray defines the color used for all filling operations as a
+// gray level from 0 (black) to 1 (white). See SetDrawColorGray() for details.
+func (f *Fpdf) SetFillColorGray(level float64) {
+	f.color.fill = f.grayValue(level, "g")
+	f.colorFlag = f.color.fill.str != f.color.text.str
+	if f.page > 0 {
+		f.out(f.color.fill.str)
+	}
+}
+
+// SetTextColorGray defines the color used for text as a gray level from 0
+// (black) to 1 (white). See SetDrawColorGray() for details.
+func (f *Fpdf) SetTextColorGray(level float64) {
+	f.color.text = f.grayValue(level, "g")
+	f.colorFlag = f.color.fill.str != f.color.text.str
+}
+
 // GetStringWidth returns the length of a string in user units. A font must be
-// currently selected.
+// currently selected. The widths of recently measured strings are cached for
+// each font, so measuring the same strings repeatedly, as when laying out
+// tables, is fast.
 func (f *Fpdf) GetStringWidth(s string) float64 {
-	if f.err != nil {
+	if !f.fontReady() {
 		return 0
 	}
-	w := 0
-	for _, ch := range []byte(s) {
-		if ch == 0 {
-			break
+	var w int
+	if glyphs := f.shapeText(s); glyphs != nil {
+		w = f.currentFont.glyphWidth(glyphs)
+	} else if w = f.currentFont.stringWidth(s); f.tabularFigures {
+		if figWd := f.currentFont.figureWidth(); figWd > 0 {
+			w += f.currentFont.figurePadding(s, figWd)
 		}
-		w += f.currentFont.Cw[rune(ch)]
 	}
-	return float64(w) * f.fontSize / 1000
+	return float64(w) * f.fontSize / 1000 * f.textScale / 100
+}
+
+// glyphSpace converts a width in user units to the glyph space of the current
+// font, in which character widths are expressed, taking horizontal scaling
+// into account.
+func (f *Fpdf) glyphSpace(w float64) float64 {
+	return w * 1000 / f.fontSize * 100 / f.textScale
+}
+
+// SetHorizontalScaling sets the horizontal scaling of text, in percent of the
+// normal width of the characters. Values below 100 condense text and values
+// above 100 extend it, which can fit a heading into a fixed width without
+// switching to a condensed font. The scaling applies to characters and word
+// spacing alike and is taken into account by GetStringWidth() and by the
+// methods that wrap text. The default value is 100. The setting remains in
+// effect across pages.
+func (f *Fpdf) SetHorizontalScaling(percent float64) {
+	if f.err != nil {
+		return
+	}
+	if percent <= 0 {
+		f.err = fmt.Errorf("horizontal text scaling must be positive: %.2f", percent)
+		return
+	}
+	f.textScale = percent
+	if f.page > 0 {
+		f.outf("%.2f Tz", percent)
+	}
+}
+
+// GetHorizontalScaling returns the horizontal scaling of text, in percent, as
+// set with SetHorizontalScaling().
+func (f *Fpdf) GetHorizontalScaling() float64 {
+	return f.textScale
 }
 
 // SetLineWidth defines the line width. By default, the value equals 0.2 mm.
 // The method can be called before the first page is created. The value is
-// retained from page to page.
+// retained from page to page. A width of 0 selects the thinnest line that the
+// output device can render, unless a width for such lines is set with
+// SetHairlineWidth().
 func (f *Fpdf) SetLineWidth(width float64) {
 	f.lineWidth = width
 	if f.page > 0 {
-		f.outf("%.2f w", width*f.k)
+		f.outf("%.2f w", f.lineWidthPt())
 	}
 }
 
@@ -785,6 +1506,84 @@ func (f *Fpdf) GetLineWidth() float64 {
 	return f.lineWidth
 }
 
+// SetHairlineWidth sets the width, in points, of the lines drawn while the
+// line width is 0. In PDF, a line width of 0 denotes the thinnest line that
+// the output device can render: a single pixel on screen, whatever the zoom
+// level, and a fraction of a point on a high resolution printer, where such
+// lines can be all but invisible. Rendering therefore varies from one viewer
+// and printer to another. With a positive widthPt, a line width of 0 is
+// written as that width instead. A value such as 0.1 gives thin lines of
+// predictable weight on every device, at the cost of lines that grow with the
+// zoom level on screen and that may be rendered thicker than a device
+// hairline on low resolution devices. A widthPt of 0, the default, restores
+// device hairlines.
+func (f *Fpdf) SetHairlineWidth(widthPt float64) {
+	if f.err != nil {
+		return
+	}
+	if widthPt < 0 {
+		f.err = fmt.Errorf("hairline width must not be negative: %.2f", widthPt)
+		return
+	}
+	f.hairlineWidth = widthPt
+	if f.page > 0 && f.lineWidth == 0 {
+		f.outf("%.2f w", f.lineWidthPt())
+	}
+}
+
+// lineWidthPt returns the width in points with which the current line width
+// is written to the page
+func (f *Fpdf) lineWidthPt() float64 {
+	if f.lineWidth == 0 {
+		return f.hairlineWidth
+	}
+	return f.lineWidth * f.k
+}
+
+// SetLineSnap enables pixel snapping of lines for display at dpi dots per
+// inch. Thin lines that fall between device pixels are rendered blurred by
+// most viewers. With snapping enabled, the coordinates of lines drawn with
+// Line(), the outlines of rectangles drawn with Rect() and the borders of
+// cells are rounded so that lines lie on whole pixels at that resolution:
+// lines that are an odd number of pixels wide are centered on a pixel and
+// the others on a pixel boundary. This is useful for forms and tables that
+// are mostly viewed on screen, for which 96 is a reasonable choice. The
+// snapped positions assume that no transformation is in effect. A dpi of 0,
+// the default, disables snapping so that coordinates are used exactly as
+// specified.
+func (f *Fpdf) SetLineSnap(dpi float64) {
+	if dpi < 0 {
+		f.err = fmt.Errorf("line snap resolution must not be negative: %.2f", dpi)
+		return
+	}
+	f.lineSnap = dpi
+}
+
+// snap rounds v, a page coordinate in points, to a device pixel for the
+// resolution set with SetLineSnap(), taking the current line width into
+// account.
+func (f *Fpdf) snap(v float64) float64 {
+	if f.lineSnap == 0 {
+		return v
+	}
+	scale := f.lineSnap / 72
+	px := math.Max(1, math.Floor(f.lineWidthPt()*scale+0.5))
+	if math.Mod(px, 2) == 1 {
+		return (math.Floor(v*scale) + 0.5) / scale
+	}
+	return math.Floor(v*scale+0.5) / scale
+}
+
+// snapRect snaps the corners of a rectangle given in points by its origin
+// and size
+func (f *Fpdf) snapRect(x, y, w, h float64) (float64, float64, float64, float64) {
+	if f.lineSnap == 0 {
+		return x, y, w, h
+	}
+	x0, y0 := f.snap(x), f.snap(y)
+	return x0, y0, f.snap(x+w) - x0, f.snap(y+h) - y0
+}
+
 // SetLineCapStyle defines the line cap style. styleStr should be "butt",
 // "round" or "square". A square style projects from the end of the line. The
 // method can be called before the first page is created. The value is
@@ -828,6 +1627,41 @@ func (f *Fpdf) SetLineJoinStyle(styleStr string) {
 	}
 }
 
+// SetRenderingIntent sets the rendering intent that viewers and printers use
+// when they convert colors to the color space of the output device, as with
+// ICC based color management. intentStr should be "AbsoluteColorimetric",
+// "RelativeColorimetric", "Saturation" or "Perceptual". An empty string
+// restores the default, which is "RelativeColorimetric". The method can be
+// called before the first page is created. The value is retained from page to
+// page.
+func (f *Fpdf) SetRenderingIntent(intentStr string) {
+	if f.err != nil {
+		return
+	}
+	switch intentStr {
+	case "RelativeColorimetric":
+		intentStr = ""
+	case "", "AbsoluteColorimetric", "Saturation", "Perceptual":
+	default:
+		f.err = fmt.Errorf("unrecognized rendering intent \"%s\"", intentStr)
+		return
+	}
+	if intentStr != f.renderingIntent {
+		f.renderingIntent = intentStr
+		if f.page > 0 {
+			f.outputRenderingIntent()
+		}
+	}
+}
+
+func (f *Fpdf) outputRenderingIntent() {
+	intentStr := f.renderingIntent
+	if intentStr == "" {
+		intentStr = "RelativeColorimetric"
+	}
+	f.outf("/%s ri", intentStr)
+}
+
 // SetDashPattern sets the dash pattern that is used to draw lines. The
 // dashArray elements are numbers that specify the lengths, in units
 // established in New(), of alternating dashes and gaps. The dash phase
@@ -866,10 +1700,61 @@ func (f *Fpdf) outputDashPattern() {
 	f.outbuf(&buf)
 }
 
+// SetCoordinateOrigin sets the origin of the coordinates accepted by the
+// drawing primitives. originStr is "topleft" (the default), in which case
+// vertical positions are measured downward from the top of the page, or
+// "bottomleft", in which case they are measured upward from the bottom of the
+// page as in the native PDF coordinate system. Horizontal positions and all
+// units of measure are unaffected.
+//
+// The origin applies to Line(), Rect(), Circle(), Ellipse(), Arc(), Polygon(),
+// Beziergon(), the Curve methods, the path methods MoveTo(), LineTo(),
+// CurveTo(), CurveBezierCubicTo() and ArcTo(), and Text(). With a bottom-left
+// origin, the point passed to Rect() is the lower left corner of the
+// rectangle. Methods that work with the current position, such as Cell(),
+// MultiCell(), Write() and SetXY(), as well as images, links and clipping,
+// continue to use the top-left origin.
+func (f *Fpdf) SetCoordinateOrigin(originStr string) {
+	switch strings.ToLower(originStr) {
+	case "topleft":
+		f.originBottom = false
+	case "bottomleft":
+		f.originBottom = true
+	default:
+		f.err = fmt.Errorf("incorrect coordinate origin: %s", originStr)
+	}
+}
+
+// userY converts a vertical position passed to a drawing primitive to the
+// top-left based position used internally. The conversion is its own inverse.
+func (f *Fpdf) userY(y float64) float64 {
+	if f.originBottom {
+		return f.h - y
+	}
+	return y
+}
+
+// userPoints returns points converted with userY. The caller's slice is not
+// modified.
+func (f *Fpdf) userPoints(points []PointType) []PointType {
+	if !f.originBottom {
+		return points
+	}
+	list := make([]PointType, len(points))
+	for j, pt := range points {
+		list[j] = PointType{pt.X, f.h - pt.Y}
+	}
+	return list
+}
+
 // Line draws a line between points (x1, y1) and (x2, y2) using the current
 // draw color, line width and cap style.
 func (f *Fpdf) Line(x1, y1, x2, y2 float64) {
-	f.outf("%.2f %.2f m %.2f %.2f l S", x1*f.k, (f.h-y1)*f.k, x2*f.k, (f.h-y2)*f.k)
+	f.line(x1, f.userY(y1), x2, f.userY(y2))
+}
+
+func (f *Fpdf) line(x1, y1, x2, y2 float64) {
+	f.outf("%.2f %.2f m %.2f %.2f l S", f.snap(x1*f.k), f.snap((f.h-y1)*f.k), f.snap(x2*f.k), f.snap((f.h-y2)*f.k))
 }
 
 // fillDrawOp corrects path painting operators
@@ -897,7 +1782,8 @@ func fillDrawOp(styleStr string) (opStr string) {
 }
 
 // Rect outputs a rectangle of width w and height h with the upper left corner
-// positioned at point (x, y).
+// positioned at point (x, y). If SetCoordinateOrigin() has established a
+// bottom-left origin, (x, y) is the lower left corner.
 //
 // It can be drawn (border only), filled (with no border) or both. styleStr can
 // be "F" for filled, "D" for outlined only, or "DF" or "FD" for outlined and
@@ -905,7 +1791,19 @@ func fillDrawOp(styleStr string) (opStr string) {
 // draw color and line width centered on the rectangle's perimeter. Filling
 // uses the current fill color.
 func (f *Fpdf) Rect(x, y, w, h float64, styleStr string) {
-	f.outf("%.2f %.2f %.2f %.2f re %s", x*f.k, (f.h-y)*f.k, w*f.k, -h*f.k, fillDrawOp(styleStr))
+	if f.originBottom {
+		y = f.h - y - h
+	}
+	f.rect(x, y, w, h, styleStr)
+}
+
+func (f *Fpdf) rect(x, y, w, h float64, styleStr string) {
+	op := fillDrawOp(styleStr)
+	x0, y0, wd, ht := x*f.k, (f.h-y)*f.k, w*f.k, -h*f.k
+	if op != "f" && op != "f*" {
+		x0, y0, wd, ht = f.snapRect(x0, y0, wd, ht)
+	}
+	f.outf("%.2f %.2f %.2f %.2f re %s", x0, y0, wd, ht, op)
 }
 
 // Circle draws a circle centered on point (x, y) with radius r.
@@ -931,7 +1829,7 @@ func (f *Fpdf) Circle(x, y, r float64, styleStr string) {
 //
 // The Circle() example demonstrates this method.
 func (f *Fpdf) Ellipse(x, y, rx, ry, degRotate float64, styleStr string) {
-	f.arc(x, y, rx, ry, degRotate, 0, 360, styleStr, false)
+	f.arc(x, f.userY(y), rx, ry, degRotate, 0, 360, styleStr, false)
 }
 
 // Polygon draws a closed figure defined by a series of vertices specified by
@@ -940,11 +1838,17 @@ func (f *Fpdf) Ellipse(x, y, rx, ry, degRotate float64, styleStr string) {
 // the polygon.
 //
 // styleStr can be "F" for filled, "D" for outlined only, or "DF" or "FD" for
-// outlined and filled. An empty string will be replaced with "D". Drawing uses
-// the current draw color and line width centered on the ellipse's perimeter.
-// Filling uses the current fill color.
+// outlined and filled. An empty string will be replaced with "D". Filling uses
+// the nonzero winding number rule; append "*" to the style ("F*", "FD*" or
+// "DF*") to use the even-odd rule instead, which leaves overlapping regions of
+// self-intersecting figures such as stars unfilled. Drawing uses the current
+// draw color and line width centered on the ellipse's perimeter. Filling uses
+// the current fill color.
+//
+// The ClipPolygonEvenOdd() example demonstrates the fill rules.
 func (f *Fpdf) Polygon(points []PointType, styleStr string) {
 	if len(points) > 2 {
+		points = f.userPoints(points)
 		for j, pt := range points {
 			if j == 0 {
 				f.point(pt.X, pt.Y)
@@ -965,9 +1869,11 @@ func (f *Fpdf) Polygon(points []PointType, styleStr string) {
 // The x and y fields of the points use the units established in New().
 //
 // styleStr can be "F" for filled, "D" for outlined only, or "DF" or "FD" for
-// outlined and filled. An empty string will be replaced with "D". Drawing uses
-// the current draw color and line width centered on the ellipse's perimeter.
-// Filling uses the current fill color.
+// outlined and filled. An empty string will be replaced with "D". As with
+// Polygon(), append "*" to the style to fill using the even-odd rule rather than
+// the nonzero winding number rule. Drawing uses the current draw color and line
+// width centered on the ellipse's perimeter. Filling uses the current fill
+// color.
 func (f *Fpdf) Beziergon(points []PointType, styleStr string) {
 
 	// Thanks, Robert Lillack, for contributing this function.
@@ -975,6 +1881,7 @@ func (f *Fpdf) Beziergon(points []PointType, styleStr string) {
 	if len(points) < 4 {
 		return
 	}
+	points = f.userPoints(points)
 	f.point(points[0].XY())
 
 	points = points[1:]
@@ -1015,6 +1922,7 @@ func (f *Fpdf) curve(cx0, cy0, cx1, cy1, x, y float64) {
 //
 // The Circle() example demonstrates this method.
 func (f *Fpdf) Curve(x0, y0, cx, cy, x1, y1 float64, styleStr string) {
+	y0, cy, y1 = f.userY(y0), f.userY(cy), f.userY(y1)
 	f.point(x0, y0)
 	f.outf("%.5f %.5f %.5f %.5f v %s", cx*f.k, (f.h-cy)*f.k, x1*f.k, (f.h-y1)*f.k,
 		fillDrawOp(styleStr))
@@ -1047,6 +1955,10 @@ func (f *Fpdf) CurveCubic(x0, y0, cx0, cy0, x1, y1, cx1, cy1 float64, styleStr s
 //
 // The Circle() example demonstrates this method.
 func (f *Fpdf) CurveBezierCubic(x0, y0, cx0, cy0, cx1, cy1, x1, y1 float64, styleStr string) {
+	f.bezierCubic(x0, f.userY(y0), cx0, f.userY(cy0), cx1, f.userY(cy1), x1, f.userY(y1), styleStr)
+}
+
+func (f *Fpdf) bezierCubic(x0, y0, cx0, cy0, cx1, cy1, x1, y1 float64, styleStr string) {
 	f.point(x0, y0)
 	f.outf("%.5f %.5f %.5f %.5f %.5f %.5f c %s", cx0*f.k, (f.h-cy0)*f.k,
 		cx1*f.k, (f.h-cy1)*f.k, x1*f.k, (f.h-y1)*f.k, fillDrawOp(styleStr))
@@ -1067,16 +1979,23 @@ func (f *Fpdf) CurveBezierCubic(x0, y0, cx0, cy0, cx1, cy1, x1, y1 float64, styl
 //
 // The Circle() example demonstrates this method.
 func (f *Fpdf) Arc(x, y, rx, ry, degRotate, degStart, degEnd float64, styleStr string) {
-	f.arc(x, y, rx, ry, degRotate, degStart, degEnd, styleStr, false)
+	f.arc(x, f.userY(y), rx, ry, degRotate, degStart, degEnd, styleStr, false)
 }
 
 // GetAlpha returns the alpha blending channel, which consists of the
 // alpha transparency value and the blend mode. See SetAlpha for more
-// details.
+// details. If the transparency of strokes differs from that of fills, set
+// with SetStrokeAlpha() or SetFillAlpha(), the fill value is returned.
 func (f *Fpdf) GetAlpha() (alpha float64, blendModeStr string) {
 	return f.alpha, f.blendMode
 }
 
+// GetStrokeAlpha returns the alpha transparency value that applies to lines
+// and the outlines of shapes. See SetStrokeAlpha() for more details.
+func (f *Fpdf) GetStrokeAlpha() float64 {
+	return f.strokeAlpha
+}
+
 // SetAlpha sets the alpha blending channel. The blending effect applies to
 // text, drawings and images.
 //
@@ -1089,9 +2008,34 @@ func (f *Fpdf) GetAlpha() (alpha float64, blendModeStr string) {
 // empty string is replaced with "Normal".
 //
 // To reset normal rendering after applying a blending mode, call this method
-// with alpha set to 1.0 and blendModeStr set to "Normal".
+// with alpha set to 1.0 and blendModeStr set to "Normal". This also restores
+// the opacity of strokes and fills set separately with SetStrokeAlpha() and
+// SetFillAlpha().
 func (f *Fpdf) SetAlpha(alpha float64, blendModeStr string) {
-	if f.err != nil || (alpha == f.alpha && blendModeStr == f.blendMode) {
+	f.setAlpha(alpha, alpha, blendModeStr)
+}
+
+// SetStrokeAlpha sets the alpha transparency value, from 0.0 (fully
+// transparent) to 1.0 (fully opaque), of lines and the outlines of shapes,
+// leaving the transparency of fills, text and images and the blend mode
+// unchanged. Together with SetFillAlpha(), this permits an opaque outline to
+// be drawn around a translucent shape. Values outside of the range result in
+// an error.
+func (f *Fpdf) SetStrokeAlpha(alpha float64) {
+	f.setAlpha(alpha, f.alpha, f.blendMode)
+}
+
+// SetFillAlpha sets the alpha transparency value, from 0.0 (fully
+// transparent) to 1.0 (fully opaque), of fills, text and images, leaving the
+// transparency of strokes and the blend mode unchanged. See SetStrokeAlpha().
+func (f *Fpdf) SetFillAlpha(alpha float64) {
+	f.setAlpha(f.strokeAlpha, alpha, f.blendMode)
+}
+
+// setAlpha selects the graphics state with the specified stroke and fill
+// alpha values and blend mode
+func (f *Fpdf) setAlpha(strokeAlpha, alpha float64, blendModeStr string) {
+	if f.err != nil || (alpha == f.alpha && strokeAlpha == f.strokeAlpha && blendModeStr == f.blendMode) {
 		return
 	}
 	var bl blendModeType
@@ -1106,21 +2050,221 @@ func (f *Fpdf) SetAlpha(alpha float64, blendModeStr string) {
 		f.err = fmt.Errorf("unrecognized blend mode \"%s\"", blendModeStr)
 		return
 	}
-	if alpha < 0.0 || alpha > 1.0 {
-		f.err = fmt.Errorf("alpha value (0.0 - 1.0) is out of range: %.3f", alpha)
-		return
+	for _, a := range []float64{strokeAlpha, alpha} {
+		if a < 0.0 || a > 1.0 {
+			f.err = fmt.Errorf("alpha value (0.0 - 1.0) is out of range: %.3f", a)
+			return
+		}
 	}
 	f.alpha = alpha
+	f.strokeAlpha = strokeAlpha
 	f.blendMode = blendModeStr
-	alphaStr := sprintf("%.3f", alpha)
-	keyStr := sprintf("%s %s", alphaStr, blendModeStr)
+	f.outf("/GS%d gs", f.blendState(strokeAlpha, alpha, blendModeStr))
+}
+
+// blendState returns the index of the graphics state resource that applies
+// the specified stroke and fill alpha values and blend mode, adding it if
+// necessary
+func (f *Fpdf) blendState(strokeAlpha, alpha float64, blendModeStr string) (pos int) {
+	strokeStr := sprintf("%.3f", strokeAlpha)
+	fillStr := sprintf("%.3f", alpha)
+	keyStr := sprintf("%s %s %s", strokeStr, fillStr, blendModeStr)
 	pos, ok := f.blendMap[keyStr]
 	if !ok {
 		pos = len(f.blendList) // at least 1
-		f.blendList = append(f.blendList, blendModeType{alphaStr, alphaStr, blendModeStr, 0})
+		f.blendList = append(f.blendList, blendModeType{strokeStr, fillStr, blendModeStr, 0})
 		f.blendMap[keyStr] = pos
 	}
-	f.outf("/GS%d gs", pos)
+	return
+}
+
+// ResetGraphicsState restores the default graphics and text settings: a line
+// width of 0.2 mm, butt line caps, miter line joins, solid lines, the default
+// rendering intent, no overprint, black draw, fill and text colors, full
+// opacity with the normal blend mode, no word spacing, no horizontal text
+// scaling and no underlining. When called on a page, the operators that
+// establish these settings are written to the page, so that drawing that
+// follows, for example a footer, is not affected by settings left over from
+// earlier content.
+//
+// The current font is retained unless resetFont is true, in which case the
+// default font established with SetDefaultFont() is selected.
+func (f *Fpdf) ResetGraphicsState(resetFont bool) {
+	if f.err != nil {
+		return
+	}
+	f.SetLineWidth(0.567 / f.k)
+	f.capStyle = 0
+	f.joinStyle = 0
+	f.dashArray = nil
+	f.dashPhase = 0
+	if f.page > 0 {
+		f.out("0 J")
+		f.out("0 j")
+		f.outputDashPattern()
+	}
+	if f.renderingIntent != "" {
+		f.renderingIntent = ""
+		if f.page > 0 {
+			f.outputRenderingIntent()
+		}
+	}
+	f.setOverprint(overprintType{})
+	f.SetDrawColor(0, 0, 0)
+	f.SetFillColor(0, 0, 0)
+	f.SetTextColor(0, 0, 0)
+	if f.page > 0 {
+		f.SetAlpha(1, "Normal")
+	} else {
+		f.alpha = 1
+		f.strokeAlpha = 1
+		f.blendMode = "Normal"
+	}
+	if f.ws != 0 {
+		f.ws = 0
+		if f.page > 0 {
+			f.out("0 Tw")
+		}
+	}
+	if f.textScale != 100 {
+		f.textScale = 100
+		if f.page > 0 {
+			f.out("100 Tz")
+		}
+	}
+	f.underline = false
+	if resetFont && f.defFontFamily != "" {
+		f.SetFont(f.defFontFamily, f.defFontStyle, f.defFontSize)
+	}
+}
+
+// SaveContext saves the current drawing context: the current position, the
+// font, underlining, horizontal text scaling, the draw, fill and text colors,
+// the line width, cap and join styles, the rendering intent, the overprint
+// settings, the dash pattern, the alpha blending channel and the coordinate
+// origin. The context is pushed onto a stack that is maintained by this
+// package and is independent of the graphics state operators of the content
+// stream, so it can be nested to any depth and span page breaks. Each call
+// should be paired with a call to RestoreContext().
+//
+// This lets a helper routine change settings freely without disturbing the
+// settings of the code that calls it.
+func (f *Fpdf) SaveContext() {
+	if f.err != nil {
+		return
+	}
+	f.contextList = append(f.contextList, f.drawingContext())
+}
+
+// drawingContext returns the current drawing context
+func (f *Fpdf) drawingContext() contextType {
+	return contextType{
+		x:               f.x,
+		y:               f.y,
+		fontFamily:      f.fontFamily,
+		fontStyle:       f.fontStyle,
+		fontSizePt:      f.fontSizePt,
+		underline:       f.underline,
+		textScale:       f.textScale,
+		lineWidth:       f.lineWidth,
+		capStyle:        f.capStyle,
+		joinStyle:       f.joinStyle,
+		renderingIntent: f.renderingIntent,
+		overprint:       f.overprint,
+		dashArray:       f.dashArray,
+		dashPhase:       f.dashPhase,
+		alpha:           f.alpha,
+		strokeAlpha:     f.strokeAlpha,
+		blendMode:       f.blendMode,
+		originBottom:    f.originBottom,
+		colorFlag:       f.colorFlag,
+		draw:            f.color.draw,
+		fill:            f.color.fill,
+		text:            f.color.text,
+		font:            f.currentFont,
+	}
+}
+
+// RestoreContext restores the drawing context most recently saved with
+// SaveContext() and removes it from the stack. Operators are written to the
+// current page only for settings that differ from the current ones. An error
+// is set if there is no saved context.
+func (f *Fpdf) RestoreContext() {
+	if f.err != nil {
+		return
+	}
+	count := len(f.contextList)
+	if count == 0 {
+		f.err = fmt.Errorf("RestoreContext called without matching SaveContext")
+		return
+	}
+	c := f.contextList[count-1]
+	f.contextList = f.contextList[:count-1]
+	if c.fontFamily != "" {
+		f.SetFont(c.fontFamily, c.fontStyle, c.fontSizePt)
+		if f.err != nil {
+			return
+		}
+	}
+	f.underline = c.underline
+	if c.textScale != f.textScale {
+		f.textScale = c.textScale
+		if f.page > 0 {
+			f.outf("%.2f Tz", f.textScale)
+		}
+	}
+	if c.lineWidth != f.lineWidth {
+		f.SetLineWidth(c.lineWidth)
+	}
+	if c.capStyle != f.capStyle {
+		f.capStyle = c.capStyle
+		if f.page > 0 {
+			f.outf("%d J", f.capStyle)
+		}
+	}
+	if c.joinStyle != f.joinStyle {
+		f.joinStyle = c.joinStyle
+		if f.page > 0 {
+			f.outf("%d j", f.joinStyle)
+		}
+	}
+	if c.renderingIntent != f.renderingIntent {
+		f.renderingIntent = c.renderingIntent
+		if f.page > 0 {
+			f.outputRenderingIntent()
+		}
+	}
+	f.setOverprint(c.overprint)
+	if !slicesEqual(c.dashArray, f.dashArray) || c.dashPhase != f.dashPhase {
+		f.dashArray = c.dashArray
+		f.dashPhase = c.dashPhase
+		if f.page > 0 {
+			f.outputDashPattern()
+		}
+	}
+	if c.draw.str != f.color.draw.str {
+		f.color.draw = c.draw
+		if f.page > 0 {
+			f.out(c.draw.str)
+		}
+	}
+	if c.fill.str != f.color.fill.str {
+		f.color.fill = c.fill
+		if f.page > 0 {
+			f.out(c.fill.str)
+		}
+	}
+	f.color.text = c.text
+	f.colorFlag = c.colorFlag
+	if f.page > 0 {
+		f.setAlpha(c.strokeAlpha, c.alpha, c.blendMode)
+	} else {
+		f.alpha = c.alpha
+		f.strokeAlpha = c.strokeAlpha
+		f.blendMode = c.blendMode
+	}
+	f.originBottom = c.originBottom
+	f.x, f.y = c.x, c.y
 }
 
 func (f *Fpdf) gradientClipStart(x, y, w, h float64) {
@@ -1137,8 +2281,8 @@ func (f *Fpdf) gradientClipEnd() {
 
 func (f *Fpdf) gradient(tp int, r1, g1, b1 int, r2, g2, b2 int, x1, y1 float64, x2, y2 float64, r float64) {
 	pos := len(f.gradientList)
-	clr1 := colorValue(r1, g1, b1, "", "")
-	clr2 := colorValue(r2, g2, b2, "", "")
+	clr1 := f.colorValue(r1, g1, b1, "", "")
+	clr2 := f.colorValue(r2, g2, b2, "", "")
 	f.gradientList = append(f.gradientList, gradientType{tp, clr1.str, clr2.str,
 		x1, y1, x2, y2, r, 0})
 	f.outf("/Sh%d sh", pos)
@@ -1213,6 +2357,9 @@ func (f *Fpdf) ClipRect(x, y, w, h float64, outline bool) {
 // example, Image(), LinearGradient(), etc) will be clipped. Call ClipEnd() to
 // restore unclipped operations.
 func (f *Fpdf) ClipText(x, y float64, txtStr string, outline bool) {
+	if !f.fontReady() {
+		return
+	}
 	f.clipNest++
 	f.outf("q BT %.5f %.5f Td %d Tr (%s) Tj ET", x*f.k, (f.h-y)*f.k, intIf(outline, 5, 7), f.escape(txtStr))
 }
@@ -1315,8 +2462,24 @@ func (f *Fpdf) ClipCircle(x, y, r float64, outline bool) {
 // LinearGradient(), etc) will be clipped by the specified polygon. Call
 // ClipEnd() to restore unclipped operations.
 //
+// The region inside the polygon is determined with the nonzero winding number
+// rule. See ClipPolygonEvenOdd() for the alternative.
+//
 // The ClipText() example demonstrates this method.
 func (f *Fpdf) ClipPolygon(points []PointType, outline bool) {
+	f.clipPolygon(points, "W", outline)
+}
+
+// ClipPolygonEvenOdd is like ClipPolygon() except that the region inside the
+// polygon is determined with the even-odd rule. Areas of a self-intersecting
+// polygon that are enclosed an even number of times, such as the center of a
+// five-pointed star, are excluded from the clipping region. Call ClipEnd() to
+// restore unclipped operations.
+func (f *Fpdf) ClipPolygonEvenOdd(points []PointType, outline bool) {
+	f.clipPolygon(points, "W*", outline)
+}
+
+func (f *Fpdf) clipPolygon(points []PointType, opStr string, outline bool) {
 	f.clipNest++
 	var s fmtBuffer
 	h := f.h
@@ -1325,14 +2488,15 @@ func (f *Fpdf) ClipPolygon(points []PointType, outline bool) {
 	for j, pt := range points {
 		s.printf("%.5f %.5f %s ", pt.X*k, (h-pt.Y)*k, strIf(j == 0, "m", "l"))
 	}
-	s.printf("h W %s", strIf(outline, "S", "n"))
+	s.printf("h %s %s", opStr, strIf(outline, "S", "n"))
 	f.out(s.String())
 }
 
 // ClipEnd ends a clipping operation that was started with a call to
-// ClipRect(), ClipRoundedRect(), ClipText(), ClipEllipse(), ClipCircle() or
-// ClipPolygon(). Clipping operations can be nested. The document cannot be
-// successfully output while a clipping operation is active.
+// ClipRect(), ClipRoundedRect(), ClipText(), ClipEllipse(), ClipCircle(),
+// ClipPolygon(), ClipPolygonEvenOdd() or DrawPath() with a clipping style.
+// Clipping operations can be nested. The document cannot be successfully
+// output while a clipping operation is active.
 //
 // The ClipText() example demonstrates this method.
 func (f *Fpdf) ClipEnd() {
@@ -1366,6 +2530,34 @@ func (f *Fpdf) SetLink(link int, y float64, page int) {
 	f.links[link] = intLinkType{page, y}
 }
 
+// AddNamedDestination defines a destination with the specified name at
+// vertical position y of the specified page. Unlike the targets of internal
+// links, named destinations can be reached from outside the document, for
+// example by other documents or by a URL with a fragment such as
+// "manual.pdf#installation". A y value of -1 indicates the current position
+// and a page value of -1 indicates the current page. Each name may be defined
+// only once.
+//
+// Within the document, a named destination is the target of a link whose
+// linkStr is "#" followed by the name. See LinkString().
+func (f *Fpdf) AddNamedDestination(name string, page int, y float64) {
+	if name == "" {
+		f.err = fmt.Errorf("named destination requires a name")
+		return
+	}
+	if _, ok := f.namedDests[name]; ok {
+		f.err = fmt.Errorf("named destination %s is already defined", name)
+		return
+	}
+	if y == -1 {
+		y = f.y
+	}
+	if page == -1 {
+		page = f.page
+	}
+	f.namedDests[name] = intLinkType{page, y}
+}
+
 // Add a new clickable link on current page
 func (f *Fpdf) newLink(x, y, w, h float64, link int, linkStr string) {
 	// linkList, ok := f.pageLinks[f.page]
@@ -1373,8 +2565,9 @@ func (f *Fpdf) newLink(x, y, w, h float64, link int, linkStr string) {
 	// linkList = make([]linkType, 0, 8)
 	// f.pageLinks[f.page] = linkList
 	// }
+	elem := f.structLink()
 	f.pageLinks[f.page] = append(f.pageLinks[f.page],
-		linkType{x * f.k, f.hPt - y*f.k, w * f.k, h * f.k, link, linkStr})
+		linkType{x * f.k, f.hPt - y*f.k, w * f.k, h * f.k, link, linkStr, elem, 0, 0})
 }
 
 // Link puts a link on a rectangular area of the page. Text or image links are
@@ -1388,7 +2581,11 @@ func (f *Fpdf) Link(x, y, w, h float64, link int) {
 // LinkString puts a link on a rectangular area of the page. Text or image
 // links are generally put via Cell(), Write() or Image(), but this method can
 // be useful for instance to define a clickable area inside an image. linkStr
-// is the target URL.
+// is the target URL. A linkStr of "#" followed by a name links to the named
+// destination of this document defined with AddNamedDestination(). A linkStr
+// that names another PDF file, such as "manual.pdf#installation", links to
+// the named destination of that document, which is found relative to this
+// one.
 func (f *Fpdf) LinkString(x, y, w, h float64, linkStr string) {
 	f.newLink(x, y, w, h, 0, linkStr)
 }
@@ -1397,7 +2594,9 @@ func (f *Fpdf) LinkString(x, y, w, h float64, linkStr string) {
 // is the title of the bookmark. level specifies the level of the bookmark in
 // the outline; 0 is the top level, 1 is just below, and so on. y specifies the
 // vertical position of the bookmark destination in the current page; -1
-// indicates the current position.
+// indicates the current position. A title that contains non-ASCII UTF-8
+// characters is stored in UTF-16 form so that it displays correctly in the
+// outline.
 func (f *Fpdf) Bookmark(txtStr string, level int, y float64) {
 	if y == -1 {
 		y = f.y
@@ -1410,8 +2609,17 @@ func (f *Fpdf) Bookmark(txtStr string, level int, y float64) {
 // precisely on the page, but it is usually easier to use Cell(), MultiCell()
 // or Write() which are the standard methods to print text.
 func (f *Fpdf) Text(x, y float64, txtStr string) {
-	txtStr = f.translator(txtStr)
-	s := sprintf("BT %.2f %.2f Td (%s) Tj ET", x*f.k, (f.h-y)*f.k, f.escape(txtStr))
+	if !f.fontReady() {
+		return
+	}
+	y = f.userY(y)
+	var s string
+	if glyphs := f.shapeText(txtStr); glyphs != nil {
+		s = sprintf(f.precision("BT %.2f %.2f Td %s ET"), x*f.k, (f.h-y)*f.k, f.glyphShow(glyphs))
+	} else {
+		txtStr = f.translator(txtStr)
+		s = sprintf(f.precision("BT %.2f %.2f Td (%s) Tj ET"), x*f.k, (f.h-y)*f.k, f.escape(txtStr))
+	}
 	if f.underline && txtStr != "" {
 		s += " " + f.dounderline(x, y, txtStr)
 	}
@@ -1436,6 +2644,233 @@ func (f *Fpdf) SetAcceptPageBreakFunc(fnc func() bool) {
 	f.acceptPageBreak = fnc
 }
 
+// KeepTogether renders the content produced by fnc so that it is not split
+// across pages. This is useful, for example, to keep a heading on the same
+// page as the paragraph that follows it.
+//
+// fnc is first called with automatic page breaking suspended. If the content
+// fits on the current page it is kept as is. Otherwise the content is
+// discarded, a new page is started, and fnc is called again with page
+// breaking in effect so that content taller than a full page still flows onto
+// subsequent pages. Consequently fnc may be called twice and should, apart
+// from the calls it makes to this instance, be free of side effects. fnc should
+// not call AddPage().
+//
+// Discarding the content removes everything the first call added to the
+// document: the page content and any pages it started, for example with a
+// form feed in MultiCell(), links and internal links, bookmarks, named
+// destinations, word positions, signature fields, structure elements and list
+// items, and the images, fonts, graphics states, gradients, patterns, layers
+// and raw objects it registered. The current position and the drawing context
+// are returned to their values at the start of the call.
+//
+// The KeepTogether() example demonstrates this method.
+func (f *Fpdf) KeepTogether(fnc func()) {
+	if f.err != nil || f.page == 0 {
+		fnc()
+		return
+	}
+	mark := f.keepMark()
+	acceptPageBreak := f.acceptPageBreak
+	f.acceptPageBreak = func() bool { return false }
+	fnc()
+	fits := f.page == mark.page && f.y <= f.pageBreakTrigger
+	f.acceptPageBreak = acceptPageBreak
+	if fits || f.err != nil {
+		return
+	}
+	// Discard the trial rendering and start the content on a new page
+	f.keepRollback(&mark)
+	if f.y > f.tMargin {
+		x := f.x
+		f.AddPageFormat(f.curOrientation, f.curPageSize)
+		f.x = x
+	}
+	fnc()
+}
+
+// keepMarkType records the extent of the document and the drawing context
+// when KeepTogether() begins, so that a trial rendering can be discarded
+type keepMarkType struct {
+	context        contextType
+	lasth          float64
+	page           int
+	pageCount      int
+	pageLen        int
+	pageState      keepPageType
+	pageLinkCount  int
+	linkCount      int
+	outlineCount   int
+	dests          map[string]bool
+	imageCount     int
+	fontCount      int
+	blendCount     int
+	overprintCount int
+	gradientCount  int
+	patternCount   int
+	wordCount      int
+	sigCount       int
+	elemCount      int
+	mcidCount      int
+	structure      structRecType
+	list           listRecType
+	layer          layerRecType
+	rawObjCount    int
+}
+
+// keepPageType holds the state of the current page that a page break
+// replaces with that of the new page
+type keepPageType struct {
+	orientation      string
+	size             SizeType
+	w, h, wPt, hPt   float64
+	pageBreakTrigger float64
+	contentStart     int
+	backgroundLen    int
+	keepPage         bool
+	blank            bool
+	insertAt         int
+}
+
+// keepMark returns the current extent of the document and drawing context
+func (f *Fpdf) keepMark() (m keepMarkType) {
+	m.context = f.drawingContext()
+	m.lasth = f.lasth
+	m.page = f.page
+	m.pageCount = len(f.pages)
+	m.pageLen = f.pages[f.page].Len()
+	m.pageState = keepPageType{f.curOrientation, f.curPageSize, f.w, f.h, f.wPt, f.hPt,
+		f.pageBreakTrigger, f.contentStart, f.backgroundLen, f.keepPage, f.blankPages[f.page], f.insertAt}
+	m.pageLinkCount = len(f.pageLinks[f.page])
+	m.linkCount = len(f.links)
+	m.outlineCount = len(f.outlines)
+	if len(f.namedDests) > 0 {
+		m.dests = make(map[string]bool, len(f.namedDests))
+		for name := range f.namedDests {
+			m.dests[name] = true
+		}
+	}
+	m.imageCount = len(f.images)
+	m.fontCount = len(f.fonts)
+	m.blendCount = len(f.blendList)
+	m.overprintCount = len(f.overprintList)
+	m.gradientCount = len(f.gradientList)
+	m.patternCount = len(f.patternList)
+	m.wordCount = len(f.wordList)
+	m.sigCount = len(f.sigFields)
+	m.elemCount = len(f.structure.elems)
+	m.mcidCount = f.structure.mcids[f.page]
+	m.structure = f.structure
+	m.structure.stack = append([]int(nil), f.structure.stack...)
+	m.list = f.list
+	m.list.counts = append([]int(nil), f.list.counts...)
+	m.list.elems = append([]int(nil), f.list.elems...)
+	m.list.bodies = append([]int(nil), f.list.bodies...)
+	m.layer = f.layer
+	m.rawObjCount = len(f.rawObjs)
+	return
+}
+
+// keepRollback removes everything added to the document since m was
+// recorded and restores the drawing context. The operators that established
+// the drawing context during the trial rendering are removed with the page
+// content, so the context is restored without writing operators.
+func (f *Fpdf) keepRollback(m *keepMarkType) {
+	// Pages started by the trial rendering follow the page on which it began
+	for len(f.pages) > m.pageCount {
+		f.deletePage(m.page + 1)
+	}
+	f.page = m.page
+	ps := &m.pageState
+	f.curOrientation, f.curPageSize = ps.orientation, ps.size
+	f.w, f.h, f.wPt, f.hPt = ps.w, ps.h, ps.wPt, ps.hPt
+	f.pageBreakTrigger = ps.pageBreakTrigger
+	f.contentStart, f.backgroundLen, f.keepPage = ps.contentStart, ps.backgroundLen, ps.keepPage
+	if !ps.blank {
+		delete(f.blankPages, f.page)
+	}
+	f.insertAt = ps.insertAt
+	f.pages[m.page].Truncate(m.pageLen)
+	f.pageLinks[m.page] = f.pageLinks[m.page][:m.pageLinkCount]
+	f.links = f.links[:m.linkCount]
+	f.outlines = f.outlines[:m.outlineCount]
+	for name := range f.namedDests {
+		if !m.dests[name] {
+			delete(f.namedDests, name)
+		}
+	}
+	for key, info := range f.images {
+		if info.i > m.imageCount {
+			delete(f.images, key)
+		}
+	}
+	for key, font := range f.fonts {
+		if font.I >= m.fontCount {
+			delete(f.fonts, key)
+		}
+	}
+	f.blendList = f.blendList[:m.blendCount]
+	for key, pos := range f.blendMap {
+		if pos >= m.blendCount {
+			delete(f.blendMap, key)
+		}
+	}
+	f.overprintList = f.overprintList[:m.overprintCount]
+	for key, pos := range f.overprintMap {
+		if pos > m.overprintCount {
+			delete(f.overprintMap, key)
+		}
+	}
+	f.gradientList = f.gradientList[:m.gradientCount]
+	f.patternList = f.patternList[:m.patternCount]
+	f.wordList = f.wordList[:m.wordCount]
+	f.sigFields = f.sigFields[:m.sigCount]
+	// Structure elements that existed before the trial rendering lose the
+	// kids it added to them
+	elems := f.structure.elems[:m.elemCount]
+	for j := range elems {
+		kids := elems[j].kids[:0]
+		for _, kid := range elems[j].kids {
+			added := kid.elem >= m.elemCount
+			if kid.elem < 0 && kid.page == m.page {
+				if kid.mcid >= 0 {
+					added = kid.mcid >= m.mcidCount
+				} else {
+					added = kid.annot >= m.pageLinkCount
+				}
+			}
+			if !added {
+				kids = append(kids, kid)
+			}
+		}
+		elems[j].kids = kids
+	}
+	mcids := f.structure.mcids
+	if mcids != nil {
+		mcids[m.page] = m.mcidCount
+	}
+	f.structure = m.structure
+	f.structure.elems = elems
+	f.structure.mcids = mcids
+	f.list = m.list
+	f.layer.list = f.layer.list[:len(m.layer.list)]
+	f.layer.currentLayer = m.layer.currentLayer
+	f.rawObjs = f.rawObjs[:m.rawObjCount]
+	for _, names := range f.rawResources {
+		for name, obj := range names {
+			if obj > m.rawObjCount {
+				delete(names, name)
+			}
+		}
+	}
+	c := &m.context
+	f.stateRestore(c)
+	f.underline = c.underline
+	f.originBottom = c.originBottom
+	f.x, f.y = c.x, c.y
+	f.lasth = m.lasth
+}
+
 // CellFormat prints a rectangular cell with optional borders, background color
 // and character string. The upper-left corner of the cell corresponds to the
 // current position. The text can be aligned or centered. After the call, the
@@ -1462,9 +2897,10 @@ func (f *Fpdf) SetAcceptPageBreakFunc(fnc func() bool) {
 //
 // alignStr specifies how the text is to be positionined within the cell.
 // Horizontal alignment is controlled by including "L", "C" or "R" (left,
-// center, right) in alignStr. Vertical alignment is controlled by including
-// "T", "M", "B" or "A" (top, middle, bottom, baseline) in alignStr. The default
-// alignment is left middle.
+// center, right) in alignStr. Including "D" aligns numeric text on its decimal
+// separator; see SetDecimalAlign() for details. Vertical alignment is
+// controlled by including "T", "M", "B" or "A" (top, middle, bottom,
+// baseline) in alignStr. The default alignment is left middle.
 //
 // fill is true to paint the cell background or false to leave it transparent.
 //
@@ -1477,6 +2913,17 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr string, borderStr string, ln int,
 	if f.err != nil {
 		return
 	}
+	logicalStr := f.actualTextStr
+	f.actualTextStr = ""
+	txtStr = f.controlText(txtStr, "")
+	// Tabular figures do not apply to headers printed at a page break. The
+	// setting is restored however CellFormat returns.
+	tabular := f.tabularFigures
+	f.tabularFigures = false
+	defer func() { f.tabularFigures = tabular }()
+	if txtStr != "" && !f.fontReady() {
+		return
+	}
 	borderStr = strings.ToUpper(borderStr)
 	k := f.k
 	if f.y+h > f.pageBreakTrigger && !f.inHeader && !f.inFooter && f.acceptPageBreak() {
@@ -1492,15 +2939,25 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr string, borderStr string, ln int,
 		if f.err != nil {
 			return
 		}
+		if f.tableHeaderFnc != nil && !f.inTableHeader {
+			f.inTableHeader = true
+			f.tableHeaderFnc()
+			f.inTableHeader = false
+			if f.err != nil {
+				return
+			}
+		}
 		f.x = x
 		if ws > 0 {
 			f.ws = ws
 			f.outf("%.3f Tw", ws*k)
 		}
 	}
+	f.tabularFigures = tabular
 	if w == 0 {
 		w = f.w - f.rMargin - f.x
 	}
+	tagLink := len(txtStr) > 0 && (link > 0 || len(linkStr) > 0) && f.structBeginLink()
 	var s fmtBuffer
 	if fill || borderStr == "1" {
 		var op string
@@ -1517,27 +2974,31 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr string, borderStr string, ln int,
 			op = "S"
 		}
 		/// dbg("(CellFormat) f.x %.2f f.k %.2f", f.x, f.k)
-		s.printf("%.2f %.2f %.2f %.2f re %s ", f.x*k, (f.h-f.y)*k, w*k, -h*k, op)
+		x0, y0, wd, ht := f.x*k, (f.h-f.y)*k, w*k, -h*k
+		if borderStr == "1" {
+			x0, y0, wd, ht = f.snapRect(x0, y0, wd, ht)
+		}
+		s.printf(f.precision("%.2f %.2f %.2f %.2f re %s "), x0, y0, wd, ht, op)
 	}
 	if len(borderStr) > 0 && borderStr != "1" {
 		// fmt.Printf("border is '%s', no fill\n", borderStr)
 		x := f.x
 		y := f.y
-		left := x * k
-		top := (f.h - y) * k
-		right := (x + w) * k
-		bottom := (f.h - (y + h)) * k
+		left := f.snap(x * k)
+		top := f.snap((f.h - y) * k)
+		right := f.snap((x + w) * k)
+		bottom := f.snap((f.h - (y + h)) * k)
 		if strings.Contains(borderStr, "L") {
-			s.printf("%.2f %.2f m %.2f %.2f l S ", left, top, left, bottom)
+			s.printf(f.precision("%.2f %.2f m %.2f %.2f l S "), left, top, left, bottom)
 		}
 		if strings.Contains(borderStr, "T") {
-			s.printf("%.2f %.2f m %.2f %.2f l S ", left, top, right, top)
+			s.printf(f.precision("%.2f %.2f m %.2f %.2f l S "), left, top, right, top)
 		}
 		if strings.Contains(borderStr, "R") {
-			s.printf("%.2f %.2f m %.2f %.2f l S ", right, top, right, bottom)
+			s.printf(f.precision("%.2f %.2f m %.2f %.2f l S "), right, top, right, bottom)
 		}
 		if strings.Contains(borderStr, "B") {
-			s.printf("%.2f %.2f m %.2f %.2f l S ", left, bottom, right, bottom)
+			s.printf(f.precision("%.2f %.2f m %.2f %.2f l S "), left, bottom, right, bottom)
 		}
 	}
 	if len(txtStr) > 0 {
@@ -1547,6 +3008,8 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr string, borderStr string, ln int,
 			dx = w - f.cMargin - f.GetStringWidth(txtStr)
 		} else if strings.Index(alignStr, "C") != -1 {
 			dx = (w - f.GetStringWidth(txtStr)) / 2
+		} else if strings.Index(alignStr, "D") != -1 {
+			dx = f.decimalAlignOffset(w, txtStr)
 		} else {
 			dx = f.cMargin
 		}
@@ -1571,28 +3034,56 @@ func (f *Fpdf) CellFormat(w, h float64, txtStr string, borderStr string, ln int,
 		if f.colorFlag {
 			s.printf("q %s ", f.color.text.str)
 		}
-		txt2 := strings.Replace(txtStr, "\\", "\\\\", -1)
-		txt2 = strings.Replace(txt2, "(", "\\(", -1)
-		txt2 = strings.Replace(txt2, ")", "\\)", -1)
+		if f.actualText {
+			if logicalStr == "" {
+				logicalStr = txtStr
+			}
+			s.printf("%s ", f.actualTextSpan(f.cellText(logicalStr)))
+		}
+		txt2 := txtStr
+		if glyphs := f.shapeText(txtStr); glyphs != nil {
+			txt2 = f.glyphShow(glyphs)
+		} else {
+			if f.currentFont.Enc != nil {
+				txt2 = symbolEncode(txt2, f.currentFont.Enc)
+			} else if f.currentFont.Tp == "TrueType" && strings.IndexByte(txt2, nbsp) != -1 {
+				// Codes above 127 of a TrueType font are assigned by the translator
+				txt2 = strings.Replace(txt2, "\xa0", f.translator("\u00a0"), -1)
+			}
+			if figWd := f.currentFont.figureWidth(); f.tabularFigures && figWd > 0 {
+				txt2 = "[" + f.tabularArray(txt2, figWd) + "] TJ"
+			} else {
+				txt2 = "(" + f.escape(txt2) + ") Tj"
+			}
+		}
 		// if strings.Contains(txt2, "end of excerpt") {
 		// dbg("f.h %.2f, f.y %.2f, h %.2f, f.fontSize %.2f, k %.2f", f.h, f.y, h, f.fontSize, k)
 		// }
-		s.printf("BT %.2f %.2f Td (%s) Tj ET", (f.x+dx)*k, (f.h-(f.y+dy+.5*h+.3*f.fontSize))*k, txt2)
+		s.printf(f.precision("BT %.2f %.2f Td %s ET"), (f.x+dx)*k, (f.h-(f.y+dy+.5*h+.3*f.fontSize))*k, txt2)
 		//BT %.2F %.2F Td (%s) Tj ET',($this->x+$dx)*$k,($this->h-($this->y+.5*$h+.3*$this->FontSize))*$k,$txt2);
 		if f.underline {
 			s.printf(" %s", f.dounderline(f.x+dx, f.y+dy+.5*h+.3*f.fontSize, txtStr))
 		}
+		if f.actualText {
+			s.printf(" EMC")
+		}
 		if f.colorFlag {
 			s.printf(" Q")
 		}
 		if link > 0 || len(linkStr) > 0 {
 			f.newLink(f.x+dx, f.y+dy+.5*h-.5*f.fontSize, f.GetStringWidth(txtStr), f.fontSize, link, linkStr)
 		}
+		if f.wordPositions {
+			f.recordWords(txtStr, f.x+dx, f.y+dy+.5*h-.5*f.fontSize)
+		}
 	}
 	str := s.String()
 	if len(str) > 0 {
 		f.out(str)
 	}
+	if tagLink {
+		f.structPop()
+	}
 	f.lasth = h
 	if ln > 0 {
 		// Go to next line
@@ -1629,28 +3120,59 @@ func (f *Fpdf) Cellf(w, h float64, fmtStr string, args ...interface{}) {
 func (f *Fpdf) SplitLines(txt []byte, w float64) [][]byte {
 	// Function contributed by Bruno Michel
 	lines := [][]byte{}
-	if f.err != nil {
+	if !f.fontReady() {
 		return lines
 	}
 	cw := &f.currentFont.Cw
-	wmax := int(math.Ceil((w - 2*f.cMargin) * 1000 / f.fontSize))
+	wmax := int(math.Ceil(f.glyphSpace(w - 2*f.cMargin)))
 	s := bytes.Replace(txt, []byte("\r"), []byte{}, -1)
 	nb := len(s)
 	for nb > 0 && s[nb-1] == '\n' {
 		nb--
 	}
 	s = s[0:nb]
+	str := string(s)
+	shy := f.currentFont.Enc == nil
+	hw := (*cw)['-']
 	sep := -1
+	hyph := -1
 	i := 0
 	j := 0
 	l := 0
+	wordEnd := 0
 	for i < nb {
 		c := s[i]
+		if c == softHyphen && shy {
+			if l+hw <= wmax {
+				hyph = i
+			}
+			i++
+			continue
+		}
+		if i >= wordEnd {
+			// Pass over a word that fits on the line as a whole, otherwise
+			// measure it a character at a time
+			var wd int
+			wd, wordEnd = f.wordWidth(str, i)
+			if wordEnd > i && l+wd <= wmax {
+				l += wd
+				i = wordEnd
+				continue
+			}
+		}
 		l += (*cw)[rune(c)]
 		if c == ' ' || c == '\t' || c == '\n' {
 			sep = i
 		}
-		if c == '\n' || l > wmax {
+		if c != '\n' && l > wmax && hyph > sep {
+			lines = append(lines, []byte(f.hyphenate(string(s[j:hyph]), true)))
+			i = hyph + 1
+			sep = -1
+			hyph = -1
+			j = i
+			l = 0
+			wordEnd = 0
+		} else if c == '\n' || (l > wmax && (sep != -1 || !f.keepWords)) {
 			if sep == -1 {
 				if i == j {
 					i++
@@ -1659,16 +3181,18 @@ func (f *Fpdf) SplitLines(txt []byte, w float64) [][]byte {
 			} else {
 				i = sep + 1
 			}
-			lines = append(lines, s[j:sep])
+			lines = append(lines, f.hyphenateBytes(s[j:sep]))
 			sep = -1
+			hyph = -1
 			j = i
 			l = 0
+			wordEnd = 0
 		} else {
 			i++
 		}
 	}
 	if i != j {
-		lines = append(lines, s[j:i])
+		lines = append(lines, f.hyphenateBytes(s[j:i]))
 	}
 	return lines
 }
@@ -1684,28 +3208,54 @@ func (f *Fpdf) SplitLines(txt []byte, w float64) [][]byte {
 // the right margin.
 //
 // h indicates the line height of each cell in the unit of measure specified in New().
+//
+// If automatic page breaking is enabled and the block does not fit on the
+// current page, it continues at the top margin of the next page. The
+// background fill and side borders are carried onto each page, and when the
+// border includes the top ("T") or bottom ("B") sides, the fragment on each
+// page is closed with those sides as well.
+//
+// Each explicit line break begins a new paragraph. The space set with
+// SetParagraphSpacing() is added above and below each paragraph, while lines
+// that wrap within a paragraph are separated by h alone.
+//
+// A soft hyphen, the cp1252 code 0xAD, marks a place at which a word may be
+// broken. It is not printed and takes no space unless the line is broken
+// there, in which case a hyphen is shown at the end of the line. A
+// non-breaking space, the cp1252 code 0xA0, is printed with the width of a
+// space but never ends a line, which keeps together text such as "10 kg" or
+// "Mr. Smith". Unlike ordinary spaces, it is not widened in justified text.
+// Write(), SplitLines() and MeasureCellHeight() treat these characters the
+// same way.
 func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill bool) {
 	// dbg("MultiCell")
-	if f.err != nil {
+	if !f.fontReady() {
+		return
+	}
+	if pieces := f.formFeedSplit(txtStr); pieces != nil {
+		x := f.x
+		for j, str := range pieces {
+			if j > 0 {
+				f.AddPageFormat(f.curOrientation, f.curPageSize)
+				f.x = x
+			}
+			f.MultiCell(w, h, str, borderStr, alignStr, fill)
+			if f.err != nil {
+				return
+			}
+		}
 		return
 	}
 	if alignStr == "" {
 		alignStr = "J"
 	}
-	cw := &f.currentFont.Cw
 	if w == 0 {
 		w = f.w - f.rMargin - f.x
 	}
-	wmax := (w - 2*f.cMargin) * 1000 / f.fontSize
-	s := strings.Replace(txtStr, "\r", "", -1)
-	nb := len(s)
-	// if nb > 0 && s[nb-1:nb] == "\n" {
-	if nb > 0 && []byte(s)[nb-1] == '\n' {
-		nb--
-		s = s[0:nb]
-	}
+	s := f.wrapText(txtStr)
 	// dbg("[%s]\n", s)
 	var b, b2 string
+	nl := 1
 	b = "0"
 	if len(borderStr) > 0 {
 		if borderStr == "1" {
@@ -1727,154 +3277,571 @@ func (f *Fpdf) MultiCell(w, h float64, txtStr, borderStr, alignStr string, fill
 			}
 		}
 	}
+	// cell prints one line of the block. If the line has been moved to a new
+	// page or column, the fragment left behind is closed with a bottom border
+	// and the new fragment is opened with a top border. The bottom border
+	// follows the footer of a page left behind, so it is drawn with its own
+	// draw color and line width.
+	var bottom float64
+	// Indent of the current line, from which the width available to it follows
+	indent := f.paraIndent
+	cell := func(txt, logicalStr, b string) {
+		page, x, y, pageHt := f.page, f.x, f.y, f.h
+		if indent == 0 {
+			f.actualTextStr = logicalStr
+			f.CellFormat(w, h, txt, b, 2, alignStr, fill, 0, "")
+		} else {
+			// The border and background span the cell and the text
+			// begins at the indent
+			f.CellFormat(w, h, "", b, 0, "", fill, 0, "")
+			f.x -= w - indent
+			f.actualTextStr = logicalStr
+			f.CellFormat(w-indent, h, txt, "", 2, alignStr, false, 0, "")
+			f.x -= indent
+		}
+		if nl > 1 && (f.page != page || math.Abs(f.y-h-y) > 1e-6) {
+			k := f.k
+			if borderStr == "1" || strings.Contains(borderStr, "B") {
+				lineStr := sprintf(f.precision("q %s %.2f w %.2f %.2f m %.2f %.2f l S Q\n"),
+					f.color.draw.str, f.lineWidthPt(), x*k, (pageHt-bottom)*k, (x+w)*k, (pageHt-bottom)*k)
+				f.pages[page].WriteString(lineStr)
+			}
+			if (borderStr == "1" || strings.Contains(borderStr, "T")) && !strings.Contains(b, "T") {
+				top := f.y - h
+				f.outf("%.2f %.2f m %.2f %.2f l S", f.x*k, (f.h-top)*k, (f.x+w)*k, (f.h-top)*k)
+			}
+		}
+		bottom = f.y
+	}
+	trimSet := ""
+	if f.wsTrim {
+		trimSet = " "
+	}
+	f.y += f.paraBefore
+	f.breakLines(s, w, func(ln lineBreakType) {
+		txt := s[ln.start:ln.end]
+		switch ln.brk {
+		case softHyphen, ' ':
+			// Automatic line break at a soft hyphen or a space
+			if alignStr == "J" {
+				if ln.gaps > 0 {
+					f.ws = ln.free / 1000 * f.fontSize / float64(ln.gaps)
+				} else {
+					f.ws = 0
+				}
+				f.outf("%.3f Tw", f.ws*f.k)
+			}
+		default:
+			if f.ws > 0 {
+				f.ws = 0
+				f.out("0 Tw")
+			}
+		}
+		indent = ln.indent
+		switch {
+		case ln.last:
+			if len(borderStr) > 0 && strings.Contains(borderStr, "B") {
+				b += "B"
+			}
+			cell(f.hyphenate(txt, false), "", b)
+			return
+		case ln.brk == softHyphen:
+			cell(f.hyphenate(txt, true), f.logicalText(txt, "\xad"), b)
+		case ln.brk == ' ':
+			cell(f.hyphenate(strings.TrimRight(txt, trimSet), false),
+				f.logicalText(strings.TrimRight(txt, " "), " "), b)
+		default:
+			cell(f.hyphenate(txt, false), "", b)
+			if ln.brk == '\n' {
+				f.y += f.paraAfter + f.paraBefore
+			}
+		}
+		nl++
+		if len(borderStr) > 0 && nl == 2 {
+			b = b2
+		}
+	})
+	f.y += f.paraAfter
+	f.x = f.lMargin
+}
+
+// lineBreakType describes a line of text wrapped by breakLines()
+type lineBreakType struct {
+	start, end int     // the line is s[start:end], without the hyphen shown at a break
+	brk        byte    // '\n', softHyphen or ' ' for a break at that character, 0 within a word
+	indent     float64 // indent of the line in user units
+	free       float64 // unused width in glyph space of a line broken at softHyphen or ' '
+	gaps       int     // number of spaces over which free can be distributed
+	last       bool    // the line is the last of the text
+}
+
+// wrapText prepares txtStr for wrapping by MultiCell() and
+// MeasureCellHeight()
+func (f *Fpdf) wrapText(txtStr string) string {
+	s := f.whitespace(strings.Replace(f.controlText(txtStr, "\n\r"), "\r", "", -1), true, true)
+	if nb := len(s); nb > 0 && s[nb-1] == '\n' {
+		s = s[0 : nb-1]
+	}
+	return s
+}
+
+// breakLines wraps s, prepared with wrapText(), into lines that fit cells of
+// width w in the current font and calls fn for each line in turn. This is the
+// wrapping algorithm shared by MultiCell() and MeasureCellHeight().
+func (f *Fpdf) breakLines(s string, w float64, fn func(ln lineBreakType)) {
+	font := f.currentFont
+	cw := &font.Cw
+	wmax := f.glyphSpace(w - 2*f.cMargin)
+	shy := font.Enc == nil
+	hw := float64((*cw)['-'])
+	ln := lineBreakType{indent: f.paraIndent}
+	lmax := wmax - f.glyphSpace(ln.indent)
+	nb := len(s)
 	sep := -1
+	hyph := -1
 	i := 0
 	j := 0
 	l := 0.0
 	ls := 0.0
+	lh := 0.0
 	ns := 0
-	nl := 1
+	nsh := 0
+	wordEnd := 0
 	for i < nb {
 		// Get next character
-		c := []byte(s)[i]
+		c := s[i]
 		if c == '\n' {
 			// Explicit line break
-			if f.ws > 0 {
-				f.ws = 0
-				f.out("0 Tw")
-			}
-			f.CellFormat(w, h, s[j:i], b, 2, alignStr, fill, 0, "")
+			ln.start, ln.end, ln.brk, ln.free, ln.gaps = j, i, '\n', 0, 0
+			fn(ln)
+			ln.indent = f.paraIndent
+			lmax = wmax - f.glyphSpace(ln.indent)
 			i++
 			sep = -1
+			hyph = -1
 			j = i
 			l = 0
 			ns = 0
-			nl++
-			if len(borderStr) > 0 && nl == 2 {
-				b = b2
+			continue
+		}
+		if c == softHyphen && shy {
+			// Soft hyphen, a break opportunity if the hyphen fits
+			if l+hw <= lmax {
+				hyph = i
+				lh = l + hw
+				nsh = ns
 			}
+			i++
 			continue
 		}
+		if i >= wordEnd {
+			// Pass over a word that fits on the line as a whole, otherwise
+			// measure it a character at a time
+			var wd int
+			wd, wordEnd = f.wordWidth(s, i)
+			if wordEnd > i && l+float64(wd) <= lmax {
+				l += float64(wd)
+				i = wordEnd
+				continue
+			}
+		}
 		if c == ' ' {
 			sep = i
 			ls = l
 			ns++
 		}
 		l += float64((*cw)[rune(c)])
-		if l > wmax {
+		if l > lmax && (sep != -1 || hyph != -1 || !f.keepWords) {
 			// Automatic line break
-			if sep == -1 {
+			ln.start = j
+			if hyph > sep {
+				ln.end, ln.brk, ln.free, ln.gaps = hyph, softHyphen, lmax-lh, nsh
+				i = hyph + 1
+			} else if sep == -1 {
 				if i == j {
 					i++
 				}
-				if f.ws > 0 {
-					f.ws = 0
-					f.out("0 Tw")
-				}
-				f.CellFormat(w, h, s[j:i], b, 2, alignStr, fill, 0, "")
+				ln.end, ln.brk, ln.free, ln.gaps = i, 0, 0, 0
 			} else {
-				if alignStr == "J" {
-					if ns > 1 {
-						f.ws = (wmax - ls) / 1000 * f.fontSize / float64(ns-1)
-					} else {
-						f.ws = 0
-					}
-					f.outf("%.3f Tw", f.ws*f.k)
-				}
-				f.CellFormat(w, h, s[j:sep], b, 2, alignStr, fill, 0, "")
-				i = sep + 1
+				ln.end, ln.brk, ln.free, ln.gaps = sep, ' ', lmax-ls, ns-1
+				i = f.skipSpaces(s, sep+1)
 			}
+			fn(ln)
 			sep = -1
+			hyph = -1
 			j = i
 			l = 0
 			ns = 0
-			nl++
-			if len(borderStr) > 0 && nl == 2 {
-				b = b2
-			}
+			wordEnd = 0
+			ln.indent = f.paraHanging
+			lmax = wmax - f.glyphSpace(ln.indent)
 		} else {
 			i++
 		}
 	}
 	// Last chunk
-	if f.ws > 0 {
-		f.ws = 0
-		f.out("0 Tw")
+	ln.start, ln.end, ln.brk, ln.free, ln.gaps, ln.last = j, nb, 0, 0, 0, true
+	fn(ln)
+}
+
+// SetParagraphSpacing sets the vertical space added before and after each
+// paragraph printed with MultiCell(). Paragraphs are delimited by explicit
+// line breaks in the text and by the boundaries of each MultiCell() call. The
+// values are specified in the unit of measure specified in New() and default
+// to zero. Line height within a paragraph continues to be governed by the h
+// argument of MultiCell().
+func (f *Fpdf) SetParagraphSpacing(before, after float64) {
+	f.paraBefore = before
+	f.paraAfter = after
+}
+
+// SetParagraphIndent sets the indentation of the lines of each paragraph
+// printed with MultiCell(), measured from the left edge of the text within
+// the cell. The first line of a paragraph is indented by firstLine and the
+// lines that follow it when the text wraps are indented by hanging. A
+// first-line indent suits prose and a hanging indent, with firstLine zero,
+// suits bibliographies; both together can set off the terms of a definition
+// list. The width available for wrapping each line is reduced by its indent,
+// and the border and background of the cells are not affected. The values
+// are specified in the unit of measure specified in New() and default to
+// zero. MeasureCellHeight() takes the indentation into account.
+func (f *Fpdf) SetParagraphIndent(firstLine, hanging float64) {
+	f.paraIndent = firstLine
+	f.paraHanging = hanging
+}
+
+// SetWordBreak controls the treatment of words that are too long to fit on a
+// line by themselves, such as URLs and long identifiers in narrow columns,
+// by MultiCell(), Write(), SplitLines() and MeasureCellHeight(). If
+// breakWords is true, which is the default, such a word begins a new line and
+// is broken at the character where it reaches the right edge of the cell, as
+// measured with the widths used by GetStringWidth(), and continues on the
+// following lines, so that no text extends beyond the cell. If breakWords is
+// false, lines are broken only at spaces and explicit line breaks, and a word
+// that is too long is printed whole on a line of its own, extending beyond
+// the cell.
+func (f *Fpdf) SetWordBreak(breakWords bool) {
+	f.keepWords = !breakWords
+}
+
+// SetWhitespace controls the treatment of spaces in text printed with
+// MultiCell(), Write() and the methods based on them. If collapse is true,
+// each run of consecutive spaces is printed as a single space, which suits
+// prose that was assembled from fragments or entered carelessly. If trim is
+// true, spaces at the beginning and end of each line, whether the line ends
+// with an explicit line break or an automatic one, are not printed. By
+// default both are false and spaces are printed as given, apart from the
+// space at which a line is automatically broken; this is what code listings
+// and other text that relies on indentation require. Tab characters, which
+// Write() advances to the next tab stop, are not affected.
+//
+// With Write(), trimming of the start of the text is applied only when the
+// current position is at the left margin, and the end of the text is trimmed
+// only when it is followed by a line break, so that text assembled from
+// several calls is joined as written.
+func (f *Fpdf) SetWhitespace(collapse, trim bool) {
+	f.wsCollapse = collapse
+	f.wsTrim = trim
+}
+
+// whitespace applies the settings of SetWhitespace() to s. The start of the
+// first line and the end of the last are trimmed only if trimStart and
+// trimEnd are true respectively.
+func (f *Fpdf) whitespace(s string, trimStart, trimEnd bool) string {
+	if f.wsCollapse && strings.Contains(s, "  ") {
+		var buf bytes.Buffer
+		buf.Grow(len(s))
+		for j := 0; j < len(s); j++ {
+			if s[j] != ' ' || j == 0 || s[j-1] != ' ' {
+				buf.WriteByte(s[j])
+			}
+		}
+		s = buf.String()
+	}
+	if f.wsTrim {
+		lines := strings.Split(s, "\n")
+		last := len(lines) - 1
+		for j, line := range lines {
+			if j > 0 || trimStart {
+				line = strings.TrimLeft(line, " ")
+			}
+			if j < last || trimEnd {
+				line = strings.TrimRight(line, " ")
+			}
+			lines[j] = line
+		}
+		s = strings.Join(lines, "\n")
 	}
-	if len(borderStr) > 0 && strings.Contains(borderStr, "B") {
-		b += "B"
+	return s
+}
+
+// skipSpaces returns the position of the first character at or after pos in
+// s that is not a space if spaces are trimmed, otherwise pos
+func (f *Fpdf) skipSpaces(s string, pos int) int {
+	for f.wsTrim && pos < len(s) && s[pos] == ' ' {
+		pos++
+	}
+	return pos
+}
+
+// softHyphen is the cp1252 code of the soft hyphen, U+00AD. Text wrapping
+// methods treat it as an invisible break opportunity within a word.
+const softHyphen = 0xAD
+
+// nbsp is the cp1252 code of the non-breaking space, U+00A0. Text wrapping
+// methods never break a line at it.
+const nbsp = 0xA0
+
+// hyphenate removes the soft hyphens from a line of text and, if hyph is
+// true, appends the hyphen that is shown at a line break. Symbolic fonts use
+// the code of the soft hyphen for a glyph of their own and are left as is.
+func (f *Fpdf) hyphenate(s string, hyph bool) string {
+	if f.currentFont.Enc != nil {
+		return s
+	}
+	s = strings.Replace(s, "\xad", "", -1)
+	if hyph {
+		s += "-"
+	}
+	return s
+}
+
+// logicalText returns the logical text of a line that is broken
+// automatically after s, with soft hyphens removed and breakStr, the soft
+// hyphen or space at which the line is broken, appended. It is empty unless
+// text is marked with SetActualText().
+func (f *Fpdf) logicalText(s, breakStr string) string {
+	if !f.actualText {
+		return ""
+	}
+	return f.hyphenate(s, false) + breakStr
+}
+
+// hyphenateBytes removes the soft hyphens from a line of text split by
+// SplitLines()
+func (f *Fpdf) hyphenateBytes(s []byte) []byte {
+	if f.currentFont.Enc != nil || bytes.IndexByte(s, softHyphen) == -1 {
+		return s
+	}
+	return bytes.Replace(s, []byte{softHyphen}, nil, -1)
+}
+
+// MeasureCellHeight returns the total height of the block that MultiCell()
+// would produce for txtStr with cell width w and line height lineHt. The same
+// wrapping algorithm is used, but nothing is written to the document and the
+// current position is not changed. Paragraph spacing set with
+// SetParagraphSpacing() is included. This is useful for reserving space or
+// deciding on page breaks before the text is placed. As with MultiCell(), a
+// value of zero for w indicates cells that reach to the right margin.
+func (f *Fpdf) MeasureCellHeight(w float64, txtStr string, lineHt float64) float64 {
+	if !f.fontReady() {
+		return 0
+	}
+	if w == 0 {
+		w = f.w - f.rMargin - f.x
+	}
+	nl := 0
+	np := 1
+	f.breakLines(f.wrapText(txtStr), w, func(ln lineBreakType) {
+		nl++
+		if ln.brk == '\n' {
+			np++
+		}
+	})
+	return float64(nl)*lineHt + float64(np)*(f.paraBefore+f.paraAfter)
+}
+
+// MultiCellBox prints txtStr in a cell of fixed width w and height h, wrapping
+// it with MultiCell() into lines of height lineHt and positioning the block of
+// lines vertically within the cell. This suits table rows in which some cells
+// hold a single line and others several: each cell of the row is given the
+// height of the tallest one, which can be found with MeasureCellHeight().
+//
+// alignStr combines a horizontal alignment, "L" for left, "C" for center, "R"
+// for right or "J" for justified, with a vertical alignment, "T" for top, "M"
+// for middle or "B" for bottom. The defaults are "L" and "M". The vertical
+// position is based on the ascent and descent of the current font, so that
+// the visible extent of the text, from the top of the tallest glyphs on the
+// first line to the bottom of the descenders on the last, is centered in the
+// cell, or kept the cell margin away from its top or bottom edge. Text that
+// does not fit in the cell extends beyond it.
+//
+// borderStr and fill apply to the whole cell and ln determines the position
+// after the call, as with CellFormat(). A value of zero for w indicates a cell
+// that reaches to the right margin. If automatic page breaking is enabled and
+// the cell does not fit on the current page, it is placed on the next one.
+func (f *Fpdf) MultiCellBox(w, h, lineHt float64, txtStr, borderStr string, ln int, alignStr string, fill bool) {
+	if !f.fontReady() {
+		return
+	}
+	if w == 0 {
+		w = f.w - f.rMargin - f.x
+	}
+	hAlignStr := "L"
+	for _, str := range []string{"C", "R", "J"} {
+		if strings.Contains(alignStr, str) {
+			hAlignStr = str
+		}
+	}
+	// The empty cell draws the border and background and makes any page break
+	f.CellFormat(w, h, "", borderStr, 0, "", fill, 0, "")
+	if f.err != nil {
+		return
+	}
+	x, y := f.x-w, f.y
+	blockHt := f.MeasureCellHeight(w, txtStr, lineHt)
+	// Distances from the top and bottom of the block to the visible extent of
+	// the text, given where MultiCell() places the baselines of the first and
+	// last lines
+	ascent := float64(f.currentFont.Desc.Ascent) * f.fontSize / 1000
+	baseline := lineHt/2 + .3*f.fontSize
+	topGap := f.paraBefore + baseline - ascent
+	bottomGap := f.paraAfter + lineHt - baseline - f.GetFontDescent()
+	top := y + (h-blockHt+bottomGap-topGap)/2
+	switch {
+	case strings.Contains(alignStr, "T"):
+		top = y + f.cMargin - topGap
+	case strings.Contains(alignStr, "B"):
+		top = y + h - f.cMargin - blockHt + bottomGap
+	}
+	trigger := f.pageBreakTrigger
+	f.pageBreakTrigger = math.MaxFloat64
+	f.x, f.y = x, top
+	f.MultiCell(w, lineHt, txtStr, "", hAlignStr, false)
+	f.pageBreakTrigger = trigger
+	f.lasth = h
+	switch ln {
+	case 0:
+		f.x, f.y = x+w, y
+	case 1:
+		f.x, f.y = f.lMargin, y+h
+	default:
+		f.x, f.y = x, y+h
 	}
-	f.CellFormat(w, h, s[j:i], b, 2, alignStr, fill, 0, "")
-	f.x = f.lMargin
 }
 
 // Output text in flowing mode
 func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
-	if f.err != nil {
+	if !f.fontReady() {
+		return
+	}
+	if pieces := f.formFeedSplit(txtStr); pieces != nil {
+		for j, str := range pieces {
+			if j > 0 {
+				f.AddPageFormat(f.curOrientation, f.curPageSize)
+			}
+			f.write(h, str, link, linkStr)
+			if f.err != nil {
+				return
+			}
+		}
+		return
+	}
+	if strings.Contains(txtStr, "\t") {
+		for j, str := range strings.Split(txtStr, "\t") {
+			if j > 0 {
+				f.writeTab(h)
+			}
+			f.write(h, str, link, linkStr)
+		}
+		return
+	}
+	if f.emoji != nil && f.writeEmoji(h, txtStr, link, linkStr) {
 		return
 	}
 	// dbg("Write")
 	cw := &f.currentFont.Cw
 	w := f.w - f.rMargin - f.x
-	wmax := (w - 2*f.cMargin) * 1000 / f.fontSize
-	s := strings.Replace(txtStr, "\r", "", -1)
+	wmax := f.glyphSpace(w - 2*f.cMargin)
+	s := f.whitespace(strings.Replace(f.controlText(txtStr, "\n\r"), "\r", "", -1), f.x <= f.lMargin, false)
 	nb := len(s)
+	shy := f.currentFont.Enc == nil
+	hw := float64((*cw)['-'])
 	sep := -1
+	hyph := -1
 	i := 0
 	j := 0
 	l := 0.0
 	nl := 1
+	// Words of text printed by glyph index are measured as a whole, since
+	// their glyphs may be substituted
+	glyphs := f.glyphLookups() != nil
+	wordEnd := 0
 	for i < nb {
 		// Get next character
 		c := []byte(s)[i]
 		if c == '\n' {
 			// Explicit line break
-			f.CellFormat(w, h, s[j:i], "", 2, "", false, link, linkStr)
+			f.CellFormat(w, h, f.hyphenate(s[j:i], false), "", 2, "", false, link, linkStr)
 			i++
 			sep = -1
+			hyph = -1
 			j = i
 			l = 0.0
-			if nl == 1 {
-				f.x = f.lMargin
-				w = f.w - f.rMargin - f.x
-				wmax = (w - 2*f.cMargin) * 1000 / f.fontSize
-			}
+			wordEnd = 0
+			// Margins may have been changed, for example by a header
+			// function called on a page break
+			f.x = f.lMargin
+			w = f.w - f.rMargin - f.x
+			wmax = f.glyphSpace(w - 2*f.cMargin)
 			nl++
 			continue
 		}
+		if c == softHyphen && shy {
+			if l+hw <= wmax {
+				hyph = i
+			}
+			i++
+			continue
+		}
+		if glyphs && i >= wordEnd {
+			var wd int
+			wd, wordEnd = f.wordWidth(s, i)
+			if wordEnd > i && l+float64(wd) <= wmax {
+				l += float64(wd)
+				i = wordEnd
+				continue
+			}
+		}
 		if c == ' ' {
 			sep = i
 		}
 		l += float64((*cw)[rune(c)])
-		if l > wmax {
+		if l > wmax && (sep != -1 || hyph != -1 || !f.keepWords || f.x > f.lMargin) {
 			// Automatic line break
-			if sep == -1 {
+			if hyph > sep {
+				f.actualTextStr = f.logicalText(s[j:hyph], "\xad")
+				f.CellFormat(w, h, f.hyphenate(s[j:hyph], true), "", 2, "", false, link, linkStr)
+				i = hyph + 1
+			} else if sep == -1 {
 				if f.x > f.lMargin {
 					// Move to next line
 					f.x = f.lMargin
 					f.y += h
 					w = f.w - f.rMargin - f.x
-					wmax = (w - 2*f.cMargin) * 1000 / f.fontSize
+					wmax = f.glyphSpace(w - 2*f.cMargin)
 					i++
 					nl++
+					wordEnd = 0
 					continue
 				}
 				if i == j {
 					i++
 				}
-				f.CellFormat(w, h, s[j:i], "", 2, "", false, link, linkStr)
+				f.CellFormat(w, h, f.hyphenate(s[j:i], false), "", 2, "", false, link, linkStr)
 			} else {
-				f.CellFormat(w, h, s[j:sep], "", 2, "", false, link, linkStr)
-				i = sep + 1
+				f.actualTextStr = f.logicalText(strings.TrimRight(s[j:sep], " "), " ")
+				f.CellFormat(w, h, f.hyphenate(s[j:sep], false), "", 2, "", false, link, linkStr)
+				i = f.skipSpaces(s, sep+1)
 			}
 			sep = -1
+			hyph = -1
 			j = i
 			l = 0.0
-			if nl == 1 {
-				f.x = f.lMargin
-				w = f.w - f.rMargin - f.x
-				wmax = (w - 2*f.cMargin) * 1000 / f.fontSize
-			}
+			wordEnd = 0
+			f.x = f.lMargin
+			w = f.w - f.rMargin - f.x
+			wmax = f.glyphSpace(w - 2*f.cMargin)
 			nl++
 		} else {
 			i++
@@ -1882,8 +3849,44 @@ func (f *Fpdf) write(h float64, txtStr string, link int, linkStr string) {
 	}
 	// Last chunk
 	if i != j {
-		f.CellFormat(l/1000*f.fontSize, h, s[j:], "", 0, "", false, link, linkStr)
+		f.CellFormat(l/1000*f.fontSize*f.textScale/100, h, f.hyphenate(s[j:], false), "", 0, "", false, link, linkStr)
+	}
+}
+
+// Advance the current position to the next tab stop. If the stop lies beyond
+// the right margin, the position moves to the left margin of the next line.
+func (f *Fpdf) writeTab(h float64) {
+	pos := f.x - f.lMargin
+	next := -1.0
+	for _, stop := range f.tabStops {
+		if stop > pos+1e-6 {
+			next = stop
+			break
+		}
+	}
+	if next < 0 {
+		// Past the last explicit stop, use the default interval of one half inch
+		interval := 36 / f.k
+		next = (math.Floor(pos/interval+1e-6) + 1) * interval
 	}
+	if f.lMargin+next > f.w-f.rMargin {
+		f.x = f.lMargin
+		f.y += h
+	} else {
+		f.x = f.lMargin + next
+	}
+}
+
+// SetTabStops sets the positions to which the tab character advances text
+// printed with Write() and related methods. Each position is a distance from
+// the left margin in the unit of measure specified in New(). The positions do
+// not need to be ordered. Beyond the last stop, and when no stops are set, tabs
+// advance to the next multiple of one half inch. A tab that would advance past
+// the right margin moves the text to the beginning of the next line. Passing
+// nil restores the default stops.
+func (f *Fpdf) SetTabStops(stops []float64) {
+	f.tabStops = append([]float64(nil), stops...)
+	sort.Float64s(f.tabStops)
 }
 
 // Write prints text from the current position. When the right margin is
@@ -1904,6 +3907,19 @@ func (f *Fpdf) Writef(h float64, fmtStr string, args ...interface{}) {
 	f.write(h, sprintf(fmtStr, args...), 0, "")
 }
 
+// WriteColored is like Write but renders txtStr with the text color specified
+// by the RGB components r, g and b (0 - 255). The text color that was in
+// effect before the call is restored afterward, so successive calls can be
+// used to build multi-colored paragraphs. See Write() for details on h and
+// txtStr.
+func (f *Fpdf) WriteColored(h float64, txtStr string, r, g, b int) {
+	tc := f.color.text
+	f.SetTextColor(r, g, b)
+	f.write(h, txtStr, 0, "")
+	f.color.text = tc
+	f.colorFlag = f.color.fill.str != f.color.text.str
+}
+
 // WriteLinkString writes text that when clicked launches an external URL. See
 // Write() for argument details.
 func (f *Fpdf) WriteLinkString(h float64, displayStr, targetStr string) {
@@ -1917,6 +3933,50 @@ func (f *Fpdf) WriteLinkID(h float64, displayStr string, linkID int) {
 	f.write(h, displayStr, linkID, "")
 }
 
+// WriteImage places an image inline with flowing text at the current
+// position, as Write() does with a word. The bottom of the image is aligned to
+// the baseline of text written with line height h in the current font. The
+// image is scaled to height imgHt, in the unit of measure specified in New(),
+// and its width is calculated to maintain the aspect ratio. If the image does
+// not fit between the current position and the right margin, it is moved to
+// the beginning of the next line. Upon method exit the current position is
+// left just to the right of the image.
+//
+// imageNameStr is handled as it is in Image(); the image type is inferred from
+// the file extension unless the image has already been registered. If imgHt is
+// zero, the height of the current font is used.
+func (f *Fpdf) WriteImage(h float64, imageNameStr string, imgHt float64) {
+	if f.err != nil {
+		return
+	}
+	info := f.RegisterImageOptions(imageNameStr, ImageOptions{})
+	if f.err != nil {
+		return
+	}
+	if imgHt <= 0 {
+		imgHt = f.fontSize
+	}
+	w := imgHt * info.w / info.h
+	if f.x+w > f.w-f.rMargin-f.cMargin && f.x > f.lMargin {
+		// Automatic line break
+		f.x = f.lMargin
+		f.y += h
+	}
+	if f.y+h > f.pageBreakTrigger && !f.inHeader && !f.inFooter && f.acceptPageBreak() {
+		// Automatic page break
+		x := f.x
+		f.AddPageFormat(f.curOrientation, f.curPageSize)
+		if f.err != nil {
+			return
+		}
+		f.x = x
+	}
+	baseline := f.y + .5*h + .3*f.fontSize
+	f.imageOut(info, f.x, baseline-imgHt, w, imgHt, false, 0, "")
+	f.x += w
+	f.lasth = h
+}
+
 // WriteAligned is an implementation of Write that makes it possible to align
 // text.
 //
@@ -1989,6 +4049,8 @@ func (f *Fpdf) ImageTypeFromMime(mimeStr string) (tp string) {
 		tp = "jpg"
 	case "image/gif":
 		tp = "gif"
+	case "image/tiff":
+		tp = "tif"
 	default:
 		f.SetErrorf("unsupported image type: %s", mimeStr)
 	}
@@ -1996,11 +4058,24 @@ func (f *Fpdf) ImageTypeFromMime(mimeStr string) (tp string) {
 }
 
 func (f *Fpdf) imageOut(info *ImageInfoType, x, y, w, h float64, flow bool, link int, linkStr string) {
+	f.imageFlipOut(info, x, y, w, h, flow, false, false, link, linkStr)
+}
+
+// imageFlipOut places an image as imageOut() does, mirrored horizontally if
+// flipH is true and vertically if flipV is true. The mirrored image occupies
+// the same rectangle as the unmirrored one.
+func (f *Fpdf) imageFlipOut(info *ImageInfoType, x, y, w, h float64, flow, flipH, flipV bool, link int, linkStr string) {
 	// Automatic width and height calculation if needed
 	if w == 0 && h == 0 {
-		// Put image at 96 dpi
-		w = -96
-		h = -96
+		if f.imageNatural {
+			// Put image at its own resolution
+			w = -info.dpi
+			h = -info.dpi
+		} else {
+			// Put image at 96 dpi
+			w = -96
+			h = -96
+		}
 	}
 	if w == -1 {
 		// Set image width to whatever value for dpi we read
@@ -2041,12 +4116,25 @@ func (f *Fpdf) imageOut(info *ImageInfoType, x, y, w, h float64, flow bool, link
 	if x < 0 {
 		x = f.x
 	}
+	info.placedWd = math.Max(info.placedWd, math.Abs(w*f.k))
+	info.placedHt = math.Max(info.placedHt, math.Abs(h*f.k))
 	// dbg("h %.2f", h)
 	// q 85.04 0 0 NaN 28.35 NaN cm /I2 Do Q
-	f.outf("q %.5f 0 0 %.5f %.5f %.5f cm /I%d Do Q", w*f.k, h*f.k, x*f.k, (f.h-(y+h))*f.k, info.i)
+	tagLink := (link > 0 || len(linkStr) > 0) && f.structBeginLink()
+	a, d, e, ty := w*f.k, h*f.k, x*f.k, (f.h-(y+h))*f.k
+	if flipH {
+		a, e = -a, e+w*f.k
+	}
+	if flipV {
+		d, ty = -d, ty+h*f.k
+	}
+	f.outf("q %.5f 0 0 %.5f %.5f %.5f cm /I%d Do Q", a, d, e, ty, info.i)
 	if link > 0 || len(linkStr) > 0 {
 		f.newLink(x, y, w, h, link, linkStr)
 	}
+	if tagLink {
+		f.structPop()
+	}
 }
 
 // Image puts a JPEG, PNG or GIF image in the current page.
@@ -2063,7 +4151,8 @@ func (f *Fpdf) Image(imageNameStr string, x, y, w, h float64, flow bool, tp stri
 
 // ImageOptions puts a JPEG, PNG or GIF image in the current page. The size it
 // will take on the page can be specified in different ways. If both w and h
-// are 0, the image is rendered at 96 dpi. If either w or h is zero, it will be
+// are 0, the image is rendered at 96 dpi, or at its natural size if
+// SetImageNaturalSize() has been called. If either w or h is zero, it will be
 // calculated from the other dimension so that the aspect ratio is maintained.
 // If w and/or h are -1, the dpi for that dimension will be read from the
 // ImageInfoType object. PNG files can contain dpi information, and if present,
@@ -2074,7 +4163,9 @@ func (f *Fpdf) Image(imageNameStr string, x, y, w, h float64, flow bool, tp stri
 // If w and h are any other negative value, their absolute values
 // indicate their dpi extents.
 //
-// Supported JPEG formats are 24 bit, 32 bit and gray scale. Supported PNG
+// Supported JPEG formats are 24 bit, 32 bit (CMYK) and gray scale. The
+// inverted CMYK values written by Adobe applications, which mark the image
+// with an APP14 segment, are supported. Supported PNG
 // formats are 24 bit, indexed color, and 8 bit indexed gray scale. If a GIF
 // image is animated, only the first frame is rendered. Transparency is
 // supported. It is possible to put a link on the image.
@@ -2105,10 +4196,57 @@ func (f *Fpdf) ImageOptions(imageNameStr string, x, y, w, h float64, flow bool,
 	if f.err != nil {
 		return
 	}
-	f.imageOut(info, x, y, w, h, flow, link, linkStr)
+	f.imageFlipOut(info, x, y, w, h, flow, options.FlipH, options.FlipV, link, linkStr)
 	return
 }
 
+// ImageFit places an image in the box of width w and height h whose upper
+// left corner is at (x, y), preserving the aspect ratio of the image except
+// in the "stretch" mode. imageNameStr is interpreted as with ImageOptions().
+//
+// fitStr specifies how the image fills the box. With "contain", the image is
+// made as large as possible while still fitting entirely in the box, and is
+// centered in it, leaving empty bands along two of its sides. With "cover",
+// the image is made as small as possible while still filling the box, and is
+// centered on it; the parts that extend beyond the box are clipped. With
+// "stretch", the image is scaled in each direction to fill the box exactly.
+func (f *Fpdf) ImageFit(imageNameStr string, x, y, w, h float64, fitStr string) {
+	if f.err != nil {
+		return
+	}
+	if w <= 0 || h <= 0 {
+		f.err = fmt.Errorf("image box must have positive dimensions")
+		return
+	}
+	fitStr = strings.ToLower(fitStr)
+	switch fitStr {
+	case "contain", "cover", "stretch":
+	default:
+		f.err = fmt.Errorf("invalid image fit mode \"%s\"", fitStr)
+		return
+	}
+	info := f.RegisterImageOptions(imageNameStr, ImageOptions{})
+	if f.err != nil {
+		return
+	}
+	if fitStr == "stretch" {
+		f.imageOut(info, x, y, w, h, false, 0, "")
+		return
+	}
+	size := SizeType{info.w, info.h}
+	sz := size.ScaleToWidth(w)
+	if (fitStr == "contain") == (sz.Ht > h) {
+		sz = size.ScaleToHeight(h)
+	}
+	if fitStr == "cover" {
+		f.ClipRect(x, y, w, h, false)
+	}
+	f.imageOut(info, x+(w-sz.Wd)/2, y+(h-sz.Ht)/2, sz.Wd, sz.Ht, false, 0, "")
+	if fitStr == "cover" {
+		f.ClipEnd()
+	}
+}
+
 // RegisterImageReader registers an image, reading it from Reader r, adding it
 // to the PDF file but not adding it to the page.
 //
@@ -2125,17 +4263,39 @@ func (f *Fpdf) RegisterImageReader(imgName, tp string, r io.Reader) (info *Image
 // parsing an image.
 //
 // ImageType's possible values are (case insensitive):
-// "JPG", "JPEG", "PNG" and "GIF". If empty, the type is inferred from
-// the file extension.
+// "JPG", "JPEG", "PNG", "GIF", "TIF" and "TIFF". If empty, the type is
+// inferred from the file extension. Only the first frame of a TIFF image is
+// used; see AddTIFFPages() for multi-page TIFF images.
 //
 // ReadDpi defines whether to attempt to automatically read the image
 // dpi information from the image file. Normally, this should be set
 // to true (understanding that not all images will have this info
 // available). However, for backwards compatibility with previous
 // versions of the API, it defaults to false.
+//
+// Quality applies only to images that are encoded by this package from an
+// image.Image value (see RegisterImageImage()). It is the JPEG quality, from 1
+// to 100, used when ImageType is "JPG" or "JPEG". Zero selects the default
+// quality of the image/jpeg package.
+//
+// Interpolate requests that viewers smooth the image when it is displayed at
+// a size other than its native resolution. This generally improves the look
+// of photographs that are scaled down, but should be left false, the default,
+// for pixel art and other images whose individual pixels should remain
+// sharply defined.
+//
+// FlipH and FlipV mirror the image horizontally and vertically when it is
+// placed with ImageOptions() or ImageImage(), without affecting the image
+// data that is embedded. The mirrored image occupies exactly the rectangle
+// that the unmirrored image would, and setting both rotates it by 180
+// degrees. They are ignored when an image is registered.
 type ImageOptions struct {
-	ImageType string
-	ReadDpi   bool
+	ImageType   string
+	ReadDpi     bool
+	Quality     int
+	Interpolate bool
+	FlipH       bool
+	FlipV       bool
 }
 
 // RegisterImageOptionsReader registers an image, reading it from Reader r, adding it
@@ -2163,25 +4323,102 @@ func (f *Fpdf) RegisterImageOptionsReader(imgName string, options ImageOptions,
 	if options.ImageType == "jpeg" {
 		options.ImageType = "jpg"
 	}
+	readDpi := options.ReadDpi || f.imageNatural
 	switch options.ImageType {
 	case "jpg":
-		info = f.parsejpg(r)
+		info = f.parsejpg(r, readDpi)
 	case "png":
-		info = f.parsepng(r, options.ReadDpi)
+		info = f.parsepng(r, readDpi)
 	case "gif":
 		info = f.parsegif(r)
+	case "tif", "tiff":
+		info = f.parsetiff(r)
 	default:
 		f.err = fmt.Errorf("unsupported image type: %s", options.ImageType)
 	}
 	if f.err != nil {
 		return
 	}
+	info.interpolate = options.Interpolate
 	info.i = len(f.images) + 1
 	f.images[imgName] = info
 
 	return
 }
 
+// RegisterImageImage registers an image.Image value, adding it to the PDF file
+// but not adding it to the page. Use Image() or ImageOptions() with imgName to
+// add the image to the page, or call ImageImage() to register and place the
+// image in one step.
+//
+// The image is encoded according to options.ImageType. If it is empty or
+// "PNG", the image is stored losslessly with FlateDecode compression and any
+// transparency is retained as a soft mask. This is the appropriate choice for
+// screenshots, diagrams and other images with sharp edges or few colors. If it
+// is "JPG" or "JPEG", the image is stored with DCTDecode compression at
+// options.Quality. This usually produces much smaller files for photographs at
+// the cost of some loss of detail; transparency is discarded. If it is
+// "CCITT", the image is reduced to black and white and stored with CCITT
+// Group 4 fax compression. This is by far the most compact encoding for
+// scanned text and line art.
+func (f *Fpdf) RegisterImageImage(imgName string, options ImageOptions, img image.Image) (info *ImageInfoType) {
+	if f.err != nil {
+		return
+	}
+	info, ok := f.images[imgName]
+	if ok {
+		return
+	}
+	var buf bytes.Buffer
+	var err error
+	switch strings.ToLower(options.ImageType) {
+	case "", "png":
+		options.ImageType = "png"
+		err = png.Encode(&buf, img)
+	case "jpg", "jpeg":
+		if options.Quality < 0 || options.Quality > 100 {
+			f.err = fmt.Errorf("JPEG quality (1 - 100) is out of range: %d", options.Quality)
+			return
+		}
+		var opt *jpeg.Options
+		if options.Quality > 0 {
+			opt = &jpeg.Options{Quality: options.Quality}
+		}
+		options.ImageType = "jpg"
+		err = jpeg.Encode(&buf, img, opt)
+	case "ccitt":
+		info = f.ccittImage(img)
+		if f.err == nil {
+			info.interpolate = options.Interpolate
+			info.i = len(f.images) + 1
+			f.images[imgName] = info
+		}
+		return
+	default:
+		f.err = fmt.Errorf("unsupported encoding for image.Image: %s", options.ImageType)
+		return
+	}
+	if err != nil {
+		f.err = err
+		return
+	}
+	return f.RegisterImageOptionsReader(imgName, options, &buf)
+}
+
+// ImageImage registers img with RegisterImageImage() and puts it on the
+// current page. See ImageOptions() for details on positioning and sizing the
+// image and RegisterImageImage() for the choice of encoding.
+func (f *Fpdf) ImageImage(imgName string, img image.Image, x, y, w, h float64, flow bool, options ImageOptions, link int, linkStr string) {
+	if f.err != nil {
+		return
+	}
+	info := f.RegisterImageImage(imgName, options, img)
+	if f.err != nil {
+		return
+	}
+	f.imageFlipOut(info, x, y, w, h, flow, options.FlipH, options.FlipV, link, linkStr)
+}
+
 // RegisterImage registers an image, adding it to the PDF file but not adding
 // it to the page. Use Image() with the same filename to add the image to the
 // page. Note that Image() calls this function, so this function is only
@@ -2236,11 +4473,25 @@ func (f *Fpdf) GetImageInfo(imageStr string) (info *ImageInfoType) {
 }
 
 // GetConversionRatio returns the conversion ratio based on the unit given when
-// creating the PDF.
+// creating the PDF. This is the number of points per user unit, so a length in
+// user units multiplied by this value is the same length in points. See also
+// GetUnit().
 func (f *Fpdf) GetConversionRatio() float64 {
 	return f.k
 }
 
+// GetUnit returns the unit of measure specified in New() in its short form:
+// "pt", "mm", "cm" or "in".
+func (f *Fpdf) GetUnit() string {
+	switch f.unitStr {
+	case "point":
+		return "pt"
+	case "inch":
+		return "in"
+	}
+	return f.unitStr
+}
+
 // GetXY returns the abscissa and ordinate of the current position.
 //
 // Note: the value returned for the abscissa will be affected by the current
@@ -2332,10 +4583,34 @@ func (f *Fpdf) OutputAndClose(w io.WriteCloser) error {
 
 // OutputFileAndClose creates or truncates the file specified by fileStr and
 // writes the PDF document to it. This method will close f and the newly
-// written file, even if an error is detected and no document is produced.
+// written file, even if an error is detected and no document is produced. The
+// file is not created or modified if an error occurs before or while the
+// document is completed, for example if one of the fonts used cannot be
+// embedded. With the "stream" buffer strategy (see SetBufferStrategy()), the
+// file is created first and the document is written to it as it is
+// completed; if an error occurs, the incomplete file is removed.
 //
 // Most examples demonstrate the use of this method.
 func (f *Fpdf) OutputFileAndClose(fileStr string) error {
+	if f.err == nil && f.state < 3 && f.bufferStrategy == "stream" {
+		// The document is written to the file as it is assembled
+		pdfFile, err := os.Create(fileStr)
+		if err != nil {
+			f.err = err
+			return f.err
+		}
+		f.Output(pdfFile)
+		pdfFile.Close()
+		if f.err != nil {
+			os.Remove(fileStr)
+		}
+		return f.err
+	}
+	if f.err == nil && f.state < 3 {
+		// Close first so that an existing file is left intact if the
+		// document cannot be completed
+		f.Close()
+	}
 	if f.err == nil {
 		pdfFile, err := os.Create(fileStr)
 		if err == nil {
@@ -2351,14 +4626,34 @@ func (f *Fpdf) OutputFileAndClose(fileStr string) error {
 // Output sends the PDF document to the writer specified by w. No output will
 // take place if an error has occured in the document generation process. w
 // remains open after this function returns. After returning, f is in a closed
-// state and its methods should not be called.
+// state and its methods should not be called. The generated document is
+// consumed by this method, so calling it a second time returns an error; use
+// GetBytes() to obtain the document more than once.
 func (f *Fpdf) Output(w io.Writer) error {
 	if f.err != nil {
+		f.removeSpill()
+		f.removeDocFile()
 		return f.err
 	}
 	// dbg("Output")
 	if f.state < 3 {
+		if f.bufferStrategy == "stream" {
+			// The document is written to w as it is assembled, leaving
+			// nothing in the buffer
+			f.streamWriter = w
+		}
 		f.Close()
+		f.streamWriter = nil
+		if f.err != nil {
+			return f.err
+		}
+	} else if f.buffer.Len() == 0 && f.docFile == nil {
+		f.err = fmt.Errorf("document has already been sent with Output")
+		return f.err
+	}
+	if f.docFile != nil {
+		f.docFileOut(w)
+		return f.err
 	}
 	_, err := f.buffer.WriteTo(w)
 	if err != nil {
@@ -2367,6 +4662,42 @@ func (f *Fpdf) Output(w io.Writer) error {
 	return f.err
 }
 
+// GetBytes closes the PDF document, if it is not already closed, and returns
+// its contents. This is convenient when the document is to be sent to a
+// client, for example in an HTTP handler, or stored somewhere other than the
+// file system. Nil and the error are returned if an error has occurred in the
+// document generation process.
+//
+// Unlike Output(), this method does not consume the generated document, so it
+// can be called any number of times and returns the same contents each time.
+// The returned slice must not be modified. GetBytes returns an error if it is
+// called after the document has been sent with Output().
+func (f *Fpdf) GetBytes() ([]byte, error) {
+	if f.err != nil {
+		f.removeSpill()
+		f.removeDocFile()
+		return nil, f.err
+	}
+	if f.state < 3 {
+		f.Close()
+		if f.err != nil {
+			return nil, f.err
+		}
+	} else if f.buffer.Len() == 0 && f.docFile == nil {
+		f.err = fmt.Errorf("document has already been sent with Output")
+		return nil, f.err
+	}
+	if f.docFile != nil {
+		// The document is held in memory from now on
+		f.docFileOut(&f.buffer)
+		if f.err != nil {
+			return nil, f.err
+		}
+	}
+	f.docShared = true
+	return f.buffer.Bytes(), nil
+}
+
 func (f *Fpdf) getpagesizestr(sizeStr string) (size SizeType) {
 	if f.err != nil {
 		return
@@ -2393,42 +4724,285 @@ func (f *Fpdf) _getpagesize(size SizeType) SizeType {
 	return size
 }
 
+// newPageBuffer returns an empty buffer for the content of a new page, taking
+// it from the buffers kept by Reset() if there are any. Pages of a document
+// tend to be of similar length, so a new buffer starts with the size of the
+// previous page to avoid repeated reallocation as it grows.
+func (f *Fpdf) newPageBuffer() *bytes.Buffer {
+	if n := len(f.pagePool); n > 0 {
+		buf := f.pagePool[n-1]
+		f.pagePool = f.pagePool[:n-1]
+		return buf
+	}
+	return bytes.NewBuffer(make([]byte, 0, f.pages[len(f.pages)-1].Len()))
+}
+
 func (f *Fpdf) beginpage(orientationStr string, size SizeType) {
 	if f.err != nil {
 		return
 	}
-	f.page++
-	f.pages = append(f.pages, bytes.NewBufferString(""))
-	f.pageLinks = append(f.pageLinks, make([]linkType, 0, 0))
-	f.state = 2
-	f.x = f.lMargin
-	f.y = f.tMargin
-	f.fontFamily = ""
-	// Check page size and orientation
-	if orientationStr == "" {
-		orientationStr = f.defOrientation
-	} else {
-		orientationStr = strings.ToUpper(orientationStr[0:1])
+	// The new page follows the current one unless InsertPage() has specified
+	// an index. It is appended and then moved into position.
+	index := f.insertAt
+	if index == 0 {
+		index = f.page + 1
+	}
+	f.spillPages()
+	f.pages = append(f.pages, f.newPageBuffer())
+	f.page = len(f.pages) - 1
+	f.keepPage = false
+	f.pageLinks = append(f.pageLinks, make([]linkType, 0, 0))
+	if index < f.page {
+		f.movePage(index)
+	}
+	f.backgroundLen = 0
+	if len(f.defPageBoxes) > 0 {
+		f.pageBoxes[f.page] = make(pageBoxMap)
+		for nameStr, box := range f.defPageBoxes {
+			f.pageBoxes[f.page][nameStr] = box
+		}
+	}
+	if f.defPageTrans != (pageTransType{}) {
+		f.pageTrans[f.page] = f.defPageTrans
+	}
+	if f.defPageRotation != 0 {
+		f.pageRotation[f.page] = f.defPageRotation
+	}
+	f.state = 2
+	f.x = f.lMargin
+	f.y = f.tMargin
+	f.fontFamily = ""
+	// Check page size and orientation
+	if orientationStr == "" {
+		orientationStr = f.defOrientation
+	} else {
+		orientationStr = strings.ToUpper(orientationStr[0:1])
+	}
+	if orientationStr != f.curOrientation || size.Wd != f.curPageSize.Wd || size.Ht != f.curPageSize.Ht {
+		// New size or orientation
+		if orientationStr == "P" {
+			f.w = size.Wd
+			f.h = size.Ht
+		} else {
+			f.w = size.Ht
+			f.h = size.Wd
+		}
+		f.wPt = f.w * f.k
+		f.hPt = f.h * f.k
+		f.pageBreakTrigger = f.h - f.bMargin
+		f.curOrientation = orientationStr
+		f.curPageSize = size
+	}
+	if orientationStr != f.defOrientation || size.Wd != f.defPageSize.Wd || size.Ht != f.defPageSize.Ht {
+		f.pageSizes[f.page] = SizeType{f.wPt, f.hPt}
+	}
+	return
+}
+
+// movePage moves the last page of the document, which has just been begun, to
+// the specified index and makes it the current page. The pages from index on
+// are shifted back by one and all references to them are renumbered.
+func (f *Fpdf) movePage(index int) {
+	last := len(f.pages) - 1
+	renumber := func(page int) int {
+		switch {
+		case page == last:
+			return index
+		case page >= index && page < last:
+			return page + 1
+		}
+		return page
+	}
+	buf, links := f.pages[last], f.pageLinks[last]
+	copy(f.pages[index+1:], f.pages[index:last])
+	copy(f.pageLinks[index+1:], f.pageLinks[index:last])
+	f.pages[index], f.pageLinks[index] = buf, links
+	f.renumberPages(renumber)
+	f.page = index
+}
+
+// renumberPages applies renumber to the page numbers of all per-page settings
+// and of all references to pages
+func (f *Fpdf) renumberPages(renumber func(page int) int) {
+	pageSizes := make(map[int]SizeType)
+	for page, size := range f.pageSizes {
+		pageSizes[renumber(page)] = size
+	}
+	f.pageSizes = pageSizes
+	pageBoxes := make(map[int]pageBoxMap)
+	for page, boxes := range f.pageBoxes {
+		pageBoxes[renumber(page)] = boxes
+	}
+	f.pageBoxes = pageBoxes
+	blankPages := make(map[int]bool)
+	for page := range f.blankPages {
+		blankPages[renumber(page)] = true
+	}
+	f.blankPages = blankPages
+	pageTrans := make(map[int]pageTransType)
+	for page, trans := range f.pageTrans {
+		pageTrans[renumber(page)] = trans
+	}
+	f.pageTrans = pageTrans
+	pageRotation := make(map[int]int)
+	for page, rotation := range f.pageRotation {
+		pageRotation[renumber(page)] = rotation
+	}
+	f.pageRotation = pageRotation
+	for j := range f.links {
+		f.links[j].page = renumber(f.links[j].page)
+	}
+	for j := range f.outlines {
+		f.outlines[j].p = renumber(f.outlines[j].p)
+	}
+	for name, d := range f.namedDests {
+		d.page = renumber(d.page)
+		f.namedDests[name] = d
+	}
+	for j := range f.sigFields {
+		f.sigFields[j].page = renumber(f.sigFields[j].page)
+	}
+	for j := range f.wordList {
+		f.wordList[j].Page = renumber(f.wordList[j].Page)
+	}
+	f.structRenumber(renumber)
+}
+
+// DeletePage removes the page at the specified index, which ranges from 1 to
+// PageCount(), from the document. This is useful for discarding a page that
+// was reserved, perhaps with InsertPage(), but turned out not to be needed.
+// The pages that follow it move forward by one and links, bookmarks and named
+// destinations are renumbered to match. References to the deleted page itself
+// are directed to the top of the page that takes its place, or of the
+// preceding page if it was the last one.
+//
+// The current page cannot be deleted, since its content has not been
+// completed. If a page before it is deleted, PageNo() decreases by one.
+func (f *Fpdf) DeletePage(index int) {
+	if f.err != nil {
+		return
+	}
+	if f.state == 3 {
+		f.closedError()
+		return
+	}
+	last := f.PageCount()
+	if index < 1 || index > last {
+		f.err = fmt.Errorf("page index %d is out of range 1 to %d", index, last)
+		return
+	}
+	if index == f.page {
+		f.err = fmt.Errorf("the current page %d cannot be deleted", index)
+		return
+	}
+	f.deletePage(index)
+}
+
+// deletePage removes the page at the specified index and renumbers the pages
+// that follow it
+func (f *Fpdf) deletePage(index int) {
+	last := len(f.pages) - 1
+	target := index
+	if index == last {
+		target = index - 1
+	}
+	for j := range f.links {
+		if f.links[j].page == index {
+			f.links[j].y = 0
+		}
+	}
+	for j := range f.outlines {
+		if f.outlines[j].p == index {
+			f.outlines[j].y = 0
+		}
+	}
+	for name, d := range f.namedDests {
+		if d.page == index {
+			d.y = 0
+			f.namedDests[name] = d
+		}
+	}
+	delete(f.pageSizes, index)
+	delete(f.pageBoxes, index)
+	delete(f.blankPages, index)
+	delete(f.pageTrans, index)
+	delete(f.pageRotation, index)
+	var sigFields []sigFieldType
+	for _, sf := range f.sigFields {
+		if sf.page != index {
+			sigFields = append(sigFields, sf)
+		}
+	}
+	f.sigFields = sigFields
+	var wordList []WordPositionType
+	for _, wp := range f.wordList {
+		if wp.Page != index {
+			wordList = append(wordList, wp)
+		}
 	}
-	if orientationStr != f.curOrientation || size.Wd != f.curPageSize.Wd || size.Ht != f.curPageSize.Ht {
-		// New size or orientation
-		if orientationStr == "P" {
-			f.w = size.Wd
-			f.h = size.Ht
-		} else {
-			f.w = size.Ht
-			f.h = size.Wd
+	f.wordList = wordList
+	f.structDropPage(index)
+	delete(f.spillMap, f.pages[index])
+	if f.spillNext == f.pages[index] {
+		f.spillNext = nil
+	}
+	f.pages = append(f.pages[:index], f.pages[index+1:]...)
+	f.pageLinks = append(f.pageLinks[:index], f.pageLinks[index+1:]...)
+	f.renumberPages(func(page int) int {
+		switch {
+		case page == index:
+			return target
+		case page > index:
+			return page - 1
 		}
-		f.wPt = f.w * f.k
-		f.hPt = f.h * f.k
-		f.pageBreakTrigger = f.h - f.bMargin
-		f.curOrientation = orientationStr
-		f.curPageSize = size
+		return page
+	})
+	if f.page > index || f.page == len(f.pages) {
+		f.page--
+	}
+}
+
+// SetSuppressEmptyPages specifies whether pages on which nothing has been
+// drawn are omitted from the document when it is closed. This removes the
+// blank page left at the end of a document by a final call to AddPage() or an
+// automatic page break that was not followed by any content. A page is empty
+// if nothing has been drawn on it between its header and its footer and it
+// has no links; the header and footer themselves are disregarded. The pages
+// that remain are renumbered and links, bookmarks and named destinations that
+// refer to an omitted page are directed to the page that takes its place, as
+// with DeletePage(). Page numbers that have already been printed, for example
+// in headers, are not updated, although the alias set with AliasNbPages() is.
+// If every page is empty, the first is kept. Suppression is off by default.
+//
+// A page that is meant to be blank, for example to begin chapters on a right
+// hand page in duplex printing, can be kept by calling KeepPage() while it is
+// the current page.
+func (f *Fpdf) SetSuppressEmptyPages(suppress bool) {
+	f.suppressBlank = suppress
+}
+
+// KeepPage marks the current page to be kept in the document even if nothing
+// is drawn on it and empty pages are suppressed. See SetSuppressEmptyPages().
+func (f *Fpdf) KeepPage() {
+	f.keepPage = true
+}
+
+// markBlankPage records the current page as empty if nothing has been drawn
+// on it since its header and it is not to be kept
+func (f *Fpdf) markBlankPage() {
+	if f.pages[f.page].Len() == f.contentStart && len(f.pageLinks[f.page]) == 0 && !f.keepPage &&
+		!f.sigFieldOnPage(f.page) {
+		f.blankPages[f.page] = true
 	}
-	if orientationStr != f.defOrientation || size.Wd != f.defPageSize.Wd || size.Ht != f.defPageSize.Ht {
-		f.pageSizes[f.page] = SizeType{f.wPt, f.hPt}
+}
+
+// dropBlankPages deletes the pages recorded as empty, keeping at least one
+func (f *Fpdf) dropBlankPages() {
+	for page := len(f.pages) - 1; page >= 1; page-- {
+		if f.blankPages[page] && len(f.pages) > 2 {
+			f.deletePage(page)
+		}
 	}
-	return
 }
 
 func (f *Fpdf) endpage() {
@@ -2452,21 +5026,66 @@ func (f *Fpdf) loadfont(r io.Reader) (def fontType) {
 	if err != nil {
 		f.err = err
 	}
+	// Definition files made by other tools may give the descent as a
+	// positive magnitude
+	def.Desc.Descent = -intAbs(def.Desc.Descent)
 	// dump(def)
 	return
 }
 
-// Escape special characters in strings
+// Escape special characters in strings. This is used for every PDF literal
+// string so that backslashes, parentheses (balanced or not) and control
+// characters cannot terminate the string or corrupt the surrounding content.
 func (f *Fpdf) escape(s string) string {
-	s = strings.Replace(s, "\\", "\\\\", -1)
-	s = strings.Replace(s, "(", "\\(", -1)
-	s = strings.Replace(s, ")", "\\)", -1)
-	s = strings.Replace(s, "\r", "\\r", -1)
-	return s
+	var j int
+	for j = 0; j < len(s); j++ {
+		c := s[j]
+		if c == '\\' || c == '(' || c == ')' || c < 0x20 || c == 0x7f {
+			break
+		}
+	}
+	if j == len(s) {
+		return s
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(s) + 8)
+	buf.WriteString(s[:j])
+	for ; j < len(s); j++ {
+		c := s[j]
+		switch c {
+		case '\\', '(', ')':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString("\\n")
+		case '\r':
+			buf.WriteString("\\r")
+		case '\t':
+			buf.WriteString("\\t")
+		case '\b':
+			buf.WriteString("\\b")
+		case '\f':
+			buf.WriteString("\\f")
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&buf, "\\%03o", c)
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	return buf.String()
 }
 
-// Format a text string
+// Format a text string. Strings that contain non-ASCII UTF-8 characters are
+// converted to UTF-16BE with BOM before being encrypted and escaped.
 func (f *Fpdf) textstring(s string) string {
+	return f.bytestring(textEncode(s))
+}
+
+// Format a string literal whose bytes are to be written as is, for example a
+// URI which must not be converted to UTF-16
+func (f *Fpdf) bytestring(s string) string {
 	if f.protect.encrypted {
 		b := []byte(s)
 		f.protect.rc4(uint32(f.n), &b)
@@ -2489,8 +5108,8 @@ func blankCount(str string) (count int) {
 func (f *Fpdf) dounderline(x, y float64, txt string) string {
 	up := float64(f.currentFont.Up)
 	ut := float64(f.currentFont.Ut)
-	w := f.GetStringWidth(txt) + f.ws*float64(blankCount(txt))
-	return sprintf("%.2f %.2f %.2f %.2f re f", x*f.k,
+	w := f.GetStringWidth(txt) + f.ws*f.textScale/100*float64(blankCount(txt))
+	return sprintf(f.precision("%.2f %.2f %.2f %.2f re f"), x*f.k,
 		(f.h-(y-up/1000*f.fontSize))*f.k, w*f.k, -ut/1000*f.fontSizePt)
 }
 
@@ -2509,7 +5128,7 @@ func (f *Fpdf) newImageInfo() *ImageInfoType {
 
 // Extract info from io.Reader with JPEG data
 // Thank you, Bruno Michel, for providing this code.
-func (f *Fpdf) parsejpg(r io.Reader) (info *ImageInfoType) {
+func (f *Fpdf) parsejpg(r io.Reader, readdpi bool) (info *ImageInfoType) {
 	info = f.newImageInfo()
 	var (
 		data bytes.Buffer
@@ -2531,11 +5150,19 @@ func (f *Fpdf) parsejpg(r io.Reader) (info *ImageInfoType) {
 	info.h = float64(config.Height)
 	info.f = "DCTDecode"
 	info.bpc = 8
+	if dpi := jpegDensity(info.data); readdpi && dpi > 0 {
+		info.dpi = dpi
+	}
 	switch config.ColorModel {
 	case color.GrayModel:
 		info.cs = "DeviceGray"
 	case color.YCbCrModel:
 		info.cs = "DeviceRGB"
+	case color.CMYKModel:
+		info.cs = "DeviceCMYK"
+		// Adobe applications write CMYK samples inverted and mark the
+		// image with an APP14 segment
+		info.invert = jpegAdobe(info.data)
 	default:
 		f.err = fmt.Errorf("image JPEG buffer has unsupported color space (%v)", config.ColorModel)
 		return
@@ -2543,6 +5170,71 @@ func (f *Fpdf) parsejpg(r io.Reader) (info *ImageInfoType) {
 	return
 }
 
+// jpegAdobe returns true if the markers that precede the image data in a JPEG
+// stream include an Adobe APP14 segment
+func jpegAdobe(data []byte) bool {
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF: // fill byte
+			pos++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8):
+			pos += 2
+			continue
+		case marker == 0xDA || marker == 0xD9: // start of scan, end of image
+			return false
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		seg := data[pos+4:]
+		if length >= 7 && len(seg) >= 5 && marker == 0xEE && string(seg[:5]) == "Adobe" {
+			return true
+		}
+		pos += 2 + length
+	}
+	return false
+}
+
+// jpegDensity returns the resolution, in dots per inch, given by the JFIF
+// APP0 segment of a JPEG stream, or zero if there is none or it specifies
+// only the aspect ratio of the pixels. As with PNG images, different
+// horizontal and vertical resolutions are ignored.
+func jpegDensity(data []byte) float64 {
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF: // fill byte
+			pos++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD8):
+			pos += 2
+			continue
+		case marker == 0xDA || marker == 0xD9: // start of scan, end of image
+			return 0
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		seg := data[pos+4:]
+		if length >= 14 && len(seg) >= 12 && marker == 0xE0 && string(seg[:5]) == "JFIF\x00" {
+			x := binary.BigEndian.Uint16(seg[8:])
+			y := binary.BigEndian.Uint16(seg[10:])
+			if x == 0 || x != y {
+				return 0
+			}
+			switch seg[7] {
+			case 1: // dots per inch
+				return float64(x)
+			case 2: // dots per centimeter
+				return float64(x) * 2.54
+			}
+			return 0
+		}
+		pos += 2 + length
+	}
+	return 0
+}
+
 // Extract info from a PNG data
 func (f *Fpdf) parsepng(r io.Reader, readdpi bool) (info *ImageInfoType) {
 	buf, err := bufferFromReader(r)
@@ -2742,11 +5434,9 @@ func (f *Fpdf) parsepngstream(buf *bytes.Buffer, readdpi bool) (info *ImageInfoT
 				}
 			}
 		}
-		data = sliceCompress(color.Bytes())
-		info.smask = sliceCompress(alpha.Bytes())
-		if f.pdfVersion < "1.4" {
-			f.pdfVersion = "1.4"
-		}
+		data = sliceCompress(color.Bytes(), f.compressLevel)
+		info.smask = sliceCompress(alpha.Bytes(), f.compressLevel)
+		f.requirePDFVersion("1.4", "image transparency")
 	}
 	info.data = data
 	return
@@ -2781,7 +5471,8 @@ func (f *Fpdf) newobj() {
 	for j := len(f.offsets); j <= f.n; j++ {
 		f.offsets = append(f.offsets, 0)
 	}
-	f.offsets[f.n] = f.buffer.Len()
+	f.docFlush(false)
+	f.offsets[f.n] = f.docOffset()
 	f.outf("%d 0 obj", f.n)
 }
 
@@ -2791,30 +5482,49 @@ func (f *Fpdf) putstream(b []byte) {
 		f.protect.rc4(uint32(f.n), &b)
 	}
 	f.out("stream")
-	f.out(string(b))
+	buf := f.target()
+	buf.Write(b)
+	buf.WriteByte('\n')
 	f.out("endstream")
 }
 
 // Add a line to the document
 func (f *Fpdf) out(s string) {
+	if f.state == 3 {
+		f.closedError()
+		return
+	}
+	buf := f.target()
+	buf.WriteString(s)
+	buf.WriteByte('\n')
+}
+
+// target returns the buffer to which output is currently written: the
+// content of the current page while a page is open, otherwise the document
+func (f *Fpdf) target() *bytes.Buffer {
 	if f.state == 2 {
-		f.pages[f.page].WriteString(s)
-		f.pages[f.page].WriteString("\n")
-	} else {
-		f.buffer.WriteString(s)
-		f.buffer.WriteString("\n")
+		return f.pages[f.page]
+	}
+	return &f.buffer.Buffer
+}
+
+// closedError sets the error that reports an attempt to change a document
+// that has been closed
+func (f *Fpdf) closedError() {
+	if f.err == nil {
+		f.err = fmt.Errorf("document is closed and can no longer be modified")
 	}
 }
 
 // Add a buffered line to the document
 func (f *Fpdf) outbuf(b *bytes.Buffer) {
-	if f.state == 2 {
-		f.pages[f.page].ReadFrom(b)
-		f.pages[f.page].WriteString("\n")
-	} else {
-		f.buffer.ReadFrom(b)
-		f.buffer.WriteString("\n")
+	if f.state == 3 {
+		f.closedError()
+		return
 	}
+	buf := f.target()
+	buf.ReadFrom(b)
+	buf.WriteByte('\n')
 }
 
 // RawWriteStr writes a string directly to the PDF generation buffer. This is a
@@ -2833,9 +5543,54 @@ func (f *Fpdf) RawWriteBuf(buf *bytes.Buffer) {
 	f.outbuf(buf)
 }
 
-// Add a formatted line to the document
+// Add a formatted line to the document. Lines added to the content of a page
+// are formatted with the output precision.
 func (f *Fpdf) outf(fmtStr string, args ...interface{}) {
-	f.out(sprintf(fmtStr, args...))
+	if f.state == 3 {
+		f.closedError()
+		return
+	}
+	if f.state == 2 {
+		fmtStr = f.precision(fmtStr)
+	}
+	// Formatting directly into the buffer avoids an intermediate string
+	buf := f.target()
+	fmt.Fprintf(buf, fmtStr, args...)
+	buf.WriteByte('\n')
+}
+
+// precision adjusts the numeric verbs in fmtStr to the output precision set
+// with SetOutputPrecision(). Coordinates are normally formatted with two
+// decimals and colors and spacing with three. Only operators of content
+// streams are formatted with it; lines that outf() adds to the content of a
+// page are adjusted there, and content that is assembled in a string first
+// passes its format through this method.
+func (f *Fpdf) precision(fmtStr string) string {
+	if f.precRepl == nil {
+		return fmtStr
+	}
+	return f.precRepl.Replace(fmtStr)
+}
+
+// SetOutputPrecision sets the number of decimals, from 0 to 10, used when
+// coordinates are written to the content stream. Colors and other values that
+// are normally written with one additional decimal keep that relationship.
+// Higher values can improve the fidelity of finely detailed drawings and lower
+// values produce smaller files. The default is 2. The setting applies to
+// content generated after the call; colors take on the new precision when they
+// are next set. Values written to object dictionaries, such as page boxes and
+// link rectangles, keep their precision.
+func (f *Fpdf) SetOutputPrecision(digits int) {
+	if digits < 0 || digits > 10 {
+		f.err = fmt.Errorf("output precision (0 - 10) is out of range: %d", digits)
+		return
+	}
+	if digits == 2 {
+		f.precRepl = nil
+		return
+	}
+	f.precRepl = strings.NewReplacer("%.2f", sprintf("%%.%df", digits),
+		"%.3f", sprintf("%%.%df", digits+1))
 }
 
 // SetDefaultCatalogSort sets the default value of the catalog sort flag that
@@ -2867,24 +5622,68 @@ func (f *Fpdf) SetCreationDate(tm time.Time) {
 	f.creationDate = tm
 }
 
+// SetDocumentID sets the two elements of the file identifier written as /ID
+// in the trailer of the document. The first identifies the document
+// permanently and the second the version of it; tools that update a document
+// keep the first and replace the second. Each element is written in
+// hexadecimal; 16 bytes, such as an MD5 sum, is customary. Both elements must
+// be non-empty, or both empty to restore the default.
+//
+// By default, both elements are the MD5 sum of the page content, images,
+// fonts and metadata of the document, which is computed when the document is
+// closed. The identifier is therefore reproducible when the creation date is
+// fixed with SetCreationDate(). The identifier of a protected document is
+// part of its encryption key.
+func (f *Fpdf) SetDocumentID(id [2]string) {
+	if (id[0] == "") != (id[1] == "") {
+		f.err = fmt.Errorf("both elements of the document ID must be set")
+		return
+	}
+	f.docID = id
+}
+
+// splitContent divides page content into pieces of roughly limit bytes. Each
+// piece ends with a newline so that no operation is divided. A limit of 0
+// returns the content as a single piece.
+func splitContent(data []byte, limit int) (list [][]byte) {
+	for limit > 0 && len(data) > limit {
+		pos := bytes.LastIndexByte(data[:limit], '\n')
+		if pos < 0 {
+			pos = bytes.IndexByte(data[limit:], '\n')
+			if pos < 0 {
+				break
+			}
+			pos += limit
+		}
+		list = append(list, data[:pos+1])
+		data = data[pos+1:]
+	}
+	if len(data) > 0 || len(list) == 0 {
+		list = append(list, data)
+	}
+	return
+}
+
+// putcontent writes a content stream object body, compressed if compression
+// is enabled
+func (f *Fpdf) putcontent(data []byte) {
+	if f.compress {
+		data = sliceCompress(data, f.compressLevel)
+		f.outf("<</Filter /FlateDecode /Length %d>>", len(data))
+	} else {
+		f.outf("<</Length %d>>", len(data))
+	}
+	f.putstream(data)
+	f.out("endobj")
+}
+
 func (f *Fpdf) putpages() {
 	var wPt, hPt float64
 	var pageSize SizeType
 	// var linkList []linkType
 	var ok bool
-	nb := f.page
-	if len(f.aliasNbPagesStr) > 0 {
-		// Replace number of pages
-		nbStr := sprintf("%d", nb)
-		for n := 1; n <= nb; n++ {
-			s := f.pages[n].String()
-			if strings.Contains(s, f.aliasNbPagesStr) {
-				s = strings.Replace(s, f.aliasNbPagesStr, nbStr, -1)
-				f.pages[n].Truncate(0)
-				f.pages[n].WriteString(s)
-			}
-		}
-	}
+	nb := len(f.pages) - 1
+	nbStr := sprintf("%d", nb)
 	if f.defOrientation == "P" {
 		wPt = f.defPageSize.Wd * f.k
 		hPt = f.defPageSize.Ht * f.k
@@ -2892,37 +5691,67 @@ func (f *Fpdf) putpages() {
 		wPt = f.defPageSize.Ht * f.k
 		hPt = f.defPageSize.Wd * f.k
 	}
+	f.pageObjs = make([]int, nb+1)
+	// Objects written after the last page: additional content streams and
+	// the annotations of tagged links
+	var extraList []func()
+	nextNum := 2 + 2*nb
+	nextKey := nb
 	for n := 1; n <= nb; n++ {
 		// Page
 		f.newobj()
+		f.pageObjs[n] = f.n
 		f.out("<</Type /Page")
 		f.out("/Parent 1 0 R")
 		pageSize, ok = f.pageSizes[n]
 		if ok {
-			f.outf("/MediaBox [0 0 %.2f %.2f]", pageSize.Wd, pageSize.Ht)
+			f.outf("/MediaBox [0 0 %.2f %.2f]", f.userSpace(pageSize.Wd), f.userSpace(pageSize.Ht))
+			f.putpageboxes(n, pageSize.Ht)
+		} else {
+			f.putpageboxes(n, hPt)
+		}
+		if rotation := f.pageRotation[n]; rotation != 0 {
+			f.outf("/Rotate %d", rotation)
 		}
 		f.out("/Resources 2 0 R")
-		// Links
-		if len(f.pageLinks[n]) > 0 {
+		if f.userUnit > 0 {
+			f.outf("/UserUnit %.5f", f.userUnit)
+		}
+		if f.structTagged() {
+			f.outf("/StructParents %d", n-1)
+		}
+		f.putpagetrans(n)
+		// Links and signature fields
+		if len(f.pageLinks[n]) > 0 || f.sigFieldOnPage(n) {
 			var annots fmtBuffer
 			annots.printf("/Annots [")
-			for _, pl := range f.pageLinks[n] {
-				annots.printf("<</Type /Annot /Subtype /Link /Rect [%.2f %.2f %.2f %.2f] /Border [0 0 0] ",
-					pl.x, pl.y, pl.x+pl.wd, pl.y-pl.ht)
-				if pl.link == 0 {
-					annots.printf("/A <</S /URI /URI %s>>>>", f.textstring(pl.linkStr))
+			for j, pl := range f.pageLinks[n] {
+				if pl.elem > 0 && f.structTagged() {
+					// Tagged annotations are indirect objects so that their
+					// structure elements can refer to them
+					nextNum++
+					f.pageLinks[n][j].obj, f.pageLinks[n][j].key = nextNum, nextKey
+					annots.printf("%d 0 R ", nextNum)
+					pl, key := pl, nextKey
+					extraList = append(extraList, func() {
+						f.newobj()
+						f.out(f.annotDict(pl, sprintf("/StructParent %d ", key), hPt))
+						f.out("endobj")
+					})
+					nextKey++
 				} else {
-					l := f.links[pl.link]
-					var sz SizeType
-					var h float64
-					sz, ok = f.pageSizes[l.page]
-					if ok {
-						h = sz.Ht
-					} else {
-						h = hPt
-					}
-					// dbg("h [%.2f], l.y [%.2f] f.k [%.2f]\n", h, l.y, f.k)
-					annots.printf("/Dest [%d 0 R /XYZ 0 %.2f null]>>", 1+2*l.page, h-l.y*f.k)
+					annots.printf("%s", f.annotDict(pl, "", hPt))
+				}
+			}
+			for j, sf := range f.sigFields {
+				if sf.page == n {
+					f.sigFields[j].obj = nextNum + 1
+					annots.printf("%d 0 R ", nextNum+1)
+					nextNum += 2
+					sf := sf
+					extraList = append(extraList, func() {
+						f.putsigfield(sf)
+					})
 				}
 			}
 			annots.printf("]")
@@ -2931,22 +5760,45 @@ func (f *Fpdf) putpages() {
 		if f.pdfVersion > "1.3" {
 			f.out("/Group <</Type /Group /S /Transparency /CS /DeviceRGB>>")
 		}
-		f.outf("/Contents %d 0 R>>", f.n+1)
-		f.out("endobj")
-		// Page content
-		f.newobj()
-		if f.compress {
-			data := sliceCompress(f.pages[n].Bytes())
-			f.outf("<</Filter /FlateDecode /Length %d>>", len(data))
-			f.putstream(data)
+		data := f.pageContent(n)
+		if len(f.aliasNbPagesStr) > 0 && bytes.Contains(data, []byte(f.aliasNbPagesStr)) {
+			// Replace number of pages
+			data = bytes.Replace(data, []byte(f.aliasNbPagesStr), []byte(nbStr), -1)
+		}
+		if f.userUnit > 0 {
+			// Keep the page content in points
+			data = append([]byte(sprintf("%.6f 0 0 %.6f 0 0 cm\n", 1/f.userUnit, 1/f.userUnit)), data...)
+		}
+		chunks := splitContent(data, f.streamLimit)
+		if len(chunks) > 1 {
+			// Additional streams are written after the last page so that page
+			// objects keep their fixed numbers
+			var contents fmtBuffer
+			contents.printf("/Contents [%d 0 R", f.n+1)
+			for _, data := range chunks[1:] {
+				nextNum++
+				contents.printf(" %d 0 R", nextNum)
+				data := data
+				extraList = append(extraList, func() {
+					f.newobj()
+					f.putcontent(data)
+				})
+			}
+			contents.printf("]>>")
+			f.out(contents.String())
 		} else {
-			f.outf("<</Length %d>>", f.pages[n].Len())
-			f.putstream(f.pages[n].Bytes())
+			f.outf("/Contents %d 0 R>>", f.n+1)
 		}
 		f.out("endobj")
+		// Page content
+		f.newobj()
+		f.putcontent(chunks[0])
+	}
+	for _, put := range extraList {
+		put()
 	}
 	// Pages root
-	f.offsets[1] = f.buffer.Len()
+	f.offsets[1] = f.docOffset()
 	f.out("1 0 obj")
 	f.out("<</Type /Pages")
 	var kids fmtBuffer
@@ -2957,11 +5809,31 @@ func (f *Fpdf) putpages() {
 	kids.printf("]")
 	f.out(kids.String())
 	f.outf("/Count %d", nb)
-	f.outf("/MediaBox [0 0 %.2f %.2f]", wPt, hPt)
+	f.outf("/MediaBox [0 0 %.2f %.2f]", f.userSpace(wPt), f.userSpace(hPt))
 	f.out(">>")
 	f.out("endobj")
 }
 
+// annotDict returns the dictionary of the annotation of link pl. keyStr
+// holds any additional entries. hPt is the height of the default page size.
+func (f *Fpdf) annotDict(pl linkType, keyStr string, hPt float64) string {
+	var annot fmtBuffer
+	annot.printf("<</Type /Annot /Subtype /Link /Rect [%.2f %.2f %.2f %.2f] /Border [0 0 0] %s",
+		f.userSpace(pl.x), f.userSpace(pl.y), f.userSpace(pl.x+pl.wd), f.userSpace(pl.y-pl.ht), keyStr)
+	if pl.link == 0 {
+		annot.printf("/A %s>>", f.linkAction(pl.linkStr))
+	} else {
+		l := f.links[pl.link]
+		h := hPt
+		if sz, ok := f.pageSizes[l.page]; ok {
+			h = sz.Ht
+		}
+		// dbg("h [%.2f], l.y [%.2f] f.k [%.2f]\n", h, l.y, f.k)
+		annot.printf("/Dest [%d 0 R /XYZ 0 %.2f null]>>", 1+2*l.page, f.userSpace(h-l.y*f.k))
+	}
+	return annot.String()
+}
+
 func (f *Fpdf) putimages() {
 	var keyList []string
 	var key string
@@ -2972,6 +5844,9 @@ func (f *Fpdf) putimages() {
 		sort.Strings(keyList)
 	}
 	for _, key = range keyList {
+		if f.imageMaxDPI > 0 {
+			f.downsampleImage(f.images[key])
+		}
 		f.putimage(f.images[key])
 	}
 }
@@ -2987,7 +5862,7 @@ func (f *Fpdf) putimage(info *ImageInfoType) {
 		f.outf("/ColorSpace [/Indexed /DeviceRGB %d %d 0 R]", len(info.pal)/3-1, f.n+1)
 	} else {
 		f.outf("/ColorSpace /%s", info.cs)
-		if info.cs == "DeviceCMYK" {
+		if info.invert {
 			f.out("/Decode [1 0 1 0 1 0 1 0]")
 		}
 	}
@@ -3008,28 +5883,32 @@ func (f *Fpdf) putimage(info *ImageInfoType) {
 	if info.smask != nil {
 		f.outf("/SMask %d 0 R", f.n+1)
 	}
+	if info.interpolate {
+		f.out("/Interpolate true")
+	}
 	f.outf("/Length %d>>", len(info.data))
 	f.putstream(info.data)
 	f.out("endobj")
 	// 	Soft mask
 	if len(info.smask) > 0 {
 		smask := &ImageInfoType{
-			w:     info.w,
-			h:     info.h,
-			cs:    "DeviceGray",
-			bpc:   8,
-			f:     info.f,
-			dp:    sprintf("/Predictor 15 /Colors 1 /BitsPerComponent 8 /Columns %d", int(info.w)),
-			data:  info.smask,
-			scale: f.k,
+			w:           info.w,
+			h:           info.h,
+			cs:          "DeviceGray",
+			bpc:         8,
+			f:           info.f,
+			dp:          sprintf("/Predictor 15 /Colors 1 /BitsPerComponent 8 /Columns %d", int(info.w)),
+			data:        info.smask,
+			scale:       f.k,
+			interpolate: info.interpolate,
 		}
 		f.putimage(smask)
 	}
 	// 	Palette
 	if info.cs == "Indexed" {
 		f.newobj()
-		if f.compress {
-			pal := sliceCompress(info.pal)
+		if f.compress && !f.noImageCompress {
+			pal := sliceCompress(info.pal, f.compressLevel)
 			f.outf("<</Filter /FlateDecode /Length %d>>", len(pal))
 			f.putstream(pal)
 		} else {
@@ -3088,30 +5967,44 @@ func (f *Fpdf) putresourcedict() {
 		for _, key = range keyList {
 			font = f.fonts[key]
 			f.outf("/F%d %d 0 R", font.I, font.N)
+			if font.glyphN > 0 {
+				f.outf("/G%d %d 0 R", font.I, font.glyphN)
+			}
 		}
 	}
+	f.putrawresources("Font")
 	f.out(">>")
 	f.out("/XObject <<")
 	f.putxobjectdict()
+	f.putrawresources("XObject")
 	f.out(">>")
 	count := len(f.blendList)
-	if count > 1 {
+	if count > 1 || len(f.overprintList) > 0 || len(f.rawResources["ExtGState"]) > 0 {
 		f.out("/ExtGState <<")
 		for j := 1; j < count; j++ {
 			f.outf("/GS%d %d 0 R", j, f.blendList[j].objNum)
 		}
+		f.putOverprintResources()
+		f.putrawresources("ExtGState")
 		f.out(">>")
 	}
 	count = len(f.gradientList)
-	if count > 1 {
+	if count > 1 || len(f.rawResources["Shading"]) > 0 {
 		f.out("/Shading <<")
 		for j := 1; j < count; j++ {
 			f.outf("/Sh%d %d 0 R", j, f.gradientList[j].objNum)
 		}
+		f.putrawresources("Shading")
 		f.out(">>")
 	}
+	f.patternPutResourceDict()
 	// Layers
 	f.layerPutResourceDict()
+	if len(f.rawResources["ColorSpace"]) > 0 {
+		f.out("/ColorSpace <<")
+		f.putrawresources("ColorSpace")
+		f.out(">>")
+	}
 }
 
 func (f *Fpdf) putBlendModes() {
@@ -3157,15 +6050,21 @@ func (f *Fpdf) putresources() {
 	}
 	f.layerPutLayers()
 	f.putBlendModes()
+	f.putOverprintStates()
 	f.putGradients()
+	f.putPatterns()
 	f.putfonts()
 	if f.err != nil {
 		return
 	}
 	f.putimages()
 	f.putTemplates()
+	f.putrawobjects()
+	if f.err != nil {
+		return
+	}
 	// 	Resource dictionary
-	f.offsets[2] = f.buffer.Len()
+	f.offsets[2] = f.docOffset()
 	f.out("2 0 obj")
 	f.out("<<")
 	f.putresourcedict()
@@ -3187,30 +6086,37 @@ func (f *Fpdf) putresources() {
 	return
 }
 
+// putinfo writes the entries of the document information dictionary. The
+// properties other than the producer have been converted to UTF-16 as they
+// were set if their isUTF8 argument was true, and they are written as they
+// are otherwise.
 func (f *Fpdf) putinfo() {
-	var tm time.Time
-	f.outf("/Producer %s", f.textstring("FPDF "+cnFpdfVersion))
+	f.outf("/Producer %s", f.textstring(f.producer))
 	if len(f.title) > 0 {
-		f.outf("/Title %s", f.textstring(f.title))
+		f.outf("/Title %s", f.bytestring(f.title))
 	}
 	if len(f.subject) > 0 {
-		f.outf("/Subject %s", f.textstring(f.subject))
+		f.outf("/Subject %s", f.bytestring(f.subject))
 	}
 	if len(f.author) > 0 {
-		f.outf("/Author %s", f.textstring(f.author))
+		f.outf("/Author %s", f.bytestring(f.author))
 	}
 	if len(f.keywords) > 0 {
-		f.outf("/Keywords %s", f.textstring(f.keywords))
+		f.outf("/Keywords %s", f.bytestring(f.keywords))
 	}
 	if len(f.creator) > 0 {
-		f.outf("/Creator %s", f.textstring(f.creator))
+		f.outf("/Creator %s", f.bytestring(f.creator))
 	}
+	f.outf("/CreationDate %s", f.textstring("D:"+f.creationTime().Format("20060102150405")))
+}
+
+// creationTime returns the creation date of the document: the date set with
+// SetCreationDate() or the time at which the document is closed
+func (f *Fpdf) creationTime() time.Time {
 	if f.creationDate.IsZero() {
-		tm = time.Now()
-	} else {
-		tm = f.creationDate
+		f.creationDate = time.Now()
 	}
-	f.outf("/CreationDate %s", f.textstring("D:"+tm.Format("20060102150405")))
+	return f.creationDate
 }
 
 func (f *Fpdf) putcatalog() {
@@ -3236,9 +6142,6 @@ func (f *Fpdf) putcatalog() {
 	case "TwoColumnRight":
 		f.out("/PageLayout /TwoColumnRight")
 	case "TwoPageLeft", "TwoPageRight":
-		if f.pdfVersion < "1.5" {
-			f.pdfVersion = "1.5"
-		}
 		f.out("/PageLayout /" + f.layoutMode)
 	}
 	// Bookmarks
@@ -3246,25 +6149,198 @@ func (f *Fpdf) putcatalog() {
 		f.outf("/Outlines %d 0 R", f.outlineRoot)
 		f.out("/PageMode /UseOutlines")
 	}
+	// Named destinations
+	if f.namedDestsObj > 0 {
+		f.outf("/Names <</Dests %d 0 R>>", f.namedDestsObj)
+	}
+	if f.lang != "" {
+		f.outf("/Lang %s", f.textstring(f.lang))
+	}
+	if f.metadataObj > 0 {
+		f.outf("/Metadata %d 0 R", f.metadataObj)
+	}
+	// Logical structure
+	if f.structure.rootObj > 0 {
+		f.out("/MarkInfo <</Marked true>>")
+		f.outf("/StructTreeRoot %d 0 R", f.structure.rootObj)
+	}
 	// Layers
 	f.layerPutCatalog()
+	// Signature fields
+	f.sigPutCatalog()
+}
+
+// requirePDFVersion raises the PDF version declared in the document header
+// to at least version, which is required by the named feature
+func (f *Fpdf) requirePDFVersion(version, featureStr string) {
+	if version > f.pdfVersion {
+		f.pdfVersion = version
+		f.pdfFeature = featureStr
+	}
 }
 
 func (f *Fpdf) putheader() {
-	if len(f.blendMap) > 0 && f.pdfVersion < "1.4" {
-		f.pdfVersion = "1.4"
+	if len(f.blendMap) > 0 {
+		f.requirePDFVersion("1.4", "transparency")
+	}
+	switch f.layoutMode {
+	case "TwoPageLeft", "TwoPageRight":
+		f.requirePDFVersion("1.5", "page layout "+f.layoutMode)
+	}
+	if f.structTagged() {
+		f.requirePDFVersion("1.4", "tagged content")
+	}
+	if f.lang != "" {
+		f.requirePDFVersion("1.4", "document language")
+	}
+	if f.noMetadata {
+		f.requirePDFVersion("1.4", "metadata stream")
+	}
+	if f.userUnit > 0 {
+		f.requirePDFVersion("1.6", "user units")
 	}
-	f.outf("%%PDF-%s", f.pdfVersion)
+	f.pageTransVersion()
+	version := f.pdfVersion
+	if f.pdfVersionSet != "" {
+		if f.pdfVersionSet < f.pdfVersion {
+			f.err = fmt.Errorf("PDF version %s is lower than version %s required by %s",
+				f.pdfVersionSet, f.pdfVersion, f.pdfFeature)
+			return
+		}
+		version = f.pdfVersionSet
+	}
+	f.outf("%%PDF-%s", version)
+}
+
+// emptyXMP is an XMP packet that describes the document with no properties
+const emptyXMP = "<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n" +
+	"<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n" +
+	"<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n" +
+	"<rdf:Description rdf:about=\"\"/>\n" +
+	"</rdf:RDF>\n" +
+	"</x:xmpmeta>\n" +
+	"<?xpacket end=\"w\"?>"
+
+// putmetadata writes the metadata stream of a document with cleared
+// metadata. The stream is not compressed, as PDF/A requires.
+func (f *Fpdf) putmetadata() {
+	f.newobj()
+	f.metadataObj = f.n
+	f.outf("<</Type /Metadata /Subtype /XML /Length %d>>", len(emptyXMP))
+	f.putstream([]byte(emptyXMP))
+	f.out("endobj")
 }
 
 func (f *Fpdf) puttrailer() {
 	f.outf("/Size %d", f.n+1)
 	f.outf("/Root %d 0 R", f.n)
-	f.outf("/Info %d 0 R", f.n-1)
+	if !f.noMetadata {
+		f.outf("/Info %d 0 R", f.n-1)
+	}
 	if f.protect.encrypted {
 		f.outf("/Encrypt %d 0 R", f.protect.objNum)
-		f.out("/ID [()()]")
 	}
+	f.out(f.fileIDEntry())
+}
+
+// fileIDEntry returns the /ID entry of the trailer
+func (f *Fpdf) fileIDEntry() string {
+	return sprintf("/ID [<%x> <%x>]", f.fileID[0], f.fileID[1])
+}
+
+// setFileID establishes the file identifier of the document, the one set
+// with SetDocumentID() or else the MD5 sum of the content of the document,
+// which is hashed before any of it is written
+func (f *Fpdf) setFileID() {
+	if f.docID[0] != "" {
+		f.fileID = f.docID
+	} else {
+		h := md5.New()
+		for n := 1; n < len(f.pages); n++ {
+			h.Write(f.pageContent(n))
+		}
+		var keyList []string
+		for key := range f.images {
+			keyList = append(keyList, key)
+		}
+		sort.Strings(keyList)
+		for _, key := range keyList {
+			h.Write([]byte(key))
+			h.Write(f.images[key].data)
+		}
+		keyList = keyList[:0]
+		for key := range f.fonts {
+			keyList = append(keyList, key)
+		}
+		sort.Strings(keyList)
+		for _, key := range keyList {
+			h.Write([]byte(key + f.fonts[key].Name))
+		}
+		if !f.noMetadata {
+			for _, str := range []string{f.producer, f.title, f.subject, f.author, f.keywords,
+				f.creator, f.creationTime().Format("20060102150405")} {
+				h.Write([]byte(str))
+			}
+		}
+		sum := string(h.Sum(nil))
+		f.fileID = [2]string{sum, sum}
+	}
+	if f.protect.encrypted {
+		f.protect.setFileID([]byte(f.fileID[0]))
+	}
+}
+
+// linkAction returns the action dictionary of a link to linkStr
+func (f *Fpdf) linkAction(linkStr string) string {
+	if strings.HasPrefix(linkStr, "#") {
+		name := linkStr[1:]
+		if _, ok := f.namedDests[name]; !ok {
+			f.err = fmt.Errorf("named destination %s is not defined", name)
+		}
+		return sprintf("<</S /GoTo /D %s>>", f.bytestring(name))
+	}
+	pos := strings.Index(linkStr, "#")
+	if pos > 0 && !strings.Contains(linkStr, ":") &&
+		strings.HasSuffix(strings.ToLower(linkStr[:pos]), ".pdf") {
+		return sprintf("<</S /GoToR /F %s /D %s>>",
+			f.bytestring(linkStr[:pos]), f.bytestring(linkStr[pos+1:]))
+	}
+	return sprintf("<</S /URI /URI %s>>", f.bytestring(linkStr))
+}
+
+// putnameddests writes the name tree of the named destinations. A single
+// node holds all of the names, which are sorted as required.
+func (f *Fpdf) putnameddests() {
+	if len(f.namedDests) == 0 {
+		return
+	}
+	names := make([]string, 0, len(f.namedDests))
+	for name := range f.namedDests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	f.newobj()
+	f.namedDestsObj = f.n
+	var s fmtBuffer
+	s.printf("<</Names [")
+	for _, name := range names {
+		d := f.namedDests[name]
+		if d.page < 1 || d.page > f.PageCount() {
+			f.err = fmt.Errorf("named destination %s refers to nonexistent page %d", name, d.page)
+			return
+		}
+		h := f.defPageSize.Ht * f.k
+		if f.defOrientation != "P" {
+			h = f.defPageSize.Wd * f.k
+		}
+		if sz, ok := f.pageSizes[d.page]; ok {
+			h = sz.Ht
+		}
+		s.printf("%s [%d 0 R /XYZ 0 %.2f null] ", f.bytestring(name), 1+2*d.page, f.userSpace(h-d.y*f.k))
+	}
+	s.printf("]>>")
+	f.out(s.String())
+	f.out("endobj")
 }
 
 func (f *Fpdf) putbookmarks() {
@@ -3308,7 +6384,7 @@ func (f *Fpdf) putbookmarks() {
 			if o.last != -1 {
 				f.outf("/Last %d 0 R", n+o.last)
 			}
-			f.outf("/Dest [%d 0 R /XYZ 0 %.2f null]", 1+2*o.p, (f.h-o.y)*f.k)
+			f.outf("/Dest [%d 0 R /XYZ 0 %.2f null]", 1+2*o.p, f.userSpace((f.h-o.y)*f.k))
 			f.out("/Count 0>>")
 			f.out("endobj")
 		}
@@ -3320,12 +6396,54 @@ func (f *Fpdf) putbookmarks() {
 	}
 }
 
+// sizeHint returns an estimate of the size of the document based on its page
+// content, images and fonts, which make up most of it. Compressed page content
+// is assumed to shrink to half of its size.
+func (f *Fpdf) sizeHint() (size int) {
+	for n := range f.pages {
+		size += f.pageLen(n)
+	}
+	if f.compress {
+		size /= 2
+	}
+	for _, info := range f.images {
+		size += len(info.data) + len(info.smask)
+	}
+	for _, font := range f.fonts {
+		if font != nil {
+			size += len(font.Data)
+		}
+	}
+	return
+}
+
 func (f *Fpdf) enddoc() {
 	if f.err != nil {
 		return
 	}
+	f.checkfonts()
+	if f.err != nil {
+		return
+	}
+	defer func() {
+		f.removeSpill()
+		if f.err != nil {
+			f.removeDocFile()
+		}
+	}()
+	f.docBegin()
+	if f.err != nil {
+		return
+	}
+	if f.docSink == nil {
+		f.buffer.Grow(f.sizeHint())
+	}
 	f.layerEndDoc()
+	f.setFileID()
 	f.putheader()
+	if f.err != nil {
+		return
+	}
 	f.putpages()
 	f.putresources()
 	if f.err != nil {
@@ -3333,12 +6451,21 @@ func (f *Fpdf) enddoc() {
 	}
 	// Bookmarks
 	f.putbookmarks()
+	f.putnameddests()
+	f.putstructtree()
+	if f.err != nil {
+		return
+	}
 	// 	Info
-	f.newobj()
-	f.out("<<")
-	f.putinfo()
-	f.out(">>")
-	f.out("endobj")
+	if !f.noMetadata {
+		f.newobj()
+		f.out("<<")
+		f.putinfo()
+		f.out(">>")
+		f.out("endobj")
+	} else {
+		f.putmetadata()
+	}
 	// 	Catalog
 	f.newobj()
 	f.out("<<")
@@ -3346,7 +6473,7 @@ func (f *Fpdf) enddoc() {
 	f.out(">>")
 	f.out("endobj")
 	// Cross-ref
-	o := f.buffer.Len()
+	o := f.docOffset()
 	f.out("xref")
 	f.outf("0 %d", f.n+1)
 	f.out("0000000000 65535 f ")
@@ -3362,11 +6489,41 @@ func (f *Fpdf) enddoc() {
 	f.outf("%d", o)
 	f.out("%%EOF")
 	f.state = 3
+	f.docFlush(true)
+	if f.linearize {
+		f.linearizeDoc(o)
+	}
 	return
 }
 
 // Path Drawing
 
+// BeginPath starts a path that is built incrementally with MoveTo(), LineTo(),
+// CurveTo(), CurveBezierCubicTo(), ArcTo() and ClosePath() and concluded with
+// DrawPath(). Calling BeginPath is optional when the path is only stroked or
+// filled, but it is required when the path is to be used for clipping with the
+// "W" or "W*" styles of DrawPath(). Coordinates are subject to any
+// transformation in effect from TransformBegin().
+//
+// BeginPath saves the graphics state, which DrawPath() restores after
+// painting the path. Colors, line width, line style, transparency and font
+// that are set between the two calls therefore apply to the path only and
+// revert to their earlier values once it is drawn.
+//
+// The BeginPath() example demonstrates this method.
+func (f *Fpdf) BeginPath() {
+	if f.err != nil {
+		return
+	}
+	if f.pathBegun {
+		f.err = fmt.Errorf("BeginPath called before the preceding path was drawn")
+		return
+	}
+	f.pathBegun = true
+	f.pathContext = f.drawingContext()
+	f.out("q")
+}
+
 // MoveTo moves the stylus to (x, y) without drawing the path from the
 // previous point. Paths must start with a MoveTo to set the original
 // stylus location or the result is undefined.
@@ -3378,6 +6535,7 @@ func (f *Fpdf) enddoc() {
 // that PDF creates nice line joins at the angles, rather than just
 // overlaying the lines.
 func (f *Fpdf) MoveTo(x, y float64) {
+	y = f.userY(y)
 	f.point(x, y)
 	f.x, f.y = x, y
 }
@@ -3388,6 +6546,10 @@ func (f *Fpdf) MoveTo(x, y float64) {
 //
 // The MoveTo() example demonstrates this method.
 func (f *Fpdf) LineTo(x, y float64) {
+	f.lineTo(x, f.userY(y))
+}
+
+func (f *Fpdf) lineTo(x, y float64) {
 	f.outf("%.2f %.2f l", x*f.k, (f.h-y)*f.k)
 	f.x, f.y = x, y
 }
@@ -3401,6 +6563,7 @@ func (f *Fpdf) LineTo(x, y float64) {
 //
 // The MoveTo() example demonstrates this method.
 func (f *Fpdf) CurveTo(cx, cy, x, y float64) {
+	cy, y = f.userY(cy), f.userY(y)
 	f.outf("%.5f %.5f %.5f %.5f v", cx*f.k, (f.h-cy)*f.k, x*f.k, (f.h-y)*f.k)
 	f.x, f.y = x, y
 }
@@ -3415,6 +6578,7 @@ func (f *Fpdf) CurveTo(cx, cy, x, y float64) {
 //
 // The MoveTo() example demonstrates this method.
 func (f *Fpdf) CurveBezierCubicTo(cx0, cy0, cx1, cy1, x, y float64) {
+	cy0, cy1, y = f.userY(cy0), f.userY(cy1), f.userY(y)
 	f.curve(cx0, cy0, cx1, cy1, x, y)
 	f.x, f.y = x, y
 }
@@ -3445,9 +6609,60 @@ func (f *Fpdf) ClosePath() {
 // centered on the
 // path. Filling uses the current fill color.
 //
+// If the path was started with BeginPath(), the graphics state saved there is
+// restored after the path is painted, so settings changed since that call
+// revert to their earlier values.
+//
+// A path started with BeginPath() can also be used to clip subsequent
+// rendering operations: styleStr "W" establishes the region enclosed by the
+// path as the clipping region using the nonzero winding number rule and "W*"
+// does so using the even-odd rule. The path itself is not painted. Call
+// ClipEnd() to restore unclipped operations; the graphics state saved by
+// BeginPath() is restored then rather than by DrawPath().
+//
 // The MoveTo() example demonstrates this method.
 func (f *Fpdf) DrawPath(styleStr string) {
-	f.outf(fillDrawOp(styleStr))
+	if f.err != nil {
+		return
+	}
+	switch styleStr {
+	case "W", "W*":
+		if !f.pathBegun {
+			f.err = fmt.Errorf("clipping path must be started with BeginPath")
+			return
+		}
+		f.pathBegun = false
+		f.clipNest++
+		f.out(styleStr + " n")
+		return
+	}
+	f.out(fillDrawOp(styleStr))
+	if f.pathBegun {
+		f.pathBegun = false
+		f.out("Q")
+		// Q has restored the graphics state that preceded the path
+		f.stateRestore(&f.pathContext)
+	}
+}
+
+// stateRestore returns the settings that are part of the graphics state of
+// the content stream to the values recorded in c, for use when the content
+// stream has been returned to that state by other means, such as the Q
+// operator. No operators are written, so settings that have changed since c
+// was recorded are written again when they are next used.
+func (f *Fpdf) stateRestore(c *contextType) {
+	f.fontFamily, f.fontStyle, f.currentFont = c.fontFamily, c.fontStyle, c.font
+	f.fontSizePt = c.fontSizePt
+	f.fontSize = c.fontSizePt / f.k
+	f.textScale = c.textScale
+	f.lineWidth = c.lineWidth
+	f.capStyle, f.joinStyle = c.capStyle, c.joinStyle
+	f.renderingIntent = c.renderingIntent
+	f.overprint = c.overprint
+	f.dashArray, f.dashPhase = c.dashArray, c.dashPhase
+	f.alpha, f.strokeAlpha, f.blendMode = c.alpha, c.strokeAlpha, c.blendMode
+	f.colorFlag = c.colorFlag
+	f.color.draw, f.color.fill, f.color.text = c.draw, c.fill, c.text
 }
 
 // ArcTo draws an elliptical arc centered at point (x, y). rx and ry specify its
@@ -3466,7 +6681,7 @@ func (f *Fpdf) DrawPath(styleStr string) {
 //
 // The MoveTo() example demonstrates this method.
 func (f *Fpdf) ArcTo(x, y, rx, ry, degRotate, degStart, degEnd float64) {
-	f.arc(x, y, rx, ry, degRotate, degStart, degEnd, "", true)
+	f.arc(x, f.userY(y), rx, ry, degRotate, degStart, degEnd, "", true)
 }
 
 func (f *Fpdf) arc(x, y, rx, ry, degRotate, degStart, degEnd float64,
@@ -3502,7 +6717,7 @@ func (f *Fpdf) arc(x, y, rx, ry, degRotate, degStart, degEnd float64,
 	if path {
 		if f.x != sx || f.y != sy {
 			// Draw connecting line to start point
-			f.LineTo(sx, sy)
+			f.lineTo(sx, sy)
 		}
 	} else {
 		f.point(sx, sy)
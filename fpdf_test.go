@@ -19,15 +19,25 @@ package gofpdf_test
 import (
 	"bufio"
 	"bytes"
+	"compress/zlib"
+	"encoding/binary"
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
 	"io"
 	"io/ioutil"
 	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/Workiva/gofpdf"
 	"github.com/Workiva/gofpdf/internal/example"
@@ -1755,3 +1765,3879 @@ func ExampleFpdf_CreateTemplate() {
 	// Output:
 	// Successfully generated pdf/Fpdf_CreateTemplate.pdf
 }
+
+// This example demonstrates a column of currency values that are aligned on
+// their decimal separators.
+func ExampleFpdf_SetDecimalAlign() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	amounts := []string{"1,234.56", "7.5", "-42.00", "100", "98,765.4"}
+	for _, amt := range amounts {
+		pdf.CellFormat(40, 7, "Item", "1", 0, "L", false, 0, "")
+		pdf.CellFormat(40, 7, amt, "1", 1, "D", false, 0, "")
+	}
+	pdf.Ln(5)
+	pdf.SetDecimalAlign(",", "0", 3)
+	for _, amt := range []string{"3,125", "12,5", "0,333"} {
+		pdf.CellFormat(40, 7, "Messwert", "1", 0, "L", false, 0, "")
+		pdf.CellFormat(40, 7, amt, "1", 1, "D", false, 0, "")
+	}
+	fileStr := example.Filename("Fpdf_SetDecimalAlign")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetDecimalAlign.pdf
+}
+
+// This example demonstrates small images that flow with text written with
+// Write().
+func ExampleFpdf_WriteImage() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.AddPage()
+	icon := example.ImageFile("golang-gopher.png")
+	for j := 0; j < 12; j++ {
+		pdf.Write(8, "The gopher ")
+		pdf.WriteImage(8, icon, 6)
+		pdf.Write(8, " is the mascot of Go. ")
+	}
+	fileStr := example.Filename("Fpdf_WriteImage")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_WriteImage.pdf
+}
+
+// This example demonstrates multi-colored text written in a single flowing
+// paragraph.
+func ExampleFpdf_WriteColored() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.WriteColored(6, "func", 0, 0, 200)
+	pdf.Write(6, " main() {\n    ")
+	pdf.WriteColored(6, "fmt", 0, 128, 0)
+	pdf.Write(6, ".Println(")
+	pdf.WriteColored(6, "\"Hello, world\"", 200, 0, 0)
+	pdf.Write(6, ")\n}")
+	fileStr := example.Filename("Fpdf_WriteColored")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_WriteColored.pdf
+}
+
+// This example demonstrates the generation of a linearized document that
+// permits viewers to display the first page before the entire file has been
+// downloaded.
+func ExampleFpdf_SetLinearized() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetLinearized(true)
+	pdf.SetFont("Helvetica", "", 14)
+	for j := 1; j <= 3; j++ {
+		pdf.AddPage()
+		pdf.Bookmark(fmt.Sprintf("Page %d", j), 0, -1)
+		pdf.Image(example.ImageFile("logo.png"), 10, 10, 30, 0, false, "", 0, "")
+		pdf.SetY(40)
+		pdf.Write(7, fmt.Sprintf("This is page %d of a linearized document. ", j))
+		pdf.WriteLinkString(7, "Visit the project site.", "https://github.com/jung-kurt/gofpdf")
+	}
+	fileStr := example.Filename("Fpdf_SetLinearized")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetLinearized.pdf
+}
+
+// This example demonstrates the specification of colors with hexadecimal
+// strings and CSS color names.
+func ExampleFpdf_SetFillColorHex() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.SetDrawColorHex("#333")
+	pdf.SetTextColorHex("#ffffff")
+	for _, str := range []string{"#1a2b3c", "#c04", "#2e8b57"} {
+		pdf.SetFillColorHex(str)
+		pdf.CellFormat(60, 12, str, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+	pdf.SetTextColorName("black")
+	for _, str := range []string{"CornflowerBlue", "gold", "salmon"} {
+		pdf.SetFillColorName(str)
+		pdf.CellFormat(60, 12, str, "1", 0, "C", true, 0, "")
+	}
+	fileStr := example.Filename("Fpdf_SetFillColorHex")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetFillColorHex.pdf
+}
+
+// This example demonstrates the measurement of a MultiCell block before it is
+// rendered. The measured height is used to draw a shaded panel behind the
+// text.
+func ExampleFpdf_MeasureCellHeight() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.AddPage()
+	txtStr := lorem()
+	for _, wd := range []float64{60, 120} {
+		ht := pdf.MeasureCellHeight(wd, txtStr, 5)
+		x, y := pdf.GetXY()
+		pdf.SetFillColor(220, 230, 240)
+		pdf.Rect(x, y, wd, ht, "F")
+		pdf.MultiCell(wd, 5, txtStr, "", "L", false)
+		pdf.Ln(10)
+	}
+	fileStr := example.Filename("Fpdf_MeasureCellHeight")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_MeasureCellHeight.pdf
+}
+
+// This example demonstrates nested lists with numbered, lettered and bulleted
+// items.
+func ExampleFpdf_ListItem() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.BeginList("1aB")
+	pdf.ListItem(0, "Preparation")
+	pdf.ListItem(1, "Gather the source material and confirm that it is complete.")
+	pdf.ListItem(1, "Review the outstanding questions from the previous report. "+lorem())
+	pdf.ListItem(2, "Finance")
+	pdf.ListItem(2, "Operations")
+	pdf.ListItem(0, "Drafting")
+	pdf.ListItem(1, "Numbering restarts at each new parent item.")
+	pdf.ListItem(0, "Review")
+	pdf.EndList()
+	pdf.Ln(6)
+	pdf.BeginList("")
+	pdf.ListItem(0, "Bullets at the first level are discs")
+	pdf.ListItem(1, "at the second level they are circles")
+	pdf.ListItem(2, "and beyond that they are squares")
+	pdf.EndList()
+	fileStr := example.Filename("Fpdf_ListItem")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ListItem.pdf
+}
+
+// This example demonstrates columnar text produced with tab characters and
+// custom tab stops.
+func ExampleFpdf_SetTabStops() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.SetTabStops([]float64{40, 90, 130})
+	pdf.Write(8, "Item\tQuantity\tUnit price\tTotal\n")
+	pdf.Write(8, "Widget\t4\t2.50\t10.00\n")
+	pdf.Write(8, "Sprocket\t12\t0.75\t9.00\n")
+	pdf.Write(8, "Gear\t1\t14.25\t14.25\n")
+	pdf.Ln(8)
+	pdf.SetTabStops(nil)
+	pdf.Write(8, "Default\tstops\tare\tspaced\tone\thalf\tinch\tapart\tand\ttabs\tthat\t"+
+		"reach\tthe\tright\tmargin\twrap\tto\tthe\tnext\tline.")
+	fileStr := example.Filename("Fpdf_SetTabStops")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetTabStops.pdf
+}
+
+// This example demonstrates the customization of the producer entry in the
+// document information dictionary.
+func ExampleFpdf_SetProducer() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetCreator("Quarterly Report Generator", false)
+	pdf.SetProducer("Reportwerk Engine 2.1 – gofpdf")
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.AddPage()
+	pdf.Write(8, "The document properties identify the application that generated this file.")
+	fileStr := example.Filename("Fpdf_SetProducer")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetProducer.pdf
+}
+
+// This example demonstrates control over the number of decimals used for
+// coordinates in the content stream. A fine spiral is drawn once with the
+// default precision and once with four decimals.
+func ExampleFpdf_SetOutputPrecision() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	spiral := func(cx, cy float64) {
+		var x0, y0 float64
+		for j := 0; j <= 720; j++ {
+			a := float64(j) * math.Pi / 90
+			r := 0.05 * float64(j)
+			x, y := cx+r*math.Cos(a), cy+r*math.Sin(a)
+			if j > 0 {
+				pdf.Line(x0, y0, x, y)
+			}
+			x0, y0 = x, y
+		}
+	}
+	pdf.SetLineWidth(0.05)
+	pdf.Text(20, 20, "Default precision (2 decimals)")
+	spiral(60, 70)
+	pdf.SetOutputPrecision(4)
+	pdf.Text(110, 20, "Four decimals")
+	spiral(150, 70)
+	pdf.SetOutputPrecision(2)
+	fileStr := example.Filename("Fpdf_SetOutputPrecision")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetOutputPrecision.pdf
+}
+
+// This example demonstrates the conversion between points and the unit of
+// measure of the document.
+func ExampleFpdf_GetUnit() {
+	for _, unitStr := range []string{"pt", "mm", "cm", "inch"} {
+		pdf := gofpdf.New("P", unitStr, "A4", "font")
+		k := pdf.GetConversionRatio()
+		fmt.Printf("%s: 1 unit = %.4f pt, 72 pt = %.4f %s\n", pdf.GetUnit(), k, 72/k, pdf.GetUnit())
+	}
+	// Output:
+	// pt: 1 unit = 1.0000 pt, 72 pt = 72.0000 pt
+	// mm: 1 unit = 2.8346 pt, 72 pt = 25.4000 mm
+	// cm: 1 unit = 28.3465 pt, 72 pt = 2.5400 cm
+	// in: 1 unit = 72.0000 pt, 72 pt = 1.0000 in
+}
+
+// This example demonstrates the nonzero winding number and even-odd rules for
+// filling and clipping self-intersecting polygons.
+func ExampleFpdf_ClipPolygonEvenOdd() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	star := func(cx, cy, r float64) (pts []gofpdf.PointType) {
+		for j := 0; j < 5; j++ {
+			a := math.Pi/2 + float64(j)*4*math.Pi/5
+			pts = append(pts, gofpdf.PointType{X: cx + r*math.Cos(a), Y: cy - r*math.Sin(a)})
+		}
+		return
+	}
+	pdf.SetFillColor(70, 130, 180)
+	pdf.Text(30, 20, "Nonzero (\"DF\")")
+	pdf.Polygon(star(55, 55, 30), "DF")
+	pdf.Text(120, 20, "Even-odd (\"DF*\")")
+	pdf.Polygon(star(145, 55, 30), "DF*")
+	pdf.Text(30, 105, "ClipPolygon")
+	pdf.ClipPolygon(star(55, 140, 30), true)
+	pdf.LinearGradient(25, 110, 60, 60, 250, 200, 0, 200, 0, 0, 0, 0, 1, 1)
+	pdf.ClipEnd()
+	pdf.Text(120, 105, "ClipPolygonEvenOdd")
+	pdf.ClipPolygonEvenOdd(star(145, 140, 30), true)
+	pdf.LinearGradient(115, 110, 60, 60, 250, 200, 0, 200, 0, 0, 0, 0, 1, 1)
+	pdf.ClipEnd()
+	fileStr := example.Filename("Fpdf_ClipPolygonEvenOdd")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ClipPolygonEvenOdd.pdf
+}
+
+// This example demonstrates a path that is built incrementally and then used
+// both for painting and for clipping.
+func ExampleFpdf_BeginPath() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	shape := func(x, y float64) {
+		pdf.MoveTo(x, y)
+		pdf.LineTo(x+60, y)
+		pdf.CurveTo(x+80, y+30, x+60, y+60)
+		pdf.ArcTo(x+30, y+60, 30, 15, 0, 0, 180)
+		pdf.ClosePath()
+	}
+	pdf.SetFillColor(200, 220, 255)
+	pdf.SetLineWidth(1)
+	pdf.BeginPath()
+	shape(20, 20)
+	pdf.DrawPath("DF")
+	pdf.BeginPath()
+	shape(110, 20)
+	pdf.DrawPath("W")
+	pdf.LinearGradient(100, 0, 100, 100, 255, 200, 0, 0, 100, 200, 0, 0, 1, 1)
+	pdf.ClipEnd()
+	pdf.TransformBegin()
+	pdf.TransformRotate(20, 60, 160)
+	pdf.BeginPath()
+	shape(20, 110)
+	pdf.DrawPath("F")
+	pdf.TransformEnd()
+	fileStr := example.Filename("Fpdf_BeginPath")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_BeginPath.pdf
+}
+
+// This example demonstrates the removal of identifying metadata from a
+// document.
+func ExampleFpdf_ClearMetadata() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetAuthor("Jane Analyst", false)
+	pdf.SetTitle("Internal review", false)
+	pdf.ClearMetadata()
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.AddPage()
+	pdf.Write(8, "This document carries no author, title, producer or creation date.")
+	fileStr := example.Filename("Fpdf_ClearMetadata")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ClearMetadata.pdf
+}
+
+// This example demonstrates the standard PDF fonts, which are available
+// without any font files. Their built-in metrics allow text to be measured and
+// wrapped.
+func ExampleFpdf_SetFont_core() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	for _, family := range []string{"Courier", "Helvetica", "Times"} {
+		for _, style := range []string{"", "B", "I", "BI"} {
+			pdf.SetFont(family, style, 12)
+			fmt.Printf("%s %-2s %.2f\n", family, style, pdf.GetStringWidth("Hello, world"))
+			pdf.MultiCell(0, 6, family+" "+style+": "+lorem(), "", "J", false)
+			pdf.Ln(2)
+		}
+	}
+	fileStr := example.Filename("Fpdf_SetFont_core")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Courier    30.48
+	// Courier B  30.48
+	// Courier I  30.48
+	// Courier BI 30.48
+	// Helvetica    22.11
+	// Helvetica B  23.99
+	// Helvetica I  22.11
+	// Helvetica BI 23.99
+	// Times    21.40
+	// Times B  22.34
+	// Times I  21.40
+	// Times BI 21.64
+	// Successfully generated pdf/Fpdf_SetFont_core.pdf
+}
+
+// This example demonstrates the symbolic standard fonts. Unicode characters
+// are converted to the built-in encodings of Symbol and ZapfDingbats.
+func ExampleFpdf_SetFont_symbolic() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	// check prints a check mark in ZapfDingbats and then restores the font
+	// and text color of the surrounding text, keeping the position after the
+	// mark
+	check := func() {
+		ptSize, _ := pdf.GetFontSize()
+		pdf.SaveContext()
+		pdf.SetFont("ZapfDingbats", "", ptSize)
+		pdf.SetTextColor(0, 128, 0)
+		pdf.CellFormat(8, 8, "✔", "", 0, "C", false, 0, "")
+		x := pdf.GetX()
+		pdf.RestoreContext()
+		pdf.SetX(x)
+	}
+	pdf.SetFont("Helvetica", "", 14)
+	items := []string{"Requirements reviewed", "Design approved", "Tests passed"}
+	for _, str := range items {
+		check()
+		pdf.CellFormat(0, 8, str, "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+	pdf.SetFont("ZapfDingbats", "", 18)
+	pdf.CellFormat(0, 10, "➔ ☎ ✈ ✉ ★ ❶ ❷ ❸ ♠ ♥", "", 1, "L", false, 0, "")
+	pdf.SetFont("Symbol", "", 16)
+	pdf.CellFormat(0, 10, "∑ αβγ ≤ ∞ ≠ √π ∫ ∂ → ∀x ∈ ℜ", "", 1, "L", false, 0, "")
+	pdf.Text(10, 80, "Δφ ≈ 2π × ∇·ψ")
+	fileStr := example.Filename("Fpdf_SetFont_symbolic")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetFont_symbolic.pdf
+}
+
+// This example demonstrates paragraph spacing that is independent of the line
+// height used for wrapped text.
+func ExampleFpdf_SetParagraphSpacing() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.AddPage()
+	txtStr := lorem() + "\n" + lorem() + "\n" + lorem()
+	pdf.MultiCell(0, 5, txtStr, "", "J", false)
+	pdf.Ln(10)
+	pdf.SetParagraphSpacing(1, 4)
+	pdf.MultiCell(0, 5, txtStr, "", "J", false)
+	fileStr := example.Filename("Fpdf_SetParagraphSpacing")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetParagraphSpacing.pdf
+}
+
+// This example demonstrates the use of KeepTogether() to prevent headings
+// from being separated from the paragraphs that follow them.
+func ExampleFpdf_KeepTogether() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.AddPage()
+	for j := 1; j <= 8; j++ {
+		pdf.KeepTogether(func() {
+			pdf.SetFont("Helvetica", "B", 14)
+			pdf.CellFormat(0, 10, fmt.Sprintf("Section %d", j), "", 1, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 11)
+			pdf.MultiCell(0, 5, lorem(), "", "J", false)
+		})
+		pdf.MultiCell(0, 5, lorem()+" "+lorem(), "", "J", false)
+		pdf.Ln(4)
+	}
+	fileStr := example.Filename("Fpdf_KeepTogether")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_KeepTogether.pdf
+}
+
+// This example demonstrates the placement of an image.Image value with
+// lossless and JPEG encodings.
+func ExampleFpdf_ImageImage() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.AddPage()
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			d := math.Hypot(float64(x-128), float64(y-128))
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(128 + 127*math.Sin(d/8)), 255})
+		}
+	}
+	pdf.Text(10, 15, "Lossless (FlateDecode)")
+	pdf.ImageImage("gradient-png", img, 10, 20, 60, 0, false, gofpdf.ImageOptions{}, 0, "")
+	pdf.Text(75, 15, "JPEG quality 90")
+	pdf.ImageImage("gradient-jpg90", img, 75, 20, 60, 0, false,
+		gofpdf.ImageOptions{ImageType: "JPG", Quality: 90}, 0, "")
+	pdf.Text(140, 15, "JPEG quality 10")
+	pdf.ImageImage("gradient-jpg10", img, 140, 20, 60, 0, false,
+		gofpdf.ImageOptions{ImageType: "JPG", Quality: 10}, 0, "")
+	fileStr := example.Filename("Fpdf_ImageImage")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageImage.pdf
+}
+
+// This example demonstrates tiling patterns for hatched, cross-hatched and
+// dotted fills.
+func ExampleFpdf_CreateTilingPattern() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.AddPage()
+	hatch := pdf.CreateTilingPattern(3, 3, func() {
+		pdf.SetDrawColor(0, 0, 128)
+		pdf.SetLineWidth(0.3)
+		pdf.Line(0, 3, 3, 0)
+		pdf.Line(-1, 1, 1, -1)
+		pdf.Line(2, 4, 4, 2)
+	})
+	cross := pdf.CreateTilingPattern(4, 4, func() {
+		pdf.SetDrawColor(128, 0, 0)
+		pdf.SetLineWidth(0.2)
+		pdf.Line(0, 2, 4, 2)
+		pdf.Line(2, 0, 2, 4)
+	})
+	dots := pdf.CreateTilingPattern(2.5, 2.5, func() {
+		pdf.SetFillColor(0, 128, 0)
+		pdf.Circle(1.25, 1.25, 0.5, "F")
+	})
+	pdf.SetFillPattern(hatch)
+	pdf.Rect(10, 20, 55, 40, "FD")
+	pdf.SetFillPattern(cross)
+	pdf.Circle(100, 40, 20, "FD")
+	pdf.SetFillPattern(dots)
+	pdf.Polygon([]gofpdf.PointType{{X: 140, Y: 60}, {X: 170, Y: 20}, {X: 200, Y: 60}}, "FD")
+	pdf.SetFillColor(255, 255, 255)
+	pdf.SetXY(10, 70)
+	pdf.CellFormat(0, 8, "Hatch, cross-hatch and dot patterns", "", 1, "C", false, 0, "")
+	fileStr := example.Filename("Fpdf_CreateTilingPattern")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_CreateTilingPattern.pdf
+}
+
+// This example demonstrates a bordered and filled MultiCell block that spans
+// three pages. Each fragment of the block is closed with its own borders.
+func ExampleFpdf_MultiCell_pageBreak() {
+	pdf := gofpdf.New("P", "mm", "A4", "font")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.SetFillColor(230, 240, 255)
+	pdf.AddPage()
+	pdf.SetY(200)
+	var txtStr string
+	for j := 0; j < 14; j++ {
+		txtStr += lorem() + "\n"
+	}
+	pdf.MultiCell(120, 5, txtStr, "1", "J", true)
+	pdf.MultiCell(120, 5, "The block above ends here.", "", "L", false)
+	fmt.Println(pdf.PageNo())
+	fileStr := example.Filename("Fpdf_MultiCell_pageBreak")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 3
+	// Successfully generated pdf/Fpdf_MultiCell_pageBreak.pdf
+}
+
+// This example demonstrates a table whose header row is repeated at the top
+// of each page the table continues onto.
+func ExampleFpdf_SetTableHeaderFunc() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.AddPage()
+	colWd := []float64{20, 60, 40}
+	header := func() {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.SetFillColor(200, 220, 255)
+		for j, str := range []string{"Row", "Item", "Amount"} {
+			pdf.CellFormat(colWd[j], 7, str, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 10)
+	}
+	header()
+	pdf.SetTableHeaderFunc(header)
+	for row := 1; row <= 90; row++ {
+		pdf.CellFormat(colWd[0], 6, strconv.Itoa(row), "LR", 0, "R", false, 0, "")
+		pdf.CellFormat(colWd[1], 6, fmt.Sprintf("Item %d", row), "LR", 0, "L", false, 0, "")
+		pdf.CellFormat(colWd[2], 6, fmt.Sprintf("%d.00", row*7), "LR", 1, "R", false, 0, "")
+	}
+	pdf.SetTableHeaderFunc(nil)
+	pdf.CellFormat(120, 0, "", "T", 1, "", false, 0, "")
+	fmt.Println(pdf.PageNo())
+	fileStr := example.Filename("Fpdf_SetTableHeaderFunc")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 3
+	// Successfully generated pdf/Fpdf_SetTableHeaderFunc.pdf
+}
+
+// This example demonstrates marking decorative content as an artifact so that
+// it is excluded from the reading order. The running header and footer are
+// marked as pagination artifacts automatically.
+func ExampleFpdf_MarkArtifactBegin() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("Helvetica", "I", 9)
+		pdf.CellFormat(0, 8, "Quarterly report", "B", 1, "R", false, 0, "")
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+	pdf.MarkArtifactBegin()
+	pdf.SetFont("Helvetica", "B", 72)
+	pdf.SetTextColor(230, 230, 230)
+	pdf.TransformBegin()
+	pdf.TransformRotate(45, 105, 160)
+	pdf.Text(40, 160, "DRAFT")
+	pdf.TransformEnd()
+	pdf.MarkArtifactEnd()
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Times", "", 12)
+	pdf.SetY(40)
+	pdf.MultiCell(0, 6, lorem(), "", "J", false)
+	fileStr := example.Filename("Fpdf_MarkArtifactBegin")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_MarkArtifactBegin.pdf
+}
+
+// This example demonstrates outline titles and document properties that
+// contain non-ASCII characters and PDF string delimiters. These are encoded
+// and escaped automatically.
+func ExampleFpdf_Bookmark_unicode() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Übersicht (Entwurf)", true)
+	pdf.SetAuthor("José Müller", true)
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.AddPage()
+	for j, str := range []string{"Einführung", "Données (\\brutes\\)", "Résumé"} {
+		pdf.Bookmark(str, 0, -1)
+		pdf.Cell(0, 10, fmt.Sprintf("Section %d", j+1))
+		pdf.Ln(12)
+	}
+	fileStr := example.Filename("Fpdf_Bookmark_unicode")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_Bookmark_unicode.pdf
+}
+
+// This example demonstrates that text containing unbalanced parentheses,
+// backslashes and control characters is escaped before it is written to the
+// content stream, so the resulting document remains valid.
+func ExampleFpdf_CellFormat_escape() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	strList := []string{
+		"unbalanced (open",
+		"unbalanced close)",
+		"))) Tj ET BT (",
+		"trailing backslash \\",
+		"control\tchars\r\x01",
+	}
+	for _, str := range strList {
+		pdf.CellFormat(0, 8, str, "", 1, "", false, 0, "")
+	}
+	pdf.Text(10, 80, strList[2])
+	pdf.SetY(90)
+	pdf.Write(6, strList[0]+" "+strList[3])
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	if err == nil {
+		for _, str := range []string{
+			`(unbalanced \(open)`,
+			`(\)\)\) Tj ET BT \()`,
+			`(trailing backslash \\)`,
+			`(control\tchars\r\001)`,
+		} {
+			fmt.Println(bytes.Contains(buf.Bytes(), []byte(str)))
+		}
+	}
+	fileStr := example.Filename("Fpdf_CellFormat_escape")
+	err = ioutil.WriteFile(fileStr, buf.Bytes(), 0600)
+	example.Summary(err, fileStr)
+	// Output:
+	// true
+	// true
+	// true
+	// true
+	// Successfully generated pdf/Fpdf_CellFormat_escape.pdf
+}
+
+// This example demonstrates the default font, which is selected when text is
+// printed before SetFont() has been called, and how to remove it to restore
+// strict behavior.
+func ExampleFpdf_SetDefaultFont() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetDefaultFont("Times", "I", 14)
+	pdf.AddPage()
+	pdf.Cell(0, 10, "Printed with the default font")
+	fileStr := example.Filename("Fpdf_SetDefaultFont")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	strict := gofpdf.New("P", "mm", "A4", "")
+	strict.SetDefaultFont("", "", 0)
+	strict.AddPage()
+	strict.Cell(0, 10, "No font")
+	fmt.Println(strict.Error())
+	// Output:
+	// Successfully generated pdf/Fpdf_SetDefaultFont.pdf
+	// font has not been set; unable to render text
+}
+
+// This example demonstrates dividing the content of a page with many drawing
+// operations into several content streams. The limit is set very low here so
+// that the effect can be seen in a small document.
+func ExampleFpdf_SetContentStreamLimit() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetContentStreamLimit(4096)
+	pdf.AddPage()
+	for j := 0; j < 400; j++ {
+		a := float64(j) * math.Pi / 200
+		pdf.Line(105, 148, 105+90*math.Cos(a), 148+90*math.Sin(a))
+	}
+	fileStr := example.Filename("Fpdf_SetContentStreamLimit")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetContentStreamLimit.pdf
+}
+
+// This example demonstrates restoring the default graphics state before the
+// page footer is drawn, so that settings used for the page content do not
+// affect it.
+func ExampleFpdf_ResetGraphicsState() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFooterFunc(func() {
+		pdf.ResetGraphicsState(false)
+		pdf.SetY(-15)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d", pdf.PageNo()), "T", 0, "C", false, 0, "")
+	})
+	pdf.SetFont("Helvetica", "U", 12)
+	pdf.AddPage()
+	pdf.SetLineWidth(2)
+	pdf.SetDashPattern([]float64{4, 2}, 0)
+	pdf.SetDrawColor(200, 0, 0)
+	pdf.SetFillColor(255, 220, 220)
+	pdf.SetTextColor(128, 0, 0)
+	pdf.SetAlpha(0.5, "Multiply")
+	pdf.Rect(20, 30, 170, 80, "FD")
+	pdf.SetXY(25, 60)
+	pdf.Cell(0, 10, "Underlined, translucent, red content")
+	fileStr := example.Filename("Fpdf_ResetGraphicsState")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ResetGraphicsState.pdf
+}
+
+// This example demonstrates drawing with the native PDF coordinate system, in
+// which the origin is at the bottom left corner of the page and vertical
+// positions increase upward.
+func ExampleFpdf_SetCoordinateOrigin() {
+	pdf := gofpdf.New("P", "pt", "Letter", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.SetCoordinateOrigin("bottomleft")
+	// Axes and a bar chart computed in PDF space
+	pdf.Line(72, 72, 540, 72)
+	pdf.Line(72, 72, 72, 400)
+	pdf.SetFillColor(80, 130, 200)
+	for j, val := range []float64{120, 260, 180, 300, 90} {
+		pdf.Rect(100+float64(j)*85, 72, 50, val, "F")
+		pdf.Text(115+float64(j)*85, 72+val+6, strconv.Itoa(int(val)))
+	}
+	pdf.Text(72, 420, "Values drawn from the bottom of the page")
+	pdf.SetCoordinateOrigin("topleft")
+	fileStr := example.Filename("Fpdf_SetCoordinateOrigin")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetCoordinateOrigin.pdf
+}
+
+// This example demonstrates helper routines that save and restore the drawing
+// context so that their settings do not affect the code that calls them.
+func ExampleFpdf_SaveContext() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Times", "", 12)
+	pdf.AddPage()
+	var badge func(x, y float64, label string, depth int)
+	badge = func(x, y float64, label string, depth int) {
+		pdf.SaveContext()
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetLineWidth(0.8)
+		pdf.SetDrawColor(0, 90, 160)
+		pdf.SetFillColor(220, 235, 250)
+		pdf.SetDashPattern([]float64{1, 1}, 0)
+		pdf.Rect(x, y, 40, 10, "FD")
+		pdf.SetXY(x, y)
+		pdf.CellFormat(40, 10, label, "", 0, "C", false, 0, "")
+		if depth > 0 {
+			badge(x+45, y, fmt.Sprintf("%s.%d", label, depth), depth-1)
+		}
+		pdf.RestoreContext()
+	}
+	pdf.Write(6, "Text before the badges. ")
+	badge(20, 40, "1", 2)
+	pdf.Write(6, "The font and position are unchanged after drawing them.")
+	pdf.Rect(20, 60, 170, 20, "D")
+	fileStr := example.Filename("Fpdf_SaveContext")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SaveContext.pdf
+}
+
+// featureFontDir writes a copy of the font Arial, to which a GSUB table has
+// been added, to a new temporary directory as features.ttf and returns the
+// directory. The table provides the features "liga", which forms the
+// ligatures fi and fl with an extension lookup, "dlig", which forms Æ and Œ
+// from AE and OE, and "smcp", which replaces lowercase letters by the phonetic
+// small capitals of the font. The features "onum", which replaces 1, 2 and 3
+// by superscripts, "tnum", which replaces the digits by themselves, and
+// "zero", which replaces 0 by Ø, stand in for figures the font lacks.
+func featureFontDir() (dir string, err error) {
+	fileStr := example.FontDir() + "/Arial.ttf"
+	data, err := ioutil.ReadFile(fileStr)
+	if err != nil {
+		return
+	}
+	ttf, err := gofpdf.TtfParse(fileStr)
+	if err != nil {
+		return
+	}
+	gid := func(r rune) int { return int(ttf.Chars[uint16(r)]) }
+	u16 := func(list ...int) []byte {
+		buf := make([]byte, 2*len(list))
+		for j, v := range list {
+			binary.BigEndian.PutUint16(buf[2*j:], uint16(v))
+		}
+		return buf
+	}
+	cat := func(list ...[]byte) []byte { return bytes.Join(list, nil) }
+	// offsets returns the number of items followed by their offsets, to which
+	// base is added, and the items themselves
+	offsets := func(base int, items ...[]byte) []byte {
+		head := u16(len(items))
+		ofs := base + 2 + 2*len(items)
+		for _, item := range items {
+			head = append(head, u16(ofs)...)
+			ofs += len(item)
+		}
+		return cat(head, cat(items...))
+	}
+	// ligatures returns a ligature substitution subtable that replaces the
+	// first two characters of each string by the third
+	ligatures := func(list ...string) []byte {
+		var firsts []int
+		sets := make(map[int][][]byte)
+		for _, str := range list {
+			r := []rune(str)
+			first := gid(r[0])
+			if sets[first] == nil {
+				firsts = append(firsts, first)
+			}
+			sets[first] = append(sets[first], u16(gid(r[2]), 2, gid(r[1])))
+		}
+		sort.Ints(firsts)
+		var sets2 [][]byte
+		for _, first := range firsts {
+			sets2 = append(sets2, offsets(0, sets[first]...))
+		}
+		body := offsets(4, sets2...)
+		cov := cat(u16(1, len(firsts)), u16(firsts...))
+		return cat(u16(1, 4+len(body)), body, cov)
+	}
+	// single returns a single substitution subtable of format 2, with a
+	// coverage table of ranges, that replaces the first character of each
+	// string by the second
+	single := func(list ...string) []byte {
+		var firsts []int
+		subst := make(map[int]int)
+		for _, str := range list {
+			r := []rune(str)
+			firsts = append(firsts, gid(r[0]))
+			subst[gid(r[0])] = gid(r[1])
+		}
+		sort.Ints(firsts)
+		var ranges, substs []byte
+		count := 0
+		for j, first := range firsts {
+			if j == 0 || first != firsts[j-1]+1 {
+				ranges = cat(ranges, u16(first, first, j))
+				count++
+			} else {
+				binary.BigEndian.PutUint16(ranges[len(ranges)-4:], uint16(first))
+			}
+			substs = cat(substs, u16(subst[first]))
+		}
+		return cat(u16(2, 6+len(substs), len(firsts)), substs, u16(2, count), ranges)
+	}
+	// delta returns a single substitution subtable of format 1 that replaces
+	// the first character of str by the second
+	delta := func(str string) []byte {
+		r := []rune(str)
+		return cat(u16(1, 6, gid(r[1])-gid(r[0])), u16(1, 1, gid(r[0])))
+	}
+	lookup := func(tp int, sub []byte) []byte { return cat(u16(tp, 0, 1, 8), sub) }
+	extension := func(tp int, sub []byte) []byte { return cat(u16(1, tp, 0, 8), sub) }
+	features := []struct {
+		tag    string
+		lookup []byte
+	}{
+		{"liga", lookup(7, extension(4, ligatures("fiﬁ", "flﬂ")))},
+		{"dlig", lookup(4, ligatures("AEÆ", "OEŒ"))},
+		{"smcp", lookup(1, single("aᴀ", "bʙ", "cᴄ", "dᴅ", "eᴇ", "gɢ", "hʜ", "iɪ", "jᴊ", "kᴋ", "lʟ",
+			"mᴍ", "nɴ", "oᴏ", "pᴘ", "rʀ", "tᴛ", "uᴜ", "vᴠ", "wᴡ", "yʏ", "zᴢ"))},
+		{"onum", lookup(1, single("1¹", "2²", "3³"))},
+		{"tnum", lookup(1, single("00", "11", "22", "33", "44", "55", "66", "77", "88", "99"))},
+		{"zero", lookup(1, delta("0Ø"))},
+	}
+	var lookups [][]byte
+	featList := u16(len(features))
+	for j, feat := range features {
+		featList = cat(featList, []byte(feat.tag), u16(2+6*len(features)+6*j))
+		lookups = append(lookups, feat.lookup)
+	}
+	langSys := u16(0, 0xFFFF, len(features))
+	for j := range features {
+		featList = cat(featList, u16(0, 1, j))
+		langSys = cat(langSys, u16(j))
+	}
+	scripts := cat(u16(1), []byte("DFLT"), u16(8, 4, 0), langSys)
+	lookupList := offsets(0, lookups...)
+	gsub := cat(u16(1, 0, 10, 10+len(scripts), 10+len(scripts)+len(featList)),
+		scripts, featList, lookupList)
+	// Rebuild the font with the table added to its directory
+	type tableType struct {
+		tag  string
+		sum  uint32
+		data []byte
+	}
+	checksum := func(b []byte) (sum uint32) {
+		for j := 0; j < len(b); j += 4 {
+			var word [4]byte
+			copy(word[:], b[j:])
+			sum += binary.BigEndian.Uint32(word[:])
+		}
+		return
+	}
+	be := binary.BigEndian
+	var tables []tableType
+	for j := 0; j < int(be.Uint16(data[4:])); j++ {
+		rec := data[12+16*j:]
+		tag := string(rec[:4])
+		if tag > "GSUB" && (len(tables) == 0 || tables[len(tables)-1].tag < "GSUB") {
+			tables = append(tables, tableType{"GSUB", checksum(gsub), gsub})
+		}
+		ofs, n := be.Uint32(rec[8:]), be.Uint32(rec[12:])
+		tables = append(tables, tableType{tag, be.Uint32(rec[4:]), data[ofs : ofs+n]})
+	}
+	sel := 0
+	for 2<<uint(sel) <= len(tables) {
+		sel++
+	}
+	font := cat(data[:4], u16(len(tables), 16<<uint(sel), sel, 16*len(tables)-16<<uint(sel)))
+	ofs := 12 + 16*len(tables)
+	for _, tbl := range tables {
+		font = cat(font, []byte(tbl.tag), u16(int(tbl.sum>>16), int(tbl.sum&0xFFFF), ofs>>16, ofs&0xFFFF,
+			len(tbl.data)>>16, len(tbl.data)&0xFFFF))
+		ofs += (len(tbl.data) + 3) &^ 3
+	}
+	for _, tbl := range tables {
+		font = cat(font, tbl.data, make([]byte, (4-len(tbl.data)%4)%4))
+	}
+	dir, err = ioutil.TempDir("", "gofpdf-features")
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(dir, "features.ttf"), font, 0644)
+	}
+	return
+}
+
+// This example demonstrates OpenType layout features. The font, a copy of
+// Arial to which a GSUB table has been added, forms the ligatures of the
+// "liga" and "dlig" features and the small capitals and figures of the "smcp"
+// and "onum" features when they are requested, which narrows the text printed
+// with it.
+func ExampleFpdf_SetFontFeatures() {
+	dir, err := featureFontDir()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	pdf := gofpdf.New("P", "mm", "A4", dir)
+	pdf.AddFont("Arial Features", "", "features.ttf")
+	pdf.SetFont("Arial Features", "", 20)
+	pdf.AddPage()
+	txtStr := "Fine fluffy waffles at the AEGEAN OENOLOGY fair of 2031"
+	for _, features := range [][]string{nil, {"liga"}, {"liga", "dlig"}, {"smcp", "onum"}, {"kern"}} {
+		pdf.SetFontFeatures(features)
+		pdf.CellFormat(0, 12, txtStr, "", 1, "", false, 0, "")
+		label := strings.Join(features, " ")
+		if label == "" {
+			label = "none"
+		}
+		fmt.Printf("%-10s %.2f\n", label, pdf.GetStringWidth(txtStr))
+	}
+	pdf.SetFontFeatures([]string{"abcd"})
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	fileStr := example.Filename("Fpdf_SetFontFeatures")
+	err = pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// none       185.10
+	// liga       185.10
+	// liga dlig  179.60
+	// smcp onum  184.32
+	// kern       185.10
+	// unrecognized font feature tag "abcd"
+	// Successfully generated pdf/Fpdf_SetFontFeatures.pdf
+}
+
+// This example demonstrates drawing glyphs by index. Each of the 247 glyphs
+// of the Calligrapher font is drawn above its index, including those that no
+// character of its character map reaches. Core fonts have no glyph indexes.
+func ExampleFpdf_DrawGlyph() {
+	pdf := gofpdf.New("P", "mm", "A4", example.FontDir())
+	pdf.AddFont("Calligrapher", "", "calligra.ttf")
+	pdf.AddPage()
+	for gid := 0; gid < 247; gid++ {
+		x, y := 14+float64(gid%13)*14.0, 14+float64(gid/13)*14.0
+		pdf.SetFont("Helvetica", "", 6)
+		pdf.Text(x, y+12, strconv.Itoa(gid))
+		pdf.SetFont("Calligrapher", "", 20)
+		pdf.DrawGlyph(uint16(gid), x+2, y+8)
+	}
+	fileStr := example.Filename("Fpdf_DrawGlyph")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	pdf = gofpdf.New("P", "mm", "A4", example.FontDir())
+	pdf.AddFont("Calligrapher", "", "calligra.ttf")
+	pdf.SetFont("Calligrapher", "", 12)
+	pdf.AddPage()
+	pdf.DrawGlyph(300, 20, 20)
+	fmt.Println(pdf.Error())
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.DrawGlyph(36, 20, 20)
+	fmt.Println(pdf.Error())
+	// Output:
+	// Successfully generated pdf/Fpdf_DrawGlyph.pdf
+	// font "CalligrapherRegular" has no glyph 300: it has 247 glyphs
+	// glyph 36 of font "Helvetica" cannot be drawn by index: only TrueType fonts have glyph indexes
+}
+
+// emojiFont returns a minimal color emoji font with an sbix table that holds
+// a single PNG glyph, a smiling face, for U+1F600.
+func emojiFont() []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			dx, dy := float64(x)-31.5, float64(y)-31.5
+			d := math.Hypot(dx, dy)
+			switch {
+			case d > 31:
+			case math.Hypot(dx+11, dy+9) < 5, math.Hypot(dx-11, dy+9) < 5,
+				dy > 6 && math.Abs(d-18) < 2.5:
+				img.Set(x, y, color.NRGBA{90, 50, 0, 255})
+			default:
+				img.Set(x, y, color.NRGBA{255, 200, 40, 255})
+			}
+		}
+	}
+	var pngBuf bytes.Buffer
+	png.Encode(&pngBuf, img)
+	var cmap, maxp, sbix bytes.Buffer
+	be := func(buf *bytes.Buffer, list ...interface{}) {
+		for _, v := range list {
+			binary.Write(buf, binary.BigEndian, v)
+		}
+	}
+	// Format 12 subtable mapping U+1F600 to glyph 1
+	be(&cmap, uint16(0), uint16(1), uint16(3), uint16(10), uint32(12))
+	be(&cmap, uint16(12), uint16(0), uint32(28), uint32(0), uint32(1))
+	be(&cmap, uint32(0x1F600), uint32(0x1F600), uint32(1))
+	be(&maxp, uint32(0x5000), uint16(2))
+	// One strike of 64 pixels per em; glyph 0 is empty
+	be(&sbix, uint16(1), uint16(1), uint32(1), uint32(12))
+	be(&sbix, uint16(64), uint16(72), uint32(16), uint32(16), uint32(24+pngBuf.Len()))
+	be(&sbix, int16(0), int16(0), []byte("png "), pngBuf.Bytes())
+	var font bytes.Buffer
+	tables := []struct {
+		tag  string
+		data []byte
+	}{{"cmap", cmap.Bytes()}, {"maxp", maxp.Bytes()}, {"sbix", sbix.Bytes()}}
+	be(&font, uint32(0x10000), uint16(len(tables)), uint16(0), uint16(0), uint16(0))
+	ofs := 12 + 16*len(tables)
+	for _, t := range tables {
+		be(&font, []byte(t.tag), uint32(0), uint32(ofs), uint32(len(t.data)))
+		ofs += len(t.data)
+	}
+	for _, t := range tables {
+		font.Write(t.data)
+	}
+	return font.Bytes()
+}
+
+// This example demonstrates color emoji written inline with text. The glyphs
+// of the emoji font are PNG images that are placed at the current font size.
+func ExampleFpdf_AddEmojiFont() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddEmojiFontFromReader(bytes.NewReader(emojiFont()))
+	pdf.SetFont("Helvetica", "", 16)
+	pdf.AddPage()
+	pdf.Write(8, "Have a nice day \U0001F600\uFE0F and see you soon \U0001F600")
+	fileStr := example.Filename("Fpdf_AddEmojiFont")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_AddEmojiFont.pdf
+}
+
+// This example demonstrates the Interpolate image option. A small image is
+// enlarged twice, once with interpolation requested and once without, which
+// preserves its pixels as crisp squares.
+func ExampleFpdf_ImageOptions_interpolation() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if (x+y)%2 == 0 {
+				img.Set(x, y, color.NRGBA{0, 80, 160, 255})
+			} else {
+				img.Set(x, y, color.NRGBA{250, 200, 0, 255})
+			}
+		}
+	}
+	pdf.RegisterImageImage("smooth", gofpdf.ImageOptions{Interpolate: true}, img)
+	pdf.RegisterImageImage("pixels", gofpdf.ImageOptions{}, img)
+	pdf.Image("smooth", 20, 20, 80, 80, false, "", 0, "")
+	pdf.Image("pixels", 110, 20, 80, 80, false, "", 0, "")
+	fileStr := example.Filename("Fpdf_ImageOptions_interpolation")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageOptions_interpolation.pdf
+}
+
+// This example demonstrates limiting the resolution of embedded images. A
+// large image placed in a small box is reduced to 150 dpi when the document
+// is written, which makes the document considerably smaller.
+func ExampleFpdf_SetImageMaxDPI() {
+	img := image.NewNRGBA(image.Rect(0, 0, 1200, 900))
+	for y := 0; y < 900; y++ {
+		for x := 0; x < 1200; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x / 5), uint8(y / 4), uint8((x ^ y) & 0xFF), 255})
+		}
+	}
+	size := func(dpi float64) int {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetImageMaxDPI(dpi)
+		pdf.AddPage()
+		pdf.RegisterImageImage("photo", gofpdf.ImageOptions{ImageType: "jpg"}, img)
+		pdf.Image("photo", 20, 20, 50, 0, false, "", 0, "")
+		var buf bytes.Buffer
+		pdf.Output(&buf)
+		return buf.Len()
+	}
+	fmt.Println(size(150) < size(0))
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetImageMaxDPI(150)
+	pdf.AddPage()
+	pdf.RegisterImageImage("photo", gofpdf.ImageOptions{}, img)
+	pdf.Image("photo", 20, 20, 50, 0, false, "", 0, "")
+	fileStr := example.Filename("Fpdf_SetImageMaxDPI")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// true
+	// Successfully generated pdf/Fpdf_SetImageMaxDPI.pdf
+}
+
+// cmykJpeg returns a minimal 8 by 8 pixel baseline JPEG with four components
+// and, if adobe is true, an Adobe APP14 segment. Every coefficient is zero so
+// each component has the mid-level value 128.
+func cmykJpeg(adobe bool) []byte {
+	var buf bytes.Buffer
+	seg := func(marker byte, data ...byte) {
+		buf.Write([]byte{0xFF, marker, byte((len(data) + 2) >> 8), byte(len(data) + 2)})
+		buf.Write(data)
+	}
+	buf.Write([]byte{0xFF, 0xD8})
+	if adobe {
+		seg(0xEE, 'A', 'd', 'o', 'b', 'e', 0, 100, 0, 0, 0, 0, 0)
+	}
+	dqt := make([]byte, 65)
+	for j := 1; j < len(dqt); j++ {
+		dqt[j] = 1
+	}
+	seg(0xDB, dqt...)
+	seg(0xC0, 8, 0, 8, 0, 8, 4, 1, 0x11, 0, 2, 0x11, 0, 3, 0x11, 0, 4, 0x11, 0)
+	// One code of length 1 in each table: DC category 0 and AC end of block
+	seg(0xC4, 0x00, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	seg(0xC4, 0x10, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	seg(0xDA, 4, 1, 0x00, 2, 0x00, 3, 0x00, 4, 0x00, 0, 63, 0)
+	buf.Write([]byte{0x00, 0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+// This example demonstrates the placement of CMYK JPEG images. Images marked
+// with an Adobe APP14 segment store inverted values and are given a Decode
+// array that restores them.
+func ExampleFpdf_RegisterImageOptionsReader_cmyk() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	opt := gofpdf.ImageOptions{ImageType: "jpg"}
+	pdf.RegisterImageOptionsReader("adobe", opt, bytes.NewReader(cmykJpeg(true)))
+	pdf.RegisterImageOptionsReader("plain", opt, bytes.NewReader(cmykJpeg(false)))
+	pdf.ImageOptions("adobe", 20, 20, 40, 40, false, opt, 0, "")
+	pdf.ImageOptions("plain", 80, 20, 40, 40, false, opt, 0, "")
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	if err == nil {
+		fmt.Println(bytes.Count(buf.Bytes(), []byte("/ColorSpace /DeviceCMYK")))
+		fmt.Println(bytes.Count(buf.Bytes(), []byte("/Decode [1 0 1 0 1 0 1 0]")))
+	}
+	fileStr := example.Filename("Fpdf_RegisterImageOptionsReader_cmyk")
+	err = ioutil.WriteFile(fileStr, buf.Bytes(), 0644)
+	example.Summary(err, fileStr)
+	// Output:
+	// 2
+	// 1
+	// Successfully generated pdf/Fpdf_RegisterImageOptionsReader_cmyk.pdf
+}
+
+// This example demonstrates the page boundaries used in print production. The
+// page is laid out with a 3 mm bleed around a trimmed size of 204 by 291 mm;
+// the background extends into the bleed so that no white edge remains after
+// cutting.
+func ExampleFpdf_SetTrimBox() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetBleedBox(0, 0, 210, 297)
+	pdf.SetTrimBox(3, 3, 204, 291)
+	pdf.SetArtBox(15, 15, 180, 267)
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	pdf.SetFillColor(200, 220, 255)
+	pdf.Rect(0, 0, 210, 297, "F")
+	pdf.SetFont("Helvetica", "B", 24)
+	pdf.Text(15, 30, "Printed with bleed")
+	pdf.AddPage()
+	pdf.SetCropBox(3, 3, 204, 291)
+	pdf.SetTrimBox(220, 3, 10, 10)
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	if err == nil {
+		fmt.Println(bytes.Count(buf.Bytes(), []byte("/TrimBox [8.50 8.50 586.77 833.39]")))
+		fmt.Println(bytes.Count(buf.Bytes(), []byte("/CropBox")))
+	}
+	fileStr := example.Filename("Fpdf_SetTrimBox")
+	err = ioutil.WriteFile(fileStr, buf.Bytes(), 0644)
+	example.Summary(err, fileStr)
+	// Output:
+	// TrimBox [220.00 3.00 10.00 10.00] extends beyond the page
+	// 2
+	// 1
+	// Successfully generated pdf/Fpdf_SetTrimBox.pdf
+}
+
+// This example demonstrates horizontal text scaling. A heading that is too
+// wide for its cell is condensed until it fits, and a paragraph is set in
+// extended type; its lines are wrapped according to the scaled widths.
+func ExampleFpdf_SetHorizontalScaling() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 28)
+	heading := "Quarterly Results for the Northern Region"
+	wd := pdf.GetStringWidth(heading)
+	pdf.SetHorizontalScaling(100 * 120 / wd)
+	pdf.CellFormat(120, 14, heading, "1", 1, "", false, 0, "")
+	fmt.Printf("%.1f\n", pdf.GetStringWidth(heading))
+	pdf.Ln(6)
+	pdf.SetFont("Times", "", 12)
+	pdf.SetHorizontalScaling(130)
+	pdf.MultiCell(120, 6, lorem(), "", "J", false)
+	pdf.SetHorizontalScaling(100)
+	fileStr := example.Filename("Fpdf_SetHorizontalScaling")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 120.0
+	// Successfully generated pdf/Fpdf_SetHorizontalScaling.pdf
+}
+
+// This example demonstrates a background image used as a watermark. The logo
+// is drawn faintly in the middle of every page, beneath the text, without
+// affecting the opacity of the text itself.
+func ExampleFpdf_BackgroundImage() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.BackgroundImage(example.ImageFile("logo.png"), 0.15, "center")
+	pdf.SetFont("Times", "", 12)
+	pdf.AddPage()
+	for j := 0; j < 4; j++ {
+		pdf.MultiCell(0, 5, lorem(), "", "J", false)
+		pdf.Ln(5)
+	}
+	pdf.AddPage()
+	pdf.BackgroundImage(example.ImageFile("logo.png"), 0.1, "tile")
+	pdf.MultiCell(0, 5, lorem(), "", "J", false)
+	fileStr := example.Filename("Fpdf_BackgroundImage")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_BackgroundImage.pdf
+}
+
+// This example demonstrates obtaining the generated document in memory, as an
+// HTTP handler would before writing it to the response. The contents remain
+// available until the document is sent with Output().
+func ExampleFpdf_GetBytes() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 16)
+	pdf.Cell(40, 10, "Generated in memory")
+	data, err := pdf.GetBytes()
+	if err == nil {
+		again, _ := pdf.GetBytes()
+		fmt.Println(bytes.HasPrefix(data, []byte("%PDF-")), bytes.Equal(data, again))
+	}
+	fileStr := example.Filename("Fpdf_GetBytes")
+	err = ioutil.WriteFile(fileStr, data, 0644)
+	example.Summary(err, fileStr)
+	// Output:
+	// true true
+	// Successfully generated pdf/Fpdf_GetBytes.pdf
+}
+
+// This example demonstrates the closed state of a document. Once Close() has
+// been called, attempts to add pages or content set an error instead of
+// corrupting the generated document, which remains available.
+func ExampleFpdf_Close() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 16)
+	pdf.AddPage()
+	pdf.Cell(40, 10, "Final content")
+	pdf.Close()
+	data, _ := pdf.GetBytes()
+	pdf.AddPage()
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	pdf.Cell(40, 10, "Too late")
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	again, _ := pdf.GetBytes()
+	fmt.Println(bytes.Equal(data, again))
+	fileStr := example.Filename("Fpdf_Close")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// document is closed and can no longer be modified
+	// document is closed and can no longer be modified
+	// true
+	// Successfully generated pdf/Fpdf_Close.pdf
+}
+
+// This example demonstrates the treatment of spaces. A code listing keeps
+// its indentation and alignment, while the same settings applied to
+// carelessly spaced prose are changed to collapse and trim runs of spaces.
+func ExampleFpdf_SetWhitespace() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Courier", "", 11)
+	code := "func main() {\n    x   := 1\n    abc := 2\n    fmt.Println(x, abc)\n}"
+	pdf.MultiCell(0, 5, code, "1", "L", false)
+	pdf.Ln(5)
+	pdf.SetFont("Times", "", 12)
+	prose := "   This   sentence   was   typed   with   extra   spaces.   \n" +
+		"  So  was  this  one,  which  is  indented  for  no  reason.  "
+	pdf.SetWhitespace(true, true)
+	fmt.Printf("%.1f\n", pdf.MeasureCellHeight(0, prose, 6))
+	pdf.MultiCell(0, 6, prose, "1", "L", false)
+	pdf.SetWhitespace(false, false)
+	fileStr := example.Filename("Fpdf_SetWhitespace")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 12.0
+	// Successfully generated pdf/Fpdf_SetWhitespace.pdf
+}
+
+// This example demonstrates printing a program listing. The code keeps its
+// indentation, the lines are numbered, and the first listing wraps its long
+// line while the second clips it.
+func ExampleFpdf_CodeBlock() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 12)
+	code := "package main\n\nimport \"fmt\"\n\nfunc main() {\n" +
+		"\tfor j := 0; j < 3; j++ {\n" +
+		"\t\tfmt.Println(\"This line is long enough that it does not fit in the width of the block\", j)\n" +
+		"\t}\n}\n"
+	pdf.SetFillColor(245, 245, 245)
+	pdf.SetDrawColor(180, 180, 180)
+	opt := gofpdf.CodeBlockOptions{FontSize: 9, LineNumbers: true, Wrap: true, Fill: true, Border: true}
+	pdf.CodeBlock(150, code, opt)
+	pdf.Ln(8)
+	pdf.Write(6, "The same listing with long lines clipped:")
+	pdf.Ln(8)
+	opt.Wrap = false
+	pdf.CodeBlock(150, code, opt)
+	fileStr := example.Filename("Fpdf_CodeBlock")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_CodeBlock.pdf
+}
+
+// This example demonstrates PageCount() used to decide on section breaks. Each
+// section starts on an odd-numbered page, so a blank page is added when a
+// section would otherwise begin on the back of the preceding one.
+func ExampleFpdf_PageCount() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Times", "", 12)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	for sec := 1; sec <= 3; sec++ {
+		if pdf.PageCount()%2 == 1 {
+			pdf.AddPage()
+		}
+		pdf.AddPage()
+		pdf.SetFont("Times", "B", 16)
+		pdf.Cell(0, 10, fmt.Sprintf("Section %d", sec))
+		pdf.Ln(12)
+		pdf.SetFont("Times", "", 12)
+		for j := 0; j < sec*2; j++ {
+			pdf.MultiCell(0, 5, lorem(), "", "", false)
+			pdf.Ln(3)
+		}
+		fmt.Printf("section %d ends on page %d\n", sec, pdf.PageCount())
+	}
+	fileStr := example.Filename("Fpdf_PageCount")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// section 1 ends on page 1
+	// section 2 ends on page 3
+	// section 3 ends on page 5
+	// Successfully generated pdf/Fpdf_PageCount.pdf
+}
+
+// This example demonstrates the compression level. The same document, which
+// embeds a TrueType font, is generated with the default level, which favors
+// speed, and with the best compression, which makes it smaller.
+func ExampleFpdf_SetCompressionLevel() {
+	size := func(level int) int {
+		pdf := gofpdf.New("P", "mm", "A4", example.FontDir())
+		pdf.SetCompressionLevel(level)
+		pdf.AddFont("Calligrapher", "", "calligra.ttf")
+		pdf.AddPage()
+		pdf.SetFont("Calligrapher", "", 14)
+		pdf.MultiCell(0, 6, lorem(), "", "", false)
+		data, _ := pdf.GetBytes()
+		return len(data)
+	}
+	fmt.Println(size(zlib.BestCompression) < size(zlib.BestSpeed))
+	pdf := gofpdf.New("P", "mm", "A4", example.FontDir())
+	pdf.SetCompressionLevel(zlib.BestCompression)
+	pdf.AddFont("Calligrapher", "", "calligra.ttf")
+	pdf.AddPage()
+	pdf.SetFont("Calligrapher", "", 14)
+	pdf.MultiCell(0, 6, lorem(), "", "", false)
+	fileStr := example.Filename("Fpdf_SetCompressionLevel")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// true
+	// Successfully generated pdf/Fpdf_SetCompressionLevel.pdf
+}
+
+// This example demonstrates overriding the underline metrics of a font. The
+// second line is underlined further below the baseline with a thinner rule so
+// that the underline clears the descenders.
+func ExampleFpdf_SetUnderlineMetrics() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetFont("Times", "U", 24)
+	up, ut := pdf.GetUnderlineMetrics()
+	fmt.Printf("%.0f %.0f\n", up, ut)
+	pdf.Cell(0, 12, "Jumpy quagga, glyphs and typography")
+	pdf.Ln(16)
+	pdf.SetUnderlineMetrics(-220, 30)
+	up, ut = pdf.GetUnderlineMetrics()
+	fmt.Printf("%.0f %.0f\n", up, ut)
+	pdf.Cell(0, 12, "Jumpy quagga, glyphs and typography")
+	fileStr := example.Filename("Fpdf_SetUnderlineMetrics")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// -100 50
+	// -220 30
+	// Successfully generated pdf/Fpdf_SetUnderlineMetrics.pdf
+}
+
+// This example demonstrates the conversion of a multi-page TIFF scan to a PDF
+// document with one page per frame. The frames of the sample file are
+// compressed with LZW, PackBits and no compression respectively.
+func ExampleFpdf_AddTIFFPages() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddTIFFPages(example.ImageFile("scan.tif"))
+	fmt.Println(pdf.PageCount())
+	wd, ht, _ := pdf.PageSize(2)
+	fmt.Printf("%.1f x %.1f mm\n", wd, ht)
+	fileStr := example.Filename("Fpdf_AddTIFFPages")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 3
+	// 73.4 x 50.1 mm
+	// Successfully generated pdf/Fpdf_AddTIFFPages.pdf
+}
+
+// This example demonstrates bilevel images stored with CCITT fax compression.
+// The first image is read from a file that already contains Group 4 encoded
+// data, which is embedded unchanged. The file, image/logo.g4, holds
+// image/logo.png converted to black and white and encoded as the second image
+// is; ccitt_test.go checks and regenerates it. The second image is converted
+// to black and white and encoded when it is registered.
+func ExampleFpdf_RegisterImageCCITTReader() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	fl, err := os.Open(example.ImageFile("logo.g4"))
+	if err == nil {
+		pdf.RegisterImageCCITTReader("logo-g4", gofpdf.CCITTOptions{K: -1, Columns: 104, Rows: 71}, fl)
+		fl.Close()
+		pdf.Image("logo-g4", 10, 10, 52, 0, false, "", 0, "")
+	} else {
+		pdf.SetError(err)
+	}
+	fl, err = os.Open(example.ImageFile("golang-gopher.png"))
+	if err == nil {
+		var img image.Image
+		img, _, err = image.Decode(fl)
+		fl.Close()
+		if err == nil {
+			pdf.ImageImage("gopher", img, 70, 10, 60, 0, false, gofpdf.ImageOptions{ImageType: "CCITT"}, 0, "")
+		}
+	}
+	if err != nil {
+		pdf.SetError(err)
+	}
+	fileStr := example.Filename("Fpdf_RegisterImageCCITTReader")
+	err = pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_RegisterImageCCITTReader.pdf
+}
+
+// This example demonstrates gray levels specified in the DeviceGray color
+// space. The colors remain in effect on the pages that are added
+// automatically.
+func ExampleFpdf_SetFillColorGray() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetFillColorGray(0.9)
+	pdf.SetDrawColorGray(0.35)
+	pdf.SetTextColorGray(0.2)
+	pdf.AddPage()
+	for j := 0; j < 60; j++ {
+		pdf.CellFormat(0, 8, fmt.Sprintf("Row %d", j+1), "1", 1, "", j%2 == 0, 0, "")
+	}
+	fmt.Println(pdf.GetFillColor())
+	fmt.Println(pdf.PageCount())
+	fileStr := example.Filename("Fpdf_SetFillColorGray")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 230 230 230
+	// 2
+	// Successfully generated pdf/Fpdf_SetFillColorGray.pdf
+}
+
+// This example demonstrates pixel snapping of lines. The two tables are
+// identical except that the lines of the second one are snapped to pixels at
+// 96 dpi, which keeps them sharp when the document is viewed at that
+// resolution.
+func ExampleFpdf_SetLineSnap() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetLineWidth(0.26)
+	pdf.AddPage()
+	table := func() {
+		for row := 0; row < 8; row++ {
+			for col := 0; col < 4; col++ {
+				pdf.CellFormat(33.3, 6.1, fmt.Sprintf("%d.%d", row+1, col+1), "1", 0, "C", false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+		pdf.Line(10, pdf.GetY()+2.05, 143.2, pdf.GetY()+2.05)
+		pdf.Ln(8)
+	}
+	table()
+	pdf.SetLineSnap(96)
+	table()
+	fileStr := example.Filename("Fpdf_SetLineSnap")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetLineSnap.pdf
+}
+
+// This example demonstrates the establishment of document defaults when the
+// instance is created, which saves repeating the same setup calls for every
+// document.
+func ExampleNewCustom() {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr:      "mm",
+		SizeStr:      "A5",
+		LineWidth:    0.5,
+		DrawColorStr: "#336699",
+		FillColorStr: "#e0e8f0",
+		TextColorStr: "#1a2b3c",
+		Margins:      &gofpdf.MarginsType{Left: 20, Top: 15, Right: 20, Bottom: 25},
+	})
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.CellFormat(0, 10, "Defaults established by NewCustom()", "1", 1, "C", true, 0, "")
+	left, top, right, bottom := pdf.GetMargins()
+	fmt.Println(left, top, right, bottom, pdf.GetLineWidth())
+	fmt.Println(pdf.GetDrawColor())
+	fileStr := example.Filename("NewCustom")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 20 15 20 25 0.5
+	// 51 102 153
+	// Successfully generated pdf/NewCustom.pdf
+}
+
+// This example demonstrates the creation of a document with functional
+// options.
+func ExampleNewWithOptions() {
+	pdf := gofpdf.NewWithOptions(
+		gofpdf.WithOrientation("L"),
+		gofpdf.WithUnit("pt"),
+		gofpdf.WithSize("Letter"),
+		gofpdf.WithCompression(false),
+		gofpdf.WithLineWidth(2),
+		gofpdf.WithDrawColor("#993333"),
+		gofpdf.WithMargins(gofpdf.MarginsType{Left: 54, Top: 54, Right: 54, Bottom: 72}),
+	)
+	pdf.SetFont("Times", "", 14)
+	pdf.AddPage()
+	pdf.CellFormat(0, 36, "Configured with NewWithOptions()", "1", 1, "C", false, 0, "")
+	wd, ht := pdf.GetPageSize()
+	fmt.Println(wd, ht, pdf.GetLineWidth())
+	fileStr := example.Filename("NewWithOptions")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 792 612 2
+	// Successfully generated pdf/NewWithOptions.pdf
+}
+
+// This example demonstrates recovery from an error. The preferred font is not
+// available, so the error is cleared and a standard font is used instead.
+func ExampleFpdf_ClearError() {
+	pdf := gofpdf.New("P", "mm", "A4", example.FontDir())
+	pdf.AddPage()
+	pdf.SetFont("Garamond Premier", "", 14)
+	if !pdf.Ok() {
+		fmt.Println("font unavailable; using Times")
+		pdf.ClearError()
+		pdf.SetFont("Times", "", 14)
+	}
+	pdf.Cell(0, 10, "Set in the fallback font")
+	fmt.Println(pdf.OkError())
+	fileStr := example.Filename("Fpdf_ClearError")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// font unavailable; using Times
+	// <nil>
+	// Successfully generated pdf/Fpdf_ClearError.pdf
+}
+
+// This example demonstrates text that follows a circle and a zigzag path. The
+// text along the top of the seal runs clockwise and the text along the bottom
+// runs counter-clockwise so that both read from left to right.
+func ExampleFpdf_TextOnCircle() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	cx, cy, r := 105.0, 80.0, 40.0
+	pdf.SetLineWidth(1)
+	pdf.Circle(cx, cy, r+8, "D")
+	pdf.Circle(cx, cy, r-8, "D")
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.SetTextColor(128, 0, 0)
+	top := "OFFICIAL SEAL OF APPROVAL"
+	angle := pdf.GetStringWidth(top) / r * 90 / math.Pi
+	pdf.TextOnCircle(cx, cy, r-2.5, top, 90+angle, true)
+	bottom := "ESTABLISHED 2014"
+	angle = pdf.GetStringWidth(bottom) / r * 90 / math.Pi
+	pdf.TextOnCircle(cx, cy, r+2.5, bottom, 270-angle, false)
+	pdf.SetFont("Times", "", 14)
+	pdf.SetTextColor(0, 0, 0)
+	var pts []gofpdf.PointType
+	for j := 0; j <= 40; j++ {
+		x := 20 + 4.25*float64(j)
+		pts = append(pts, gofpdf.PointType{X: x, Y: 170 + 10*math.Sin(float64(j)/4)})
+	}
+	pdf.SetLineWidth(0.2)
+	pdf.SetDrawColor(160, 160, 160)
+	for j := 1; j < len(pts); j++ {
+		pdf.Line(pts[j-1].X, pts[j-1].Y, pts[j].X, pts[j].Y)
+	}
+	pdf.TextOnPath(pts, "Text can follow any path that is made up of straight segments", 5)
+	fileStr := example.Filename("Fpdf_TextOnCircle")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_TextOnCircle.pdf
+}
+
+// This example demonstrates the PDF version declared in the document header.
+// Transparency requires version 1.4, so forcing version 1.3 on a document
+// that uses it results in an error.
+func ExampleFpdf_SetPDFVersion() {
+	header := func(pdf *gofpdf.Fpdf) {
+		var buf bytes.Buffer
+		err := pdf.Output(&buf)
+		if err == nil {
+			fmt.Println(strings.SplitN(buf.String(), "\n", 2)[0])
+		} else {
+			fmt.Println(err)
+		}
+	}
+	for _, version := range []int{0, 3, 7} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		if version > 0 {
+			pdf.SetPDFVersion(1, version)
+		}
+		pdf.AddPage()
+		pdf.SetAlpha(0.5, "Normal")
+		pdf.Rect(20, 20, 50, 50, "F")
+		header(pdf)
+	}
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.Rect(20, 20, 50, 50, "F")
+	fileStr := example.Filename("Fpdf_SetPDFVersion")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// %PDF-1.4
+	// PDF version 1.3 is lower than version 1.4 required by transparency
+	// %PDF-1.7
+	// Successfully generated pdf/Fpdf_SetPDFVersion.pdf
+}
+
+// This example demonstrates named destinations. The table of contents on the
+// first page links to the chapters by name, and other documents or URLs such
+// as "Fpdf_AddNamedDestination.pdf#chapter2" can link to them the same way.
+func ExampleFpdf_AddNamedDestination() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.AddPage()
+	pdf.Cell(0, 10, "Contents")
+	pdf.Ln(12)
+	for j := 1; j <= 3; j++ {
+		pdf.CellFormat(0, 8, fmt.Sprintf("Chapter %d", j), "", 1, "L", false, 0,
+			fmt.Sprintf("#chapter%d", j))
+	}
+	for j := 1; j <= 3; j++ {
+		pdf.AddPage()
+		pdf.AddNamedDestination(fmt.Sprintf("chapter%d", j), -1, -1)
+		pdf.Cell(0, 10, fmt.Sprintf("Chapter %d", j))
+	}
+	pdf.AddNamedDestination("chapter1", 2, 0)
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	fileStr := example.Filename("Fpdf_AddNamedDestination")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// named destination chapter1 is already defined
+	// Successfully generated pdf/Fpdf_AddNamedDestination.pdf
+}
+
+// BenchmarkDrawCalls measures the generation of a document with thousands of
+// drawing operations and text cells on each page. Run it with -benchmem to
+// see the allocations made per document.
+func BenchmarkDrawCalls(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetFont("Helvetica", "", 8)
+		for p := 0; p < 10; p++ {
+			pdf.AddPage()
+			for j := 0; j < 2000; j++ {
+				x, y := float64(10+j%180), float64(10+j%270)
+				pdf.Line(x, y, x+10, y+5)
+				pdf.Rect(x, y, 4, 3, "D")
+				pdf.Text(x, y, "Sample")
+			}
+		}
+		if _, err := pdf.GetBytes(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// reuseDocument writes a document of several pages with pdf and sends it to
+// ioutil.Discard
+func reuseDocument(b *testing.B, pdf *gofpdf.Fpdf) {
+	pdf.SetFont("Helvetica", "", 10)
+	for p := 0; p < 5; p++ {
+		pdf.AddPage()
+		for j := 0; j < 40; j++ {
+			pdf.CellFormat(0, 6, "Reused instance", "1", 1, "L", false, 0, "")
+		}
+	}
+	if err := pdf.Output(ioutil.Discard); err != nil {
+		b.Fatal(err)
+	}
+}
+
+// BenchmarkNewDocument generates each document with a new instance. Compare
+// its allocations with those of BenchmarkResetDocument.
+func BenchmarkNewDocument(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		reuseDocument(b, gofpdf.New("P", "mm", "A4", ""))
+	}
+}
+
+// BenchmarkResetDocument generates each document with the same instance,
+// which is cleared with Reset() and reuses its maps and buffers.
+func BenchmarkResetDocument(b *testing.B) {
+	b.ReportAllocs()
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	for n := 0; n < b.N; n++ {
+		pdf.Reset(true)
+		reuseDocument(b, pdf)
+	}
+}
+
+// TestStringWidthCache measures more distinct strings than the width cache
+// of a font holds, twice over, and checks each width against the sum of the
+// widths of its characters.
+func TestStringWidthCache(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 10)
+	sum := func(s string) (w float64) {
+		for j := 0; j < len(s); j++ {
+			w += pdf.GetStringWidth(s[j : j+1])
+		}
+		return
+	}
+	for pass := 0; pass < 2; pass++ {
+		for j := 0; j < 3000; j++ {
+			s := strconv.Itoa(j*7919) + "Wil"
+			if got, want := pdf.GetStringWidth(s), sum(s); math.Abs(got-want) > 1e-9 {
+				t.Fatalf("width of %q: got %f, want %f", s, got, want)
+			}
+		}
+	}
+}
+
+// BenchmarkGetStringWidth measures the words of a paragraph repeatedly, as
+// when the cells of a table are laid out, so that most widths are taken from
+// the cache of the font.
+func BenchmarkGetStringWidth(b *testing.B) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 10)
+	words := strings.Fields(lorem())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, word := range words {
+			pdf.GetStringWidth(word)
+		}
+	}
+}
+
+// BenchmarkMultiCell wraps a paragraph into a narrow column many times over.
+func BenchmarkMultiCell(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.AddPage()
+		for j := 0; j < 50; j++ {
+			pdf.MultiCell(60, 5, lorem(), "", "J", false)
+		}
+		if pdf.Err() {
+			b.Fatal(pdf.Error())
+		}
+	}
+}
+
+// This example demonstrates the reuse of an instance for several documents.
+// The font added for the first document is kept by Reset() and need not be
+// parsed again for the second.
+func ExampleFpdf_Reset() {
+	pdf := gofpdf.New("P", "mm", "A4", example.FontDir())
+	pdf.AddFont("Calligrapher", "", "calligra.ttf")
+	for j := 1; j <= 2; j++ {
+		pdf.AddPage()
+		pdf.SetFont("Calligrapher", "", 24)
+		pdf.Cell(0, 20, fmt.Sprintf("Document %d", j))
+		_, err := pdf.GetBytes()
+		fmt.Println(pdf.PageCount(), err)
+		if j == 1 {
+			pdf.Reset(true)
+		}
+	}
+	fileStr := example.Filename("Fpdf_Reset")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 1 <nil>
+	// 1 <nil>
+	// Successfully generated pdf/Fpdf_Reset.pdf
+}
+
+// This example demonstrates the low-level methods for writing PDF objects
+// that this package does not otherwise produce. A form XObject draws a
+// square, and a radial shading that refers to a separate function object
+// fills another square. Both are added to the resource dictionary and used by name in page
+// content.
+func ExampleFpdf_NewObject() {
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	pdf.AddPage()
+	form := pdf.NewObject()
+	pdf.SetObject(form, "/Type /XObject /Subtype /Form /BBox [0 0 100 100]",
+		[]byte("0 0 1 rg 10 10 80 80 re f"))
+	pdf.AddResource("XObject", "ExampleForm", form)
+	fnc := pdf.NewObject()
+	shading := pdf.NewObject()
+	pdf.SetObject(fnc, "<</FunctionType 2 /Domain [0 1] /C0 [1 1 0] /C1 [1 0 0] /N 1>>", nil)
+	pdf.SetObject(shading, "<</ShadingType 3 /ColorSpace /DeviceRGB "+
+		"/Coords [50 50 0 50 50 50] /Function "+pdf.ObjectRef(fnc)+">>", nil)
+	pdf.AddResource("Shading", "ExampleShading", shading)
+	pdf.RawWriteStr("q 1 0 0 1 100 600 cm /ExampleForm Do Q")
+	pdf.RawWriteStr("q 250 600 100 100 re W n 1 0 0 1 250 600 cm /ExampleShading sh Q")
+	pdf.AddResource("Shading", "ExampleShading", shading)
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	fileStr := example.Filename("Fpdf_NewObject")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// resource Shading ExampleShading is already defined
+	// Successfully generated pdf/Fpdf_NewObject.pdf
+}
+
+// This example demonstrates a change of the right margin between two
+// paragraphs on the same page. The second paragraph wraps at the new margin
+// and therefore needs more lines.
+func ExampleFpdf_SetRightMargin() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Times", "", 12)
+	pdf.AddPage()
+	txtStr := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 8)
+	for _, margin := range []float64{10, 80} {
+		pdf.SetRightMargin(margin)
+		y := pdf.GetY()
+		pdf.MultiCell(0, 6, txtStr, "1", "J", false)
+		_, _, right, _ := pdf.GetMargins()
+		fmt.Printf("right margin %.0f mm: %.0f lines\n", right, (pdf.GetY()-y)/6)
+		pdf.Ln(6)
+	}
+	fileStr := example.Filename("Fpdf_SetRightMargin")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// right margin 10 mm: 4 lines
+	// right margin 80 mm: 6 lines
+	// Successfully generated pdf/Fpdf_SetRightMargin.pdf
+}
+
+// This example demonstrates a line chart of two series with grid lines and a
+// legend. The axis ranges are derived from the data.
+func ExampleFpdf_LineChart() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.AddPage()
+	var sine, cosine []gofpdf.PointType
+	for j := 0; j <= 60; j++ {
+		x := float64(j) / 10
+		sine = append(sine, gofpdf.PointType{X: x, Y: 3 * math.Sin(x)})
+		cosine = append(cosine, gofpdf.PointType{X: x, Y: 2 * math.Cos(x)})
+	}
+	pdf.SetLineWidth(0.3)
+	pdf.LineChart(20, 20, 170, 100, []gofpdf.ChartSeriesType{
+		{Label: "3 sin(x)", Points: sine, LineWidth: 0.6},
+		{Label: "2 cos(x)", Points: cosine, LineWidth: 0.6},
+	}, gofpdf.ChartOptions{Grid: true, Legend: true})
+	pdf.LineChart(20, 140, 170, 80, []gofpdf.ChartSeriesType{
+		{Label: "Revenue", ColorStr: "#2ca02c", Points: []gofpdf.PointType{
+			{X: 2015, Y: 12}, {X: 2016, Y: 15}, {X: 2017, Y: 14}, {X: 2018, Y: 19}, {X: 2019, Y: 23}}},
+	}, gofpdf.ChartOptions{XMin: 2015, XMax: 2019, XTicks: 4, YMin: 0, YMax: 25, Legend: true})
+	fileStr := example.Filename("Fpdf_LineChart")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_LineChart.pdf
+}
+
+// This example demonstrates vertical and horizontal bar charts. The second
+// chart includes a negative value and gives each bar the color of its sign.
+func ExampleFpdf_BarChart() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.AddPage()
+	pdf.BarChart(20, 20, 170, 100, []string{"Q1", "Q2", "Q3", "Q4"},
+		[]float64{12.5, 17.25, 9.75, 21}, gofpdf.BarChartOptions{Grid: true})
+	pdf.BarChart(20, 140, 170, 80, []string{"North", "South", "East", "West"},
+		[]float64{4.2, -1.8, 2.5, 0.7}, gofpdf.BarChartOptions{
+			Horizontal:     true,
+			ColorStrs:      []string{"#2ca02c", "#d62728", "#2ca02c", "#2ca02c"},
+			ValueFormatStr: "%+.1f%%",
+		})
+	fileStr := example.Filename("Fpdf_BarChart")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_BarChart.pdf
+}
+
+// This example adds a table of contents in front of the chapters it lists
+// after they have been written. The links and bookmarks of the chapters
+// continue to refer to them after they are shifted back by one page.
+func ExampleFpdf_InsertPage() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 14)
+	titles := []string{"Introduction", "Methods", "Results"}
+	var links []int
+	for _, title := range titles {
+		pdf.AddPage()
+		pdf.Bookmark(title, 0, -1)
+		link := pdf.AddLink()
+		pdf.SetLink(link, -1, -1)
+		links = append(links, link)
+		pdf.Cell(0, 10, title)
+	}
+	pdf.InsertPage(1)
+	pdf.Cell(0, 10, "Contents")
+	pdf.Ln(15)
+	for j, title := range titles {
+		pdf.CellFormat(0, 8, fmt.Sprintf("%s ... page %d", title, j+2), "", 1, "", false, links[j], "")
+	}
+	fmt.Printf("page %d of %d\n", pdf.PageNo(), pdf.PageCount())
+	fileStr := example.Filename("Fpdf_InsertPage")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// page 1 of 4
+	// Successfully generated pdf/Fpdf_InsertPage.pdf
+}
+
+// This example reserves a page for a preface that turns out not to be needed
+// and deletes it before the document is closed. The bookmarks of the
+// remaining pages are renumbered.
+func ExampleFpdf_DeletePage() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 14)
+	pdf.AddPage()
+	for _, title := range []string{"Introduction", "Summary"} {
+		pdf.AddPage()
+		pdf.Bookmark(title, 0, -1)
+		pdf.Cell(0, 10, title)
+	}
+	fmt.Printf("page %d of %d\n", pdf.PageNo(), pdf.PageCount())
+	pdf.DeletePage(1)
+	fmt.Printf("page %d of %d\n", pdf.PageNo(), pdf.PageCount())
+	fileStr := example.Filename("Fpdf_DeletePage")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// page 3 of 3
+	// page 2 of 2
+	// Successfully generated pdf/Fpdf_DeletePage.pdf
+}
+
+// This example prints a long URL in a narrow column, first broken at the
+// character level so that it stays within the border, the default, and then
+// kept whole so that it extends beyond the border.
+func ExampleFpdf_SetWordBreak() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	txtStr := "Details are available at https://example.com/reports/2019/annual/summary"
+	pdf.MultiCell(40, 6, txtStr, "1", "L", false)
+	pdf.Ln(10)
+	pdf.SetWordBreak(false)
+	pdf.MultiCell(40, 6, txtStr, "1", "L", false)
+	fileStr := example.Filename("Fpdf_SetWordBreak")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetWordBreak.pdf
+}
+
+// This example produces a tagged document whose heading, paragraphs, list
+// and link are recorded in the structure tree so that assistive technology
+// can present them in reading order.
+func ExampleFpdf_SetTagged() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTagged(true)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.BeginStructElement("Document")
+	pdf.BeginStructElement("H1")
+	pdf.SetFontSize(18)
+	pdf.Cell(0, 10, "Shopping list")
+	pdf.SetFontSize(12)
+	pdf.EndStructElement()
+	pdf.Ln(15)
+	pdf.BeginList("1B")
+	pdf.ListItem(0, "Fruit")
+	pdf.ListItem(1, "Apples")
+	pdf.ListItem(1, "Pears")
+	pdf.ListItem(0, "Bread")
+	pdf.EndList()
+	pdf.Ln(5)
+	pdf.BeginStructElement("P")
+	pdf.Write(6, "Prices are listed at ")
+	pdf.WriteLinkString(6, "example.com", "https://example.com")
+	pdf.Write(6, ".")
+	pdf.EndStructElement()
+	pdf.EndStructElement()
+	fileStr := example.Filename("Fpdf_SetTagged")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetTagged.pdf
+}
+
+// This example prints the same amounts formatted in the United States style
+// and in the German style, each column aligned on its decimal separator.
+func ExampleFpdf_NumberCell() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	amounts := []float64{1234.56, -7.5, 98765.4, 0.125}
+	us := gofpdf.NumberFormatType{DecimalSepStr: ".", ThousandsSepStr: ",", Decimals: 2, PrefixStr: "$"}
+	de := gofpdf.NumberFormatType{DecimalSepStr: ",", ThousandsSepStr: ".", Decimals: 2, SuffixStr: " EUR"}
+	for _, amt := range amounts {
+		pdf.SetNumberFormat(us)
+		pdf.NumberCell(50, 7, amt, "1", 0, "", false)
+		pdf.SetNumberFormat(de)
+		pdf.NumberCell(50, 7, amt, "1", 1, "", false)
+	}
+	fmt.Println(pdf.FormatNumber(1234.56))
+	fileStr := example.Filename("Fpdf_NumberCell")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 1.234,56 EUR
+	// Successfully generated pdf/Fpdf_NumberCell.pdf
+}
+
+// This example places the same image in square boxes with each of the fit
+// modes. The boxes are outlined to show how the image relates to them.
+func ExampleFpdf_ImageFit() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	for j, fitStr := range []string{"contain", "cover", "stretch"} {
+		x := 15 + float64(j)*62
+		pdf.ImageFit(example.ImageFile("logo.png"), x, 20, 50, 50, fitStr)
+		pdf.Rect(x, 20, 50, 50, "D")
+		pdf.Text(x, 80, fitStr)
+	}
+	fileStr := example.Filename("Fpdf_ImageFit")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageFit.pdf
+}
+
+// This example compresses the page content but not the image data that the
+// package encodes itself. It confirms that a JPEG image, which is already
+// compressed, is embedded with a single DCTDecode filter.
+func ExampleFpdf_SetCompressionForImages() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(true)
+	pdf.SetCompressionForImages(false)
+	pdf.AddPage()
+	pdf.Image(example.ImageFile("logo.jpg"), 10, 10, 40, 0, false, "", 0, "")
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	if err == nil {
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.HasPrefix(line, "/Filter") {
+				fmt.Println(line)
+			}
+		}
+	}
+	fileStr := example.Filename("Fpdf_SetCompressionForImages")
+	if err == nil {
+		err = ioutil.WriteFile(fileStr, buf.Bytes(), 0644)
+	}
+	example.Summary(err, fileStr)
+	// Output:
+	// /Filter /DCTDecode
+	// Successfully generated pdf/Fpdf_SetCompressionForImages.pdf
+}
+
+// This example prints the descent of a standard font and of a TrueType font.
+// The descriptor gives it as a negative number of thousandths of the font
+// size, as in the PDF specification, while GetFontDescent() gives a positive
+// distance in the unit of measure of the document.
+func ExampleFpdf_GetFontDescent() {
+	pdf := gofpdf.New("P", "pt", "A4", "")
+	show := func(familyStr string) {
+		pdf.SetFont(familyStr, "", 20)
+		fmt.Printf("%s: descriptor %d, descent %.2f pt\n", familyStr,
+			pdf.GetFontDesc("", "").Descent, pdf.GetFontDescent())
+	}
+	show("Helvetica")
+	pdf.SetFontLocation(example.FontDir())
+	pdf.AddFont("Calligrapher", "", "calligra.ttf")
+	show("Calligrapher")
+	// Output:
+	// Helvetica: descriptor -207, descent 4.14 pt
+	// Calligrapher: descriptor -234, descent 4.68 pt
+}
+
+// This example prints a table row whose cells hold different amounts of text.
+// Every cell is given the height of the tallest one and the text of each is
+// placed at the top, in the middle or at the bottom.
+func ExampleFpdf_MultiCellBox() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.AddPage()
+	cells := []string{
+		"Top",
+		"Middle, centered on the ascent and descent of the font",
+		"Bottom",
+		"A longer description that wraps onto several lines and so " +
+			"determines the height of the row",
+	}
+	aligns := []string{"LT", "CM", "RB", "J"}
+	wd, lineHt := 45.0, 5.0
+	rowHt := 0.0
+	for _, txtStr := range cells {
+		rowHt = math.Max(rowHt, pdf.MeasureCellHeight(wd, txtStr, lineHt))
+	}
+	rowHt += 4
+	for j, txtStr := range cells {
+		pdf.MultiCellBox(wd, rowHt, lineHt, txtStr, "1", 0, aligns[j], false)
+	}
+	pdf.Ln(-1)
+	fileStr := example.Filename("Fpdf_MultiCellBox")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_MultiCellBox.pdf
+}
+
+// This example marks the places at which long words may be broken with soft
+// hyphens. A hyphen is printed only where a line is actually broken, as shown
+// by the lines returned by SplitLines().
+func ExampleFpdf_MultiCell_softHyphen() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	txtStr := "Insurance con\xadtracts cover the trans\xadpor\xadta\xadtion of " +
+		"perish\xadable goods and equip\xadment."
+	for _, line := range pdf.SplitLines([]byte(txtStr), 40) {
+		fmt.Println(string(line))
+	}
+	pdf.MultiCell(40, 6, txtStr, "1", "J", false)
+	fileStr := example.Filename("Fpdf_MultiCell_softHyphen")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Insurance contracts
+	// cover the transpor-
+	// tation of perishable
+	// goods and equip-
+	// ment.
+	// Successfully generated pdf/Fpdf_MultiCell_softHyphen.pdf
+}
+
+// This example joins quantities and their units with non-breaking spaces so
+// that a line is never broken between them. The non-breaking spaces are shown
+// as underscores in the lines returned by SplitLines().
+func ExampleFpdf_MultiCell_nonBreakingSpace() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	txtStr := "The parcel for Mr.\xa0Smith weighs 10\xa0kg and measures 40\xa0cm on each side."
+	for _, line := range pdf.SplitLines([]byte(txtStr), 45) {
+		fmt.Println(strings.Replace(string(line), "\xa0", "_", -1))
+	}
+	pdf.MultiCell(45, 6, txtStr, "1", "J", false)
+	fileStr := example.Filename("Fpdf_MultiCell_nonBreakingSpace")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// The parcel for
+	// Mr._Smith weighs
+	// 10_kg and measures
+	// 40_cm on each side.
+	// Successfully generated pdf/Fpdf_MultiCell_nonBreakingSpace.pdf
+}
+
+// This example places images at their natural size, as given by the
+// resolution they specify. The gopher image of 1000 by 1000 pixels specifies
+// 600 dpi and the logo, which specifies 72 dpi, is placed at that resolution.
+func ExampleFpdf_SetImageNaturalSize() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetImageNaturalSize(true)
+	pdf.AddPage()
+	for _, nameStr := range []string{"golang-gopher.png", "logo.jpg"} {
+		pdf.Image(example.ImageFile(nameStr), -1, -1, 0, 0, true, "", 0, "")
+		wd, ht := pdf.GetImageInfo(example.ImageFile(nameStr)).Extent()
+		fmt.Printf("%s: %.1f x %.1f mm\n", nameStr, wd, ht)
+	}
+	fileStr := example.Filename("Fpdf_SetImageNaturalSize")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// golang-gopher.png: 42.3 x 42.3 mm
+	// logo.jpg: 36.7 x 25.0 mm
+	// Successfully generated pdf/Fpdf_SetImageNaturalSize.pdf
+}
+
+// This example draws shapes with opaque outlines around translucent fills
+// that let the overlapping shapes show through.
+func ExampleFpdf_SetStrokeAlpha() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.SetLineWidth(1.5)
+	pdf.SetDrawColor(0, 0, 128)
+	pdf.SetFillAlpha(0.3)
+	pdf.SetFillColor(255, 0, 0)
+	pdf.Circle(60, 60, 30, "FD")
+	pdf.SetFillColor(0, 160, 0)
+	pdf.Circle(90, 60, 30, "FD")
+	pdf.SetStrokeAlpha(0.5)
+	pdf.SetFillAlpha(1)
+	pdf.SetFillColor(255, 220, 0)
+	pdf.Rect(50, 100, 50, 30, "FD")
+	fmt.Println(pdf.GetStrokeAlpha())
+	pdf.SetAlpha(1, "Normal")
+	fmt.Println(pdf.GetStrokeAlpha())
+	fileStr := example.Filename("Fpdf_SetStrokeAlpha")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 0.5
+	// 1
+	// Successfully generated pdf/Fpdf_SetStrokeAlpha.pdf
+}
+
+// This example prints prose with a first-line indent and a list of
+// references with a hanging indent.
+func ExampleFpdf_SetParagraphIndent() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Times", "", 12)
+	pdf.AddPage()
+	pdf.SetParagraphIndent(10, 0)
+	pdf.MultiCell(0, 5, lorem()+"\n"+lorem(), "", "J", false)
+	pdf.Ln(5)
+	pdf.SetParagraphIndent(0, 10)
+	refs := []string{
+		"Kernighan, B. W. and Ritchie, D. M. The C Programming Language. " +
+			"Second edition. Prentice Hall, 1988.",
+		"Donovan, A. A. A. and Kernighan, B. W. The Go Programming Language. " +
+			"Addison-Wesley, 2015.",
+	}
+	for _, ref := range refs {
+		pdf.MultiCell(100, 5, ref, "", "L", false)
+		fmt.Printf("%.0f mm\n", pdf.MeasureCellHeight(100, ref, 5))
+	}
+	fileStr := example.Filename("Fpdf_SetParagraphIndent")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 15 mm
+	// 10 mm
+	// Successfully generated pdf/Fpdf_SetParagraphIndent.pdf
+}
+
+// This example declares the language of a tagged document and marks a phrase
+// in another language so that screen readers pronounce it correctly.
+func ExampleFpdf_SetLang() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTagged(true)
+	pdf.SetLang("en-US")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.BeginStructElement("P")
+	pdf.Write(6, "The guests were greeted with ")
+	pdf.BeginStructElement("Span")
+	pdf.SetStructElementLang("fr")
+	pdf.Write(6, "bienvenue chez nous")
+	pdf.EndStructElement()
+	pdf.Write(6, " at the door.")
+	pdf.EndStructElement()
+	fileStr := example.Filename("Fpdf_SetLang")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	pdf = gofpdf.New("P", "mm", "A4", "")
+	pdf.SetLang("en_US")
+	fmt.Println(pdf.Error())
+	// Output:
+	// Successfully generated pdf/Fpdf_SetLang.pdf
+	// invalid language tag "en_US"
+}
+
+// This example omits the empty page left at the end of the document while
+// keeping the page that is intentionally left blank before the second
+// chapter.
+func ExampleFpdf_SetSuppressEmptyPages() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetSuppressEmptyPages(true)
+	pdf.SetFont("Helvetica", "", 16)
+	pdf.AddPage()
+	pdf.Cell(0, 10, "Chapter 1")
+	pdf.AddPage()
+	pdf.KeepPage()
+	pdf.AddPage()
+	pdf.Cell(0, 10, "Chapter 2")
+	pdf.AddPage()
+	fmt.Printf("%d pages before closing\n", pdf.PageCount())
+	fileStr := example.Filename("Fpdf_SetSuppressEmptyPages")
+	err := pdf.OutputFileAndClose(fileStr)
+	fmt.Printf("%d pages in the document\n", pdf.PageCount())
+	example.Summary(err, fileStr)
+	// Output:
+	// 4 pages before closing
+	// 3 pages in the document
+	// Successfully generated pdf/Fpdf_SetSuppressEmptyPages.pdf
+}
+
+// This example places an image as it is, mirrored horizontally, mirrored
+// vertically and mirrored both ways, each in a box of the same size.
+func ExampleFpdf_ImageOptions_flip() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	fileStr := example.ImageFile("logo.png")
+	for j, flip := range [][2]bool{{false, false}, {true, false}, {false, true}, {true, true}} {
+		x := 10 + float64(j%2)*60
+		y := 10 + float64(j/2)*60
+		pdf.Rect(x, y, 50, 50, "D")
+		pdf.ImageOptions(fileStr, x, y, 50, 50, false,
+			gofpdf.ImageOptions{FlipH: flip[0], FlipV: flip[1]}, 0, "")
+	}
+	fileStr = example.Filename("Fpdf_ImageOptions_flip")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageOptions_flip.pdf
+}
+
+// This example demonstrates a page six meters long, which exceeds the limit
+// of 200 inches that applies to unscaled pages. Drawing takes place in
+// millimeters as usual.
+func ExampleFpdf_SetUserUnit() {
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		UnitStr: "mm",
+		Size:    gofpdf.SizeType{Wd: 1000, Ht: 6000},
+	})
+	pdf.SetUserUnit(10)
+	pdf.SetFont("Helvetica", "", 120)
+	pdf.AddPage()
+	pdf.SetLineWidth(5)
+	for y := 500.0; y < 6000; y += 500 {
+		pdf.Line(100, y, 900, y)
+		pdf.Text(100, y-30, fmt.Sprintf("%.0f mm", y))
+	}
+	fileStr := example.Filename("Fpdf_SetUserUnit")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetUserUnit.pdf
+}
+
+// This example draws the same colors with each of the four rendering
+// intents. The intent only affects the output of color managed viewers and
+// printers.
+func ExampleFpdf_SetRenderingIntent() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	y := 20.0
+	for _, intentStr := range []string{"AbsoluteColorimetric", "RelativeColorimetric",
+		"Saturation", "Perceptual"} {
+		pdf.SetRenderingIntent(intentStr)
+		pdf.Text(20, y+8, intentStr)
+		for j := 0; j < 4; j++ {
+			pdf.SetFillColor(255-60*j, 40*j, 60+60*j)
+			pdf.Rect(80+25*float64(j), y, 20, 12, "F")
+		}
+		y += 20
+	}
+	pdf.SetRenderingIntent("Gamut")
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	fileStr := example.Filename("Fpdf_SetRenderingIntent")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// unrecognized rendering intent "Gamut"
+	// Successfully generated pdf/Fpdf_SetRenderingIntent.pdf
+}
+
+// This example marks justified and hyphenated text with its logical text, so
+// that text copied from the document reads "transportation" rather than
+// "transpor- tation". A word drawn along a curve is given its logical text
+// explicitly.
+func ExampleFpdf_SetActualText() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetActualText(true)
+	pdf.AddPage()
+	pdf.MultiCell(42, 5, "Insurance contracts cover the transpor\xadtation of "+
+		"perishable goods and equip\xadment.", "1", "J", false)
+	pdf.BeginActualText("Seal of approval")
+	pdf.TextOnCircle(120, 50, 25, "Seal of approval", 140, true)
+	pdf.EndActualText()
+	fileStr := example.Filename("Fpdf_SetActualText")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetActualText.pdf
+}
+
+// This example adds a signature field whose appearance shows a handwritten
+// signature with the name of the signer and the date. The field remains to
+// be signed with external software.
+func ExampleFpdf_SignatureField() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.MultiCell(0, 6, "The parties agree to the terms set out above.", "", "L", false)
+	sig, err := gofpdf.SVGBasicFileParse(example.ImageFile("signature.svg"))
+	if err != nil {
+		pdf.SetError(err)
+	}
+	pdf.SignatureField("Signature1", 20, 40, 90, 35, func() {
+		scale := 70 / sig.Wd
+		pdf.SetLineCapStyle("round")
+		pdf.SetLineWidth(0.25)
+		pdf.SetDrawColor(0, 0, 128)
+		pdf.SetXY(25, 42)
+		pdf.SVGBasicWrite(&sig, scale)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.Text(25, 68, "Digitally signed by Jane Doe")
+		pdf.Text(25, 72, "Date: 2026-10-14")
+	})
+	pdf.Rect(20, 40, 90, 35, "D")
+	fileStr := example.Filename("Fpdf_SignatureField")
+	err = pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SignatureField.pdf
+}
+
+// This example creates a short slideshow. Each slide is shown with a
+// different transition effect and the viewer advances automatically after
+// three seconds, except on the last slide.
+func ExampleFpdf_SetPageTransition() {
+	pdf := gofpdf.New("L", "mm", "A5", "")
+	pdf.SetFont("Helvetica", "B", 36)
+	for _, styleStr := range []string{"Wipe", "Dissolve", "Split", "Push"} {
+		pdf.AddPage()
+		duration := 3.0
+		if styleStr == "Push" {
+			duration = 0
+		}
+		pdf.SetPageTransition(styleStr, duration)
+		pdf.CellFormat(0, 120, styleStr, "", 0, "C", false, 0, "")
+	}
+	pdf.SetPageTransition("Curtain", 0)
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	fileStr := example.Filename("Fpdf_SetPageTransition")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// unrecognized page transition style "Curtain"
+	// Successfully generated pdf/Fpdf_SetPageTransition.pdf
+}
+
+// This example embeds a TrueType font and prints text that includes
+// characters outside of ASCII. Each embedded font is given a ToUnicode CMap,
+// so that the text can be copied from the document and searched.
+func ExampleFpdf_AddFont_toUnicode() {
+	pdf := gofpdf.New("P", "mm", "A4", example.FontDir())
+	pdf.SetCompression(false)
+	pdf.AddFont("Calligrapher", "", "calligra.ttf")
+	pdf.SetFont("Calligrapher", "", 24)
+	pdf.AddPage()
+	pdf.Cell(0, 12, "Copy this text: 10\xa0kg")
+	pdf.TextOnCircle(105, 110, 40, "Größe und Maße", 135, true)
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	if err == nil {
+		fmt.Printf("%d ToUnicode CMap\n", strings.Count(buf.String(), "/CMapName /Adobe-Identity-UCS"))
+	}
+	fileStr := example.Filename("Fpdf_AddFont_toUnicode")
+	if err == nil {
+		err = ioutil.WriteFile(fileStr, buf.Bytes(), 0644)
+	}
+	example.Summary(err, fileStr)
+	// Output:
+	// 1 ToUnicode CMap
+	// Successfully generated pdf/Fpdf_AddFont_toUnicode.pdf
+}
+
+// This example generates the same document twice with a fixed creation
+// date. The file identifier derived from the content is the same for both,
+// so the output is reproducible. The identifier can also be set explicitly.
+func ExampleFpdf_SetDocumentID() {
+	idFnc := func(id [2]string) string {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetCreationDate(time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC))
+		if id[0] != "" {
+			pdf.SetDocumentID(id)
+		}
+		pdf.SetFont("Helvetica", "", 16)
+		pdf.AddPage()
+		pdf.Cell(0, 10, "Reproducible output")
+		var buf bytes.Buffer
+		pdf.Output(&buf)
+		str := buf.String()
+		pos := strings.Index(str, "/ID [")
+		return str[pos : pos+strings.Index(str[pos:], "]")+1]
+	}
+	fmt.Println(idFnc([2]string{}) == idFnc([2]string{}))
+	fmt.Println(idFnc([2]string{"report-0001", "rev-2"}))
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetDocumentID([2]string{"report-0001", ""})
+	fmt.Println(pdf.Error())
+	// Output:
+	// true
+	// /ID [<7265706f72742d30303031> <7265762d32>]
+	// both elements of the document ID must be set
+}
+
+// This example frames a certificate with a border of small logos, each of
+// which is rotated to follow the edge on which it lies. A second row of
+// logos follows a zigzag line.
+func ExampleFpdf_ImageAlongRect() {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.AddPage()
+	imageStr := example.ImageFile("logo.png")
+	pdf.ImageAlongRect(imageStr, 15, 15, 267, 180, 12, 0)
+	pdf.SetFont("Times", "B", 36)
+	pdf.SetXY(15, 60)
+	pdf.CellFormat(267, 20, "Certificate of Completion", "", 2, "C", false, 0, "")
+	pdf.ImageAlongPath(imageStr, []gofpdf.PointType{{X: 60, Y: 120}, {X: 100, Y: 140},
+		{X: 140, Y: 120}, {X: 180, Y: 140}, {X: 220, Y: 120}}, 8, 0, 2)
+	fileStr := example.Filename("Fpdf_ImageAlongRect")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_ImageAlongRect.pdf
+}
+
+// This example paints cyan boxes over a magenta band, once knocking out the
+// band and once overprinting it. The CMYK colors are selected with raw
+// operators. With overprint mode 1, the zero magenta component of the cyan
+// box leaves the band intact, so both inks are printed where they overlap.
+// The difference is visible in separation previews and in viewers that
+// simulate overprint.
+func ExampleFpdf_SetOverprint() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddPage()
+	pdf.RawWriteStr("0 1 0 0 k")
+	pdf.Rect(20, 20, 170, 50, "F")
+	pdf.RawWriteStr("1 0 0 0 k")
+	pdf.Rect(40, 30, 50, 30, "F")
+	pdf.SetOverprint(true, true)
+	pdf.SetOverprintMode(1)
+	pdf.Rect(120, 30, 50, 30, "F")
+	pdf.SetOverprint(false, false)
+	pdf.SetOverprintMode(2)
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	fileStr := example.Filename("Fpdf_SetOverprint")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// overprint mode must be 0 or 1, not 2
+	// Successfully generated pdf/Fpdf_SetOverprint.pdf
+}
+
+// This example records the positions of the words of a justified paragraph
+// and highlights each occurrence of a search term by drawing a translucent
+// box over it, as a search overlay would.
+func ExampleFpdf_SetWordPositions() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Times", "", 14)
+	pdf.AddPage()
+	pdf.SetWordPositions(true)
+	pdf.Cell(0, 10, "Search results for \"PDF\"")
+	pdf.Ln(12)
+	pdf.MultiCell(100, 7, "Portable Document Format (PDF) is a file format "+
+		"used to present documents in a manner independent of application "+
+		"software, hardware and operating systems. Each PDF file encapsulates "+
+		"a complete description of a fixed-layout flat document.", "", "J", false)
+	pdf.SetWordPositions(false)
+	pdf.SetFillColor(255, 220, 0)
+	pdf.SetAlpha(0.4, "Multiply")
+	var count int
+	for _, wp := range pdf.GetWordPositions() {
+		if strings.Trim(wp.Text, "()") == "PDF" {
+			pdf.Rect(wp.X, wp.Y, wp.Wd, wp.Ht, "F")
+			count++
+		}
+	}
+	fmt.Printf("%d words, %d matches\n", len(pdf.GetWordPositions()), count)
+	fileStr := example.Filename("Fpdf_SetWordPositions")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 39 words, 2 matches
+	// Successfully generated pdf/Fpdf_SetWordPositions.pdf
+}
+
+// This example draws a grid with lines of zero width. The first grid uses
+// device hairlines, whose weight depends on the viewer or printer. The second
+// grid maps a width of zero to an explicit 0.1 point, which looks the same
+// everywhere.
+func ExampleFpdf_SetHairlineWidth() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	grid := func(x, y float64, label string) {
+		pdf.Text(x, y-3, label)
+		for j := 0.0; j <= 80; j += 5 {
+			pdf.Line(x+j, y, x+j, y+80)
+			pdf.Line(x, y+j, x+80, y+j)
+		}
+	}
+	pdf.SetLineWidth(0)
+	grid(15, 30, "Device hairlines")
+	pdf.SetHairlineWidth(0.1)
+	grid(110, 30, "0.1 point hairlines")
+	fileStr := example.Filename("Fpdf_SetHairlineWidth")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_SetHairlineWidth.pdf
+}
+
+// This example prints a column of amounts in a font with proportional
+// figures, first with CellFormat() and then with NumberCell(), which prints
+// tabular figures so that the digits line up.
+func ExampleFpdf_NumberCell_tabular() {
+	pdf := gofpdf.New("P", "mm", "A4", example.FontDir())
+	pdf.AddFont("Calligrapher", "", "calligra.ttf")
+	pdf.SetFont("Calligrapher", "", 16)
+	pdf.AddPage()
+	amounts := []float64{1111.11, 9876.50, 40.07, 711.18}
+	for _, amount := range amounts {
+		pdf.CellFormat(50, 9, pdf.FormatNumber(amount), "1", 0, "R", false, 0, "")
+		pdf.NumberCell(50, 9, amount, "1", 1, "", false)
+	}
+	fileStr := example.Filename("Fpdf_NumberCell_tabular")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// Successfully generated pdf/Fpdf_NumberCell_tabular.pdf
+}
+
+// This example prints text that contains control characters. In the "strip"
+// mode, each control character printed in a cell is removed, except for the
+// tab, which is printed as a space. In the "replace" mode, a placeholder is
+// printed in its place. With Write(), tabs advance to the next tab stop and
+// a form feed starts a new page.
+func ExampleFpdf_SetControlChars() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	var codes []byte
+	for ch := byte(0); ch < 32; ch++ {
+		codes = append(codes, ch)
+	}
+	codes = append(codes, 127)
+	for _, modeStr := range []string{"strip", "replace"} {
+		pdf.SetControlChars(modeStr, "\xa4")
+		for _, ch := range codes {
+			pdf.CellFormat(0, 3, "A"+string(ch)+"B", "", 1, "L", false, 0, "")
+		}
+	}
+	pdf.SetControlChars("strip", "")
+	pdf.SetTabStops([]float64{40})
+	pdf.SetY(pdf.GetY() + 5)
+	pdf.Write(6, "Name:\tValue")
+	fmt.Printf("tab stop at %.1f mm\n", pdf.GetX()-pdf.GetStringWidth("Value"))
+	pdf.Write(6, "\nEnd of the first page\fStart of the second page")
+	fmt.Printf("%d pages\n", pdf.PageCount())
+	pdf.SetControlChars("escape", "")
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	str := buf.String()
+	fmt.Printf("stripped: %d, tab as space: %d, replaced: %d\n", strings.Count(str, "(AB) Tj"),
+		strings.Count(str, "(A B) Tj"), strings.Count(str, "(A\xa4B) Tj"))
+	fileStr := example.Filename("Fpdf_SetControlChars")
+	if err == nil {
+		err = ioutil.WriteFile(fileStr, buf.Bytes(), 0644)
+	}
+	example.Summary(err, fileStr)
+	// Output:
+	// tab stop at 50.0 mm
+	// 2 pages
+	// unrecognized control character mode "escape"
+	// stripped: 32, tab as space: 2, replaced: 32
+	// Successfully generated pdf/Fpdf_SetControlChars.pdf
+}
+
+// This example adds a page that is laid out in portrait orientation like the
+// others but is displayed rotated by 90 degrees, as is common for a wide
+// table or a landscape scan bound into a portrait document.
+func ExampleFpdf_SetPageRotation() {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetFont("Helvetica", "", 16)
+	pdf.AddPage()
+	pdf.Cell(0, 10, "This page is shown upright")
+	pdf.AddPage()
+	pdf.SetPageRotation(90)
+	pdf.Cell(0, 10, "This page is shown rotated clockwise")
+	pdf.SetPageRotation(45)
+	fmt.Println(pdf.Error())
+	pdf.ClearError()
+	fileStr := example.Filename("Fpdf_SetPageRotation")
+	err := pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// page rotation must be a multiple of 90 degrees: 45
+	// Successfully generated pdf/Fpdf_SetPageRotation.pdf
+}
+
+// This example generates a long report with the "file" buffer strategy, which
+// moves the content of completed pages to a temporary file to keep memory
+// use low. The document is the same as the one generated in memory.
+func ExampleFpdf_SetBufferStrategy() {
+	report := func(strategyStr string) *gofpdf.Fpdf {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetBufferStrategy(strategyStr)
+		pdf.SetCreationDate(time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC))
+		pdf.AliasNbPages("")
+		pdf.SetFooterFunc(func() {
+			pdf.SetY(-15)
+			pdf.CellFormat(0, 10, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+		})
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.AddPage()
+		for j := 1; j <= 500; j++ {
+			pdf.CellFormat(0, 6, fmt.Sprintf("Line item %d", j), "B", 1, "L", false, 0, "")
+		}
+		return pdf
+	}
+	mem, err := report("memory").GetBytes()
+	pdf := report("file")
+	var buf bytes.Buffer
+	if err == nil {
+		err = pdf.Output(&buf)
+	}
+	fmt.Printf("%d pages, identical: %v\n", pdf.PageCount(), bytes.Equal(mem, buf.Bytes()))
+	pdf = report("disk")
+	fmt.Println(pdf.Error())
+	pdf = report("file")
+	fileStr := example.Filename("Fpdf_SetBufferStrategy")
+	err = pdf.OutputFileAndClose(fileStr)
+	example.Summary(err, fileStr)
+	// Output:
+	// 12 pages, identical: true
+	// unrecognized buffer strategy "disk"
+	// Successfully generated pdf/Fpdf_SetBufferStrategy.pdf
+}
+
+// TestResetRemovesTemporaryFiles verifies that Reset() removes the temporary
+// files of the "file" buffer strategy, both while pages are being added and
+// after the document has been closed but not sent.
+func TestResetRemovesTemporaryFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gofpdf-reset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tmpStr := os.Getenv("TMPDIR")
+	os.Setenv("TMPDIR", dir)
+	defer os.Setenv("TMPDIR", tmpStr)
+	count := func() int {
+		list, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(list)
+	}
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	for _, closeDoc := range []bool{false, true} {
+		pdf.SetBufferStrategy("file")
+		pdf.SetFont("Helvetica", "", 12)
+		for j := 0; j < 4; j++ {
+			pdf.AddPage()
+			pdf.Cell(40, 10, "Temporary page content")
+		}
+		if closeDoc {
+			pdf.Close()
+		}
+		if err := pdf.Error(); err != nil {
+			t.Fatal(err)
+		}
+		if count() == 0 {
+			t.Fatalf("no temporary file was created (closed: %v)", closeDoc)
+		}
+		pdf.Reset(false)
+		if n := count(); n != 0 {
+			t.Fatalf("%d temporary files remain after Reset (closed: %v)", n, closeDoc)
+		}
+	}
+}
+
+// TestDrawPathRestoresState verifies that settings changed while a path is
+// built with BeginPath() are written again after DrawPath(), whose Q
+// operator restores the graphics state that preceded the path.
+func TestDrawPathRestoresState(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.BeginPath()
+	pdf.SetDrawColor(255, 0, 0)
+	pdf.SetLineWidth(2)
+	pdf.SetFont("Times", "", 12)
+	pdf.MoveTo(20, 20)
+	pdf.LineTo(80, 20)
+	pdf.DrawPath("D")
+	if r, g, b := pdf.GetDrawColor(); r != 0 || g != 0 || b != 0 {
+		t.Errorf("draw color after path is (%d, %d, %d), not black", r, g, b)
+	}
+	if wd := pdf.GetLineWidth(); wd > 1 {
+		t.Errorf("line width after path is %.2f, not the default", wd)
+	}
+	pdf.SetDrawColor(255, 0, 0)
+	pdf.SetLineWidth(2)
+	pdf.SetFont("Times", "", 12)
+	pdf.Line(20, 40, 80, 40)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	pos := strings.Index(doc, "\nQ\n")
+	if pos < 0 {
+		t.Fatal("path is not enclosed in q and Q")
+	}
+	after := doc[pos:]
+	for _, opStr := range []string{"1.000 0.000 0.000 RG", "5.67 w", "/F1 12.00 Tf"} {
+		if !strings.Contains(after, opStr) {
+			t.Errorf("operator %q is not written again after the path", opStr)
+		}
+	}
+}
+
+// TestKeepTogetherRollback verifies that a block that does not fit on the
+// current page is moved to the next page without leaving behind any of the
+// links, bookmarks, named destinations or graphics states of the rendering
+// that was discarded.
+func TestKeepTogetherRollback(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.SetY(240)
+	pdf.KeepTogether(func() {
+		link := pdf.AddLink()
+		pdf.SetLink(link, -1, -1)
+		pdf.Bookmark("Block", 0, -1)
+		pdf.AddNamedDestination("block", -1, -1)
+		pdf.SetAlpha(0.5, "Normal")
+		for j := 0; j < 6; j++ {
+			pdf.CellFormat(0, 10, fmt.Sprintf("Line %d", j), "1", 1, "L", false, link, "")
+		}
+		pdf.SetAlpha(1, "Normal")
+	})
+	if pdf.PageNo() != 2 {
+		t.Fatalf("block ends on page %d, not 2", pdf.PageNo())
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	pos := strings.Index(doc, "Line 0")
+	if pos < 0 || strings.Index(doc[pos+1:], "Line 0") >= 0 {
+		t.Fatal("block content is not present exactly once")
+	}
+	for _, c := range []struct {
+		str   string
+		count int
+	}{
+		{"/Subtype /Link", 6},
+		{"/Title (Block)", 1},
+		{"/Type /ExtGState", 2},
+		{"/Annots", 1},
+	} {
+		if n := strings.Count(doc, c.str); n != c.count {
+			t.Errorf("%q occurs %d times, not %d", c.str, n, c.count)
+		}
+	}
+}
+
+// TestKeepTogetherPages verifies that a page started by a form feed in the
+// trial rendering of a block is removed along with the rest of it.
+func TestKeepTogetherPages(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetControlChars("strip", "")
+	pdf.AddPage()
+	pdf.SetY(250)
+	layer := -1
+	pdf.KeepTogether(func() {
+		layer = pdf.AddLayer("Block", true)
+		pdf.MultiCell(0, 10, "Before\fAfter", "", "L", false)
+	})
+	if pdf.PageNo() != 3 || layer != 0 {
+		t.Fatalf("block ends on page %d with layer %d, not page 3 with layer 0", pdf.PageNo(), layer)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	for _, c := range []struct {
+		str   string
+		count int
+	}{
+		{"(Before)", 1},
+		{"(After)", 1},
+		{"/Count 3", 1},
+		{"/Type /OCG", 1},
+	} {
+		if n := strings.Count(doc, c.str); n != c.count {
+			t.Errorf("%q occurs %d times, not %d", c.str, n, c.count)
+		}
+	}
+}
+
+// TestCreateTilingPatternIsolation verifies that drawing the cell of a
+// tiling pattern leaves the current page, its links and the drawing state
+// of the instance unchanged.
+func TestCreateTilingPatternIsolation(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.SetDrawColor(10, 20, 30)
+	pdf.SetXY(50, 60)
+	id := pdf.CreateTilingPattern(10, 10, func() {
+		pdf.SetDrawColor(255, 0, 0)
+		pdf.SetFont("Times", "B", 6)
+		pdf.SetXY(0, 0)
+		pdf.CellFormat(10, 5, "Cell text", "1", 1, "L", false, 0, "http://example.com")
+	})
+	if r, g, b := pdf.GetDrawColor(); r != 10 || g != 20 || b != 30 {
+		t.Errorf("draw color after pattern is (%d, %d, %d)", r, g, b)
+	}
+	if x, y := pdf.GetXY(); x != 50 || y != 60 {
+		t.Errorf("position after pattern is (%.2f, %.2f)", x, y)
+	}
+	pdf.SetFillPattern(id)
+	pdf.Rect(10, 10, 40, 40, "F")
+	pdf.Text(10, 80, "Page text")
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	for _, c := range []struct {
+		str   string
+		count int
+	}{
+		{"(Cell text)", 1},
+		{"(Page text)", 1},
+		{"/Subtype /Link", 0},
+		{"/PatternType 1", 1},
+	} {
+		if n := strings.Count(doc, c.str); n != c.count {
+			t.Errorf("%q occurs %d times, not %d", c.str, n, c.count)
+		}
+	}
+}
+
+// TestErrorConcurrency exercises the error methods from several goroutines
+// at once. Run it with -race to check that access to the error is
+// serialized.
+func TestErrorConcurrency(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	done := make(chan bool)
+	for j := 0; j < 4; j++ {
+		go func(j int) {
+			for k := 0; k < 500; k++ {
+				switch (j + k) % 4 {
+				case 0:
+					pdf.SetError(fmt.Errorf("error %d", k))
+				case 1:
+					pdf.SetErrorf("error %d", k)
+				case 2:
+					pdf.Ok()
+					pdf.Err()
+					pdf.Error()
+					pdf.OkError()
+				default:
+					pdf.ClearError()
+				}
+			}
+			done <- true
+		}(j)
+	}
+	for j := 0; j < 4; j++ {
+		<-done
+	}
+	pdf.ClearError()
+	if !pdf.Ok() {
+		t.Fatal("error is set after ClearError")
+	}
+}
+
+// TestCodeBlockWrapsCharacters checks that a wrapped line of a code block is
+// broken between characters rather than within the bytes of one.
+func TestCodeBlockWrapsCharacters(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.AddPage()
+	pdf.CodeBlock(43, strings.Repeat("é", 60), gofpdf.CodeBlockOptions{FontSize: 10, Wrap: true})
+	var buf bytes.Buffer
+	err := pdf.Output(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows int
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if !strings.HasSuffix(line, ") Tj ET") {
+			continue
+		}
+		txt := line[strings.Index(line, "(")+1 : len(line)-len(") Tj ET")]
+		if !utf8.ValidString(txt) {
+			t.Fatalf("row %d is broken within a character: %q", rows+1, txt)
+		}
+		rows++
+	}
+	if rows < 2 {
+		t.Fatalf("expected the line to wrap, got %d rows", rows)
+	}
+}
+
+// featureDoc returns the uncompressed document that results when fnc prints
+// text in the font of featureFontDir(), selected at 12 points, along with the
+// glyph indexes of the characters of the font
+func featureDoc(t *testing.T, fnc func(pdf *gofpdf.Fpdf)) (doc string, gids map[uint16]uint16) {
+	dir, err := featureFontDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ttf, err := gofpdf.TtfParse(filepath.Join(dir, "features.ttf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pdf := gofpdf.New("P", "mm", "A4", dir)
+	pdf.SetCompression(false)
+	pdf.AddFont("Arial Features", "", "features.ttf")
+	pdf.SetFont("Arial Features", "", 12)
+	pdf.AddPage()
+	fnc(pdf)
+	var buf bytes.Buffer
+	if err = pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String(), ttf.Chars
+}
+
+// TestFontFeatures checks the glyph indexes that are printed for ligatures,
+// the widths and the character map of the Type0 font that prints them, and
+// that text is printed as usual when no requested feature applies.
+func TestFontFeatures(t *testing.T) {
+	doc, gids := featureDoc(t, func(pdf *gofpdf.Fpdf) {
+		pdf.SetFontFeatures([]string{"liga"})
+		pdf.Cell(0, 10, "fifl AE")
+		pdf.SetFontFeatures([]string{"liga", "dlig"})
+		pdf.Text(20, 40, "fifl AE")
+		w := pdf.GetStringWidth("AE")
+		pdf.SetFontFeatures([]string{"kern"})
+		pdf.Cell(0, 10, "fifl AE")
+		if w >= pdf.GetStringWidth("AE") {
+			t.Errorf("width of ligature Æ, %.2f, is not less than that of AE", w)
+		}
+		pdf.SetFontFeatures(nil)
+		pdf.Cell(0, 10, "fifl AE")
+	})
+	// hex returns the glyph indexes of the characters of s as a hex string
+	hex := func(s string) (str string) {
+		for _, r := range s {
+			str += fmt.Sprintf("%04X", gids[uint16(r)])
+		}
+		return
+	}
+	for _, want := range []string{
+		"/G0 12.00 Tf <" + hex("ﬁﬂ AE") + "> Tj /F0 12.00 Tf",
+		"/G0 12.00 Tf <" + hex("ﬁﬂ Æ") + "> Tj /F0 12.00 Tf",
+		"<" + hex("ﬁ") + "> <00660069>",
+		"<" + hex("Æ") + "> <00410045>",
+		fmt.Sprintf("/W [%d [278]", gids[' ']),
+		fmt.Sprintf("%d [1000]", gids['Æ']),
+		"/G0 10 0 R",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document does not contain %q", want)
+		}
+	}
+	if n := strings.Count(doc, "(fifl AE) Tj"); n != 2 {
+		t.Errorf("expected text to be printed as usual twice, got %d times", n)
+	}
+	doc, _ = featureDoc(t, func(pdf *gofpdf.Fpdf) {
+		pdf.SetFontFeatures([]string{"liga"})
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.Cell(0, 10, "fifl AE")
+	})
+	if !strings.Contains(doc, "(fifl AE) Tj") || strings.Contains(doc, "/Type0") {
+		t.Errorf("features are applied to a core font")
+	}
+}
+
+// TestFontFeaturesSingle checks the glyph indexes and widths of small capitals
+// and figures that replace the characters of text, and the tabular figures
+// that NumberCell() prints when the "tnum" feature is requested.
+func TestFontFeaturesSingle(t *testing.T) {
+	var widths [2]float64
+	doc, gids := featureDoc(t, func(pdf *gofpdf.Fpdf) {
+		pdf.SetFontFeatures([]string{"smcp", "onum", "zero"})
+		pdf.Cell(0, 10, "ab 1230")
+		_, size := pdf.GetFontSize()
+		widths = [2]float64{pdf.GetStringWidth("ab 1230"), size}
+		pdf.SetFontFeatures([]string{"tnum"})
+		pdf.NumberCell(40, 10, 1234.5, "", 1, "", false)
+		pdf.SetFontFeatures([]string{"liga"})
+		pdf.NumberCell(40, 10, 1234.5, "", 1, "", false)
+	})
+	hex := func(s string) (str string) {
+		for _, r := range s {
+			str += fmt.Sprintf("%04X", gids[uint16(r)])
+		}
+		return
+	}
+	for _, want := range []string{
+		"<" + hex("ᴀʙ ¹²³Ø") + "> Tj",
+		"<" + hex("1,234.50") + "> Tj",
+		"(1,234.50) Tj",
+		"<" + hex("ᴀ") + "> <0061>",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document does not contain %q", want)
+		}
+	}
+	// Widths of the substituted glyphs in thousandths of the font size
+	want := float64(496+531+278+333+333+333+778) * widths[1] / 1000
+	if math.Abs(widths[0]-want) > 1e-9 {
+		t.Errorf("width of substituted text: got %f, want %f", widths[0], want)
+	}
+}
+
+// TestDrawGlyph checks that glyphs drawn by index are printed with the Type0
+// font, with their widths taken from the hmtx table and a character map entry
+// for those that the character map of the font reaches.
+func TestDrawGlyph(t *testing.T) {
+	fileStr := example.FontDir() + "/calligra.ttf"
+	ttf, err := gofpdf.TtfParse(fileStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reached := make(map[uint16]bool)
+	for _, gid := range ttf.Chars {
+		reached[gid] = true
+	}
+	unreached := uint16(1)
+	for reached[unreached] {
+		unreached++
+	}
+	pdf := gofpdf.New("P", "mm", "A4", example.FontDir())
+	pdf.SetCompression(false)
+	pdf.AddFont("Calligrapher", "", "calligra.ttf")
+	pdf.SetFont("Calligrapher", "", 20)
+	pdf.AddPage()
+	pdf.SetTextColor(200, 0, 0)
+	pdf.DrawGlyph(ttf.Chars['g'], 20, 20)
+	pdf.DrawGlyph(unreached, 40, 20)
+	var buf bytes.Buffer
+	if err = pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	for _, want := range []string{
+		fmt.Sprintf("q 0.784 0.000 0.000 rg BT 56.69 785.20 Td /G0 20.00 Tf <%04X> Tj /F0 20.00 Tf ET Q",
+			ttf.Chars['g']),
+		fmt.Sprintf("BT 113.39 785.20 Td /G0 20.00 Tf <%04X> Tj /F0 20.00 Tf ET", unreached),
+		fmt.Sprintf("%d [%d]", ttf.Chars['g'], ttf.Widths[ttf.Chars['g']]),
+		fmt.Sprintf("%d [%d]", unreached, ttf.Widths[unreached]),
+		fmt.Sprintf("1 beginbfchar\n<%04X> <0067>\nendbfchar", ttf.Chars['g']),
+		"/G0 ",
+	} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("document does not contain %q", want)
+		}
+	}
+}
+
+// TestArtifacts verifies that headers and footers are marked as pagination
+// artifacts only in tagged mode and that an artifact begun with
+// MarkArtifactBegin() is ended and begun again across a page break.
+func TestArtifacts(t *testing.T) {
+	for _, tagged := range []bool{false, true} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetCompression(false)
+		pdf.SetTagged(tagged)
+		pdf.SetHeaderFunc(func() {
+			pdf.CellFormat(0, 10, "Header", "", 1, "C", false, 0, "")
+		})
+		pdf.SetFooterFunc(func() {
+			pdf.SetY(-15)
+			pdf.CellFormat(0, 10, "Footer", "", 0, "C", false, 0, "")
+		})
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.AddPage()
+		if tagged {
+			pdf.BeginStructElement("P")
+		}
+		pdf.MarkArtifactBegin()
+		pdf.CellFormat(0, 10, "Decoration", "", 1, "L", false, 0, "")
+		pdf.AddPage()
+		pdf.CellFormat(0, 10, "Decoration", "", 1, "L", false, 0, "")
+		pdf.MarkArtifactEnd()
+		if tagged {
+			pdf.EndStructElement()
+		}
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Fatal(err)
+		}
+		doc := buf.String()
+		pagination := 0
+		if tagged {
+			pagination = 2
+		}
+		for _, c := range []struct {
+			str   string
+			count int
+		}{
+			{"/Artifact <</Type /Pagination /Subtype /Header>> BDC", pagination},
+			{"/Artifact <</Type /Pagination /Subtype /Footer>> BDC", pagination},
+			{"/Artifact BMC", 2},
+		} {
+			if n := strings.Count(doc, c.str); n != c.count {
+				t.Errorf("tagged %v: %q occurs %d times, not %d", tagged, c.str, n, c.count)
+			}
+		}
+		// Each page stream balances its marked-content sequences
+		for _, page := range strings.Split(doc, "endstream")[:2] {
+			begins, ends := 0, 0
+			for _, line := range strings.Split(page, "\n") {
+				if strings.HasSuffix(line, " BMC") || strings.HasSuffix(line, " BDC") {
+					begins++
+				} else if line == "EMC" {
+					ends++
+				}
+			}
+			if begins != ends {
+				t.Errorf("tagged %v: page begins %d sequences and ends %d", tagged, begins, ends)
+			}
+		}
+	}
+}
+
+// hintReader reads the bit fields of a hint stream
+type hintReader struct {
+	data []byte
+	bit  uint
+}
+
+func (r *hintReader) read(bits uint) (v int) {
+	for j := uint(0); j < bits; j++ {
+		pos := r.bit / 8
+		if pos < uint(len(r.data)) && r.data[pos]&(0x80>>(r.bit%8)) != 0 {
+			v |= 1 << (bits - 1 - j)
+		}
+		r.bit++
+	}
+	return
+}
+
+// align skips to the next byte boundary
+func (r *hintReader) align() {
+	r.bit = (r.bit + 7) / 8 * 8
+}
+
+// TestLinearizedLayout verifies the linearization dictionary, the
+// cross-reference tables and the hint tables of a linearized document whose
+// pages have additional content streams, tagged link annotations and a
+// signature field. The pages share the resource dictionary and so the fonts
+// and images it refers to, all of which belong to the first page section.
+func TestLinearizedLayout(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetLinearized(true)
+	pdf.SetTagged(true)
+	pdf.SetContentStreamLimit(200)
+	pdf.SetFont("Helvetica", "", 12)
+	for j := 1; j <= 3; j++ {
+		pdf.AddPage()
+		for k := 0; k < 5; k++ {
+			pdf.CellFormat(0, 8, fmt.Sprintf("Page %d, line %d", j, k), "", 1, "L", false, 0, "")
+		}
+		link := pdf.AddLink()
+		pdf.SetLink(link, 0, 1)
+		pdf.CellFormat(0, 8, "First page", "", 1, "L", false, link, "")
+		if j > 1 {
+			pdf.Image(example.ImageFile("logo.png"), 10, 100, 30, 0, false, "", 0, "")
+		}
+	}
+	pdf.SignatureField("Approval", 20, 200, 60, 20, nil)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.String()
+	objAt := func(ofs int) int {
+		if ofs <= 0 || ofs >= len(doc) {
+			return 0
+		}
+		m := regexp.MustCompile(`^(\d+) 0 obj\n`).FindStringSubmatch(doc[ofs:])
+		if m == nil {
+			return 0
+		}
+		num, _ := strconv.Atoi(m[1])
+		return num
+	}
+	atoi := func(s string) int {
+		v, _ := strconv.Atoi(s)
+		return v
+	}
+
+	// Linearization dictionary
+	m := regexp.MustCompile(`^%PDF-\d\.\d\n(\d+) 0 obj\n<</Linearized 1 /L (\d+) /H \[(\d+) (\d+)\] /O (\d+) /E (\d+) /N (\d+) /T (\d+)>>`).FindStringSubmatch(doc)
+	if m == nil {
+		t.Fatal("document does not begin with a linearization dictionary")
+	}
+	linNum, l, hOfs, hLen, o, e, n, tPos := atoi(m[1]), atoi(m[2]), atoi(m[3]), atoi(m[4]), atoi(m[5]), atoi(m[6]), atoi(m[7]), atoi(m[8])
+	if l != len(doc) {
+		t.Errorf("/L is %d, not the file length %d", l, len(doc))
+	}
+	if n != 3 {
+		t.Errorf("/N is %d, not 3", n)
+	}
+
+	// Object offsets of both cross-reference tables
+	offsets := make(map[int]int)
+	xrefRe := regexp.MustCompile(`xref\n(\d+) (\d+)\n`)
+	xrefs := xrefRe.FindAllStringSubmatchIndex(doc, -1)
+	if len(xrefs) != 2 {
+		t.Fatalf("document has %d cross-reference tables, not 2", len(xrefs))
+	}
+	for j, x := range xrefs {
+		first, count := atoi(doc[x[2]:x[3]]), atoi(doc[x[4]:x[5]])
+		if j == 0 && first != linNum {
+			t.Errorf("first page cross-reference table begins with object %d, not %d", first, linNum)
+		}
+		for k := 0; k < count; k++ {
+			entry := doc[x[1]+20*k : x[1]+20*k+20]
+			if entry[17] != 'n' {
+				continue
+			}
+			ofs := atoi(entry[:10])
+			if num := objAt(ofs); num != first+k {
+				t.Errorf("cross-reference offset %d of object %d points to object %d", ofs, first+k, num)
+			}
+			offsets[first+k] = ofs
+		}
+		if j == 1 && doc[x[1]-1:x[1]] != "\n" {
+			t.Fatal("main cross-reference table is malformed")
+		}
+	}
+	if main := xrefs[1]; tPos != main[1]-1 || !strings.HasPrefix(doc[tPos:], "\n0000000000 65535 f") {
+		t.Errorf("/T is %d, not %d, the offset of the first entry of the main table less one", tPos, main[1]-1)
+	}
+
+	// Pages, in order, and the objects their dictionaries refer to
+	objBody := func(num int) string {
+		ofs, ok := offsets[num]
+		if !ok {
+			t.Fatalf("object %d is not in a cross-reference table", num)
+		}
+		end := strings.Index(doc[ofs:], "endobj")
+		return doc[ofs : ofs+end]
+	}
+	refRe := regexp.MustCompile(`(\d+) 0 R`)
+	var pages []int
+	for num := range offsets {
+		if strings.Contains(objBody(num), "/Type /Pages") {
+			for _, ref := range refRe.FindAllStringSubmatch(objBody(num), -1) {
+				pages = append(pages, atoi(ref[1]))
+			}
+		}
+	}
+	if len(pages) != 3 || pages[0] != o {
+		t.Fatalf("pages are %v, the first of which is not /O %d", pages, o)
+	}
+	pageRefs := make([][]int, 3)
+	for p, num := range pages {
+		body := objBody(num)
+		for _, key := range []string{"/Contents", "/Annots"} {
+			pos := strings.Index(body, key)
+			if pos < 0 {
+				continue
+			}
+			val := body[pos:]
+			if end := strings.Index(val, "]"); end >= 0 && strings.HasPrefix(val[len(key):], " [") {
+				val = val[:end]
+			} else {
+				val = val[:strings.Index(val, " R")+2]
+			}
+			for _, ref := range refRe.FindAllStringSubmatch(val, -1) {
+				pageRefs[p] = append(pageRefs[p], atoi(ref[1]))
+			}
+		}
+		if p > 0 && len(pageRefs[p]) < 4 {
+			t.Errorf("page %d refers to %v, not to several content streams and an annotation", p+1, pageRefs[p])
+		}
+	}
+
+	// Hint stream, which immediately precedes the first page
+	hintNum := objAt(hOfs)
+	if hintNum == 0 || offsets[hintNum] != hOfs {
+		t.Fatalf("/H offset %d does not point to the hint stream", hOfs)
+	}
+	if num := objAt(hOfs + hLen); num != o {
+		t.Errorf("hint stream is followed by object %d, not the first page %d", num, o)
+	}
+	hm := regexp.MustCompile(`^\d+ 0 obj\n<</Length (\d+) /S (\d+)>>\nstream\n`).FindStringSubmatch(doc[hOfs:])
+	if hm == nil {
+		t.Fatal("hint stream is malformed")
+	}
+	hintStart := hOfs + len(hm[0])
+	hints := []byte(doc[hintStart : hintStart+atoi(hm[1])])
+	adjust := func(ofs int) int {
+		if ofs >= hOfs {
+			return ofs + hLen
+		}
+		return ofs
+	}
+	objLen := func(num int) int {
+		return len(objBody(num)) + len("endobj\n")
+	}
+
+	// Page offset hint table
+	r := &hintReader{data: hints}
+	minObjs, firstOfs := r.read(32), r.read(32)
+	objBits := uint(r.read(16))
+	minLen := r.read(32)
+	lenBits := uint(r.read(16))
+	r.read(32)
+	contentOfsBits := uint(r.read(16))
+	r.read(32)
+	contentLenBits := uint(r.read(16))
+	sharedBits, idBits, numerBits := uint(r.read(16)), uint(r.read(16)), uint(r.read(16))
+	r.read(16)
+	if adjust(firstOfs) != offsets[o] {
+		t.Errorf("hint table locates the first page at %d, not %d", adjust(firstOfs), offsets[o])
+	}
+	counts := make([]int, 3)
+	lengths := make([]int, 3)
+	shared := make([][]int, 3)
+	for p := range counts {
+		counts[p] = minObjs + r.read(objBits)
+	}
+	r.align()
+	for p := range lengths {
+		lengths[p] = minLen + r.read(lenBits)
+	}
+	r.align()
+	for p := range shared {
+		shared[p] = make([]int, r.read(sharedBits))
+	}
+	r.align()
+	for p := range shared {
+		for k := range shared[p] {
+			shared[p][k] = r.read(idBits)
+		}
+	}
+	r.align()
+	for p := range shared {
+		for range shared[p] {
+			r.read(numerBits)
+		}
+	}
+	r.align()
+	r.read(3 * contentOfsBits)
+	r.align()
+	r.read(3 * contentLenBits)
+	r.align()
+	if int(r.bit/8) != atoi(hm[2]) {
+		t.Errorf("page offset hint table ends at %d, not at /S %d", r.bit/8, atoi(hm[2]))
+	}
+	// The objects of each page are numbered consecutively from its page
+	// object and, after the first page, follow each other from /E on
+	pos := e
+	for p, num := range pages {
+		total := 0
+		for k := 0; k < counts[p]; k++ {
+			total += objLen(num + k)
+		}
+		if total != lengths[p] {
+			t.Errorf("page %d: objects have %d bytes, hint table records %d", p+1, total, lengths[p])
+		}
+		if p == 0 {
+			if offsets[num]+total != e {
+				t.Errorf("first page section ends at %d, not /E %d", offsets[num]+total, e)
+			}
+			continue
+		}
+		if offsets[num] != pos {
+			t.Errorf("page %d begins at %d, not %d", p+1, offsets[num], pos)
+		}
+		pos += total
+		for _, ref := range pageRefs[p] {
+			if ref < num || ref >= num+counts[p] {
+				t.Errorf("page %d: object %d is not among the objects of the page", p+1, ref)
+			}
+		}
+		if len(shared[p]) == 0 {
+			t.Errorf("page %d refers to no shared objects", p+1)
+		}
+	}
+	if len(shared[0]) != 0 {
+		t.Errorf("first page has %d shared object references, not 0", len(shared[0]))
+	}
+
+	// Shared object hint table
+	r = &hintReader{data: hints[atoi(hm[2]):]}
+	sharedNum, sharedOfs, firstCount, total := r.read(32), r.read(32), r.read(32), r.read(32)
+	groupBits := uint(r.read(16))
+	minGroup := r.read(32)
+	groupLenBits := uint(r.read(16))
+	if firstCount != counts[0] {
+		t.Errorf("shared object table has %d entries for the first page, not %d", firstCount, counts[0])
+	}
+	if total != firstCount {
+		t.Fatalf("shared object table has %d entries for the shared objects section, not 0", total-firstCount)
+	}
+	if sharedNum != 0 || sharedOfs != 0 {
+		t.Errorf("empty shared objects section is located at object %d, offset %d", sharedNum, sharedOfs)
+	}
+	for k := 0; k < total; k++ {
+		num := o + k
+		if length := minGroup + r.read(groupLenBits); length != objLen(num) {
+			t.Errorf("shared object %d: hint table records %d bytes, not %d", num, length, objLen(num))
+		}
+	}
+	r.align()
+	if groupBits != 0 {
+		t.Errorf("shared object groups have %d bits of objects, not 0", groupBits)
+	}
+	for p := 1; p < 3; p++ {
+		for _, idx := range shared[p] {
+			if idx >= total {
+				t.Errorf("page %d refers to shared object %d of %d", p+1, idx, total)
+			}
+		}
+	}
+}
+
+// TestPDFVersion verifies the version declared in the header of documents
+// that use each of the features that require a version above 1.3, and the
+// error that results from setting a lower version.
+func TestPDFVersion(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	img.Set(1, 1, color.NRGBA{255, 0, 0, 128})
+	var pngBuf bytes.Buffer
+	png.Encode(&pngBuf, img)
+	for _, c := range []struct {
+		nameStr    string
+		versionStr string
+		fnc        func(pdf *gofpdf.Fpdf)
+	}{
+		{"none", "1.3", func(pdf *gofpdf.Fpdf) {}},
+		{"transparency", "1.4", func(pdf *gofpdf.Fpdf) { pdf.SetAlpha(0.5, "Normal") }},
+		{"image transparency", "1.4", func(pdf *gofpdf.Fpdf) {
+			pdf.RegisterImageOptionsReader("alpha", gofpdf.ImageOptions{ImageType: "png"}, &pngBuf)
+			pdf.Image("alpha", 10, 10, 10, 0, false, "", 0, "")
+		}},
+		{"tagged content", "1.4", func(pdf *gofpdf.Fpdf) {
+			pdf.SetTagged(true)
+			pdf.BeginStructElement("P")
+			pdf.Cell(0, 10, "Tagged")
+			pdf.EndStructElement()
+		}},
+		{"document language", "1.4", func(pdf *gofpdf.Fpdf) { pdf.SetLang("en-US") }},
+		{"layers", "1.5", func(pdf *gofpdf.Fpdf) {
+			pdf.BeginLayer(pdf.AddLayer("Layer", true))
+			pdf.Cell(0, 10, "Layer")
+			pdf.EndLayer()
+		}},
+		{"ActualText", "1.5", func(pdf *gofpdf.Fpdf) {
+			pdf.BeginActualText("Text")
+			pdf.Cell(0, 10, "T e x t")
+			pdf.EndActualText()
+		}},
+		{"automatic ActualText", "1.5", func(pdf *gofpdf.Fpdf) {
+			pdf.SetActualText(true)
+			pdf.Cell(0, 10, "Text")
+		}},
+		{"page layout", "1.5", func(pdf *gofpdf.Fpdf) { pdf.SetDisplayMode("default", "TwoPageLeft") }},
+		{"page transition", "1.5", func(pdf *gofpdf.Fpdf) { pdf.SetPageTransition("Fade", 0) }},
+		{"older page transition", "1.3", func(pdf *gofpdf.Fpdf) { pdf.SetPageTransition("Wipe", 0) }},
+		{"cleared metadata", "1.4", func(pdf *gofpdf.Fpdf) { pdf.ClearMetadata() }},
+		{"user units", "1.6", func(pdf *gofpdf.Fpdf) { pdf.SetUserUnit(2) }},
+	} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.AddPage()
+		c.fnc(pdf)
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Errorf("%s: %s", c.nameStr, err)
+			continue
+		}
+		if header := "%PDF-" + c.versionStr + "\n"; !strings.HasPrefix(buf.String(), header) {
+			t.Errorf("%s: header is %q, not %q", c.nameStr, buf.String()[:9], header)
+		}
+		if c.versionStr == "1.3" {
+			continue
+		}
+		// A lower version set explicitly is an error
+		pdf = gofpdf.New("P", "mm", "A4", "")
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.SetPDFVersion(1, 3)
+		pdf.AddPage()
+		pngBuf.Reset()
+		png.Encode(&pngBuf, img)
+		c.fnc(pdf)
+		pdf.Close()
+		if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), "lower than version "+c.versionStr) {
+			t.Errorf("%s: version 1.3 results in error %v", c.nameStr, err)
+		}
+	}
+	// A prior error leaves the version alone
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetError(fmt.Errorf("prior error"))
+	pdf.SetPDFVersion(2, 0)
+	if err := pdf.Error(); err == nil || err.Error() != "prior error" {
+		t.Errorf("SetPDFVersion replaced the prior error with %v", err)
+	}
+}
+
+// TestClearMetadata verifies that a document with cleared metadata has no
+// document information dictionary and an XMP metadata stream without
+// properties in its place
+func TestClearMetadata(t *testing.T) {
+	for _, linearized := range []bool{false, true} {
+		pdf := gofpdf.New("P", "mm", "A4", "")
+		pdf.SetCompression(false)
+		pdf.SetLinearized(linearized)
+		pdf.SetAuthor("Author", false)
+		pdf.SetFont("Helvetica", "", 12)
+		pdf.AddPage()
+		pdf.Cell(0, 10, "Sanitized")
+		pdf.ClearMetadata()
+		var buf bytes.Buffer
+		if err := pdf.Output(&buf); err != nil {
+			t.Fatal(err)
+		}
+		str := buf.String()
+		for _, key := range []string{"/Info", "/Author", "/Producer", "/CreationDate"} {
+			if strings.Contains(str, key) {
+				t.Errorf("linearized %v: document contains %s", linearized, key)
+			}
+		}
+		m := regexp.MustCompile(`/Metadata (\d+) 0 R`).FindStringSubmatch(str)
+		if m == nil {
+			t.Errorf("linearized %v: catalog has no metadata stream", linearized)
+			continue
+		}
+		obj := "\n" + m[1] + " 0 obj\n<</Type /Metadata /Subtype /XML /Length "
+		if !strings.Contains(str, obj) || !strings.Contains(str, "<rdf:Description rdf:about=\"\"/>") {
+			t.Errorf("linearized %v: metadata stream %s 0 R is missing or not empty", linearized, m[1])
+		}
+	}
+}
+
+// TestOutputPrecision verifies that the output precision applies to the
+// operators of the page content and not to the values of object
+// dictionaries
+func TestOutputPrecision(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetOutputPrecision(4)
+	pdf.AddPage()
+	pdf.SetDrawColor(255, 0, 0)
+	pdf.Rect(10, 10, 100/3.0, 20, "D")
+	pdf.LinkString(10, 10, 100/3.0, 20, "https://github.com/jung-kurt/gofpdf")
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	str := buf.String()
+	for _, s := range []string{
+		"1.00000 0.00000 0.00000 RG",
+		"28.3465 813.5435 94.4882 -56.6929 re S",
+		"/MediaBox [0 0 595.28 841.89]",
+		"/Rect [28.35 813.54 122.83 756.85]",
+	} {
+		if !strings.Contains(str, s) {
+			t.Errorf("document does not contain %q", s)
+		}
+	}
+}
+
+// TestSignatureFieldAppearanceError verifies that an appearance that fails
+// leaves neither its content on the page nor its drawing settings in effect
+func TestSignatureFieldAppearanceError(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.AddPage()
+	pdf.SetLineWidth(0.5)
+	pdf.SignatureField("Signature1", 20, 40, 90, 35, func() {
+		pdf.SetDrawColor(255, 0, 0)
+		pdf.SetLineWidth(2)
+		pdf.Text(20, 50, "Appearance")
+		pdf.SetY(290)
+		pdf.Cell(0, 10, "Overflow")
+	})
+	if err := pdf.Error(); err == nil || !strings.Contains(err.Error(), "does not fit") {
+		t.Fatalf("unexpected error %v", err)
+	}
+	pdf.ClearError()
+	if r, g, b := pdf.GetDrawColor(); r != 0 || g != 0 || b != 0 {
+		t.Errorf("draw color is %d %d %d after the failed appearance", r, g, b)
+	}
+	if w := pdf.GetLineWidth(); w != 0.5 {
+		t.Errorf("line width is %.2f after the failed appearance", w)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "(Appearance)") || strings.Contains(buf.String(), "(Overflow)") {
+		t.Errorf("content of the failed appearance remains on the page")
+	}
+}
+
+// TestMultiCellBorderFooter verifies that the bottom border that closes the
+// part of a block left behind on a page is drawn with the settings of the
+// block rather than those left by the footer
+func TestMultiCellBorderFooter(t *testing.T) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.SetFooterFunc(func() {
+		pdf.SetDrawColor(255, 0, 0)
+		pdf.SetLineWidth(1)
+		pdf.Line(10, 290, 200, 290)
+	})
+	pdf.AddPage()
+	pdf.SetDrawColor(0, 0, 255)
+	pdf.SetLineWidth(0.3)
+	pdf.SetY(260)
+	pdf.MultiCell(100, 10, strings.Repeat("Line\n", 6), "1", "L", false)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile(`q 0\.000 0\.000 1\.000 RG 0\.85 w [\d.]+ [\d.]+ m [\d.]+ [\d.]+ l S Q`)
+	if n := len(re.FindAllString(buf.String(), -1)); n != 1 {
+		t.Errorf("found %d bottom borders with the settings of the block, want 1", n)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// The simple font of a TrueType font addresses its glyphs with single-byte
+// codes. Text that is printed by glyph index, because glyph substitutions
+// apply to it, is printed instead with a Type0 font that accompanies the
+// simple font. Its two-byte character codes are the glyph indexes of the
+// embedded font file, which the two fonts share. The Type0 font of a
+// TrueType font is named /G followed by the number that follows /F in the
+// name of the simple font, and it is written only if glyphs have been
+// printed with it.
+
+// glyphLookups returns the GSUB lookups of the features requested with
+// SetFontFeatures() that the current font provides. A nil list indicates that
+// text in the current font is printed with the simple font as usual. The
+// numbers printed by NumberCell() are printed by glyph index only if the font
+// provides the requested "tnum" feature, the tabular figures of which take
+// the place of the padded digits.
+func (f *Fpdf) glyphLookups() []int {
+	font := f.currentFont
+	if len(f.fontFeatures) == 0 || font == nil || font.gsub == nil {
+		return nil
+	}
+	if f.tabularFigures {
+		tnum := false
+		for _, tag := range f.fontFeatures {
+			tnum = tnum || tag == "tnum"
+		}
+		if _, ok := font.gsub.features["tnum"]; !ok || !tnum {
+			return nil
+		}
+	}
+	return font.gsub.lookupList(f.fontFeatures)
+}
+
+// shapeText converts s to glyphs of the current font and applies to them the
+// substitutions of the requested font features. s is taken as UTF-8 if it is
+// valid UTF-8 and as cp1252 otherwise. Nil is returned if s is printed with
+// the simple font.
+func (f *Fpdf) shapeText(s string) []gsubGlyph {
+	lookups := f.glyphLookups()
+	if lookups == nil {
+		return nil
+	}
+	font := f.currentFont
+	if !utf8.ValidString(s) {
+		s = cp1252Decode(s)
+	}
+	glyphs := make([]gsubGlyph, 0, len(s))
+	for _, r := range s {
+		gid, ok := font.gids[r]
+		if !ok && r == nbsp {
+			gid = font.gids[' ']
+		}
+		glyphs = append(glyphs, gsubGlyph{gid, string(r)})
+	}
+	glyphs = font.gsub.apply(glyphs, lookups)
+	for j := range glyphs {
+		if int(glyphs[j].gid) >= len(font.gw) {
+			// Substituted by a glyph the font does not have
+			glyphs[j].gid = 0
+		}
+	}
+	return glyphs
+}
+
+// glyphWidth returns the width of glyphs in glyph space
+func (font *fontType) glyphWidth(glyphs []gsubGlyph) (w int) {
+	for _, g := range glyphs {
+		w += font.gw[g.gid]
+	}
+	return
+}
+
+// wordWidth returns the width in glyph space of the word that begins at
+// position i of s and the position just past it, as fontType.wordWidth() does
+// for the current font, except that text printed by glyph index is measured
+// with its substituted glyphs
+func (f *Fpdf) wordWidth(s string, i int) (w, end int) {
+	if f.glyphLookups() == nil {
+		return f.currentFont.wordWidth(s, i)
+	}
+	end = wordEnd(s, i)
+	return f.currentFont.glyphWidth(f.shapeText(s[i:end])), end
+}
+
+// glyphShow returns the operators that print glyphs with the Type0 font of
+// the current font. The Type0 font is selected for the purpose and the simple
+// font is selected again afterward. The glyphs are recorded for the widths
+// and the character map of the Type0 font.
+func (f *Fpdf) glyphShow(glyphs []gsubGlyph) string {
+	font := f.currentFont
+	if font.glyphs == nil {
+		font.glyphs = make(map[uint16]string)
+	}
+	var s fmtBuffer
+	s.printf(f.precision("/G%d %.2f Tf "), font.I, f.fontSizePt)
+	// Word spacing applies only to the single-byte code 32, so spaces are
+	// widened by adjustments of the glyph positions instead
+	adj := -f.ws * f.k * 1000 / f.fontSizePt
+	if f.ws != 0 {
+		s.WriteString("[")
+	}
+	s.WriteString("<")
+	for _, g := range glyphs {
+		if font.glyphs[g.gid] == "" {
+			font.glyphs[g.gid] = g.text
+		}
+		s.printf("%04X", g.gid)
+		if f.ws != 0 && g.text == " " {
+			s.printf(f.precision(">%.3f<"), adj)
+		}
+	}
+	s.WriteString(">")
+	if f.ws != 0 {
+		s.WriteString("] TJ")
+	} else {
+		s.WriteString(" Tj")
+	}
+	s.printf(f.precision(" /F%d %.2f Tf"), font.I, f.fontSizePt)
+	return s.String()
+}
+
+// putglyphfont writes the Type0 font of font, which shares the embedded font
+// file and the font descriptor, the object number of which is descriptor,
+// with it. Only the widths of the glyphs that have been printed are listed.
+func (f *Fpdf) putglyphfont(font *fontType, descriptor int) {
+	gids := make([]int, 0, len(font.glyphs))
+	for gid := range font.glyphs {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+	f.newobj()
+	font.glyphN = f.n
+	f.out("<</Type /Font")
+	f.outf("/BaseFont /%s", font.Name)
+	f.out("/Subtype /Type0")
+	f.out("/Encoding /Identity-H")
+	f.outf("/DescendantFonts [%d 0 R]", f.n+1)
+	f.outf("/ToUnicode %d 0 R", f.n+2)
+	f.out(">>")
+	f.out("endobj")
+
+	// Descendant font
+	f.newobj()
+	f.out("<</Type /Font")
+	f.outf("/BaseFont /%s", font.Name)
+	f.out("/Subtype /CIDFontType2")
+	f.out("/CIDSystemInfo <</Registry (Adobe) /Ordering (Identity) /Supplement 0>>")
+	f.outf("/FontDescriptor %d 0 R", descriptor)
+	f.outf("/DW %d", font.Desc.MissingWidth)
+	var s fmtBuffer
+	s.WriteString("/W [")
+	for j, gid := range gids {
+		if j > 0 {
+			s.WriteString(" ")
+		}
+		s.printf("%d [%d]", gid, font.gw[gid])
+	}
+	s.WriteString("]")
+	f.out(s.String())
+	f.out("/CIDToGIDMap /Identity")
+	f.out(">>")
+	f.out("endobj")
+
+	// Mapping of glyph indexes to Unicode for text extraction
+	f.newobj()
+	f.putcontent(glyphCMap(font, gids))
+}
+
+// glyphCMap returns the CMap that maps the glyph indexes in gids, printed with
+// the Type0 font of font, to the text they represent
+func glyphCMap(font *fontType, gids []int) []byte {
+	var s fmtBuffer
+	s.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	s.WriteString("/CIDSystemInfo <</Registry (Adobe) /Ordering (UCS) /Supplement 0>> def\n")
+	s.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	s.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// Glyphs printed by index with DrawGlyph() may represent no text
+	mapped := make([]int, 0, len(gids))
+	for _, gid := range gids {
+		if font.glyphs[uint16(gid)] != "" {
+			mapped = append(mapped, gid)
+		}
+	}
+	// A bfchar section holds at most 100 mappings
+	for j := 0; j < len(mapped); j += 100 {
+		end := j + 100
+		if end > len(mapped) {
+			end = len(mapped)
+		}
+		s.printf("%d beginbfchar\n", end-j)
+		for _, gid := range mapped[j:end] {
+			s.printf("<%04X> <%X>\n", gid, utf8toutf16(font.glyphs[uint16(gid)], false))
+		}
+		s.WriteString("endbfchar\n")
+	}
+	s.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+	return []byte(s.String())
+}
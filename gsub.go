@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"sort"
+)
+
+// Lookup types of the GSUB table that are applied
+const (
+	gsubSingle   = 1
+	gsubLigature = 4
+	gsubExtended = 7
+)
+
+// gsubType holds the glyph substitutions of a font, taken from its GSUB
+// table. Only the features of the default language system of one script are
+// kept: the default script if there is one, otherwise Latin, otherwise the
+// first script of the font.
+type gsubType struct {
+	features map[string][]int // lookup indexes by feature tag
+	lookups  [][]gsubSubtable // subtables of each lookup
+}
+
+// gsubSubtable is a subtable of a GSUB lookup. Subtables of extension
+// lookups are replaced by the subtables they point to.
+type gsubSubtable struct {
+	tp   int // lookup type
+	data beReader
+}
+
+// gsubGlyph is a glyph of shaped text along with the text it represents. The
+// text of a ligature is that of the characters it replaces.
+type gsubGlyph struct {
+	gid  uint16
+	text string
+}
+
+// parseGsub reads the GSUB table of a font. Nil is returned if the table
+// provides no features. Offsets that point beyond the table are read as
+// zeros, which leave the glyphs concerned unchanged.
+func parseGsub(data []byte) *gsubType {
+	b := beReader(data)
+	if len(b) < 10 || b.u16(0) != 1 {
+		return nil
+	}
+	scripts := b.u16(4)
+	featList := b.u16(6)
+	lookupList := b.u16(8)
+	// Script, preferring DFLT to latn to the first one listed
+	script := -1
+	for j := 0; j < b.u16(scripts); j++ {
+		rec := scripts + 2 + 6*j
+		tag := string(b.slice(rec, 4))
+		if script < 0 || tag == "DFLT" || tag == "latn" && string(b.slice(script, 4)) != "DFLT" {
+			script = rec
+		}
+	}
+	if script < 0 {
+		return nil
+	}
+	scriptTbl := scripts + b.u16(script+4)
+	langSys := b.u16(scriptTbl)
+	if langSys == 0 {
+		if b.u16(scriptTbl+2) == 0 {
+			return nil
+		}
+		// The first language system stands in for the default one
+		langSys = b.u16(scriptTbl + 8)
+	}
+	langSys += scriptTbl
+	g := &gsubType{features: make(map[string][]int)}
+	// Features of the language system, including the required one
+	featIdx := make([]int, 0, b.u16(langSys+4)+1)
+	if req := b.u16(langSys + 2); req != 0xFFFF {
+		featIdx = append(featIdx, req)
+	}
+	for j := 0; j < b.u16(langSys+4); j++ {
+		featIdx = append(featIdx, b.u16(langSys+6+2*j))
+	}
+	for _, idx := range featIdx {
+		if idx >= b.u16(featList) {
+			continue
+		}
+		rec := featList + 2 + 6*idx
+		tag := string(b.slice(rec, 4))
+		feat := featList + b.u16(rec+4)
+		for k := 0; k < b.u16(feat+2); k++ {
+			g.features[tag] = append(g.features[tag], b.u16(feat+4+2*k))
+		}
+	}
+	if len(g.features) == 0 {
+		return nil
+	}
+	g.lookups = make([][]gsubSubtable, b.u16(lookupList))
+	for j := range g.lookups {
+		lookup := lookupList + b.u16(lookupList+2+2*j)
+		tp := b.u16(lookup)
+		for k := 0; k < b.u16(lookup+4); k++ {
+			sub := lookup + b.u16(lookup+6+2*k)
+			subTp := tp
+			if tp == gsubExtended {
+				subTp = b.u16(sub + 2)
+				sub += b.u32(sub + 4)
+			}
+			if sub < len(b) {
+				g.lookups[j] = append(g.lookups[j], gsubSubtable{subTp, b[sub:]})
+			}
+		}
+	}
+	return g
+}
+
+// lookupList returns the indexes of the lookups of the features in tags that
+// the font provides. The lookups are in the order of the lookup list, in
+// which they are to be applied.
+func (g *gsubType) lookupList(tags []string) (list []int) {
+	seen := make(map[int]bool)
+	for _, tag := range tags {
+		for _, idx := range g.features[tag] {
+			if idx < len(g.lookups) && !seen[idx] {
+				seen[idx] = true
+				list = append(list, idx)
+			}
+		}
+	}
+	sort.Ints(list)
+	return
+}
+
+// apply applies the specified lookups, in turn, to glyphs and returns the
+// substituted glyphs
+func (g *gsubType) apply(glyphs []gsubGlyph, lookups []int) []gsubGlyph {
+	for _, idx := range lookups {
+		out := make([]gsubGlyph, 0, len(glyphs))
+		for pos := 0; pos < len(glyphs); {
+			n := 0
+			for _, sub := range g.lookups[idx] {
+				var glyph gsubGlyph
+				if glyph, n = sub.substitute(glyphs[pos:]); n > 0 {
+					out = append(out, glyph)
+					break
+				}
+			}
+			if n == 0 {
+				out = append(out, glyphs[pos])
+				n = 1
+			}
+			pos += n
+		}
+		glyphs = out
+	}
+	return glyphs
+}
+
+// substitute applies the subtable to the glyphs at the start of glyphs. It
+// returns the replacement glyph and the number of glyphs it replaces, or 0
+// if the subtable does not apply.
+func (sub gsubSubtable) substitute(glyphs []gsubGlyph) (glyph gsubGlyph, n int) {
+	b := sub.data
+	format := b.u16(0)
+	ofs := b.u16(2)
+	cov := gsubCoverage(b.slice(ofs, len(b)-ofs), glyphs[0].gid)
+	if cov < 0 {
+		return
+	}
+	switch {
+	case sub.tp == gsubSingle && format == 1:
+		// The glyph index is offset by a constant
+		glyph = glyphs[0]
+		glyph.gid = uint16(int(glyph.gid) + int(int16(b.u16(4))))
+		return glyph, 1
+	case sub.tp == gsubSingle && format == 2:
+		if cov < b.u16(4) {
+			glyph = glyphs[0]
+			glyph.gid = uint16(b.u16(6 + 2*cov))
+			return glyph, 1
+		}
+	case sub.tp == gsubLigature && format == 1:
+		// The first ligature of the set whose components follow applies
+		if cov >= b.u16(4) {
+			return
+		}
+		set := b.u16(6 + 2*cov)
+		for j := 0; j < b.u16(set); j++ {
+			lig := set + b.u16(set+2+2*j)
+			count := b.u16(lig + 2)
+			if count == 0 || count > len(glyphs) {
+				continue
+			}
+			k := 1
+			for k < count && uint16(b.u16(lig+4+2*(k-1))) == glyphs[k].gid {
+				k++
+			}
+			if k == count {
+				glyph.gid = uint16(b.u16(lig))
+				for _, g := range glyphs[:count] {
+					glyph.text += g.text
+				}
+				return glyph, count
+			}
+		}
+	}
+	return
+}
+
+// gsubCoverage returns the coverage index of gid in the coverage table b, or
+// -1 if gid is not covered
+func gsubCoverage(b beReader, gid uint16) int {
+	id := int(gid)
+	switch b.u16(0) {
+	case 1:
+		count := b.u16(2)
+		j := sort.Search(count, func(j int) bool { return b.u16(4+2*j) >= id })
+		if j < count && b.u16(4+2*j) == id {
+			return j
+		}
+	case 2:
+		count := b.u16(2)
+		j := sort.Search(count, func(j int) bool { return b.u16(4+6*j+2) >= id })
+		if j < count && b.u16(4+6*j) <= id {
+			return b.u16(4+6*j+4) + id - b.u16(4+6*j)
+		}
+	}
+	return -1
+}
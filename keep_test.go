@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2013-2015 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"reflect"
+	"testing"
+)
+
+// keepRestored lists the fields of Fpdf holding slices or maps that
+// keepRollback() restores, directly or by deleting the pages that a trial
+// rendering started
+var keepRestored = []string{
+	"pages", "pageSizes", "pageBoxes", "blankPages", "pageTrans", "pageRotation",
+	"pageLinks", "links", "sigFields", "outlines", "namedDests", "fonts",
+	"images", "overprintList", "overprintMap", "blendList", "blendMap",
+	"gradientList", "patternList", "wordList", "structure", "list", "layer",
+	"rawObjs", "rawResources", "spillMap",
+}
+
+// keepExempt lists the fields of Fpdf holding slices or maps that a trial
+// rendering leaves alone or that need not be restored, with the reason
+var keepExempt = map[string]string{
+	"offsets":         "written with the document",
+	"pageObjs":        "written with the document",
+	"templateObjects": "written with the document",
+	"buffer":          "written with the document",
+	"templates":       "keyed by template ID, so using a template again has no effect",
+	"pagePool":        "holds unused page buffers",
+	"fontCache":       "holds fonts for reuse by other documents",
+	"stdPageSizes":    "setting",
+	"defPageBoxes":    "setting",
+	"tabStops":        "setting",
+	"fontFeatures":    "setting",
+	"protect":         "setting",
+	"dashArray":       "drawing context",
+	"contextList":     "drawing context, saved and restored in pairs by fnc",
+	"pathContext":     "drawing context of a path, which fnc completes",
+}
+
+// TestKeepTogetherCoverage verifies that every field of Fpdf that holds
+// slices or maps, directly or in a nested structure, is either restored by
+// keepRollback() or exempt. A field added without either fails the test, so
+// that the content it records cannot escape the rollback of KeepTogether()
+// unnoticed.
+func TestKeepTogetherCoverage(t *testing.T) {
+	var holds func(tp reflect.Type) bool
+	holds = func(tp reflect.Type) bool {
+		switch tp.Kind() {
+		case reflect.Slice, reflect.Map:
+			return true
+		case reflect.Array:
+			return holds(tp.Elem())
+		case reflect.Struct:
+			for j := 0; j < tp.NumField(); j++ {
+				if holds(tp.Field(j).Type) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	restored := make(map[string]bool)
+	for _, name := range keepRestored {
+		restored[name] = true
+	}
+	tp := reflect.TypeOf(Fpdf{})
+	for j := 0; j < tp.NumField(); j++ {
+		fld := tp.Field(j)
+		if !holds(fld.Type) {
+			continue
+		}
+		if _, ok := keepExempt[fld.Name]; !ok && !restored[fld.Name] {
+			t.Errorf("Fpdf.%s is neither restored by keepRollback() nor exempt", fld.Name)
+		}
+	}
+}
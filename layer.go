@@ -77,9 +77,7 @@ func (f *Fpdf) OpenLayerPane() {
 
 func (f *Fpdf) layerEndDoc() {
 	if len(f.layer.list) > 0 {
-		if f.pdfVersion < "1.5" {
-			f.pdfVersion = "1.5"
-		}
+		f.requirePDFVersion("1.5", "layers")
 	}
 }
 
@@ -87,17 +85,18 @@ func (f *Fpdf) layerPutLayers() {
 	for j, l := range f.layer.list {
 		f.newobj()
 		f.layer.list[j].objNum = f.n
-		f.outf("<</Type /OCG /Name %s>>", f.textstring(utf8toutf16(l.name)))
+		f.outf("<</Type /OCG /Name %s>>", f.textstring(l.name))
 		f.out("endobj")
 	}
 }
 
 func (f *Fpdf) layerPutResourceDict() {
-	if len(f.layer.list) > 0 {
+	if len(f.layer.list) > 0 || len(f.rawResources["Properties"]) > 0 {
 		f.out("/Properties <<")
 		for j, layer := range f.layer.list {
 			f.outf("/OC%d %d 0 R", j, layer.objNum)
 		}
+		f.putrawresources("Properties")
 		f.out(">>")
 	}
 
@@ -0,0 +1,526 @@
+/*
+ * Copyright (c) 2013-2016 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+// Linearization ("fast web view") is implemented as a pass over the completed
+// document. The objects written by enddoc() are split apart, renumbered so
+// that the first page and the document-level objects it needs occupy the
+// highest object numbers, and reassembled in the order described in annex F
+// of the PDF specification.
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// SetLinearized specifies whether the document is to be linearized when it
+// is output. A linearized ("web optimized") document is arranged so that its
+// first page can be displayed by a viewer before the entire file has been
+// downloaded. Linearization is off by default. It cannot be combined with
+// document protection; see SetProtection().
+func (f *Fpdf) SetLinearized(flag bool) {
+	f.linearize = flag
+}
+
+type linObjType struct {
+	num  int    // original object number
+	body []byte // object content between the "obj" and "endobj" keywords
+}
+
+// bitWriter packs unsigned values, most significant bit first, as required by
+// the hint tables of a linearized document
+type bitWriter struct {
+	buf   bytes.Buffer
+	acc   uint64
+	count uint
+}
+
+func (w *bitWriter) write(val uint64, bits uint) {
+	for j := bits; j > 0; j-- {
+		w.acc = (w.acc << 1) | ((val >> (j - 1)) & 1)
+		w.count++
+		if w.count == 8 {
+			w.buf.WriteByte(byte(w.acc))
+			w.acc = 0
+			w.count = 0
+		}
+	}
+}
+
+// flush pads the current byte with zero bits
+func (w *bitWriter) flush() {
+	if w.count > 0 {
+		w.write(0, 8-w.count)
+	}
+}
+
+// linSplitObjects returns the objects of the document as found in its buffer.
+// xrefPos is the position of the cross-reference table, which immediately
+// follows the last object.
+func (f *Fpdf) linSplitObjects(buf []byte, xrefPos int) (list map[int]*linObjType) {
+	type posType struct{ num, pos int }
+	posList := make([]posType, 0, f.n)
+	for j := 1; j <= f.n; j++ {
+		posList = append(posList, posType{j, f.offsets[j]})
+	}
+	gensort(len(posList),
+		func(a, b int) bool { return posList[a].pos < posList[b].pos },
+		func(a, b int) { posList[a], posList[b] = posList[b], posList[a] })
+	list = make(map[int]*linObjType)
+	for j, p := range posList {
+		end := xrefPos
+		if j+1 < len(posList) {
+			end = posList[j+1].pos
+		}
+		obj := buf[p.pos:end]
+		start := bytes.IndexByte(obj, '\n') + 1
+		stop := bytes.LastIndex(obj, []byte("endobj"))
+		if start <= 0 || stop < start {
+			f.err = fmt.Errorf("unable to linearize object %d", p.num)
+			return
+		}
+		list[p.num] = &linObjType{num: p.num, body: obj[start:stop]}
+	}
+	return
+}
+
+// linScanRefs calls fnc for each indirect reference ("n 0 R") found in the
+// dictionary portion of body, skipping literal strings and stream data. It
+// returns a copy of body in which each reference is replaced by the value
+// returned from fnc.
+func linScanRefs(body []byte, fnc func(num int) int) []byte {
+	dict := body
+	var stream []byte
+	if pos := bytes.Index(body, []byte("\nstream\n")); pos >= 0 {
+		dict, stream = body[:pos], body[pos:]
+	} else if bytes.HasPrefix(body, []byte("stream\n")) {
+		dict, stream = body[:0], body
+	}
+	var out bytes.Buffer
+	depth := 0
+	ln := len(dict)
+	for j := 0; j < ln; j++ {
+		c := dict[j]
+		switch {
+		case depth > 0:
+			out.WriteByte(c)
+			switch c {
+			case '\\':
+				if j+1 < ln {
+					j++
+					out.WriteByte(dict[j])
+				}
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+		case c == '(':
+			depth++
+			out.WriteByte(c)
+		case c >= '0' && c <= '9' && (j == 0 || !isDigitByte(dict[j-1])):
+			k := j
+			for k < ln && isDigitByte(dict[k]) {
+				k++
+			}
+			if bytes.HasPrefix(dict[k:], []byte(" 0 R")) && (k+4 == ln || !isRegularByte(dict[k+4])) {
+				num, _ := strconv.Atoi(string(dict[j:k]))
+				out.WriteString(strconv.Itoa(fnc(num)))
+				out.WriteString(" 0 R")
+				j = k + 3
+			} else {
+				out.Write(dict[j:k])
+				j = k - 1
+			}
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.Write(stream)
+	return out.Bytes()
+}
+
+func isDigitByte(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isRegularByte returns true if c is neither a PDF delimiter nor white space
+func isRegularByte(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return false
+	}
+	return true
+}
+
+// linDictRef returns the number of the object that the last entry named key
+// in dict refers to, or the first object if the value is an array, or 0 if
+// dict has no such entry. The last entry is taken because strings that
+// precede it might contain the key.
+func linDictRef(dict []byte, key string) (num int) {
+	pos := bytes.LastIndex(dict, []byte(key+" "))
+	if pos < 0 {
+		return
+	}
+	b := bytes.TrimLeft(dict[pos+len(key):], " [")
+	for j := 0; j < len(b) && isDigitByte(b[j]); j++ {
+		num = 10*num + int(b[j]-'0')
+	}
+	return
+}
+
+// linearizeDoc rearranges the completed document in f.buffer. xrefPos is the
+// position of the cross-reference table that concludes the document body.
+func (f *Fpdf) linearizeDoc(xrefPos int) {
+	if f.err != nil {
+		return
+	}
+	if f.protect.encrypted {
+		f.err = fmt.Errorf("linearization is not supported for protected documents")
+		return
+	}
+	src := f.buffer.Bytes()
+	headerLen := bytes.IndexByte(src, '\n') + 1
+	objList := f.linSplitObjects(src, xrefPos)
+	if f.err != nil {
+		return
+	}
+	nb := len(f.pages) - 1
+	trailer := src[xrefPos:]
+	catalogNum := linDictRef(trailer, "/Root")
+	infoNum := linDictRef(trailer, "/Info")
+	// The page tree root is reserved as object 1 by putpages()
+	const treeNum = 1
+	pageNum := func(p int) int { return f.pageObjs[p] }
+	isPageObj := make(map[int]bool)
+	for p := 1; p <= nb; p++ {
+		isPageObj[pageNum(p)] = true
+	}
+
+	// Collect the objects used by each page, that is, the objects reached
+	// from its page object without passing through the page tree, other
+	// pages, the catalog or the document information dictionary. They are
+	// listed in order of object number, led by the page object.
+	useList := make([][]int, nb+1)
+	users := make(map[int]int)
+	for p := 1; p <= nb; p++ {
+		seen := make(map[int]bool)
+		var visit func(num int)
+		visit = func(num int) {
+			obj, ok := objList[num]
+			if !ok || seen[num] || num == treeNum || num == catalogNum || num == infoNum ||
+				isPageObj[num] && num != pageNum(p) {
+				return
+			}
+			seen[num] = true
+			linScanRefs(obj.body, func(ref int) int {
+				visit(ref)
+				return ref
+			})
+		}
+		visit(pageNum(p))
+		list := make([]int, 0, len(seen))
+		for num := range seen {
+			users[num]++
+			if num != pageNum(p) {
+				list = append(list, num)
+			}
+		}
+		sort.Ints(list)
+		useList[p] = append([]int{pageNum(p)}, list...)
+	}
+
+	// Assign new object numbers. The objects of the pages other than the
+	// first, each page led by its page object, come first, followed by the
+	// objects that these pages share and the remaining objects, all numbered
+	// from 1 in their original order. The linearization dictionary, the
+	// catalog, the page tree root, the objects used by the first page and the
+	// primary hint stream follow.
+	firstSet := make(map[int]bool)
+	for _, num := range useList[1] {
+		firstSet[num] = true
+	}
+	var restList []int
+	pageCount := make([]int, nb+1)
+	pageCount[1] = len(useList[1])
+	for p := 2; p <= nb; p++ {
+		for _, num := range useList[p] {
+			if users[num] == 1 {
+				restList = append(restList, num)
+				pageCount[p]++
+			}
+		}
+	}
+	sharedStart := len(restList)
+	for j := 1; j <= f.n; j++ {
+		if users[j] > 1 && !firstSet[j] {
+			restList = append(restList, j)
+		}
+	}
+	sharedEnd := len(restList)
+	for j := 1; j <= f.n; j++ {
+		if users[j] == 0 && j != catalogNum && j != treeNum {
+			restList = append(restList, j)
+		}
+	}
+	firstList := append([]int{catalogNum, treeNum}, useList[1]...)
+	// Entries of the shared object hint table: the objects of the first page,
+	// then those of the shared objects section
+	sharedList := append(append([]int(nil), useList[1]...), restList[sharedStart:sharedEnd]...)
+	sharedIdx := make(map[int]int)
+	for j, num := range sharedList {
+		sharedIdx[num] = j
+	}
+	pageShared := make([][]int, nb+1)
+	for p := 2; p <= nb; p++ {
+		for _, num := range useList[p] {
+			if users[num] > 1 {
+				pageShared[p] = append(pageShared[p], sharedIdx[num])
+			}
+		}
+	}
+	numMap := make(map[int]int)
+	for j, num := range restList {
+		numMap[num] = j + 1
+	}
+	linNum := len(restList) + 1
+	for j, num := range firstList {
+		numMap[num] = linNum + 1 + j
+	}
+	hintNum := linNum + len(firstList) + 1
+	size := hintNum + 1
+	renumber := func(num int) int {
+		if n, ok := numMap[num]; ok {
+			return n
+		}
+		return num
+	}
+	objBytes := func(num int) []byte {
+		var b bytes.Buffer
+		b.WriteString(strconv.Itoa(numMap[num]))
+		b.WriteString(" 0 obj\n")
+		b.Write(linScanRefs(objList[num].body, renumber))
+		b.WriteString("endobj\n")
+		return b.Bytes()
+	}
+	objLen := make(map[int]int)
+	firstBytes := make([][]byte, len(firstList))
+	for j, num := range firstList {
+		firstBytes[j] = objBytes(num)
+		objLen[num] = len(firstBytes[j])
+	}
+	restBytes := make([][]byte, len(restList))
+	for j, num := range restList {
+		restBytes[j] = objBytes(num)
+		objLen[num] = len(restBytes[j])
+	}
+
+	// Layout. All values that are not known until the document has been
+	// assembled are written with fixed widths so that offsets remain stable.
+	fixed := func(v int) string { return sprintf("%010d", v) }
+	linDict := func(l, hOfs, hLen, e, t int) []byte {
+		return []byte(sprintf("%d 0 obj\n<</Linearized 1 /L %s /H [%s %s] /O %d /E %s /N %d /T %s>>\nendobj\n",
+			linNum, fixed(l), fixed(hOfs), fixed(hLen), numMap[pageNum(1)], fixed(e), nb, fixed(t)))
+	}
+	firstXref := func(offsets []int, prev int) []byte {
+		var b fmtBuffer
+		b.printf("xref\n%d %d\n", linNum, size-linNum)
+		for _, ofs := range offsets {
+			b.printf("%010d 00000 n \n", ofs)
+		}
+		b.printf("trailer\n<</Size %d /Prev %s /Root %d 0 R", size, fixed(prev), numMap[catalogNum])
+		if infoNum > 0 {
+			b.printf(" /Info %d 0 R", numMap[infoNum])
+		}
+		b.printf(" %s>>\nstartxref\n0\n%%%%EOF\n", f.fileIDEntry())
+		return b.Bytes()
+	}
+	hintObj := func(sharedPos int, data []byte) []byte {
+		var b fmtBuffer
+		b.printf("%d 0 obj\n<</Length %d /S %d>>\nstream\n", hintNum, len(data), sharedPos)
+		b.Write(data)
+		b.WriteString("\nendstream\nendobj\n")
+		return b.Bytes()
+	}
+
+	// The hint tables use fixed field widths, so the length of the hint stream
+	// does not depend on the offsets and lengths it records. Each page entry
+	// lists the shared objects used by the page and the shared object table
+	// has an entry, a group of one object, for each object of the first page
+	// and each object of the shared objects section.
+	least := func(list []int) (v int) {
+		v = list[0]
+		for _, x := range list {
+			if x < v {
+				v = x
+			}
+		}
+		return
+	}
+	hintData := func(firstOfs int, pageLen, contentOfs, contentLen, sharedLen []int,
+		sharedNum, sharedOfs int) ([]byte, int) {
+		var w bitWriter
+		deltas := func(list []int) {
+			minVal := least(list)
+			for _, v := range list {
+				w.write(uint64(v-minVal), 32)
+			}
+			w.flush()
+		}
+		counts := pageCount[1:]
+		w.write(uint64(least(counts)), 32)
+		w.write(uint64(firstOfs), 32)
+		w.write(32, 16)
+		w.write(uint64(least(pageLen)), 32)
+		w.write(32, 16)
+		w.write(uint64(least(contentOfs)), 32)
+		w.write(32, 16)
+		w.write(uint64(least(contentLen)), 32)
+		w.write(32, 16)
+		w.write(32, 16) // shared object references
+		w.write(32, 16) // shared object identifiers
+		w.write(0, 16)  // fractional position numerator
+		w.write(1, 16)  // fractional position denominator
+		deltas(counts)
+		deltas(pageLen)
+		for p := 1; p <= nb; p++ {
+			w.write(uint64(len(pageShared[p])), 32)
+		}
+		w.flush()
+		for p := 1; p <= nb; p++ {
+			for _, idx := range pageShared[p] {
+				w.write(uint64(idx), 32)
+			}
+		}
+		w.flush()
+		deltas(contentOfs)
+		deltas(contentLen)
+		sharedPos := w.buf.Len()
+		w.write(uint64(sharedNum), 32)
+		w.write(uint64(sharedOfs), 32)
+		w.write(uint64(len(useList[1])), 32)
+		w.write(uint64(len(sharedList)), 32)
+		w.write(0, 16) // objects in a group
+		w.write(uint64(least(sharedLen)), 32)
+		w.write(32, 16)
+		deltas(sharedLen)
+		for range sharedList {
+			w.write(0, 1) // signature
+		}
+		w.flush()
+		return w.buf.Bytes(), sharedPos
+	}
+
+	// First pass determines sizes, second pass uses actual offsets
+	var out bytes.Buffer
+	hOfs, hLen, e, t, l, prev, firstXrefPos := 0, 0, 0, 0, 0, 0, 0
+	for pass := 0; pass < 2; pass++ {
+		out.Reset()
+		out.Write(src[:headerLen])
+		out.Write(linDict(l, hOfs, hLen, e, t))
+		firstXrefPos = out.Len()
+		fpOffsets := make([]int, size-linNum)
+		fpOffsets[0] = headerLen
+		xrefLen := len(firstXref(fpOffsets, prev))
+		pos := firstXrefPos + xrefLen
+		objPos := make(map[int]int)
+		// Document-level objects precede the hint stream
+		for j := 0; j < 2; j++ {
+			objPos[firstList[j]] = pos
+			pos += len(firstBytes[j])
+		}
+		hOfs = pos
+		zeroList := make([]int, nb)
+		hd, sharedPos := hintData(0, zeroList, zeroList, zeroList, make([]int, len(sharedList)), 0, 0)
+		hLen = len(hintObj(sharedPos, hd))
+		pos += hLen
+		for j := 2; j < len(firstList); j++ {
+			objPos[firstList[j]] = pos
+			pos += len(firstBytes[j])
+		}
+		e = pos
+		for j, num := range restList {
+			objPos[num] = pos
+			pos += len(restBytes[j])
+		}
+		prev = pos
+		t = pos + len(sprintf("xref\n0 %d", linNum))
+		// Hint table values; offsets beyond the hint stream exclude its length
+		adj := func(ofs int) int {
+			if ofs > hOfs {
+				return ofs - hLen
+			}
+			return ofs
+		}
+		pageLen := make([]int, nb)
+		contentOfs := make([]int, nb)
+		contentLen := make([]int, nb)
+		for p := 1; p <= nb; p++ {
+			pg := pageNum(p)
+			content := linDictRef(objList[pg].body, "/Contents")
+			contentOfs[p-1] = objPos[content] - objPos[pg]
+			contentLen[p-1] = objLen[content]
+			if p == 1 {
+				pageLen[0] = e - objPos[pg]
+			} else {
+				for _, num := range useList[p] {
+					if users[num] == 1 {
+						pageLen[p-1] += objLen[num]
+					}
+				}
+			}
+		}
+		sharedLen := make([]int, len(sharedList))
+		for j, num := range sharedList {
+			sharedLen[j] = objLen[num]
+		}
+		sharedNum, sharedOfs := 0, 0
+		if sharedEnd > sharedStart {
+			num := restList[sharedStart]
+			sharedNum, sharedOfs = numMap[num], adj(objPos[num])
+		}
+		hd, sharedPos = hintData(adj(objPos[pageNum(1)]), pageLen, contentOfs, contentLen, sharedLen,
+			sharedNum, sharedOfs)
+		// Assemble
+		for j := range firstList {
+			fpOffsets[numMap[firstList[j]]-linNum] = objPos[firstList[j]]
+		}
+		fpOffsets[hintNum-linNum] = hOfs
+		out.Write(firstXref(fpOffsets, prev))
+		out.Write(firstBytes[0])
+		out.Write(firstBytes[1])
+		out.Write(hintObj(sharedPos, hd))
+		for j := 2; j < len(firstList); j++ {
+			out.Write(firstBytes[j])
+		}
+		for _, b := range restBytes {
+			out.Write(b)
+		}
+		var xref fmtBuffer
+		xref.printf("xref\n0 %d\n0000000000 65535 f \n", linNum)
+		for _, num := range restList {
+			xref.printf("%010d 00000 n \n", objPos[num])
+		}
+		xref.printf("trailer\n<</Size %d>>\nstartxref\n%d\n%%%%EOF\n", linNum, firstXrefPos)
+		out.Write(xref.Bytes())
+		l = out.Len()
+	}
+	f.buffer.Reset()
+	f.buffer.Write(out.Bytes())
+}
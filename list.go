@@ -0,0 +1,203 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type listRecType struct {
+	active   bool
+	styleStr string  // one marker style per level, the last repeats
+	x        float64 // left edge of the list
+	counts   []int   // item count per level
+	elems    []int   // L structure element per level, in tagged mode
+	bodies   []int   // LBody structure element of the last item per level
+}
+
+// BeginList starts a bulleted or numbered list. Items are added with ListItem()
+// and the list is concluded with EndList().
+//
+// styleStr specifies the marker used for each nesting level. Each character
+// applies to the corresponding level and the last one is used for any deeper
+// levels. "B" indicates a bullet (a disc at the first level, a circle at the
+// second and a square beyond that), "1" indicates decimal numbers, "a"
+// indicates lowercase letters and "A" indicates uppercase letters. For
+// example, "1aB" numbers the top level items, letters the second level items
+// and places bullets in front of the rest. An empty string is equivalent to
+// "B".
+//
+// The list begins at the current horizontal position. The ListItem() example
+// demonstrates this method.
+func (f *Fpdf) BeginList(styleStr string) {
+	if f.err != nil {
+		return
+	}
+	if f.list.active {
+		f.err = fmt.Errorf("BeginList called before the preceding list was ended")
+		return
+	}
+	for _, c := range styleStr {
+		switch c {
+		case 'B', '1', 'a', 'A':
+		default:
+			f.err = fmt.Errorf("invalid list style \"%s\"", styleStr)
+			return
+		}
+	}
+	if styleStr == "" {
+		styleStr = "B"
+	}
+	f.list = listRecType{active: true, styleStr: styleStr, x: f.x}
+	if f.structure.on {
+		f.list.elems = []int{f.structAdd("L", f.structTop())}
+	}
+}
+
+// ListItem adds an item to the list started with BeginList(). level is the
+// zero-based nesting level of the item. Each level is indented by three times
+// the current font size. The marker for the item is placed in the indentation
+// and txtStr is wrapped with MultiCell() so that continuation lines align with
+// the first line of text. The line height is 1.25 times the current font
+// size.
+//
+// Numbering at a given level continues until an item at a lower level is
+// added, at which point it restarts at one.
+func (f *Fpdf) ListItem(level int, txtStr string) {
+	if f.err != nil {
+		return
+	}
+	if !f.list.active {
+		f.err = fmt.Errorf("ListItem called outside of BeginList and EndList")
+		return
+	}
+	if level < 0 {
+		level = 0
+	}
+	for len(f.list.counts) <= level {
+		f.list.counts = append(f.list.counts, 0)
+	}
+	f.list.counts = f.list.counts[:level+1]
+	f.list.counts[level]++
+	indent := 3 * f.fontSize
+	h := 1.25 * f.fontSize
+	f.x = f.list.x + float64(level)*indent
+	style := f.list.styleStr[len(f.list.styleStr)-1]
+	if level < len(f.list.styleStr) {
+		style = f.list.styleStr[level]
+	}
+	var body int
+	if f.structure.on {
+		var lbl int
+		lbl, body = f.listTagItem(level)
+		f.structPush(lbl)
+	}
+	if style == 'B' {
+		f.CellFormat(indent, h, "", "", 0, "L", false, 0, "")
+		f.listBullet(level, f.x-indent+f.cMargin, f.y+h/2)
+	} else {
+		f.CellFormat(indent, h, listLabel(style, f.list.counts[level]), "", 0, "L", false, 0, "")
+	}
+	if f.structure.on {
+		f.structPop()
+		f.structPush(body)
+	}
+	f.MultiCell(f.w-f.rMargin-f.x, h, txtStr, "", "L", false)
+	if f.structure.on {
+		f.structPop()
+	}
+}
+
+// listTagItem adds the structure elements of an item at the specified level
+// and returns its Lbl and LBody elements. The list of a nested level is a
+// child of the LBody element of the last item at the level above.
+func (f *Fpdf) listTagItem(level int) (lbl, body int) {
+	if len(f.list.elems) == 0 {
+		f.list.elems = []int{f.structAdd("L", f.structTop())}
+	}
+	for lv := len(f.list.elems); lv <= level; lv++ {
+		parent := f.list.elems[lv-1]
+		if lv-1 < len(f.list.bodies) {
+			parent = f.list.bodies[lv-1]
+		}
+		f.list.elems = append(f.list.elems, f.structAdd("L", parent))
+	}
+	f.list.elems = f.list.elems[:level+1]
+	if len(f.list.bodies) > level {
+		f.list.bodies = f.list.bodies[:level]
+	}
+	li := f.structAdd("LI", f.list.elems[level])
+	lbl = f.structAdd("Lbl", li)
+	body = f.structAdd("LBody", li)
+	for len(f.list.bodies) < level {
+		f.list.bodies = append(f.list.bodies, f.list.elems[len(f.list.bodies)])
+	}
+	f.list.bodies = append(f.list.bodies, body)
+	return
+}
+
+// EndList concludes the list started with BeginList() and restores the
+// horizontal position to the left edge of the list.
+func (f *Fpdf) EndList() {
+	if f.err != nil {
+		return
+	}
+	if !f.list.active {
+		f.err = fmt.Errorf("EndList called without a matching BeginList")
+		return
+	}
+	f.x = f.list.x
+	f.list = listRecType{}
+}
+
+// listBullet draws the bullet for the specified nesting level with its left
+// edge at x and centered vertically on y. The bullet is drawn in the current
+// text color.
+func (f *Fpdf) listBullet(level int, x, y float64) {
+	r := 0.15 * f.fontSize
+	clr := f.color.text
+	f.out("q")
+	f.out(clr.str)
+	f.out(f.colorValue(clr.ir, clr.ig, clr.ib, "G", "RG").str)
+	f.outf("%.2f w", 0.1*f.fontSize*f.k)
+	switch level {
+	case 0:
+		f.arc(x+r, y, r, r, 0, 0, 360, "F", false)
+	case 1:
+		f.arc(x+r, y, r, r, 0, 0, 360, "D", false)
+	default:
+		f.rect(x, y-r, 2*r, 2*r, "F")
+	}
+	f.out("Q")
+}
+
+// listLabel returns the marker text of item number n for the specified
+// numbering style.
+func listLabel(style byte, n int) string {
+	switch style {
+	case 'a', 'A':
+		var buf []byte
+		for n > 0 {
+			n--
+			buf = append([]byte{style + byte(n%26)}, buf...)
+			n /= 26
+		}
+		return string(buf) + "."
+	}
+	return strconv.Itoa(n) + "."
+}
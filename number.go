@@ -0,0 +1,192 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// NumberFormatType specifies how numbers are formatted by FormatNumber() and
+// NumberCell(). It is set with SetNumberFormat().
+//
+// DecimalSepStr separates the integer part from the fractional digits, for
+// example "." in the United States or "," in much of Europe. An empty value
+// is replaced with ".".
+//
+// ThousandsSepStr separates each group of three integer digits, for example
+// "," or "." respectively. It may be empty for no grouping, or a space or any
+// other string. The separators are inserted as given and printed like the
+// rest of the text of the cell; they are not subject to any conversion of
+// character encoding.
+//
+// Decimals is the number of fractional digits. Values are rounded to this
+// precision.
+//
+// PrefixStr and SuffixStr are placed before and after the number, for example
+// "$" or " EUR". A minus sign precedes the prefix of negative numbers.
+type NumberFormatType struct {
+	DecimalSepStr   string
+	ThousandsSepStr string
+	Decimals        int
+	PrefixStr       string
+	SuffixStr       string
+}
+
+// SetNumberFormat sets the format used by FormatNumber() and NumberCell(). By
+// default, numbers are printed with two decimals in the United States style,
+// for example 1,234.56. The decimal separator and the number of decimals are
+// also applied to the decimal alignment mode of CellFormat(), as if passed to
+// SetDecimalAlign() with the current pad string, so that "D" alignment of
+// formatted numbers works with either style.
+func (f *Fpdf) SetNumberFormat(format NumberFormatType) {
+	if format.DecimalSepStr == "" {
+		format.DecimalSepStr = "."
+	}
+	if format.Decimals < 0 {
+		format.Decimals = 0
+	}
+	f.numberFormat = format
+	f.SetDecimalAlign(format.DecimalSepStr, f.decimalPadStr, format.Decimals)
+}
+
+// GetNumberFormat returns the format set with SetNumberFormat().
+func (f *Fpdf) GetNumberFormat() NumberFormatType {
+	return f.numberFormat
+}
+
+// FormatNumber returns value formatted as specified with SetNumberFormat(),
+// for example "1,234.56" or "1.234,56".
+func (f *Fpdf) FormatNumber(value float64) string {
+	nf := f.numberFormat
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	numStr := strconv.FormatFloat(math.Abs(value), 'f', nf.Decimals, 64)
+	intStr, fracStr := numStr, ""
+	if pos := strings.IndexByte(numStr, '.'); pos >= 0 {
+		intStr, fracStr = numStr[:pos], numStr[pos+1:]
+	}
+	var buf bytes.Buffer
+	// A value that rounds to zero is printed without a sign
+	if value < 0 && strings.Trim(numStr, "0.") != "" {
+		buf.WriteString("-")
+	}
+	buf.WriteString(nf.PrefixStr)
+	for j := 0; j < len(intStr); j++ {
+		if j > 0 && (len(intStr)-j)%3 == 0 {
+			buf.WriteString(nf.ThousandsSepStr)
+		}
+		buf.WriteByte(intStr[j])
+	}
+	if fracStr != "" {
+		buf.WriteString(nf.DecimalSepStr)
+		buf.WriteString(fracStr)
+	}
+	buf.WriteString(nf.SuffixStr)
+	return buf.String()
+}
+
+// NumberCell prints value, formatted with FormatNumber(), in a cell. The
+// arguments other than value are those of CellFormat(). An empty alignStr is
+// replaced with "R"; since the numbers all have the same number of decimals,
+// this lines them up on their decimal separators. Include "D" in alignStr to
+// line them up with numbers that have been formatted differently.
+//
+// The digits are printed as tabular figures, each occupying the width of the
+// widest digit of the current font, so that the digits of the numbers in a
+// column line up even if the figures of the font are proportional. Each
+// digit is centered in the common width. Fonts whose digits already share a
+// width, which include the core fonts, are printed as usual. If the OpenType
+// "tnum" feature has been requested with SetFontFeatures() and the font
+// provides it, the tabular figures of the font are printed instead.
+func (f *Fpdf) NumberCell(w, h, value float64, borderStr string, ln int, alignStr string, fill bool) {
+	if alignStr == "" {
+		alignStr = "R"
+	}
+	f.tabularFigures = true
+	f.CellFormat(w, h, f.FormatNumber(value), borderStr, ln, alignStr, fill, 0, "")
+	f.tabularFigures = false
+}
+
+// figureWidth returns the width of the widest digit of font, or 0 if all of
+// its digits have the same width or its characters are not addressed by
+// cp1252 codes
+func (font *fontType) figureWidth() (wd int) {
+	if font.Enc != nil {
+		return 0
+	}
+	uniform := true
+	for ch := '0'; ch <= '9'; ch++ {
+		cw := font.Cw[ch]
+		if ch > '0' && cw != wd {
+			uniform = false
+		}
+		if cw > wd {
+			wd = cw
+		}
+	}
+	if uniform {
+		return 0
+	}
+	return
+}
+
+// figurePadding returns the width, in glyph units, that is added to txtStr
+// when its digits are printed as tabular figures of width figWd
+func (font *fontType) figurePadding(txtStr string, figWd int) (pad int) {
+	for j := 0; j < len(txtStr); j++ {
+		if ch := txtStr[j]; ch >= '0' && ch <= '9' {
+			pad += figWd - font.Cw[rune(ch)]
+		}
+	}
+	return
+}
+
+// tabularArray returns the elements of a TJ array that prints txtStr in the
+// current font with each digit centered in the width figWd
+func (f *Fpdf) tabularArray(txtStr string, figWd int) string {
+	var buf bytes.Buffer
+	var start, adj int
+	// run writes the characters that precede end, preceded by the pending
+	// adjustment, and then adds shift to the adjustment
+	run := func(end, shift int) {
+		if end > start {
+			if adj > 0 {
+				buf.WriteString(strconv.Itoa(-adj))
+				adj = 0
+			}
+			buf.WriteString("(" + f.escape(txtStr[start:end]) + ")")
+		}
+		start = end
+		adj += shift
+	}
+	for j := 0; j < len(txtStr); j++ {
+		if ch := txtStr[j]; ch >= '0' && ch <= '9' {
+			pad := figWd - f.currentFont.Cw[rune(ch)]
+			run(j, pad/2)
+			run(j+1, pad-pad/2)
+		}
+	}
+	run(len(txtStr), 0)
+	if adj > 0 {
+		buf.WriteString(strconv.Itoa(-adj))
+	}
+	return buf.String()
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+// optionsType collects the settings made by the options passed to
+// NewWithOptions()
+type optionsType struct {
+	init  InitType
+	setup []func(f *Fpdf)
+}
+
+// Option configures a new Fpdf instance created with NewWithOptions().
+type Option func(opt *optionsType)
+
+// NewWithOptions returns a pointer to a new Fpdf instance configured by the
+// specified options, which are applied in order. Settings that are not
+// specified have the same defaults as in New(). Unlike the positional
+// arguments of New(), options can be added in later versions of this package
+// without affecting existing callers:
+//
+//	pdf := gofpdf.NewWithOptions(
+//		gofpdf.WithUnit("pt"),
+//		gofpdf.WithSize("Letter"),
+//		gofpdf.WithCompression(false),
+//	)
+func NewWithOptions(options ...Option) (f *Fpdf) {
+	var opt optionsType
+	for _, o := range options {
+		o(&opt)
+	}
+	f = new(Fpdf)
+	f.newFnc = func(f *Fpdf) {
+		f.optionsInit(&opt)
+	}
+	f.optionsInit(&opt)
+	return
+}
+
+// optionsInit establishes the initial settings of f specified by opt
+func (f *Fpdf) optionsInit(opt *optionsType) {
+	f.customInit(&opt.init)
+	for _, fnc := range opt.setup {
+		if f.err != nil {
+			break
+		}
+		fnc(f)
+	}
+}
+
+// WithOrientation sets the default page orientation, "P" or "Portrait" for
+// portrait mode and "L" or "Landscape" for landscape mode. The default is
+// portrait mode.
+func WithOrientation(orientationStr string) Option {
+	return func(opt *optionsType) {
+		opt.init.OrientationStr = orientationStr
+	}
+}
+
+// WithUnit sets the unit of length used in size parameters: "pt", "mm", "cm"
+// or "in". The default is "mm". The lengths given to other options are
+// measured in this unit regardless of the order of the options.
+func WithUnit(unitStr string) Option {
+	return func(opt *optionsType) {
+		opt.init.UnitStr = unitStr
+	}
+}
+
+// WithSize sets the default page size to one of the standard sizes "A3",
+// "A4", "A5", "Letter" or "Legal". The default is "A4".
+func WithSize(sizeStr string) Option {
+	return func(opt *optionsType) {
+		opt.init.SizeStr = sizeStr
+	}
+}
+
+// WithCustomSize sets the default page size to the specified width and
+// height, overriding WithSize().
+func WithCustomSize(size SizeType) Option {
+	return func(opt *optionsType) {
+		opt.init.Size = size
+	}
+}
+
+// WithFontDir sets the file system location in which font resources are
+// found. The default is ".".
+func WithFontDir(fontDirStr string) Option {
+	return func(opt *optionsType) {
+		opt.init.FontDirStr = fontDirStr
+	}
+}
+
+// WithCompression specifies whether page content is compressed, as with
+// SetCompression(). Compression is enabled by default.
+func WithCompression(compress bool) Option {
+	return func(opt *optionsType) {
+		opt.setup = append(opt.setup, func(f *Fpdf) {
+			f.SetCompression(compress)
+		})
+	}
+}
+
+// WithLineWidth sets the default line width.
+func WithLineWidth(width float64) Option {
+	return func(opt *optionsType) {
+		opt.init.LineWidth = width
+	}
+}
+
+// WithDrawColor sets the default draw color as a hexadecimal string such as
+// "#1a2b3c".
+func WithDrawColor(hexStr string) Option {
+	return func(opt *optionsType) {
+		opt.init.DrawColorStr = hexStr
+	}
+}
+
+// WithFillColor sets the default fill color as a hexadecimal string such as
+// "#1a2b3c".
+func WithFillColor(hexStr string) Option {
+	return func(opt *optionsType) {
+		opt.init.FillColorStr = hexStr
+	}
+}
+
+// WithTextColor sets the default text color as a hexadecimal string such as
+// "#1a2b3c".
+func WithTextColor(hexStr string) Option {
+	return func(opt *optionsType) {
+		opt.init.TextColorStr = hexStr
+	}
+}
+
+// WithMargins sets the page margins and the automatic page break margin.
+func WithMargins(margins MarginsType) Option {
+	return func(opt *optionsType) {
+		opt.init.Margins = &margins
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+)
+
+// overprintType describes the overprint settings of the graphics state
+type overprintType struct {
+	fill, stroke bool
+	mode         int
+}
+
+// overprintStateType is an ExtGState resource that establishes overprint
+// settings
+type overprintStateType struct {
+	op     overprintType
+	objNum int
+}
+
+// SetOverprint specifies whether fills, text and images (fill) and strokes
+// (stroke) that are painted in a subtractive color space such as CMYK
+// overprint the content beneath them rather than knocking it out. With
+// overprint, the colorants of the underlying content that are not used by the
+// painted area are left intact when the document is separated for printing;
+// this is used, for example, to avoid gaps between adjoining colors when the
+// plates are misregistered. Most screen viewers ignore this setting unless
+// they simulate overprint. See SetOverprintMode() for the treatment of zero
+// colorant values.
+//
+// Overprint is off by default. The setting can be made before the first page
+// is created and is retained from page to page.
+func (f *Fpdf) SetOverprint(fill, stroke bool) {
+	op := f.overprint
+	op.fill, op.stroke = fill, stroke
+	f.setOverprint(op)
+}
+
+// SetOverprintMode sets the overprint mode, which applies to content painted
+// in a DeviceCMYK color space while overprint is enabled with SetOverprint().
+// In mode 0, the default, every colorant of the painted area replaces the
+// underlying one, even where its value is zero. In mode 1, colorants whose
+// value is zero leave the underlying ones unchanged, so that, for example,
+// cyan text printed over a magenta background shows both inks.
+func (f *Fpdf) SetOverprintMode(mode int) {
+	if f.err != nil {
+		return
+	}
+	if mode != 0 && mode != 1 {
+		f.err = fmt.Errorf("overprint mode must be 0 or 1, not %d", mode)
+		return
+	}
+	op := f.overprint
+	op.mode = mode
+	f.setOverprint(op)
+}
+
+// GetOverprint returns the overprint settings for fills and strokes and the
+// overprint mode. See SetOverprint() and SetOverprintMode().
+func (f *Fpdf) GetOverprint() (fill, stroke bool, mode int) {
+	return f.overprint.fill, f.overprint.stroke, f.overprint.mode
+}
+
+func (f *Fpdf) setOverprint(op overprintType) {
+	if f.err != nil || op == f.overprint {
+		return
+	}
+	f.overprint = op
+	if f.page > 0 {
+		f.outputOverprint()
+	}
+}
+
+// outputOverprint selects the graphics state resource that establishes the
+// current overprint settings, adding it if necessary
+func (f *Fpdf) outputOverprint() {
+	pos, ok := f.overprintMap[f.overprint]
+	if !ok {
+		if f.overprintMap == nil {
+			f.overprintMap = make(map[overprintType]int)
+		}
+		f.overprintList = append(f.overprintList, overprintStateType{op: f.overprint})
+		pos = len(f.overprintList)
+		f.overprintMap[f.overprint] = pos
+	}
+	f.outf("/OP%d gs", pos)
+}
+
+func (f *Fpdf) putOverprintStates() {
+	for j, st := range f.overprintList {
+		f.newobj()
+		f.overprintList[j].objNum = f.n
+		f.outf("<</Type /ExtGState /OP %t /op %t /OPM %d>>", st.op.stroke, st.op.fill, st.op.mode)
+		f.out("endobj")
+	}
+}
+
+func (f *Fpdf) putOverprintResources() {
+	for j, st := range f.overprintList {
+		f.outf("/OP%d %d 0 R", j+1, st.objNum)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+)
+
+// pageBoxType is a page boundary rectangle in user units, measured from the
+// upper left corner of the page
+type pageBoxType struct {
+	x, y, wd, ht float64
+}
+
+// pageBoxMap holds the boundaries of a page by name, for example "TrimBox"
+type pageBoxMap map[string]pageBoxType
+
+// pageBoxNames lists the page boundaries, other than the media box, in the
+// order in which they are written to the page dictionary
+var pageBoxNames = []string{"CropBox", "BleedBox", "TrimBox", "ArtBox"}
+
+// SetCropBox sets the crop box of the current page, the region to which the
+// page is clipped when it is displayed or printed. The rectangle is specified
+// by its upper left corner (x, y) and its extent (wd, ht) in the units
+// established in New(). If no page has been added yet, the box is applied to
+// every page that is subsequently added; otherwise it applies to the current
+// page only. A page for which no crop box is set is shown in its entirety.
+//
+// The box must lie within the page and have a positive extent.
+func (f *Fpdf) SetCropBox(x, y, wd, ht float64) {
+	f.setPageBox("CropBox", x, y, wd, ht)
+}
+
+// SetBleedBox sets the bleed box of the current page, the region to which the
+// page is clipped in a production environment. Content that extends past the
+// trim box to allow for imprecise cutting lies within it. See SetCropBox()
+// for a description of the arguments. The bleed box defaults to the crop box.
+func (f *Fpdf) SetBleedBox(x, y, wd, ht float64) {
+	f.setPageBox("BleedBox", x, y, wd, ht)
+}
+
+// SetTrimBox sets the trim box of the current page, the intended dimensions
+// of the finished page after it has been cut. See SetCropBox() for a
+// description of the arguments. The trim box defaults to the crop box.
+func (f *Fpdf) SetTrimBox(x, y, wd, ht float64) {
+	f.setPageBox("TrimBox", x, y, wd, ht)
+}
+
+// SetArtBox sets the art box of the current page, the extent of the page's
+// meaningful content. See SetCropBox() for a description of the arguments.
+// The art box defaults to the crop box.
+func (f *Fpdf) SetArtBox(x, y, wd, ht float64) {
+	f.setPageBox("ArtBox", x, y, wd, ht)
+}
+
+func (f *Fpdf) setPageBox(nameStr string, x, y, wd, ht float64) {
+	if f.err != nil {
+		return
+	}
+	if f.originBottom {
+		y = f.h - y - ht
+	}
+	const tolerance = 1e-6
+	if wd <= 0 || ht <= 0 {
+		f.err = fmt.Errorf("%s must have a positive width and height", nameStr)
+		return
+	}
+	if x < -tolerance || y < -tolerance || x+wd > f.w+tolerance || y+ht > f.h+tolerance {
+		f.err = fmt.Errorf("%s [%.2f %.2f %.2f %.2f] extends beyond the page", nameStr, x, y, wd, ht)
+		return
+	}
+	box := pageBoxType{x, y, wd, ht}
+	if f.page == 0 {
+		f.defPageBoxes[nameStr] = box
+		return
+	}
+	if f.pageBoxes[f.page] == nil {
+		f.pageBoxes[f.page] = make(pageBoxMap)
+	}
+	f.pageBoxes[f.page][nameStr] = box
+}
+
+// putpageboxes writes the boundaries that have been set for page n, whose
+// height is hPt points
+func (f *Fpdf) putpageboxes(n int, hPt float64) {
+	boxes := f.pageBoxes[n]
+	for _, nameStr := range pageBoxNames {
+		if box, ok := boxes[nameStr]; ok {
+			f.outf("/%s [%.2f %.2f %.2f %.2f]", nameStr, f.userSpace(box.x*f.k),
+				f.userSpace(hPt-(box.y+box.ht)*f.k), f.userSpace((box.x+box.wd)*f.k), f.userSpace(hPt-box.y*f.k))
+		}
+	}
+}
+
+// SetPageRotation sets the angle, in degrees clockwise, by which viewers and
+// printers rotate the current page when it is displayed or printed. degrees
+// must be a multiple of 90; negative values and values of 360 or more are
+// reduced to 0, 90, 180 or 270. This rotates the whole page, including its
+// boundaries, without affecting its content or the coordinates with which
+// the content is placed, which suits, for example, a landscape scan placed on
+// a portrait page. To rotate content on the page, use TransformRotate()
+// instead.
+//
+// If no page has been added yet, the rotation is applied to every page that
+// is subsequently added; otherwise it applies to the current page only.
+func (f *Fpdf) SetPageRotation(degrees int) {
+	if f.err != nil {
+		return
+	}
+	if degrees%90 != 0 {
+		f.err = fmt.Errorf("page rotation must be a multiple of 90 degrees: %d", degrees)
+		return
+	}
+	degrees = (degrees%360 + 360) % 360
+	if f.page == 0 {
+		f.defPageRotation = degrees
+		return
+	}
+	if degrees == 0 {
+		delete(f.pageRotation, f.page)
+		return
+	}
+	f.pageRotation[f.page] = degrees
+}
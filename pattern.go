@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"fmt"
+)
+
+type patternType struct {
+	w, h   float64 // cell size in points
+	y      float64 // bottom of cell in page coordinates, in points
+	data   []byte  // content stream of the cell
+	objNum int
+}
+
+// CreateTilingPattern creates a tiling pattern with a cell of width w and
+// height h and returns its identifier for use with SetFillPattern(). Filling
+// a shape with the pattern repeats the cell horizontally and vertically across
+// the shape.
+//
+// draw is called once to render the content of the cell. Within draw, the
+// usual drawing methods of this instance are used with coordinates relative to
+// the upper left corner of the cell, so that (0, 0) and (w, h) are the
+// opposite corners of the cell. Content outside of the cell is clipped. The
+// cell begins with the current font, and any changes to colors, line width,
+// the font or other drawing state made within draw apply only to the cell, as
+// does the current position. The cell is not rendered on the current page,
+// and links and word positions recorded within draw are discarded.
+//
+// The pattern is anchored at the upper left corner of the page, so adjacent
+// shapes filled with the same pattern line up seamlessly.
+func (f *Fpdf) CreateTilingPattern(w, h float64, draw func()) (id int) {
+	if f.err != nil {
+		return
+	}
+	if w <= 0 || h <= 0 {
+		f.err = fmt.Errorf("tiling pattern cell must have positive dimensions")
+		return
+	}
+	// The cell is drawn into a buffer of its own in place of the content of
+	// the current page, or of a temporary page if there is none yet
+	page, state := f.page, f.state
+	if page == 0 {
+		f.pages = append(f.pages, nil)
+		f.pageLinks = append(f.pageLinks, nil)
+		f.page = len(f.pages) - 1
+	}
+	var buf bytes.Buffer
+	pageBuf, pageLinks := f.pages[f.page], f.pageLinks[f.page]
+	f.pages[f.page], f.pageLinks[f.page] = &buf, nil
+	context := f.drawingContext()
+	lasth, keepPage, wordCount := f.lasth, f.keepPage, len(f.wordList)
+	acceptPageBreak := f.acceptPageBreak
+	f.acceptPageBreak = func() bool { return false }
+	// The content of the cell does not belong to the logical structure
+	stack, mcOpen := f.structure.stack, f.structure.mcOpen
+	f.structure.stack, f.structure.mcOpen = nil, false
+	f.state = 2
+	if f.currentFont != nil {
+		f.outf("BT /F%d %.2f Tf ET", f.currentFont.I, f.fontSizePt)
+	}
+	draw()
+	f.pages[f.page], f.pageLinks[f.page] = pageBuf, pageLinks
+	if page == 0 {
+		f.pages = f.pages[:len(f.pages)-1]
+		f.pageLinks = f.pageLinks[:len(f.pageLinks)-1]
+	}
+	f.page, f.state = page, state
+	f.acceptPageBreak = acceptPageBreak
+	f.structure.stack, f.structure.mcOpen = stack, mcOpen
+	f.wordList = f.wordList[:wordCount]
+	f.keepPage = keepPage
+	// The settings changed within draw were written to the cell only
+	f.stateRestore(&context)
+	f.underline, f.originBottom = context.underline, context.originBottom
+	f.x, f.y, f.lasth = context.x, context.y, lasth
+	if f.err != nil {
+		return
+	}
+	f.patternList = append(f.patternList, patternType{
+		w:    w * f.k,
+		h:    h * f.k,
+		y:    (f.h - h) * f.k,
+		data: buf.Bytes(),
+	})
+	return len(f.patternList) - 1
+}
+
+// SetFillPattern sets the tiling pattern identified by id, as returned by
+// CreateTilingPattern(), as the color used for all subsequent filling
+// operations. Call SetFillColor() to return to a solid fill.
+func (f *Fpdf) SetFillPattern(id int) {
+	if f.err != nil {
+		return
+	}
+	if id < 1 || id >= len(f.patternList) {
+		f.err = fmt.Errorf("invalid tiling pattern identifier: %d", id)
+		return
+	}
+	f.color.fill = clrType{str: sprintf("/Pattern cs /P%d scn", id)}
+	f.colorFlag = f.color.fill.str != f.color.text.str
+	if f.page > 0 {
+		f.out(f.color.fill.str)
+	}
+}
+
+func (f *Fpdf) putPatterns() {
+	filter := ""
+	if f.compress {
+		filter = "/Filter /FlateDecode "
+	}
+	for j := 1; j < len(f.patternList); j++ {
+		pt := f.patternList[j]
+		data := pt.data
+		if f.compress {
+			data = sliceCompress(data, f.compressLevel)
+		}
+		f.newobj()
+		f.patternList[j].objNum = f.n
+		f.outf("<<%s/Type /Pattern /PatternType 1 /PaintType 1 /TilingType 1", filter)
+		f.outf("/BBox [0 %.2f %.2f %.2f] /XStep %.2f /YStep %.2f", pt.y, pt.w, pt.y+pt.h, pt.w, pt.h)
+		if f.userUnit > 0 {
+			// Pattern space is the default coordinate space of the page
+			f.outf("/Matrix [%.6f 0 0 %.6f 0 0]", 1/f.userUnit, 1/f.userUnit)
+		}
+		f.outf("/Resources 2 0 R /Length %d>>", len(data))
+		f.putstream(data)
+		f.out("endobj")
+	}
+}
+
+func (f *Fpdf) patternPutResourceDict() {
+	if len(f.patternList) > 1 || len(f.rawResources["Pattern"]) > 0 {
+		f.out("/Pattern <<")
+		for j := 1; j < len(f.patternList); j++ {
+			f.outf("/P%d %d 0 R", j, f.patternList[j].objNum)
+		}
+		f.putrawresources("Pattern")
+		f.out(">>")
+	}
+}
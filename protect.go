@@ -41,6 +41,7 @@ type protectType struct {
 	pValue        int
 	padding       []byte
 	encryptionKey []byte
+	keyData       []byte // padded user password, O value and permissions hashed into the key
 	objNum        int
 	rc4cipher     *rc4.Cipher
 	rc4n          uint32 // Object number associated with rc4 cipher
@@ -107,8 +108,18 @@ func (p *protectType) setProtection(privFlag byte, userPassStr, ownerPassStr str
 	buf = append(buf, userPass...)
 	buf = append(buf, p.oValue...)
 	buf = append(buf, privFlag, 0xff, 0xff, 0xff)
+	p.keyData = buf
+	p.setFileID(nil)
+	p.pValue = -(int(privFlag^255) + 1)
+}
+
+// setFileID derives the encryption key from the first element of the file
+// identifier of the document, which must be known before any object is
+// encrypted
+func (p *protectType) setFileID(id []byte) {
+	buf := append(append([]byte(nil), p.keyData...), id...)
 	sum := md5.Sum(buf)
 	p.encryptionKey = sum[0:5]
 	p.uValue = p.uValueGen()
-	p.pValue = -(int(privFlag^255) + 1)
+	p.rc4cipher = nil
 }
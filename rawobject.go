@@ -0,0 +1,217 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The methods in this file let add-ons write PDF objects that this package
+// does not otherwise produce. They are advanced and unsafe: this package does
+// not check the objects for validity, and an object that does not conform to
+// the PDF specification results in a damaged document. An understanding of
+// the PDF specification is needed to use them correctly.
+//
+// Object numbers are assigned when the document is closed, so objects are
+// identified by the values returned by NewObject() until then. A raw object
+// is made available to page content by adding it to the resource dictionary
+// with AddResource(), after which content written with RawWriteStr() can
+// refer to it by name.
+
+// rawObjType holds an object allocated with NewObject()
+type rawObjType struct {
+	valueStr string
+	stream   []byte
+	set      bool
+	num      int // object number, assigned when the document is closed
+}
+
+// rawResourceCategories lists the resource categories to which raw objects
+// can be added
+var rawResourceCategories = []string{"ColorSpace", "ExtGState", "Font", "Pattern",
+	"Properties", "Shading", "XObject"}
+
+// rawRefPrefix and rawRefSuffix delimit the placeholders returned by
+// ObjectRef(). The NUL character does not occur in the syntax of PDF objects
+// outside of strings and streams.
+const (
+	rawRefPrefix = "\x00R"
+	rawRefSuffix = "\x00"
+)
+
+// NewObject allocates a new object and returns its identifier, which is used
+// with SetObject(), ObjectRef() and AddResource(). The content of the object
+// is specified with SetObject(). See the description of these methods for
+// caveats.
+func (f *Fpdf) NewObject() int {
+	f.rawObjs = append(f.rawObjs, rawObjType{})
+	return len(f.rawObjs)
+}
+
+// SetObject specifies the content of the object obj allocated with
+// NewObject(). It is written unchanged when the document is closed.
+//
+// If stream is nil, valueStr is the value of the object, for example a
+// dictionary such as "<</FunctionType 2 /Domain [0 1] /N 1>>". Otherwise the
+// object is a stream, and valueStr holds the entries of the stream dictionary
+// without the enclosing "<<" and ">>" delimiters. The /Length entry is added by
+// this method and must not be included. The stream data is written as is, so
+// any filter named in the dictionary must have been applied already.
+//
+// valueStr can refer to other objects allocated with NewObject() by means of
+// ObjectRef(). Strings within valueStr are not encrypted, so raw objects cannot
+// be combined with document protection; see SetProtection().
+func (f *Fpdf) SetObject(obj int, valueStr string, stream []byte) {
+	if obj < 1 || obj > len(f.rawObjs) {
+		f.err = fmt.Errorf("invalid raw object identifier %d", obj)
+		return
+	}
+	f.rawObjs[obj-1] = rawObjType{valueStr: valueStr, stream: stream, set: true}
+}
+
+// ObjectRef returns a placeholder that is replaced by an indirect reference,
+// such as "12 0 R", to the object obj allocated with NewObject() when the
+// document is closed. It is valid only within the values passed to
+// SetObject(); page content refers to objects by the names assigned with
+// AddResource().
+func (f *Fpdf) ObjectRef(obj int) string {
+	return rawRefPrefix + strconv.Itoa(obj) + rawRefSuffix
+}
+
+// AddResource adds the object obj allocated with NewObject() to the resource
+// dictionary of the document under nameStr, so that page content can refer to
+// it as "/" followed by nameStr. For example, a form XObject added with the
+// name "ChartA" is drawn by writing "/ChartA Do" with RawWriteStr().
+//
+// categoryStr is the category of the resource: "ColorSpace", "ExtGState",
+// "Font", "Pattern", "Properties", "Shading" or "XObject". nameStr must not
+// clash with the names this package assigns to its own resources, which
+// consist of a few capital letters followed by a number, such as "F1" or
+// "GS2". Prefixing names with the name of the add-on avoids clashes.
+func (f *Fpdf) AddResource(categoryStr, nameStr string, obj int) {
+	if f.err != nil {
+		return
+	}
+	if obj < 1 || obj > len(f.rawObjs) {
+		f.err = fmt.Errorf("invalid raw object identifier %d", obj)
+		return
+	}
+	valid := false
+	for _, cat := range rawResourceCategories {
+		if cat == categoryStr {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		f.err = fmt.Errorf("unsupported resource category: %s", categoryStr)
+		return
+	}
+	if nameStr == "" || strings.ContainsAny(nameStr, " \t\r\n/()<>[]{}%") {
+		f.err = fmt.Errorf("invalid resource name: %q", nameStr)
+		return
+	}
+	if f.rawResources == nil {
+		f.rawResources = make(map[string]map[string]int)
+	}
+	names, ok := f.rawResources[categoryStr]
+	if !ok {
+		names = make(map[string]int)
+		f.rawResources[categoryStr] = names
+	}
+	if _, ok = names[nameStr]; ok {
+		f.err = fmt.Errorf("resource %s %s is already defined", categoryStr, nameStr)
+		return
+	}
+	names[nameStr] = obj
+}
+
+// putrawobjects writes the objects allocated with NewObject()
+func (f *Fpdf) putrawobjects() {
+	if len(f.rawObjs) == 0 {
+		return
+	}
+	if f.protect.encrypted {
+		f.err = fmt.Errorf("raw objects cannot be combined with document protection")
+		return
+	}
+	for j := range f.rawObjs {
+		if !f.rawObjs[j].set {
+			f.err = fmt.Errorf("raw object %d has no content", j+1)
+			return
+		}
+		f.rawObjs[j].num = f.n + 1 + j
+	}
+	for _, obj := range f.rawObjs {
+		valueStr := f.rawResolve(obj.valueStr)
+		if f.err != nil {
+			return
+		}
+		f.newobj()
+		if obj.stream != nil {
+			f.out("<<" + valueStr + sprintf(" /Length %d>>", len(obj.stream)))
+			f.putstream(obj.stream)
+		} else {
+			f.out(valueStr)
+		}
+		f.out("endobj")
+	}
+}
+
+// rawResolve replaces the placeholders written by ObjectRef() in s with
+// references to the corresponding objects
+func (f *Fpdf) rawResolve(s string) string {
+	var b fmtBuffer
+	for {
+		pos := strings.Index(s, rawRefPrefix)
+		if pos < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:pos])
+		s = s[pos+len(rawRefPrefix):]
+		end := strings.Index(s, rawRefSuffix)
+		if end < 0 {
+			f.err = fmt.Errorf("invalid raw object reference")
+			return ""
+		}
+		obj, err := strconv.Atoi(s[:end])
+		if err != nil || obj < 1 || obj > len(f.rawObjs) {
+			f.err = fmt.Errorf("invalid raw object reference")
+			return ""
+		}
+		b.printf("%d 0 R", f.rawObjs[obj-1].num)
+		s = s[end+len(rawRefSuffix):]
+	}
+}
+
+// putrawresources writes the entries of the resource dictionary for the raw
+// objects added to categoryStr with AddResource()
+func (f *Fpdf) putrawresources(categoryStr string) {
+	names := f.rawResources[categoryStr]
+	keyList := make([]string, 0, len(names))
+	for name := range names {
+		keyList = append(keyList, name)
+	}
+	sort.Strings(keyList)
+	for _, name := range keyList {
+		f.out("/" + name + sprintf(" %d 0 R", f.rawObjs[names[name]-1].num))
+	}
+}
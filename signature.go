@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sigFieldType is a signature field and its widget annotation
+type sigFieldType struct {
+	name           string
+	page           int
+	x0, y0, x1, y1 float64 // rectangle of the widget in points
+	data           []byte  // content of the appearance stream
+	obj            int     // object number of the field
+}
+
+// SignatureField adds a signature field named nameStr to the current page.
+// Its widget occupies the rectangle with upper-left corner (x, y), width w
+// and height h, measured in the unit of measure specified in New(). The
+// field is written unsigned, ready to be signed by external software, and
+// is listed in the interactive form of the document. This package does not
+// reserve space for the signature value, so the signer adds the signature
+// dictionary with its /ByteRange and /Contents entries itself.
+//
+// The visible appearance of the field, typically an image of a handwritten
+// signature together with the name of the signer and the date, is drawn by
+// appearanceFnc with the usual methods, such as ImageOptions() and Text(),
+// in page coordinates. What it draws becomes the appearance stream of the
+// widget rather than page content and is clipped to the field rectangle. The
+// current position and drawing settings are restored afterwards. The
+// appearance must fit on the current page; drawing that would cause a page
+// break results in an error. If appearanceFnc is nil, the field has an empty
+// appearance.
+//
+// Field names must be unique within the document and must not contain
+// periods.
+func (f *Fpdf) SignatureField(nameStr string, x, y, w, h float64, appearanceFnc func()) {
+	if f.err != nil {
+		return
+	}
+	if f.page == 0 || f.state != 2 {
+		f.err = fmt.Errorf("cannot add a signature field without first adding a page")
+		return
+	}
+	if nameStr == "" || strings.Contains(nameStr, ".") {
+		f.err = fmt.Errorf("invalid signature field name \"%s\"", nameStr)
+		return
+	}
+	for _, sf := range f.sigFields {
+		if sf.name == nameStr {
+			f.err = fmt.Errorf("signature field \"%s\" already exists", nameStr)
+			return
+		}
+	}
+	k := f.k
+	sf := sigFieldType{name: nameStr, page: f.page,
+		x0: x * k, y0: (f.h - y - h) * k, x1: (x + w) * k, y1: (f.h - y) * k}
+	if appearanceFnc != nil {
+		sf.data = f.captureContent(appearanceFnc)
+		if f.err != nil {
+			return
+		}
+	}
+	f.sigFields = append(f.sigFields, sf)
+}
+
+// captureContent calls fnc and returns what it draws on the current page,
+// removing it from the page. The content begins with the operators that
+// establish the current colors, line width and font, so that it can stand
+// alone as a form XObject.
+func (f *Fpdf) captureContent(fnc func()) (data []byte) {
+	f.structSuspend()
+	f.SaveContext()
+	page := f.page
+	buf := f.pages[page]
+	start := buf.Len()
+	acceptPageBreak := f.acceptPageBreak
+	f.acceptPageBreak = func() bool { return false }
+	if f.color.draw.str != "0 G" {
+		f.out(f.color.draw.str)
+	}
+	if f.color.fill.str != "0 g" {
+		f.out(f.color.fill.str)
+	}
+	f.outf("%.2f w", f.lineWidthPt())
+	if f.fontFamily != "" {
+		familyStr, styleStr := f.fontFamily, f.fontStyle
+		if f.underline {
+			styleStr += "U"
+		}
+		f.fontFamily = ""
+		f.SetFont(familyStr, styleStr, f.fontSizePt)
+	}
+	fnc()
+	f.acceptPageBreak = acceptPageBreak
+	if f.err == nil && (f.page != page || f.y > f.pageBreakTrigger) {
+		f.err = fmt.Errorf("signature field appearance does not fit on page %d", page)
+	}
+	if f.err == nil {
+		data = append(data, buf.Bytes()[start:]...)
+	}
+	// The page and the drawing context are left as they were found, whether or
+	// not fnc succeeded. RestoreContext does nothing while an error is set.
+	err := f.err
+	f.err = nil
+	buf.Truncate(start)
+	f.RestoreContext()
+	f.structResume()
+	if err != nil {
+		f.err = err
+	}
+	return
+}
+
+// sigFieldOnPage reports whether a signature field has been added to page
+func (f *Fpdf) sigFieldOnPage(page int) bool {
+	for _, sf := range f.sigFields {
+		if sf.page == page {
+			return true
+		}
+	}
+	return false
+}
+
+// putsigfield writes signature field sf and its appearance stream as two
+// consecutive objects
+func (f *Fpdf) putsigfield(sf sigFieldType) {
+	f.newobj()
+	f.outf("<</Type /Annot /Subtype /Widget /FT /Sig /T %s /F 4", f.textstring(sf.name))
+	f.outf("/Rect [%.2f %.2f %.2f %.2f] /P %d 0 R /AP <</N %d 0 R>>>>",
+		f.userSpace(sf.x0), f.userSpace(sf.y0), f.userSpace(sf.x1), f.userSpace(sf.y1), 1+2*sf.page, f.n+1)
+	f.out("endobj")
+	filter := ""
+	data := sf.data
+	if f.compress {
+		filter = "/Filter /FlateDecode "
+		data = sliceCompress(data, f.compressLevel)
+	}
+	f.newobj()
+	f.outf("<<%s/Type /XObject /Subtype /Form /BBox [%.2f %.2f %.2f %.2f]", filter, sf.x0, sf.y0, sf.x1, sf.y1)
+	if f.userUnit > 0 {
+		f.outf("/Matrix [%.6f 0 0 %.6f 0 0]", 1/f.userUnit, 1/f.userUnit)
+	}
+	f.outf("/Resources 2 0 R /Length %d>>", len(data))
+	f.putstream(data)
+	f.out("endobj")
+}
+
+// sigPutCatalog writes the interactive form dictionary that lists the
+// signature fields
+func (f *Fpdf) sigPutCatalog() {
+	if len(f.sigFields) == 0 {
+		return
+	}
+	var fields fmtBuffer
+	fields.printf("/AcroForm <</Fields [")
+	for j, sf := range f.sigFields {
+		if j > 0 {
+			fields.printf(" ")
+		}
+		fields.printf("%d 0 R", sf.obj)
+	}
+	fields.printf("]>>")
+	f.out(fields.String())
+}
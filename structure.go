@@ -0,0 +1,374 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// structKidType is a kid of a structure element: a child element, a
+// marked-content sequence or a link annotation
+type structKidType struct {
+	elem  int // index of the child element, or -1
+	page  int // page of the marked content or annotation
+	mcid  int // marked-content ID, or -1 for an annotation
+	annot int // index of the annotation in the links of its page
+}
+
+type structElemType struct {
+	typeStr string
+	parent  int    // index of the parent element, -1 for the root
+	lang    string // language of the content, empty if that of the parent
+	kids    []structKidType
+}
+
+type structRecType struct {
+	on      bool
+	elems   []structElemType
+	stack   []int       // elements whose content is being drawn, innermost last
+	mcids   map[int]int // number of marked-content IDs assigned, by page
+	mcOpen  bool        // a marked-content sequence is open on the current page
+	mcStart int         // page buffer length before the open sequence began
+	mcEnd   int         // page buffer length after the open sequence began
+	rootObj int         // object number of the structure tree root
+}
+
+// SetTagged enables or disables tagged mode, in which the logical structure
+// of the document is recorded so that assistive technology can present its
+// content in reading order. This is a first step towards accessible documents
+// such as those conforming to PDF/UA.
+//
+// In tagged mode, lists created with BeginList() are tagged with L elements
+// containing an LI element for each item, which in turn contains an Lbl
+// element for the marker and an LBody element for the text. Nested levels
+// are tagged as lists within the LBody element of the preceding item. Each
+// link created with CellFormat(), Write(), Image(), Link() or LinkString() is
+// tagged with a Link element that refers to the link annotation and, except
+// for the latter two, contains the text or image of the link. Other content
+// is associated with structure elements by drawing it between calls to
+// BeginStructElement() and EndStructElement(). Running headers and footers
+// are marked as artifacts and do not belong to the structure.
+func (f *Fpdf) SetTagged(tagged bool) {
+	f.structure.on = tagged
+	if f.structure.mcids == nil {
+		f.structure.mcids = make(map[int]int)
+	}
+}
+
+// BeginStructElement begins a structure element of the specified type, such
+// as "Document", "H1", "P" or "Table", in tagged mode. See SetTagged(). The
+// element is a child of the element begun most recently that has not yet
+// been ended, or of the structure tree root if there is none. Content drawn
+// until the element is ended with EndStructElement() belongs to it, except
+// for content that belongs to nested elements. Elements may span pages.
+func (f *Fpdf) BeginStructElement(typeStr string) {
+	if f.err != nil {
+		return
+	}
+	if !f.structure.on {
+		f.err = fmt.Errorf("BeginStructElement requires tagged mode; see SetTagged")
+		return
+	}
+	if typeStr == "" || strings.ContainsAny(typeStr, " \t\r\n()<>[]{}/%#") {
+		f.err = fmt.Errorf("invalid structure element type \"%s\"", typeStr)
+		return
+	}
+	f.structPush(f.structAdd(typeStr, f.structTop()))
+}
+
+// EndStructElement ends the structure element begun most recently with
+// BeginStructElement().
+func (f *Fpdf) EndStructElement() {
+	if f.err != nil {
+		return
+	}
+	if len(f.structure.stack) == 0 {
+		f.err = fmt.Errorf("EndStructElement called without matching BeginStructElement")
+		return
+	}
+	f.structPop()
+}
+
+// SetStructElementLang sets the natural language of the content of the
+// structure element begun most recently with BeginStructElement() that has
+// not yet been ended, overriding the language of the document set with
+// SetLang() or that of an enclosing element. This is typically applied to a
+// Span element that holds a phrase in a foreign language, so that screen
+// readers switch pronunciation for it. langStr is a language tag as defined
+// by BCP 47, for example "fr" or "pt-BR". An invalid tag results in an error.
+func (f *Fpdf) SetStructElementLang(langStr string) {
+	if f.err != nil {
+		return
+	}
+	elem := f.structTop()
+	if elem < 0 {
+		f.err = fmt.Errorf("SetStructElementLang called outside of a structure element")
+		return
+	}
+	if !validLangTag(langStr) {
+		f.err = fmt.Errorf("invalid language tag \"%s\"", langStr)
+		return
+	}
+	f.structure.elems[elem].lang = langStr
+}
+
+// validLangTag reports whether s has the form of a BCP 47 language tag:
+// subtags of one to eight letters and digits separated by hyphens, the first
+// of which consists of letters
+func validLangTag(s string) bool {
+	for j, sub := range strings.Split(s, "-") {
+		if len(sub) == 0 || len(sub) > 8 {
+			return false
+		}
+		for _, c := range sub {
+			letter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+			if !letter && (j == 0 || c < '0' || c > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// structTop returns the index of the innermost element whose content is being
+// drawn, or -1 if there is none
+func (f *Fpdf) structTop() int {
+	if n := len(f.structure.stack); n > 0 {
+		return f.structure.stack[n-1]
+	}
+	return -1
+}
+
+// structAdd adds an element of the specified type as the last child of
+// parent and returns its index
+func (f *Fpdf) structAdd(typeStr string, parent int) int {
+	elem := len(f.structure.elems)
+	f.structure.elems = append(f.structure.elems, structElemType{typeStr: typeStr, parent: parent})
+	if parent >= 0 {
+		f.structKid(parent, structKidType{elem: elem, mcid: -1})
+	}
+	return elem
+}
+
+func (f *Fpdf) structKid(elem int, kid structKidType) {
+	e := &f.structure.elems[elem]
+	e.kids = append(e.kids, kid)
+}
+
+// structPush makes elem the element to which drawn content belongs until the
+// matching call to structPop()
+func (f *Fpdf) structPush(elem int) {
+	f.structSuspend()
+	f.structure.stack = append(f.structure.stack, elem)
+	f.structResume()
+}
+
+func (f *Fpdf) structPop() {
+	f.structSuspend()
+	f.structure.stack = f.structure.stack[:len(f.structure.stack)-1]
+	f.structResume()
+}
+
+// structResume begins a marked-content sequence on the current page for the
+// innermost element, if any
+func (f *Fpdf) structResume() {
+	elem := f.structTop()
+	if elem < 0 || f.state != 2 || f.structure.mcOpen {
+		return
+	}
+	mcid := f.structure.mcids[f.page]
+	f.structure.mcids[f.page] = mcid + 1
+	f.structKid(elem, structKidType{elem: -1, page: f.page, mcid: mcid})
+	f.structure.mcStart = f.pages[f.page].Len()
+	f.outf("/%s <</MCID %d>> BDC", f.structure.elems[elem].typeStr, mcid)
+	f.structure.mcEnd = f.pages[f.page].Len()
+	f.structure.mcOpen = true
+}
+
+// structSuspend ends the open marked-content sequence, if any. A sequence to
+// which nothing has been drawn is removed.
+func (f *Fpdf) structSuspend() {
+	if !f.structure.mcOpen {
+		return
+	}
+	f.structure.mcOpen = false
+	page := f.pages[f.page]
+	if page.Len() != f.structure.mcEnd {
+		f.out("EMC")
+		return
+	}
+	page.Truncate(f.structure.mcStart)
+	mcid := f.structure.mcids[f.page] - 1
+	f.structure.mcids[f.page] = mcid
+	e := &f.structure.elems[f.structTop()]
+	for j := len(e.kids) - 1; j >= 0; j-- {
+		if kid := e.kids[j]; kid.elem < 0 && kid.page == f.page && kid.mcid == mcid {
+			e.kids = append(e.kids[:j], e.kids[j+1:]...)
+			break
+		}
+	}
+}
+
+// structLink returns the 1-based index of the Link element for a link
+// annotation about to be added as the next link of the current page, or 0
+// if the document is not tagged
+func (f *Fpdf) structLink() int {
+	if !f.structure.on || f.page == 0 {
+		return 0
+	}
+	elem := f.structTop()
+	if elem < 0 || f.structure.elems[elem].typeStr != "Link" {
+		elem = f.structAdd("Link", elem)
+	}
+	f.structKid(elem, structKidType{elem: -1, page: f.page, mcid: -1, annot: len(f.pageLinks[f.page])})
+	return elem + 1
+}
+
+// structBeginLink begins a Link element for the content of a link if the
+// document is tagged, and reports whether it has done so
+func (f *Fpdf) structBeginLink() bool {
+	if !f.structure.on || f.page == 0 {
+		return false
+	}
+	f.structPush(f.structAdd("Link", f.structTop()))
+	return true
+}
+
+// structRenumber applies renumber to the pages of marked content and
+// annotations
+func (f *Fpdf) structRenumber(renumber func(page int) int) {
+	for j := range f.structure.elems {
+		kids := f.structure.elems[j].kids
+		for k := range kids {
+			if kids[k].elem < 0 {
+				kids[k].page = renumber(kids[k].page)
+			}
+		}
+	}
+	mcids := make(map[int]int)
+	for page, n := range f.structure.mcids {
+		mcids[renumber(page)] = n
+	}
+	f.structure.mcids = mcids
+}
+
+// structDropPage removes the marked content and annotations of the specified
+// page, which is about to be deleted
+func (f *Fpdf) structDropPage(page int) {
+	for j := range f.structure.elems {
+		e := &f.structure.elems[j]
+		kids := e.kids[:0]
+		for _, kid := range e.kids {
+			if kid.elem >= 0 || kid.page != page {
+				kids = append(kids, kid)
+			}
+		}
+		e.kids = kids
+	}
+	delete(f.structure.mcids, page)
+}
+
+// structTagged reports whether the document has a structure tree
+func (f *Fpdf) structTagged() bool {
+	return f.structure.on && len(f.structure.elems) > 0
+}
+
+// putstructtree writes the structure tree root, the structure elements and
+// the parent tree that maps marked content and annotations back to their
+// elements. Pages use their zero-based index as parent tree key and tagged
+// annotations follow, numbered as assigned by putpages().
+func (f *Fpdf) putstructtree() {
+	if !f.structTagged() {
+		return
+	}
+	nb := len(f.pages) - 1
+	rootObj := f.n + 1
+	elemObj := func(elem int) int {
+		if elem < 0 {
+			return rootObj
+		}
+		return rootObj + 1 + elem
+	}
+	treeObj := rootObj + 1 + len(f.structure.elems)
+	pageObj := func(page int) int { return 1 + 2*page }
+	// Parent tree entries
+	pageRefs := make([][]int, nb+1)
+	for page := 1; page <= nb; page++ {
+		pageRefs[page] = make([]int, f.structure.mcids[page])
+	}
+	annotRefs := make(map[int]int)
+	var rootKids fmtBuffer
+	for j, e := range f.structure.elems {
+		if e.parent < 0 {
+			rootKids.printf(" %d 0 R", elemObj(j))
+		}
+		for _, kid := range e.kids {
+			switch {
+			case kid.elem >= 0:
+			case kid.mcid >= 0:
+				pageRefs[kid.page][kid.mcid] = elemObj(j)
+			default:
+				annotRefs[f.pageLinks[kid.page][kid.annot].key] = elemObj(j)
+			}
+		}
+	}
+	f.newobj()
+	f.out("<</Type /StructTreeRoot")
+	f.outf("/K [%s]", strings.TrimSpace(rootKids.String()))
+	f.outf("/ParentTree %d 0 R", treeObj)
+	f.outf("/ParentTreeNextKey %d>>", nb+len(annotRefs))
+	f.out("endobj")
+	for _, e := range f.structure.elems {
+		var kids fmtBuffer
+		for _, kid := range e.kids {
+			switch {
+			case kid.elem >= 0:
+				kids.printf(" %d 0 R", elemObj(kid.elem))
+			case kid.mcid >= 0:
+				kids.printf(" <</Type /MCR /Pg %d 0 R /MCID %d>>", pageObj(kid.page), kid.mcid)
+			default:
+				kids.printf(" <</Type /OBJR /Pg %d 0 R /Obj %d 0 R>>", pageObj(kid.page),
+					f.pageLinks[kid.page][kid.annot].obj)
+			}
+		}
+		f.newobj()
+		f.outf("<</Type /StructElem /S /%s /P %d 0 R", e.typeStr, elemObj(e.parent))
+		if e.lang != "" {
+			f.outf("/Lang %s", f.textstring(e.lang))
+		}
+		f.outf("/K [%s]>>", strings.TrimSpace(kids.String()))
+		f.out("endobj")
+	}
+	var nums fmtBuffer
+	nums.printf("<</Nums [")
+	for page := 1; page <= nb; page++ {
+		var refs fmtBuffer
+		for _, ref := range pageRefs[page] {
+			refs.printf(" %d 0 R", ref)
+		}
+		nums.printf("%d [%s] ", page-1, strings.TrimSpace(refs.String()))
+	}
+	for key := nb; key < nb+len(annotRefs); key++ {
+		nums.printf("%d %d 0 R ", key, annotRefs[key])
+	}
+	nums.printf("]>>")
+	f.newobj()
+	f.out(nums.String())
+	f.out("endobj")
+	f.structure.rootObj = rootObj
+}
@@ -42,13 +42,13 @@ func (f *Fpdf) SVGBasicWrite(sb *SVGBasicType, scale float64) {
 				f.SetXY(x, y)
 			case 'L':
 				newX, newY = val(0)
-				f.Line(x, y, newX, newY)
+				f.line(x, y, newX, newY)
 				x, y = newX, newY
 			case 'C':
 				cx0, cy0 = val(0)
 				cx1, cy1 = val(2)
 				newX, newY = val(4)
-				f.CurveCubic(x, y, cx0, cy0, newX, newY, cx1, cy1, "D")
+				f.bezierCubic(x, y, cx0, cy0, cx1, cy1, newX, newY, "D")
 				x, y = newX, newY
 			default:
 				f.SetErrorf("Unexpected path command '%c'", seg.Cmd)
@@ -149,6 +149,9 @@ func (f *Fpdf) putTemplates() {
 			for _, key = range keyList {
 				font = f.fonts[key]
 				f.outf("/F%d %d 0 R", font.I, font.N)
+				if font.glyphN > 0 {
+					f.outf("/G%d %d 0 R", font.I, font.glyphN)
+				}
 			}
 		}
 		f.out(">>")
@@ -188,7 +191,7 @@ func (f *Fpdf) putTemplates() {
 		buffer := t.Bytes()
 		// fmt.Println("Put template bytes", string(buffer[:]))
 		if f.compress {
-			buffer = sliceCompress(buffer)
+			buffer = sliceCompress(buffer, f.compressLevel)
 		}
 		f.outf("/Length %d >>", len(buffer))
 		f.putstream(buffer)
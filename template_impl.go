@@ -25,8 +25,8 @@ func newTpl(corner PointType, size SizeType, unitStr, fontDirStr string, fn func
 	}
 	sizeStr := ""
 
-	fpdf := fpdfNew(orientationStr, unitStr, sizeStr, fontDirStr, size)
-	tpl := Tpl{*fpdf}
+	var tpl Tpl
+	tpl.Fpdf.fpdfInit(orientationStr, unitStr, sizeStr, fontDirStr, size)
 	if copyFrom != nil {
 		tpl.loadParamsFromFpdf(copyFrom)
 	}
@@ -94,6 +94,7 @@ func (t *Tpl) loadParamsFromFpdf(f *Fpdf) {
 	t.Fpdf.x = f.x
 	t.Fpdf.y = f.y
 	t.Fpdf.lineWidth = f.lineWidth
+	t.Fpdf.hairlineWidth = f.hairlineWidth
 	t.Fpdf.capStyle = f.capStyle
 	t.Fpdf.joinStyle = f.joinStyle
 
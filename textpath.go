@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+	"math"
+)
+
+// TextOnCircle prints txtStr along a circle centered on (cx, cy) with radius
+// r, placing each character separately, rotated to follow the circle. The
+// baseline of the text lies on the circle. This is useful for seals, badges
+// and similar circular designs.
+//
+// startAngle is the position of the start of the text in degrees
+// counter-clockwise from the positive x axis, that is, from the 3 o'clock
+// position. If clockwise is true, the text runs clockwise with the tops of
+// the characters pointing away from the center, which suits text along the
+// top of the circle. Otherwise it runs counter-clockwise with the tops
+// pointing towards the center, which keeps text along the bottom of the
+// circle upright. Either way, the text subtends an angle of
+// GetStringWidth(txtStr) / r radians, so text centered at the top of the
+// circle starts at 90 + GetStringWidth(txtStr) / r * 90 / math.Pi degrees.
+//
+// Characters advance by their widths in the current font. Kerning is not
+// applied, since the kerning data of fonts is not read by this package.
+func (f *Fpdf) TextOnCircle(cx, cy, r float64, txtStr string, startAngle float64, clockwise bool) {
+	if !f.fontReady() {
+		return
+	}
+	cy = f.userY(cy)
+	if r <= 0 {
+		return
+	}
+	k := f.k
+	x0, y0, rPt := cx*k, (f.h-cy)*k, r*k
+	theta := startAngle * math.Pi / 180
+	dir := 1.0
+	if clockwise {
+		dir = -1
+	}
+	f.glyphsOut(txtStr, func(wd float64) (x, y, angle float64) {
+		// Midpoint of the character on the circle and the direction of travel
+		mid := theta + dir*wd/2/rPt
+		tx, ty := -dir*math.Sin(mid), dir*math.Cos(mid)
+		x = x0 + rPt*math.Cos(mid) - tx*wd/2
+		y = y0 + rPt*math.Sin(mid) - ty*wd/2
+		theta += dir * wd / rPt
+		return x, y, math.Atan2(ty, tx)
+	})
+}
+
+// TextOnPath prints txtStr along the path formed by the straight segments
+// connecting points, placing each character separately, rotated to follow
+// the segment on which its center falls. The baseline of the text lies on
+// the path and the tops of the characters point to the left of the direction
+// of travel. Curves can be followed by approximating them with short
+// segments.
+//
+// offset is the distance along the path, in user units, at which the text
+// starts. Characters that extend beyond the end of the path continue in the
+// direction of the last segment. Kerning is not applied; see TextOnCircle().
+func (f *Fpdf) TextOnPath(points []PointType, txtStr string, offset float64) {
+	if !f.fontReady() {
+		return
+	}
+	if len(points) < 2 {
+		f.err = fmt.Errorf("text path requires at least two points")
+		return
+	}
+	k := f.k
+	pts := make([]PointType, len(points))
+	for j, pt := range points {
+		pts[j] = PointType{X: pt.X * k, Y: (f.h - f.userY(pt.Y)) * k}
+	}
+	pos := offset * k
+	f.glyphsOut(txtStr, func(wd float64) (x, y, angle float64) {
+		mid := pos + wd/2
+		pos += wd
+		// Follow the segment containing the midpoint of the character
+		seg, dist, tx, ty := pathSegment(pts, mid)
+		along := mid - dist - wd/2
+		return pts[seg].X + tx*along, pts[seg].Y + ty*along, math.Atan2(ty, tx)
+	})
+}
+
+// ImageAlongPath repeats the image imageNameStr along the path formed by the
+// straight segments connecting points, as for a decorative border. The image
+// is registered as with ImageOptions(). Each copy has width w and height h in
+// user units; if one of them is zero, it is calculated from the other to
+// preserve the aspect ratio of the image. The copies are centered on the path
+// and rotated to follow the segment on which their centers fall, with the
+// tops of the images pointing to the left of the direction of travel.
+//
+// The first copy starts at the beginning of the path. spacing is the gap in
+// user units, measured along the path, between the end of each copy and the
+// start of the next, so a spacing of zero places the copies edge to edge.
+// Copies that would extend beyond the end of the path are omitted. See
+// ImageAlongRect() for a border that fits a rectangle exactly.
+func (f *Fpdf) ImageAlongPath(imageNameStr string, points []PointType, w, h, spacing float64) {
+	if f.err != nil {
+		return
+	}
+	if len(points) < 2 {
+		f.err = fmt.Errorf("image path requires at least two points")
+		return
+	}
+	info, w, h := f.pathImage(imageNameStr, w, h)
+	if f.err != nil {
+		return
+	}
+	if spacing < 0 {
+		f.err = fmt.Errorf("image spacing must not be negative")
+		return
+	}
+	k := f.k
+	pts := make([]PointType, len(points))
+	for j, pt := range points {
+		pts[j] = PointType{X: pt.X * k, Y: (f.h - f.userY(pt.Y)) * k}
+	}
+	var length float64
+	for j := 1; j < len(pts); j++ {
+		length += math.Hypot(pts[j].X-pts[j-1].X, pts[j].Y-pts[j-1].Y)
+	}
+	const tolerance = 1e-6
+	for pos := 0.0; pos+w*k <= length+tolerance; pos += (w + spacing) * k {
+		f.pathImageOut(info, pts, pos+w*k/2, w*k, h*k)
+	}
+}
+
+// ImageAlongRect repeats the image imageNameStr along the edges of the
+// rectangle whose upper left corner is (x, y) and whose extent is (wd, ht),
+// for example to frame a certificate. The copies are centered on the edges,
+// which are traversed clockwise, so that the tops of the images point away
+// from the rectangle. See ImageAlongPath() for the interpretation of
+// imageNameStr and of the width tileW and height tileH of each copy.
+//
+// Each edge holds a whole number of copies, as close to tileW apart as
+// possible, so that the first and last copies of each edge meet the corners.
+func (f *Fpdf) ImageAlongRect(imageNameStr string, x, y, wd, ht, tileW, tileH float64) {
+	if f.err != nil {
+		return
+	}
+	if wd <= 0 || ht <= 0 {
+		f.err = fmt.Errorf("image border must have a positive width and height")
+		return
+	}
+	info, tileW, tileH := f.pathImage(imageNameStr, tileW, tileH)
+	if f.err != nil {
+		return
+	}
+	if f.originBottom {
+		y = f.h - y - ht
+	}
+	k := f.k
+	x0, y0, x1, y1 := x*k, (f.h-y)*k, (x+wd)*k, (f.h-y-ht)*k
+	corners := []PointType{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}, {x0, y0}}
+	for j := 0; j < 4; j++ {
+		edge := corners[j : j+2]
+		length := math.Hypot(edge[1].X-edge[0].X, edge[1].Y-edge[0].Y)
+		count := math.Max(1, math.Floor(length/(tileW*k)+0.5))
+		step := length / count
+		for n := 0.0; n < count; n++ {
+			f.pathImageOut(info, edge, (n+0.5)*step, tileW*k, tileH*k)
+		}
+	}
+}
+
+// pathImage registers the image used by ImageAlongPath() and ImageAlongRect()
+// and returns it along with the size of each copy in user units
+func (f *Fpdf) pathImage(imageNameStr string, w, h float64) (info *ImageInfoType, wd, ht float64) {
+	if w < 0 || h < 0 || w == 0 && h == 0 {
+		f.err = fmt.Errorf("image tile must have a positive size")
+		return
+	}
+	info = f.RegisterImageOptions(imageNameStr, ImageOptions{})
+	if f.err != nil {
+		return
+	}
+	if w == 0 {
+		w = h * info.w / info.h
+	}
+	if h == 0 {
+		h = w * info.h / info.w
+	}
+	info.placedWd = math.Max(info.placedWd, w*f.k)
+	info.placedHt = math.Max(info.placedHt, h*f.k)
+	return info, w, h
+}
+
+// pathImageOut places a copy of an image of width wd and height ht, in
+// points, centered on the point at distance pos along pts and rotated to
+// follow it
+func (f *Fpdf) pathImageOut(info *ImageInfoType, pts []PointType, pos, wd, ht float64) {
+	seg, dist, tx, ty := pathSegment(pts, pos)
+	cx, cy := pts[seg].X+tx*(pos-dist), pts[seg].Y+ty*(pos-dist)
+	f.outf("q %.5f %.5f %.5f %.5f %.5f %.5f cm /I%d Do Q", wd*tx, wd*ty, -ht*ty, ht*tx,
+		cx-(wd*tx-ht*ty)/2, cy-(wd*ty+ht*tx)/2, info.i)
+}
+
+// pathSegment finds the segment of the path pts that contains the point at
+// distance pos along it. It returns the index of the segment, the distance
+// along the path at which the segment starts and the unit vector in its
+// direction. Positions beyond the end of the path fall on the last segment.
+func pathSegment(pts []PointType, pos float64) (seg int, dist, tx, ty float64) {
+	var segLen float64
+	for seg = 0; seg < len(pts)-1; seg++ {
+		segLen = math.Hypot(pts[seg+1].X-pts[seg].X, pts[seg+1].Y-pts[seg].Y)
+		if seg == len(pts)-2 || dist+segLen > pos {
+			break
+		}
+		dist += segLen
+	}
+	tx, ty = 1.0, 0.0
+	if segLen > 0 {
+		tx, ty = (pts[seg+1].X-pts[seg].X)/segLen, (pts[seg+1].Y-pts[seg].Y)/segLen
+	}
+	return
+}
+
+// glyphsOut prints the characters of txtStr one by one. For each character,
+// place is called with its width in points and returns the position of the
+// start of its baseline and the angle of the baseline, in radians, in page
+// coordinates.
+func (f *Fpdf) glyphsOut(txtStr string, place func(wd float64) (x, y, angle float64)) {
+	var s fmtBuffer
+	if f.colorFlag {
+		s.printf("q %s ", f.color.text.str)
+	}
+	for _, r := range txtStr {
+		ch := string(r)
+		x, y, angle := place(f.GetStringWidth(ch) * f.k)
+		if r == ' ' {
+			continue
+		}
+		sin, cos := math.Sincos(angle)
+		s.printf(f.precision("q %.5f %.5f %.5f %.5f %.2f %.2f cm BT 0 0 Td (%s) Tj ET Q "),
+			cos, sin, -sin, cos, x, y, f.escape(f.translator(ch)))
+	}
+	if f.colorFlag {
+		s.printf("Q")
+	}
+	f.out(s.String())
+}
@@ -0,0 +1,620 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// TIFF tags used by the image reader
+const (
+	tiffImageWidth      = 256
+	tiffImageLength     = 257
+	tiffBitsPerSample   = 258
+	tiffCompression     = 259
+	tiffPhotometric     = 262
+	tiffFillOrder       = 266
+	tiffStripOffsets    = 273
+	tiffSamplesPerPixel = 277
+	tiffRowsPerStrip    = 278
+	tiffStripByteCounts = 279
+	tiffXResolution     = 282
+	tiffYResolution     = 283
+	tiffPlanarConfig    = 284
+	tiffT4Options       = 292
+	tiffResolutionUnit  = 296
+	tiffPredictor       = 317
+	tiffColorMap        = 320
+	tiffTileOffsets     = 324
+	tiffInkSet          = 332
+	tiffExtraSamples    = 338
+)
+
+// tiffIFD holds the fields of one image file directory, that is, of one frame
+// of a TIFF file. The values of rational fields are stored as numerator and
+// denominator pairs.
+type tiffIFD map[uint16][]uint32
+
+// get returns the first value of the field tag, or def if the field is absent.
+func (ifd tiffIFD) get(tag uint16, def uint32) uint32 {
+	if v := ifd[tag]; len(v) > 0 {
+		return v[0]
+	}
+	return def
+}
+
+// AddTIFFPages adds a page for each frame of the TIFF image in the file
+// specified by fileStr. This is convenient for multi-page document scans,
+// which are commonly delivered as TIFF files. See AddTIFFPagesFromReader()
+// for details.
+func (f *Fpdf) AddTIFFPages(fileStr string) {
+	if f.err != nil {
+		return
+	}
+	file, err := os.Open(fileStr)
+	if err != nil {
+		f.err = err
+		return
+	}
+	defer file.Close()
+	f.AddTIFFPagesFromReader(fileStr, file)
+}
+
+// AddTIFFPagesFromReader reads a TIFF image from r and adds a page for each
+// of its frames. Each page is sized to its frame at the resolution recorded
+// in the file, or at 72 dpi if none is recorded, and is covered entirely by
+// the frame. The header and footer functions, if any, are called for these
+// pages as for any other.
+//
+// The frames are registered as images named imgName followed by "#" and the
+// 1-based frame number, for example "scan.tif#2", so that they can be placed
+// again with Image() or ImageOptions().
+//
+// Uncompressed frames and frames compressed with LZW, PackBits or Deflate
+// are decoded and stored with FlateDecode compression. Bilevel frames
+// compressed with CCITT Group 3 or Group 4 fax encoding are passed through
+// unchanged with CCITTFaxDecode compression, which keeps documents of scanned
+// text small. Bilevel, grayscale, palette, RGB and CMYK frames with 1, 2, 4,
+// 8 or 16 bits per sample are supported; 16-bit samples are reduced to 8
+// bits. An alpha channel of an 8-bit frame is retained as a soft mask. Tiled,
+// JPEG-compressed and planar frames are not supported.
+func (f *Fpdf) AddTIFFPagesFromReader(imgName string, r io.Reader) {
+	if f.err != nil {
+		return
+	}
+	data, err := ioutil.ReadAll(r)
+	var frames []tiffIFD
+	if err == nil {
+		frames, err = tiffFrames(data)
+	}
+	if err != nil {
+		f.err = fmt.Errorf("unable to read TIFF image: %s", err)
+		return
+	}
+	for j, ifd := range frames {
+		name := sprintf("%s#%d", imgName, j+1)
+		info, ok := f.images[name]
+		if !ok {
+			info = f.tiffImage(data, ifd)
+			if f.err != nil {
+				f.err = fmt.Errorf("unable to read frame %d of TIFF image: %s", j+1, f.err)
+				return
+			}
+			info.i = len(f.images) + 1
+			f.images[name] = info
+		}
+		xdpi, ydpi := tiffResolution(ifd)
+		wd, ht := info.w*72/xdpi/f.k, info.h*72/ydpi/f.k
+		f.AddPageFormat("P", SizeType{Wd: wd, Ht: ht})
+		f.ImageOptions(name, 0, 0, wd, ht, false, ImageOptions{}, 0, "")
+		if f.err != nil {
+			return
+		}
+	}
+}
+
+// parsetiff extracts info from the first frame of the TIFF image in r
+func (f *Fpdf) parsetiff(r io.Reader) (info *ImageInfoType) {
+	data, err := ioutil.ReadAll(r)
+	var frames []tiffIFD
+	if err == nil {
+		frames, err = tiffFrames(data)
+	}
+	if err != nil {
+		f.err = fmt.Errorf("unable to read TIFF image: %s", err)
+		return
+	}
+	info = f.tiffImage(data, frames[0])
+	if f.err != nil {
+		f.err = fmt.Errorf("unable to read TIFF image: %s", f.err)
+	}
+	return
+}
+
+// tiffFrames returns the image file directories of the TIFF file in data
+func tiffFrames(data []byte) (frames []tiffIFD, err error) {
+	var bo binary.ByteOrder
+	switch {
+	case len(data) < 8:
+		return nil, fmt.Errorf("file is too short")
+	case string(data[0:4]) == "II*\x00":
+		bo = binary.LittleEndian
+	case string(data[0:4]) == "MM\x00*":
+		bo = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("not a TIFF file")
+	}
+	seen := make(map[uint32]bool)
+	for ofs := bo.Uint32(data[4:]); ofs != 0; {
+		if seen[ofs] {
+			return nil, fmt.Errorf("image file directories form a loop")
+		}
+		seen[ofs] = true
+		if int64(ofs)+2 > int64(len(data)) {
+			return nil, fmt.Errorf("image file directory extends beyond the end of the file")
+		}
+		count := int64(bo.Uint16(data[ofs:]))
+		end := int64(ofs) + 2 + 12*count
+		if end+4 > int64(len(data)) {
+			return nil, fmt.Errorf("image file directory extends beyond the end of the file")
+		}
+		ifd := make(tiffIFD)
+		for rec := int64(ofs) + 2; rec < end; rec += 12 {
+			tag := bo.Uint16(data[rec:])
+			tp := bo.Uint16(data[rec+2:])
+			n := int64(bo.Uint32(data[rec+4:]))
+			var size int64
+			switch tp {
+			case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+				size = 1
+			case 3, 8: // SHORT, SSHORT
+				size = 2
+			case 4, 9: // LONG, SLONG
+				size = 4
+			case 5, 10: // RATIONAL, SRATIONAL
+				size = 8
+			default:
+				continue
+			}
+			pos := rec + 8
+			if size*n > 4 {
+				pos = int64(bo.Uint32(data[rec+8:]))
+			}
+			if pos+size*n > int64(len(data)) {
+				return nil, fmt.Errorf("field %d extends beyond the end of the file", tag)
+			}
+			vals := make([]uint32, 0, n)
+			for k := int64(0); k < n; k++ {
+				p := pos + k*size
+				switch size {
+				case 1:
+					vals = append(vals, uint32(data[p]))
+				case 2:
+					vals = append(vals, uint32(bo.Uint16(data[p:])))
+				case 4:
+					vals = append(vals, bo.Uint32(data[p:]))
+				case 8:
+					vals = append(vals, bo.Uint32(data[p:]), bo.Uint32(data[p+4:]))
+				}
+			}
+			ifd[tag] = vals
+		}
+		frames = append(frames, ifd)
+		ofs = bo.Uint32(data[end:])
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("file contains no images")
+	}
+	return
+}
+
+// tiffResolution returns the horizontal and vertical resolution of a frame in
+// dots per inch
+func tiffResolution(ifd tiffIFD) (xdpi, ydpi float64) {
+	res := func(tag uint16) float64 {
+		v := ifd[tag]
+		if len(v) < 2 || v[0] == 0 || v[1] == 0 {
+			return 0
+		}
+		return float64(v[0]) / float64(v[1])
+	}
+	xdpi, ydpi = res(tiffXResolution), res(tiffYResolution)
+	switch ifd.get(tiffResolutionUnit, 2) {
+	case 2: // inch
+	case 3: // centimeter
+		xdpi, ydpi = xdpi*2.54, ydpi*2.54
+	default:
+		xdpi, ydpi = 0, 0
+	}
+	if xdpi == 0 || ydpi == 0 {
+		xdpi, ydpi = 72, 72
+	}
+	return
+}
+
+// tiffImage converts one frame of the TIFF file in data to image info. Errors
+// are reported in f.err.
+func (f *Fpdf) tiffImage(data []byte, ifd tiffIFD) (info *ImageInfoType) {
+	info = f.newImageInfo()
+	w := int(ifd.get(tiffImageWidth, 0))
+	h := int(ifd.get(tiffImageLength, 0))
+	if w <= 0 || h <= 0 || w > 1<<20 || h > 1<<20 {
+		f.err = fmt.Errorf("invalid image size %d x %d", w, h)
+		return
+	}
+	spp := int(ifd.get(tiffSamplesPerPixel, 1))
+	bps := int(ifd.get(tiffBitsPerSample, 1))
+	for _, v := range ifd[tiffBitsPerSample] {
+		if int(v) != bps {
+			f.err = fmt.Errorf("samples of different sizes are not supported")
+			return
+		}
+	}
+	compression := ifd.get(tiffCompression, 1)
+	photometric := ifd.get(tiffPhotometric, 0)
+	if _, ok := ifd[tiffTileOffsets]; ok {
+		f.err = fmt.Errorf("tiled images are not supported")
+		return
+	}
+	if spp > 1 && ifd.get(tiffPlanarConfig, 1) != 1 {
+		f.err = fmt.Errorf("planar images are not supported")
+		return
+	}
+	offsets, counts := ifd[tiffStripOffsets], ifd[tiffStripByteCounts]
+	if len(offsets) == 0 || len(offsets) != len(counts) {
+		f.err = fmt.Errorf("invalid strip offsets")
+		return
+	}
+	strips := make([][]byte, len(offsets))
+	for j := range offsets {
+		if int64(offsets[j])+int64(counts[j]) > int64(len(data)) {
+			f.err = fmt.Errorf("image data extends beyond the end of the file")
+			return
+		}
+		strips[j] = data[offsets[j] : offsets[j]+counts[j]]
+		if ifd.get(tiffFillOrder, 1) == 2 {
+			strips[j] = tiffReverseBits(strips[j])
+		}
+	}
+	info.w, info.h = float64(w), float64(h)
+	info.dpi, _ = tiffResolution(ifd)
+
+	// Fax encoded bilevel images are passed through
+	switch compression {
+	case 2, 3, 4:
+		if spp != 1 || bps != 1 {
+			f.err = fmt.Errorf("fax compression requires a bilevel image")
+			return
+		}
+		if len(strips) != 1 {
+			f.err = fmt.Errorf("fax compressed images with more than one strip are not supported")
+			return
+		}
+		var k int
+		t4 := ifd.get(tiffT4Options, 0)
+		switch compression {
+		case 2: // Modified Huffman run length encoding
+			k = -2
+		case 3: // Group 3, one or two dimensional
+			k = int(t4 & 1)
+		case 4: // Group 4
+			k = -1
+		}
+		info.data = strips[0]
+		info.cs = "DeviceGray"
+		info.bpc = 1
+		info.f = "CCITTFaxDecode"
+		info.dp = ccittParms(k, w, h, compression == 3 && t4&4 != 0, photometric == 1)
+		return
+	}
+
+	// All other images are decoded
+	colors := 1
+	switch photometric {
+	case 0, 1: // WhiteIsZero, BlackIsZero
+		info.cs = "DeviceGray"
+	case 2: // RGB
+		colors = 3
+		info.cs = "DeviceRGB"
+	case 3: // palette
+		info.cs = "Indexed"
+		cmap := ifd[tiffColorMap]
+		n := 1 << uint(bps)
+		if bps > 8 || len(cmap) < 3*n {
+			f.err = fmt.Errorf("invalid color map")
+			return
+		}
+		info.pal = make([]byte, 3*n)
+		for j := 0; j < n; j++ {
+			for c := 0; c < 3; c++ {
+				info.pal[3*j+c] = byte(cmap[c*n+j] >> 8)
+			}
+		}
+	case 5: // separated
+		if ifd.get(tiffInkSet, 1) != 1 {
+			f.err = fmt.Errorf("only CMYK separations are supported")
+			return
+		}
+		colors = 4
+		info.cs = "DeviceCMYK"
+	default:
+		f.err = fmt.Errorf("unsupported photometric interpretation %d", photometric)
+		return
+	}
+	if spp < colors {
+		f.err = fmt.Errorf("too few samples per pixel")
+		return
+	}
+	switch bps {
+	case 1, 2, 4, 8, 16:
+	default:
+		f.err = fmt.Errorf("unsupported bits per sample %d", bps)
+		return
+	}
+	if spp > colors && bps < 8 {
+		f.err = fmt.Errorf("extra samples require at least 8 bits per sample")
+		return
+	}
+	rowLen := (w*spp*bps + 7) / 8
+	if int64(rowLen)*int64(h) > 1<<30 {
+		f.err = fmt.Errorf("image is too large")
+		return
+	}
+	rowsPerStrip := int(ifd.get(tiffRowsPerStrip, uint32(h)))
+	if rowsPerStrip <= 0 || rowsPerStrip > h {
+		rowsPerStrip = h
+	}
+	pix := make([]byte, 0, rowLen*h)
+	for j, strip := range strips {
+		rows := h - j*rowsPerStrip
+		if rows <= 0 {
+			break
+		}
+		if rows > rowsPerStrip {
+			rows = rowsPerStrip
+		}
+		var buf []byte
+		var err error
+		switch compression {
+		case 1:
+			buf = strip
+		case 5:
+			buf, err = tiffLZW(strip, rows*rowLen)
+		case 8, 32946:
+			var zr io.ReadCloser
+			if zr, err = zlib.NewReader(bytes.NewReader(strip)); err == nil {
+				buf, err = ioutil.ReadAll(io.LimitReader(zr, int64(rows*rowLen)))
+				zr.Close()
+			}
+		case 32773:
+			buf = tiffPackBits(strip, rows*rowLen)
+		default:
+			err = fmt.Errorf("unsupported compression %d", compression)
+		}
+		if err != nil {
+			f.err = err
+			return
+		}
+		if len(buf) < rows*rowLen {
+			f.err = fmt.Errorf("image data is truncated")
+			return
+		}
+		pix = append(pix, buf[:rows*rowLen]...)
+	}
+	if len(pix) < rowLen*h {
+		f.err = fmt.Errorf("image data is truncated")
+		return
+	}
+	switch ifd.get(tiffPredictor, 1) {
+	case 1:
+	case 2: // horizontal differencing
+		if bps != 8 {
+			f.err = fmt.Errorf("horizontal differencing requires 8 bits per sample")
+			return
+		}
+		for y := 0; y < h; y++ {
+			row := pix[y*rowLen : (y+1)*rowLen]
+			for x := spp; x < len(row); x++ {
+				row[x] += row[x-spp]
+			}
+		}
+	default:
+		f.err = fmt.Errorf("unsupported predictor")
+		return
+	}
+	if bps == 16 {
+		// Keep the most significant byte of each sample
+		hi := 0
+		if binary.LittleEndian == tiffByteOrder(data) {
+			hi = 1
+		}
+		for j := 0; j < len(pix)/2; j++ {
+			pix[j] = pix[2*j+hi]
+		}
+		pix = pix[:len(pix)/2]
+		bps = 8
+		rowLen /= 2
+	}
+	if spp > colors {
+		// Split off the alpha channel, if any, and drop other extra samples
+		n := w * h
+		img := make([]byte, 0, n*colors)
+		var alpha []byte
+		extra := ifd[tiffExtraSamples]
+		if len(extra) > 0 && (extra[0] == 1 || extra[0] == 2) {
+			alpha = make([]byte, 0, n)
+		}
+		for j := 0; j < n; j++ {
+			px := pix[j*spp : (j+1)*spp]
+			img = append(img, px[:colors]...)
+			if alpha != nil {
+				alpha = append(alpha, px[colors])
+			}
+		}
+		pix = img
+		if alpha != nil {
+			info.smask = deflateRows(alpha, true, 1, w, h, w, h, false, f.compressLevel)
+			f.requirePDFVersion("1.4", "image transparency")
+		}
+	}
+	if photometric == 0 {
+		for j := range pix {
+			pix[j] = ^pix[j]
+		}
+	}
+	info.bpc = bps
+	info.f = "FlateDecode"
+	if f.noImageCompress && info.smask == nil {
+		info.f = ""
+		info.data = pix
+	} else if bps == 8 && info.cs != "Indexed" {
+		info.data = deflateRows(pix, true, colors, w, h, w, h, false, f.compressLevel)
+		info.dp = sprintf("/Predictor 15 /Colors %d /BitsPerComponent 8 /Columns %d", colors, w)
+	} else {
+		info.data = sliceCompress(pix, f.compressLevel)
+	}
+	return
+}
+
+// tiffByteOrder returns the byte order of the TIFF file in data
+func tiffByteOrder(data []byte) binary.ByteOrder {
+	if data[0] == 'I' {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// ccittParms returns the decode parameters of CCITT fax encoded data. A k
+// value of -2 denotes the Modified Huffman encoding of TIFF, which is
+// one dimensional without end of line codes.
+func ccittParms(k, w, h int, byteAlign, blackIs1 bool) string {
+	var s fmtBuffer
+	if k == -2 {
+		// Modified Huffman data has neither end of line codes nor an end of
+		// block code, and each row starts on a byte boundary
+		s.printf("/K 0 /EncodedByteAlign true /EndOfBlock false")
+	} else {
+		s.printf("/K %d", k)
+		if byteAlign {
+			s.printf(" /EncodedByteAlign true")
+		}
+	}
+	s.printf(" /Columns %d /Rows %d", w, h)
+	if blackIs1 {
+		s.printf(" /BlackIs1 true")
+	}
+	return s.String()
+}
+
+// tiffReverseBits returns a copy of data with the bit order of each byte
+// reversed
+func tiffReverseBits(data []byte) []byte {
+	out := make([]byte, len(data))
+	for j, b := range data {
+		b = b>>4 | b<<4
+		b = (b&0xCC)>>2 | (b&0x33)<<2
+		out[j] = (b&0xAA)>>1 | (b&0x55)<<1
+	}
+	return out
+}
+
+// tiffPackBits decodes PackBits data, stopping after max bytes
+func tiffPackBits(src []byte, max int) []byte {
+	out := make([]byte, 0, max)
+	for pos := 0; pos < len(src) && len(out) < max; {
+		n := int(int8(src[pos]))
+		pos++
+		switch {
+		case n >= 0:
+			end := pos + n + 1
+			if end > len(src) {
+				end = len(src)
+			}
+			out = append(out, src[pos:end]...)
+			pos = end
+		case n > -128:
+			if pos < len(src) {
+				for j := 0; j < 1-n; j++ {
+					out = append(out, src[pos])
+				}
+				pos++
+			}
+		}
+	}
+	return out
+}
+
+// tiffLZW decodes TIFF LZW data, stopping after max bytes. Unlike the
+// variant implemented by package compress/lzw, TIFF increases the code width
+// one code early.
+func tiffLZW(src []byte, max int) ([]byte, error) {
+	const clearCode, eoiCode = 256, 257
+	out := make([]byte, 0, max)
+	table := make([][]byte, 258, 4096)
+	for j := 0; j < 256; j++ {
+		table[j] = []byte{byte(j)}
+	}
+	width := uint(9)
+	var prev []byte
+	var acc uint32
+	var nBits uint
+	for pos := 0; len(out) < max; {
+		for nBits < width && pos < len(src) {
+			acc = acc<<8 | uint32(src[pos])
+			nBits += 8
+			pos++
+		}
+		if nBits < width {
+			break
+		}
+		code := int(acc>>(nBits-width)) & (1<<width - 1)
+		nBits -= width
+		switch {
+		case code == clearCode:
+			table = table[:258]
+			width = 9
+			prev = nil
+			continue
+		case code == eoiCode:
+			return out, nil
+		}
+		var entry []byte
+		switch {
+		case code < len(table):
+			entry = table[code]
+		case code == len(table) && prev != nil:
+			entry = append(append([]byte(nil), prev...), prev[0])
+		default:
+			return nil, fmt.Errorf("invalid LZW code")
+		}
+		out = append(out, entry...)
+		if prev != nil && len(table) < 4096 {
+			table = append(table, append(append([]byte(nil), prev...), entry[0]))
+			if len(table) == 1<<width-1 && width < 12 {
+				width++
+			}
+		}
+		prev = entry
+	}
+	return out, nil
+}
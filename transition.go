@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"fmt"
+)
+
+// pageTransType is the transition effect with which a page is shown and the
+// time for which it is displayed
+type pageTransType struct {
+	style string  // transition style, empty for none
+	dur   float64 // display duration in seconds, 0 for no automatic advance
+}
+
+// pageTransVersions lists the transition styles and the PDF version each
+// requires
+var pageTransVersions = map[string]string{
+	"Split": "1.1", "Blinds": "1.1", "Box": "1.1", "Wipe": "1.1", "Dissolve": "1.1",
+	"Glitter": "1.1", "Fly": "1.5", "Push": "1.5", "Cover": "1.5", "Uncover": "1.5", "Fade": "1.5",
+}
+
+// SetPageTransition sets the effect with which the current page is shown
+// when a viewer presents the document as a slideshow, typically in full
+// screen mode. styleStr is one of "Split", "Blinds", "Box", "Wipe",
+// "Dissolve", "Glitter", "Fly", "Push", "Cover", "Uncover" or "Fade", or
+// empty for no effect, which is the default. The last five were introduced
+// with PDF version 1.5. Each effect uses its default direction and lasts one
+// second.
+//
+// If duration is positive, the viewer advances to the next page
+// automatically after showing the page for duration seconds. Otherwise, the
+// page is shown until the user moves on.
+//
+// If no page has been added yet, the transition is applied to every page
+// that is subsequently added; otherwise it applies to the current page only.
+func (f *Fpdf) SetPageTransition(styleStr string, duration float64) {
+	if f.err != nil {
+		return
+	}
+	if _, ok := pageTransVersions[styleStr]; !ok && styleStr != "" {
+		f.err = fmt.Errorf("unrecognized page transition style \"%s\"", styleStr)
+		return
+	}
+	if duration < 0 {
+		duration = 0
+	}
+	trans := pageTransType{style: styleStr, dur: duration}
+	if f.page == 0 {
+		f.defPageTrans = trans
+		return
+	}
+	f.pageTrans[f.page] = trans
+}
+
+// putpagetrans writes the transition and display duration of page n
+func (f *Fpdf) putpagetrans(n int) {
+	trans, ok := f.pageTrans[n]
+	if !ok {
+		return
+	}
+	if trans.style != "" {
+		f.outf("/Trans <</Type /Trans /S /%s>>", trans.style)
+	}
+	if trans.dur > 0 {
+		f.outf("/Dur %.2f", trans.dur)
+	}
+}
+
+// pageTransVersion raises the PDF version as required by the transition
+// styles of the pages
+func (f *Fpdf) pageTransVersion() {
+	for page := 1; page < len(f.pages); page++ {
+		trans := f.pageTrans[page]
+		if version := pageTransVersions[trans.style]; version > "1.3" {
+			f.requirePDFVersion(version, "page transition "+trans.style)
+		}
+	}
+}
@@ -23,8 +23,10 @@ package gofpdf
 // Port to Go: Kurt Jung, 2013-07-15
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"strings"
@@ -46,25 +48,48 @@ type TTFType struct {
 	CapHeight              int16
 	Widths                 []uint16
 	Chars                  map[uint16]uint16
+	Symbolic               bool   // Chars is taken from a (3,0) Microsoft Symbol character map
+	gsub                   []byte // GSUB table, nil if the font has none
 }
 
 type ttfParser struct {
 	TTFType
-	f                *os.File
+	f                *bytes.Reader
+	data             []byte
 	tables           map[string]uint32
+	lengths          map[string]uint32
+	table            string // table being read
+	tableEnd         int64  // offset of the end of the table being read
+	readErr          error  // first attempt to read beyond the table
 	numberOfHMetrics uint16
 	numGlyphs        uint16
 }
 
+// ttfRequiredTables lists the tables without which a font cannot be used,
+// with the exception of the name, OS/2 and post tables, which are reported
+// when they are read
+var ttfRequiredTables = []string{"head", "hhea", "maxp", "hmtx", "cmap"}
+
 // TtfParse extracts various metrics from a TrueType font file.
+//
+// The file is validated before it is used. An error that names the table
+// concerned is returned if the table directory is inconsistent with the file,
+// if a required table (head, hhea, maxp, hmtx or cmap) is missing, if the
+// checksum of a table other than head does not match its contents, or if a
+// table is too short for the values it declares. The checksum of the head
+// table is not verified because it is frequently calculated incorrectly by
+// font tools.
 func TtfParse(fileStr string) (TtfRec TTFType, err error) {
 	var t ttfParser
-	t.f, err = os.Open(fileStr)
+	t.data, err = ioutil.ReadFile(fileStr)
 	if err != nil {
 		return
 	}
+	t.f = bytes.NewReader(t.data)
+	t.tableEnd = int64(len(t.data))
 	version, err := t.ReadStr(4)
 	if err != nil {
+		err = fmt.Errorf("unrecognized file format")
 		return
 	}
 	if version == "OTTO" {
@@ -75,19 +100,8 @@ func TtfParse(fileStr string) (TtfRec TTFType, err error) {
 		err = fmt.Errorf("unrecognized file format")
 		return
 	}
-	numTables := int(t.ReadUShort())
-	t.Skip(3 * 2) // searchRange, entrySelector, rangeShift
-	t.tables = make(map[string]uint32)
-	var tag string
-	for j := 0; j < numTables; j++ {
-		tag, err = t.ReadStr(4)
-		if err != nil {
-			return
-		}
-		t.Skip(4) // checkSum
-		offset := t.ReadULong()
-		t.Skip(4) // length
-		t.tables[tag] = offset
+	if err = t.parseDirectory(); err != nil {
+		return
 	}
 	if err = t.ParseHead(); err != nil {
 		return
@@ -113,16 +127,72 @@ func TtfParse(fileStr string) (TtfRec TTFType, err error) {
 	if err = t.ParsePost(); err != nil {
 		return
 	}
-	t.f.Close()
+	if ofs, ok := t.tables["GSUB"]; ok {
+		t.gsub = t.data[ofs : ofs+t.lengths["GSUB"]]
+	}
 	return t.TTFType, err
 }
 
+// parseDirectory reads the table directory and verifies that each table lies
+// within the file, that its checksum is correct and that the required tables
+// are present.
+func (t *ttfParser) parseDirectory() (err error) {
+	numTables := int(t.ReadUShort())
+	t.Skip(3 * 2) // searchRange, entrySelector, rangeShift
+	if t.readErr != nil || numTables == 0 || 12+16*numTables > len(t.data) {
+		return fmt.Errorf("font table directory is truncated")
+	}
+	t.tables = make(map[string]uint32)
+	t.lengths = make(map[string]uint32)
+	var tag string
+	for j := 0; j < numTables; j++ {
+		tag, err = t.ReadStr(4)
+		if err != nil {
+			return
+		}
+		checkSum := t.ReadULong()
+		offset := t.ReadULong()
+		length := t.ReadULong()
+		if uint64(offset)+uint64(length) > uint64(len(t.data)) {
+			return fmt.Errorf("font table %q extends beyond the end of the file", tag)
+		}
+		if tag != "head" && ttfChecksum(t.data[offset:offset+length]) != checkSum {
+			return fmt.Errorf("font table %q is corrupt: checksum mismatch", tag)
+		}
+		t.tables[tag] = offset
+		t.lengths[tag] = length
+	}
+	for _, tag = range ttfRequiredTables {
+		if _, ok := t.tables[tag]; !ok {
+			return fmt.Errorf("required font table %q is missing", tag)
+		}
+	}
+	return
+}
+
+// ttfChecksum returns the sum of the big-endian 32-bit words of data, which
+// is padded with zeros to a multiple of four bytes
+func ttfChecksum(data []byte) (sum uint32) {
+	for len(data) >= 4 {
+		sum += binary.BigEndian.Uint32(data)
+		data = data[4:]
+	}
+	if len(data) > 0 {
+		var pad [4]byte
+		copy(pad[:], data)
+		sum += binary.BigEndian.Uint32(pad[:])
+	}
+	return
+}
+
 func (t *ttfParser) ParseHead() (err error) {
-	err = t.Seek("head")
+	if err = t.Seek("head"); err != nil {
+		return
+	}
 	t.Skip(3 * 4) // version, fontRevision, checkSumAdjustment
 	magicNumber := t.ReadULong()
 	if magicNumber != 0x5F0F3CF5 {
-		err = fmt.Errorf("incorrect magic number")
+		err = fmt.Errorf("font table \"head\" is corrupt: incorrect magic number")
 		return
 	}
 	t.Skip(2) // flags
@@ -132,6 +202,9 @@ func (t *ttfParser) ParseHead() (err error) {
 	t.TTFType.Ymin = t.ReadShort()
 	t.TTFType.Xmax = t.ReadShort()
 	t.TTFType.Ymax = t.ReadShort()
+	if err = t.readErr; err == nil && t.TTFType.UnitsPerEm == 0 {
+		err = fmt.Errorf("font table \"head\" is corrupt: units per em is zero")
+	}
 	return
 }
 
@@ -140,6 +213,7 @@ func (t *ttfParser) ParseHhea() (err error) {
 	if err == nil {
 		t.Skip(4 + 15*2)
 		t.numberOfHMetrics = t.ReadUShort()
+		err = t.readErr
 	}
 	return
 }
@@ -149,11 +223,16 @@ func (t *ttfParser) ParseMaxp() (err error) {
 	if err == nil {
 		t.Skip(4)
 		t.numGlyphs = t.ReadUShort()
+		err = t.readErr
 	}
 	return
 }
 
 func (t *ttfParser) ParseHmtx() (err error) {
+	if t.numberOfHMetrics == 0 || t.numberOfHMetrics > t.numGlyphs {
+		return fmt.Errorf("font table \"hhea\" is corrupt: %d horizontal metrics for %d glyphs",
+			t.numberOfHMetrics, t.numGlyphs)
+	}
 	err = t.Seek("hmtx")
 	if err == nil {
 		t.TTFType.Widths = make([]uint16, 0, 8)
@@ -167,6 +246,7 @@ func (t *ttfParser) ParseHmtx() (err error) {
 				t.TTFType.Widths = append(t.TTFType.Widths, lastWidth)
 			}
 		}
+		err = t.readErr
 	}
 	return
 }
@@ -178,17 +258,37 @@ func (t *ttfParser) ParseCmap() (err error) {
 	}
 	t.Skip(2) // version
 	numTables := int(t.ReadUShort())
-	offset31 := int64(0)
+	// A Windows Unicode subtable is preferred to a Unicode platform subtable.
+	// Symbol fonts only have a Windows Symbol subtable, which maps the codes
+	// of the font to U+F000 - U+F0FF or, occasionally, to U+0000 - U+00FF.
+	offset31, offset0, offset30 := int64(0), int64(0), int64(0)
 	for j := 0; j < numTables; j++ {
 		platformID := t.ReadUShort()
 		encodingID := t.ReadUShort()
 		offset = int64(t.ReadULong())
-		if platformID == 3 && encodingID == 1 {
+		switch {
+		case platformID == 3 && encodingID == 1:
 			offset31 = offset
+		case platformID == 0 && encodingID <= 3 && offset0 == 0:
+			offset0 = offset
+		case platformID == 3 && encodingID == 0:
+			offset30 = offset
+		}
+	}
+	if offset31 == 0 {
+		if offset0 != 0 {
+			offset31 = offset0
+		} else {
+			offset31 = offset30
+			t.TTFType.Symbolic = true
 		}
 	}
 	if offset31 == 0 {
-		err = fmt.Errorf("no Unicode encoding found")
+		err = fmt.Errorf("no Unicode or symbol character map found")
+		return
+	}
+	if offset31 >= int64(t.lengths["cmap"]) {
+		err = fmt.Errorf("font table \"cmap\" is corrupt: subtable lies outside the table")
 		return
 	}
 	startCount := make([]uint16, 0, 8)
@@ -198,6 +298,9 @@ func (t *ttfParser) ParseCmap() (err error) {
 	t.TTFType.Chars = make(map[uint16]uint16)
 	t.f.Seek(int64(t.tables["cmap"])+offset31, os.SEEK_SET)
 	format := t.ReadUShort()
+	if t.readErr != nil {
+		return t.readErr
+	}
 	if format != 4 {
 		err = fmt.Errorf("unexpected subtable format: %d", format)
 		return
@@ -248,7 +351,7 @@ func (t *ttfParser) ParseCmap() (err error) {
 			}
 		}
 	}
-	return
+	return t.readErr
 }
 
 func (t *ttfParser) ParseName() (err error) {
@@ -280,7 +383,9 @@ func (t *ttfParser) ParseName() (err error) {
 				t.TTFType.PostScriptName = re.ReplaceAllString(s, "")
 			}
 		}
-		if t.TTFType.PostScriptName == "" {
+		if t.readErr != nil {
+			err = t.readErr
+		} else if t.TTFType.PostScriptName == "" {
 			err = fmt.Errorf("the name PostScript was not found")
 		}
 	}
@@ -306,6 +411,7 @@ func (t *ttfParser) ParseOS2() (err error) {
 		} else {
 			t.TTFType.CapHeight = 0
 		}
+		err = t.readErr
 	}
 	return
 }
@@ -319,6 +425,7 @@ func (t *ttfParser) ParsePost() (err error) {
 		t.TTFType.UnderlinePosition = t.ReadShort()
 		t.TTFType.UnderlineThickness = t.ReadShort()
 		t.TTFType.IsFixedPitch = t.ReadULong() != 0
+		err = t.readErr
 	}
 	return
 }
@@ -327,17 +434,39 @@ func (t *ttfParser) Seek(tag string) (err error) {
 	ofs, ok := t.tables[tag]
 	if ok {
 		t.f.Seek(int64(ofs), os.SEEK_SET)
+		t.table = tag
+		t.tableEnd = int64(ofs) + int64(t.lengths[tag])
 	} else {
 		err = fmt.Errorf("table not found: %s", tag)
 	}
 	return
 }
 
+// available returns true if n bytes can be read from the current table. If
+// not, the parser's read error is set.
+func (t *ttfParser) available(n int) bool {
+	pos, _ := t.f.Seek(0, os.SEEK_CUR)
+	if pos+int64(n) <= t.tableEnd {
+		return true
+	}
+	if t.readErr == nil {
+		if t.table == "" {
+			t.readErr = fmt.Errorf("font file is truncated")
+		} else {
+			t.readErr = fmt.Errorf("font table %q is truncated", t.table)
+		}
+	}
+	return false
+}
+
 func (t *ttfParser) Skip(n int) {
 	t.f.Seek(int64(n), os.SEEK_CUR)
 }
 
 func (t *ttfParser) ReadStr(length int) (str string, err error) {
+	if !t.available(length) {
+		return "", t.readErr
+	}
 	var n int
 	buf := make([]byte, length)
 	n, err = t.f.Read(buf)
@@ -352,16 +481,25 @@ func (t *ttfParser) ReadStr(length int) (str string, err error) {
 }
 
 func (t *ttfParser) ReadUShort() (val uint16) {
+	if !t.available(2) {
+		return
+	}
 	binary.Read(t.f, binary.BigEndian, &val)
 	return
 }
 
 func (t *ttfParser) ReadShort() (val int16) {
+	if !t.available(2) {
+		return
+	}
 	binary.Read(t.f, binary.BigEndian, &val)
 	return
 }
 
 func (t *ttfParser) ReadULong() (val uint32) {
+	if !t.available(4) {
+		return
+	}
 	binary.Read(t.f, binary.BigEndian, &val)
 	return
 }
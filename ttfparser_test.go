@@ -18,7 +18,10 @@ package gofpdf_test
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
+	"io/ioutil"
+	"os"
 
 	"github.com/Workiva/gofpdf"
 	"github.com/Workiva/gofpdf/internal/example"
@@ -45,6 +48,59 @@ func ExampleTtfParse() {
 	// Ymax:                  899
 }
 
+// This example demonstrates the errors reported for damaged font files. Each
+// file is a copy of a valid font that has been altered in a different way.
+func ExampleTtfParse_corrupt() {
+	data, err := ioutil.ReadFile(example.FontDir() + "/calligra.ttf")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// tableRec returns the position of the directory record for tag
+	tableRec := func(tag string) int {
+		for j := 0; j < int(binary.BigEndian.Uint16(data[4:])); j++ {
+			if string(data[12+16*j:16+16*j]) == tag {
+				return 12 + 16*j
+			}
+		}
+		return -1
+	}
+	alter := func(fnc func(b []byte) []byte) {
+		b := fnc(append([]byte(nil), data...))
+		file, err := ioutil.TempFile("", "ttf")
+		if err == nil {
+			file.Write(b)
+			file.Close()
+			_, err = gofpdf.TtfParse(file.Name())
+			os.Remove(file.Name())
+		}
+		fmt.Println(err)
+	}
+	alter(func(b []byte) []byte { return b[:len(b)/2] })
+	alter(func(b []byte) []byte {
+		rec := tableRec("cmap")
+		copy(b[rec:], "cmaq")
+		return b
+	})
+	alter(func(b []byte) []byte {
+		ofs := binary.BigEndian.Uint32(b[tableRec("hmtx")+8:])
+		b[ofs] ^= 0xFF
+		return b
+	})
+	alter(func(b []byte) []byte {
+		rec := tableRec("hhea")
+		ofs := binary.BigEndian.Uint32(b[rec+8:])
+		binary.BigEndian.PutUint32(b[rec+12:], 8)
+		binary.BigEndian.PutUint32(b[rec+4:], binary.BigEndian.Uint32(b[ofs:])+binary.BigEndian.Uint32(b[ofs+4:]))
+		return b
+	})
+	// Output:
+	// font table "OS/2" extends beyond the end of the file
+	// required font table "cmap" is missing
+	// font table "hmtx" is corrupt: checksum mismatch
+	// font table "hhea" is truncated
+}
+
 func hexStr(s string) string {
 	var b bytes.Buffer
 	b.WriteString("\"")
@@ -72,3 +128,69 @@ func ExampleFpdf_GetStringWidth() {
 	// "\xe4\xb8\x96\xe7\x95\x8c":      width  5.88, bytes  6, runes  2
 	// "\xe7\x61\x20\x76\x61\x3f":      width 12.47, bytes  6, runes  6
 }
+
+// This example demonstrates the parsing of a symbol font, which has a
+// Microsoft Symbol character map instead of a Unicode one. The font is a copy
+// of a regular font with the platform and encoding of its character maps
+// changed accordingly.
+func ExampleTtfParse_symbol() {
+	data, err := ioutil.ReadFile(example.FontDir() + "/calligra.ttf")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	be := binary.BigEndian
+	for j := 0; j < int(be.Uint16(data[4:])); j++ {
+		rec := 12 + 16*j
+		if string(data[rec:rec+4]) != "cmap" {
+			continue
+		}
+		ofs, n := int(be.Uint32(data[rec+8:])), int(be.Uint32(data[rec+12:]))
+		cmap := data[ofs : ofs+n]
+		for k := 0; k < int(be.Uint16(cmap[2:])); k++ {
+			// Windows Unicode becomes Windows Symbol, Unicode becomes ISO
+			switch be.Uint16(cmap[4+8*k:]) {
+			case 0:
+				be.PutUint16(cmap[4+8*k:], 2)
+			case 3:
+				be.PutUint16(cmap[6+8*k:], 0)
+			}
+		}
+		var sum uint32
+		for k := 0; k < n; k += 4 {
+			var word [4]byte
+			copy(word[:], cmap[k:])
+			sum += be.Uint32(word[:])
+		}
+		be.PutUint32(data[rec+4:], sum)
+	}
+	dir, err := ioutil.TempDir("", "ttf")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(dir+"/symbol.ttf", data, 0644)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ttf, err := gofpdf.TtfParse(dir + "/symbol.ttf")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Symbolic: %v\n", ttf.Symbolic)
+	pdf := gofpdf.New("P", "mm", "A4", dir)
+	pdf.AddFont("Symbol Calligrapher", "", "symbol.ttf")
+	pdf.SetFont("Symbol Calligrapher", "", 16)
+	// U+F041 - U+F043 select the same codes as "ABC"
+	w1 := pdf.GetStringWidth("\uF041\uF042\uF043")
+	w2 := pdf.GetStringWidth("ABC")
+	fmt.Printf("Widths match: %v\n", w1 == w2 && w1 > 0)
+	fmt.Println(pdf.Error())
+	// Output:
+	// Symbolic: true
+	// Widths match: true
+	// <nil>
+}
@@ -19,12 +19,16 @@ package gofpdf
 import (
 	"bytes"
 	"compress/zlib"
+	"encoding/binary"
 	"fmt"
 	// "github.com/davecgh/go-spew/spew"
 
 	"io"
 	"math"
 	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
 )
 
 func round(f float64) int {
@@ -80,12 +84,36 @@ func slicesEqual(a, b []float64) bool {
 	return true
 }
 
-// Returns a zlib-compressed copy of the specified byte array
-func sliceCompress(data []byte) []byte {
+// huffmanOnly is the value of zlib.HuffmanOnly, which is not defined before
+// Go 1.8
+const huffmanOnly = -2
+
+// zlibPools holds zlib writers for reuse, indexed by compression level from
+// huffmanOnly (-2) to zlib.BestCompression (9). Setting up a writer
+// allocates several hundred kilobytes, which would otherwise be repeated for
+// every page, image and font.
+var zlibPools [12]sync.Pool
+
+// Returns a copy of the specified byte array compressed with zlib at the
+// specified level
+func sliceCompress(data []byte, level int) []byte {
 	var buf bytes.Buffer
-	cmp, _ := zlib.NewWriterLevel(&buf, zlib.BestSpeed)
+	if level < huffmanOnly || level > zlib.BestCompression {
+		cmp, _ := zlib.NewWriterLevel(&buf, level)
+		cmp.Write(data)
+		cmp.Close()
+		return buf.Bytes()
+	}
+	pool := &zlibPools[level-huffmanOnly]
+	cmp, ok := pool.Get().(*zlib.Writer)
+	if ok {
+		cmp.Reset(&buf)
+	} else {
+		cmp, _ = zlib.NewWriterLevel(&buf, level)
+	}
 	cmp.Write(data)
 	cmp.Close()
+	pool.Put(cmp)
 	return buf.Bytes()
 }
 
@@ -104,16 +132,29 @@ func sliceUncompress(data []byte) (outData []byte, err error) {
 	return
 }
 
-// Convert UTF-8 to UTF-16BE with BOM; from http://www.fpdf.org/
-func utf8toutf16(s string) string {
+// Convert UTF-8 to UTF-16BE; from http://www.fpdf.org/. If withBOM is true,
+// the result begins with the byte order mark, as required for PDF text
+// strings such as the entries of the document information dictionary.
+// Contexts that specify the encoding themselves, such as the destination
+// strings of a ToUnicode CMap, require the raw form without it.
+func utf8toutf16(s string, withBOM bool) string {
 	res := make([]byte, 0, 8)
-	res = append(res, 0xFE, 0xFF)
+	if withBOM {
+		res = append(res, 0xFE, 0xFF)
+	}
 	nb := len(s)
 	i := 0
 	for i < nb {
 		c1 := byte(s[i])
 		i++
-		if c1 >= 224 {
+		if c1 >= 240 {
+			// 4-byte character, encoded as a surrogate pair
+			c2, c3, c4 := byte(s[i]), byte(s[i+1]), byte(s[i+2])
+			i += 3
+			r := (rune(c1&0x07)<<18 | rune(c2&0x3F)<<12 | rune(c3&0x3F)<<6 | rune(c4&0x3F)) - 0x10000
+			hi, lo := 0xD800+r>>10, 0xDC00+r&0x3FF
+			res = append(res, byte(hi>>8), byte(hi), byte(lo>>8), byte(lo))
+		} else if c1 >= 224 {
 			// 3-byte character
 			c2 := byte(s[i])
 			i++
@@ -135,6 +176,52 @@ func utf8toutf16(s string) string {
 	return string(res)
 }
 
+// textEncode prepares s for use as a PDF text string such as a document
+// property, outline title or layer name. A string that already begins with
+// the UTF-16BE byte order mark or that consists of ASCII characters only is
+// returned unchanged. Otherwise, valid UTF-8 is converted to UTF-16BE with
+// BOM, and anything else is assumed to be single-byte encoded and left as is.
+func textEncode(s string) string {
+	if strings.HasPrefix(s, "\xfe\xff") {
+		return s
+	}
+	for j := 0; j < len(s); j++ {
+		if s[j] >= 0x80 {
+			if utf8.ValidString(s) {
+				return utf8toutf16(s, true)
+			}
+			return s
+		}
+	}
+	return s
+}
+
+// cp1252High holds the Unicode code points of the cp1252 codes 0x80 through
+// 0x9F. The other codes coincide with those of ISO 8859-1.
+var cp1252High = [32]rune{
+	0x20AC, 0xFFFD, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0xFFFD, 0x017D, 0xFFFD,
+	0xFFFD, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0xFFFD, 0x017E, 0x0178,
+}
+
+// cp1252Decode converts the cp1252 encoded string s to UTF-8
+func cp1252Decode(s string) string {
+	var buf bytes.Buffer
+	for j := 0; j < len(s); j++ {
+		c := s[j]
+		switch {
+		case c < 0x80:
+			buf.WriteByte(c)
+		case c < 0xA0:
+			buf.WriteRune(cp1252High[c-0x80])
+		default:
+			buf.WriteRune(rune(c))
+		}
+	}
+	return buf.String()
+}
+
 // Return a if cnd is true, otherwise b
 func intIf(cnd bool, a, b int) int {
 	if cnd {
@@ -184,3 +271,32 @@ func (s *SizeType) ScaleToHeight(height float64) SizeType {
 	width := s.Wd * height / s.Ht
 	return SizeType{width, height}
 }
+
+// beReader reads big-endian values from binary data such as font tables.
+// Reads beyond the end of the data yield zeros and nil slices rather than
+// panicking.
+type beReader []byte
+
+// u16 returns the unsigned 16-bit value at pos
+func (b beReader) u16(pos int) int {
+	if pos < 0 || pos+2 > len(b) {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(b[pos:]))
+}
+
+// u32 returns the unsigned 32-bit value at pos
+func (b beReader) u32(pos int) int {
+	if pos < 0 || pos+4 > len(b) {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(b[pos:]))
+}
+
+// slice returns the n bytes at pos
+func (b beReader) slice(pos, n int) []byte {
+	if pos < 0 || n < 0 || pos+n > len(b) {
+		return nil
+	}
+	return b[pos : pos+n]
+}
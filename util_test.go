@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2013-2015 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestUtf8toutf16 converts characters of each UTF-8 sequence length with and
+// without the byte order mark.
+func TestUtf8toutf16(t *testing.T) {
+	for _, tc := range []struct {
+		s, utf16 string
+	}{
+		{"", ""},
+		{"A", "\x00A"},
+		{"é", "\x00\xe9"},
+		{"€", "\x20\xac"},
+		{"😀", "\xd8\x3d\xde\x00"},
+		{"Aé€😀", "\x00A\x00\xe9\x20\xac\xd8\x3d\xde\x00"},
+	} {
+		for _, withBOM := range []bool{false, true} {
+			want := tc.utf16
+			if withBOM {
+				want = "\xfe\xff" + want
+			}
+			if got := utf8toutf16(tc.s, withBOM); got != want {
+				t.Errorf("utf8toutf16(%q, %v) = %q, want %q", tc.s, withBOM, got, want)
+			}
+		}
+	}
+}
+
+// TestUtf8toutf16Callers checks that the ToUnicode CMap of a font receives
+// UTF-16 without the byte order mark and that the document properties
+// receive it exactly once.
+func TestUtf8toutf16Callers(t *testing.T) {
+	cmap := string(toUnicodeCMap(&fontType{UniDiff: []rune{'é', '😀'}}))
+	if !strings.Contains(cmap, "<80> <00E9>\n<81> <D83DDE00>\n") || strings.Contains(cmap, "FEFF") {
+		t.Errorf("unexpected ToUnicode mappings:\n%s", cmap)
+	}
+	pdf := New("P", "mm", "A4", "")
+	pdf.SetTitle("Grüße", true)
+	pdf.SetAuthor("Zoë", true)
+	for _, str := range []string{pdf.title, pdf.author, pdf.textstring(pdf.title)} {
+		if n := strings.Count(str, "\xfe\xff"); n != 1 {
+			t.Errorf("%q has %d byte order marks, want 1", str, n)
+		}
+	}
+}
+
+// TestDocumentPropertyEncoding checks that document properties set as
+// ISO-8859-1 are written as they are, even if their bytes happen to form
+// valid UTF-8
+func TestDocumentPropertyEncoding(t *testing.T) {
+	pdf := New("P", "mm", "A4", "")
+	pdf.SetCompression(false)
+	pdf.SetTitle("Zo\xc3\xab", false)
+	pdf.SetAuthor("Zo\xc3\xab", true)
+	pdf.AddPage()
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []string{"/Title (Zo\xc3\xab)", "/Author (\xfe\xff\\000Z\\000o\\000\xeb)"} {
+		if !strings.Contains(buf.String(), entry) {
+			t.Errorf("document does not contain %q", entry)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2014 Kurt Jung (Gmail: kurt.w.jung)
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package gofpdf
+
+// WordPositionType describes a word printed on a page while word positions
+// are recorded. See SetWordPositions().
+type WordPositionType struct {
+	Page   int     // page on which the word is printed
+	Text   string  // the word in UTF-8
+	X, Y   float64 // upper left corner of the bounding box, lower left if the origin is at the bottom of the page
+	Wd, Ht float64 // extent of the bounding box
+}
+
+// SetWordPositions turns the recording of word positions on or off. While it
+// is on, the text and bounding box of each word printed with Cell(),
+// CellFormat(), MultiCell(), Write() and the methods based on them is
+// recorded. The positions can be retrieved with GetWordPositions(), for
+// example to build an overlay that highlights the results of a search. A word
+// is a sequence of characters separated by spaces; non-breaking spaces do not
+// separate words.
+//
+// The bounding box of a word extends horizontally from the start of its first
+// character to the end of its last one and vertically over the font size, as
+// with the clickable area of a link in a cell. Coordinates and extents are in
+// the units established in New(), measured from the origin in effect when the
+// word is printed, so that a box can be drawn over each word with Rect().
+func (f *Fpdf) SetWordPositions(on bool) {
+	f.wordPositions = on
+}
+
+// GetWordPositions returns the positions of the words recorded while word
+// positions are turned on with SetWordPositions(), in the order in which they
+// were printed. Page numbers reflect any pages that have since been moved or
+// deleted.
+func (f *Fpdf) GetWordPositions() []WordPositionType {
+	return append([]WordPositionType(nil), f.wordList...)
+}
+
+// recordWords records the positions of the words of txtStr, printed by
+// CellFormat() in the current font starting at x on a line whose box in the
+// font size starts at y, both in user units from the upper left corner of the
+// page
+func (f *Fpdf) recordWords(txtStr string, x, y float64) {
+	// Word spacing is scaled along with the characters
+	ws := f.ws * f.textScale / 100
+	ht := f.fontSize
+	if f.originBottom {
+		y = f.h - y - ht
+	}
+	// The offset of each word is accumulated as the line is scanned, rather
+	// than measuring the text that precedes every word
+	spaceWd := f.GetStringWidth(" ") + ws
+	start := -1
+	for j := 0; j <= len(txtStr); j++ {
+		if j < len(txtStr) && txtStr[j] != ' ' {
+			if start < 0 {
+				start = j
+			}
+			continue
+		}
+		if start >= 0 {
+			wordStr := txtStr[start:j]
+			wd := f.GetStringWidth(wordStr)
+			f.wordList = append(f.wordList, WordPositionType{
+				Page: f.page,
+				Text: f.cellText(wordStr),
+				X:    x,
+				Y:    y,
+				Wd:   wd,
+				Ht:   ht,
+			})
+			x += wd
+			start = -1
+		}
+		x += spaceWd
+	}
+}